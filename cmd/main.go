@@ -13,6 +13,7 @@ import (
 	"github.com/learn/api-shop/internal/infra"
 	"github.com/learn/api-shop/internal/repo"
 	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
 	"github.com/sirupsen/logrus"
 	"go.uber.org/dig"
 )
@@ -27,13 +28,49 @@ func main() {
 
 	container.Provide(infra.LoadPgDatabaseCfg)
 	container.Provide(infra.LoadMuxCfg)
+	container.Provide(infra.LoadCurrency)
+	container.Provide(infra.LoadRewardStockPolicy)
+	container.Provide(infra.LoadCheckoutLimits)
+	container.Provide(infra.LoadReservationPolicy)
+	container.Provide(infra.LoadCartPolicy)
+	container.Provide(clock.New)
 	container.Provide(infra.LoadHttpServer)
 	container.Provide(infra.NewDatabases)
 	container.Provide(infra.NewMux)
 	container.Provide(repo.NewOrderRepository)
 	container.Provide(repo.NewProductRepository)
 	container.Provide(repo.NewPromoRepository)
+	container.Provide(repo.NewStockMovementRepository)
+	container.Provide(repo.NewReservationRepository)
+	container.Provide(repo.NewIdempotencyRepository)
+	container.Provide(repo.NewCartRepository)
+	container.Provide(repo.NewCouponRepository)
+	container.Provide(repo.NewOrderPromoRepository)
+	container.Provide(repo.NewBundleRepository)
+	container.Provide(service.NewDiscountPromotion, dig.Group(service.PromotionGroup))
+	container.Provide(service.NewFreeUnitPromotion, dig.Group(service.PromotionGroup))
+	container.Provide(service.NewGiftPromotion, dig.Group(service.PromotionGroup))
+	container.Provide(service.NewProductPromotion, dig.Group(service.PromotionGroup))
+	container.Provide(service.NewBuyGetPromotion, dig.Group(service.PromotionGroup))
+	container.Provide(service.NewTieredPromotion, dig.Group(service.PromotionGroup))
+	container.Provide(service.NewOrderDiscountPromotion, dig.Group(service.OrderPromotionGroup))
+	container.Provide(service.NewOrderAmountPromotion, dig.Group(service.OrderPromotionGroup))
+	container.Provide(service.NewOrderGiftPromotion, dig.Group(service.OrderPromotionGroup))
+	container.Provide(service.NewPriceBundle, dig.Group(service.BundleGroup))
+	container.Provide(service.NewDiscountBundle, dig.Group(service.BundleGroup))
+	container.Provide(service.NewPromotionRegistry)
 	container.Provide(service.NewCheckoutUsecase)
+	container.Provide(service.NewCatalogUsecase)
+	container.Provide(service.NewOrderUsecase)
+	container.Provide(service.NewOrderStatusUsecase)
+	container.Provide(service.NewPromoUsecase)
+	container.Provide(service.NewProductUsecase)
+	container.Provide(service.NewStockUsecase)
+	container.Provide(service.NewCartUsecase)
+	container.Provide(service.NewCouponUsecase)
+	container.Provide(service.NewOrderPromoUsecase)
+	container.Provide(service.NewBundleUsecase)
+	container.Provide(service.NewReservationSweeper)
 
 	if err := container.Invoke(controller.NewCheckoutHandler); err != nil {
 		logrus.Fatal(err.Error())
@@ -58,7 +95,7 @@ func startApp(di *dig.Container) error {
 		}
 	}()
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
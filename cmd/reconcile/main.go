@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/learn/api-shop/internal/infra"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/sirupsen/logrus"
+)
+
+// reconcile recomputes every product's qty from the stock ledger and lists
+// the products whose qty drifted from it. It exits with status 1 on drift.
+func main() {
+	err := godotenv.Load()
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+
+	cfg, err := infra.LoadPgDatabaseCfg()
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+
+	dbs := infra.NewDatabases(infra.DatabaseCfgs{Pg: cfg})
+	defer dbs.Pg.Close()
+
+	stockUsecase := service.NewStockUsecase(service.StockUsecaseImpl{
+		StockMovementRepo: repo.NewStockMovementRepository(repo.StockMovementRepoImpl{DB: dbs.Pg}),
+	})
+
+	drift, err := stockUsecase.ReconcileStock(context.Background())
+	if err != nil {
+		logrus.Fatal(err.Error())
+	}
+
+	if len(drift) == 0 {
+		fmt.Println("stock is in line with the ledger")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRODUCT ID\tSKU\tNAME\tQTY\tLEDGER QTY\tDRIFT")
+	for _, v := range drift {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%d\n", v.ProductID, v.Sku, v.Name, v.Qty, v.LedgerQty, v.Qty-v.LedgerQty)
+	}
+	w.Flush()
+
+	os.Exit(1)
+}
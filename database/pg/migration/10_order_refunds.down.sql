@@ -0,0 +1,11 @@
+DROP TABLE order_refunds;
+
+ALTER TABLE order_details
+	DROP CONSTRAINT order_details_refunded_qty_check;
+ALTER TABLE order_details
+	DROP COLUMN refunded_qty,
+	DROP COLUMN refunded_amount;
+
+ALTER TABLE orders
+	DROP COLUMN status,
+	DROP COLUMN refunded_total;
@@ -0,0 +1,26 @@
+ALTER TABLE orders
+	ADD COLUMN status varchar(32) NOT NULL DEFAULT 'placed',
+	ADD COLUMN refunded_total numeric(50, 3) NOT NULL DEFAULT 0;
+
+ALTER TABLE order_details
+	ADD COLUMN refunded_qty int4 NOT NULL DEFAULT 0,
+	ADD COLUMN refunded_amount numeric(50, 3) NOT NULL DEFAULT 0;
+ALTER TABLE order_details
+	ADD CONSTRAINT order_details_refunded_qty_check CHECK (refunded_qty >= 0 AND refunded_qty <= qty);
+
+-- one row per refunded line, a cancellation refunds every line left
+CREATE TABLE order_refunds (
+	refund_id bigserial NOT NULL,
+	order_id int8 NOT NULL,
+	order_detail_id int8 NOT NULL,
+	product_id int8 NOT NULL,
+	qty int4 NOT NULL,
+	amount numeric(50, 3) NOT NULL,
+	reason varchar(255) NOT NULL DEFAULT '',
+	actor varchar(255) NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT refund_id_pkey PRIMARY KEY (refund_id),
+	CONSTRAINT order_refunds_qty_check CHECK (qty > 0)
+);
+
+CREATE INDEX order_refunds_order_id_idx ON order_refunds (order_id);
@@ -0,0 +1,5 @@
+DROP TABLE order_status_history;
+
+UPDATE orders SET status = 'placed' WHERE status IN ('pending_payment', 'paid', 'fulfilled', 'delivered');
+ALTER TABLE orders
+	ALTER COLUMN status SET DEFAULT 'placed';
@@ -0,0 +1,18 @@
+-- checkout now leaves orders waiting for payment; orders placed before were
+-- already settled
+ALTER TABLE orders
+	ALTER COLUMN status SET DEFAULT 'pending_payment';
+UPDATE orders SET status = 'paid' WHERE status = 'placed';
+
+CREATE TABLE order_status_history (
+	history_id bigserial NOT NULL,
+	order_id int8 NOT NULL,
+	from_status varchar(32) NOT NULL DEFAULT '',
+	to_status varchar(32) NOT NULL,
+	actor varchar(255) NOT NULL DEFAULT '',
+	note varchar(255) NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT history_id_pkey PRIMARY KEY (history_id)
+);
+
+CREATE INDEX order_status_history_order_id_idx ON order_status_history (order_id, history_id);
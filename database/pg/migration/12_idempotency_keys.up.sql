@@ -0,0 +1,10 @@
+-- a checkout retried with the same key gets the response stored here instead
+-- of placing a second order
+CREATE TABLE idempotency_keys (
+	idempotency_key varchar(255) NOT NULL,
+	request_hash varchar(64) NOT NULL,
+	response jsonb NULL,
+	order_id int8 NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT idempotency_key_pkey PRIMARY KEY (idempotency_key)
+);
@@ -0,0 +1,21 @@
+-- a cart without customer_id belongs to a guest; an open cart expires once
+-- it has been idle longer than CART_IDLE_TTL
+CREATE TABLE carts (
+	cart_id bigserial NOT NULL,
+	customer_id varchar(255) NOT NULL DEFAULT '',
+	status varchar(32) NOT NULL DEFAULT 'open',
+	order_id int8 NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	updated_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT cart_id_pkey PRIMARY KEY (cart_id)
+);
+
+CREATE TABLE cart_items (
+	cart_item_id bigserial NOT NULL,
+	cart_id int8 NOT NULL,
+	product_id int8 NOT NULL,
+	qty int4 NOT NULL,
+	CONSTRAINT cart_item_id_pkey PRIMARY KEY (cart_item_id),
+	CONSTRAINT cart_items_cart_id_product_id_key UNIQUE (cart_id, product_id),
+	CONSTRAINT cart_items_qty_check CHECK (qty > 0)
+);
@@ -0,0 +1,8 @@
+ALTER TABLE promos
+	DROP CONSTRAINT promos_promo_type_check;
+
+UPDATE promos SET promo_type = 'product' WHERE promo_type IN ('free_unit', 'gift');
+
+CREATE TYPE promo_type_enum as enum('product', 'discount');
+ALTER TABLE promos
+	ALTER COLUMN promo_type TYPE promo_type_enum USING promo_type::promo_type_enum;
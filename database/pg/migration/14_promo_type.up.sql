@@ -0,0 +1,11 @@
+-- promo kinds are registered in the service now, so the column only checks
+-- that a type looks like a kind name; the service validates it on write
+ALTER TABLE promos
+	ALTER COLUMN promo_type TYPE varchar(64) USING promo_type::text;
+DROP TYPE promo_type_enum;
+
+UPDATE promos SET promo_type = 'free_unit' WHERE promo_type = 'product' AND reward = product_id;
+UPDATE promos SET promo_type = 'gift' WHERE promo_type = 'product';
+
+ALTER TABLE promos
+	ADD CONSTRAINT promos_promo_type_check CHECK (promo_type ~ '^[a-z][a-z0-9_]*$');
@@ -0,0 +1,30 @@
+-- codes are stored upper case; a zero usage_limit or per_customer_limit means
+-- the coupon can be redeemed any number of times
+CREATE TABLE coupons (
+	coupon_id bigserial NOT NULL,
+	code varchar(32) NOT NULL,
+	coupon_type varchar(32) NOT NULL,
+	reward numeric(50, 3) NOT NULL,
+	min_basket numeric(50, 3) NOT NULL DEFAULT 0,
+	usage_limit int4 NOT NULL DEFAULT 0,
+	per_customer_limit int4 NOT NULL DEFAULT 0,
+	active bool NOT NULL DEFAULT true,
+	expires_at timestamp NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT coupon_id_pkey PRIMARY KEY (coupon_id),
+	CONSTRAINT coupons_code_key UNIQUE (code),
+	CONSTRAINT coupons_limits_check CHECK (usage_limit >= 0 AND per_customer_limit >= 0)
+);
+
+-- one row per coupon an order redeemed, written in the checkout transaction
+CREATE TABLE coupon_redemptions (
+	redemption_id bigserial NOT NULL,
+	coupon_id int8 NOT NULL,
+	order_id int8 NOT NULL,
+	customer_id varchar(255) NOT NULL DEFAULT '',
+	discount numeric(50, 3) NOT NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT redemption_id_pkey PRIMARY KEY (redemption_id)
+);
+
+CREATE INDEX coupon_redemptions_coupon_id_customer_id_idx ON coupon_redemptions (coupon_id, customer_id);
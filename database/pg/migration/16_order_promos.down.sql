@@ -0,0 +1,4 @@
+DROP TABLE order_discounts;
+ALTER TABLE order_details
+	DROP COLUMN order_promo_id;
+DROP TABLE order_promos;
@@ -0,0 +1,34 @@
+-- order promos run once every line is priced; a promo applies when the order
+-- reaches every threshold that is set, a zero threshold is not checked
+CREATE TABLE order_promos (
+	order_promo_id bigserial NOT NULL,
+	promo_type varchar(64) NOT NULL,
+	reward numeric(50, 3) NOT NULL,
+	min_subtotal numeric(50, 3) NOT NULL DEFAULT 0,
+	min_items int4 NOT NULL DEFAULT 0,
+	priority int4 NOT NULL DEFAULT 0,
+	stack_group varchar(64) NOT NULL DEFAULT '',
+	active bool NOT NULL DEFAULT true,
+	starts_at timestamp NULL,
+	ends_at timestamp NULL,
+	timezone varchar(64) NOT NULL DEFAULT '',
+	CONSTRAINT order_promo_id_pkey PRIMARY KEY (order_promo_id),
+	CONSTRAINT order_promos_thresholds_check CHECK (min_subtotal >= 0 AND min_items >= 0),
+	CONSTRAINT order_promos_window_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+-- free items given by an order promo are stored as order details under it
+ALTER TABLE order_details
+	ADD COLUMN order_promo_id int8 NOT NULL DEFAULT 0;
+
+-- one row per order promo that took an amount off an order
+CREATE TABLE order_discounts (
+	order_discount_id bigserial NOT NULL,
+	order_id int8 NOT NULL,
+	order_promo_id int8 NOT NULL,
+	promo_type varchar(64) NOT NULL,
+	discount numeric(50, 3) NOT NULL,
+	CONSTRAINT order_discount_id_pkey PRIMARY KEY (order_discount_id)
+);
+
+CREATE INDEX order_discounts_order_id_idx ON order_discounts (order_id);
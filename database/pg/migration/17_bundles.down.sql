@@ -0,0 +1,2 @@
+DROP TABLE bundle_items;
+DROP TABLE bundles;
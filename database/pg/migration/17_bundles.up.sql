@@ -0,0 +1,24 @@
+-- a bundle is priced as a whole: reward is the bundle's price or a percent
+-- off what its items cost, depending on bundle_type
+CREATE TABLE bundles (
+	bundle_id bigserial NOT NULL,
+	name varchar(255) NOT NULL,
+	bundle_type varchar(64) NOT NULL,
+	reward numeric(50, 3) NOT NULL,
+	active bool NOT NULL DEFAULT true,
+	starts_at timestamp NULL,
+	ends_at timestamp NULL,
+	timezone varchar(64) NOT NULL DEFAULT '',
+	CONSTRAINT bundle_id_pkey PRIMARY KEY (bundle_id),
+	CONSTRAINT bundles_reward_check CHECK (reward > 0),
+	CONSTRAINT bundles_window_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+-- the products, and how many of each, one application of a bundle takes
+CREATE TABLE bundle_items (
+	bundle_id int8 NOT NULL,
+	product_id int8 NOT NULL,
+	qty int4 NOT NULL,
+	CONSTRAINT bundle_items_pkey PRIMARY KEY (bundle_id, product_id),
+	CONSTRAINT bundle_items_qty_check CHECK (qty > 0)
+);
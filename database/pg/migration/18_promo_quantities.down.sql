@@ -0,0 +1,8 @@
+-- buy_get and tiered promos can't be expressed without free_qty and tiers,
+-- and 14_promo_type.down.sql can't cast them back to promo_type_enum
+DELETE FROM promos WHERE promo_type IN ('buy_get', 'tiered');
+
+ALTER TABLE promos
+	DROP CONSTRAINT promos_free_qty_check,
+	DROP COLUMN free_qty,
+	DROP COLUMN tiers;
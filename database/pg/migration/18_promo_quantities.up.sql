@@ -0,0 +1,6 @@
+-- free_qty is how many units of every min_qty a buy_get promo gives away,
+-- tiers are the qty break points of a tiered promo as [{min_qty, percent}]
+ALTER TABLE promos
+	ADD COLUMN free_qty int4 NOT NULL DEFAULT 0,
+	ADD COLUMN tiers jsonb NOT NULL DEFAULT '[]',
+	ADD CONSTRAINT promos_free_qty_check CHECK (free_qty >= 0);
@@ -0,0 +1,2 @@
+-- the gifts 19_free_unit_min_qty.up.sql wrote can't be told apart from ones
+-- created since, and are valid either way
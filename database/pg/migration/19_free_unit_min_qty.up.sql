@@ -0,0 +1,5 @@
+-- a free_unit promo with min_qty 1 makes every unit of the line free;
+-- 14_promo_type.up.sql turned the old product promos rewarding their own
+-- product into such rows, which gave one unit away with the line, so they
+-- become gifts of their own product
+UPDATE promos SET promo_type = 'gift' WHERE promo_type = 'free_unit' AND min_qty < 2;
@@ -0,0 +1,11 @@
+DROP INDEX coupon_redemptions_coupon_id_idx;
+
+ALTER TABLE coupon_redemptions
+	ADD COLUMN customer_id varchar(255) NOT NULL DEFAULT '';
+
+CREATE INDEX coupon_redemptions_coupon_id_customer_id_idx ON coupon_redemptions (coupon_id, customer_id);
+
+ALTER TABLE coupons
+	DROP CONSTRAINT coupons_limits_check,
+	ADD COLUMN per_customer_limit int4 NOT NULL DEFAULT 0,
+	ADD CONSTRAINT coupons_limits_check CHECK (usage_limit >= 0 AND per_customer_limit >= 0);
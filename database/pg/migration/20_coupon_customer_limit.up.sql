@@ -0,0 +1,10 @@
+-- the customer a checkout names can't be trusted, so coupons are only limited
+-- overall; dropping per_customer_limit drops coupons_limits_check with it
+ALTER TABLE coupons
+	DROP COLUMN per_customer_limit,
+	ADD CONSTRAINT coupons_limits_check CHECK (usage_limit >= 0);
+
+ALTER TABLE coupon_redemptions
+	DROP COLUMN customer_id;
+
+CREATE INDEX coupon_redemptions_coupon_id_idx ON coupon_redemptions (coupon_id);
@@ -0,0 +1,2 @@
+ALTER TABLE order_details
+	DROP COLUMN coupon_id;
@@ -0,0 +1,16 @@
+-- free items given by a coupon are stored as order details under it
+ALTER TABLE order_details
+	ADD COLUMN coupon_id int8 NOT NULL DEFAULT 0;
+
+-- the free lines product coupons gave so far carry neither a promo nor an
+-- order promo; the order's redemption of a coupon rewarding the product
+-- tells which coupon it was
+UPDATE order_details od SET coupon_id = c.coupon_id
+	FROM coupon_redemptions cr
+	JOIN coupons c ON c.coupon_id = cr.coupon_id
+	WHERE cr.order_id = od.order_id
+		AND c.coupon_type = 'product'
+		AND c.reward = od.product_id
+		AND od.price = 0
+		AND od.promo_id = 0
+		AND od.order_promo_id = 0;
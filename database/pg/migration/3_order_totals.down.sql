@@ -0,0 +1,4 @@
+ALTER TABLE orders
+	DROP COLUMN subtotal,
+	DROP COLUMN discount_total,
+	DROP COLUMN item_count;
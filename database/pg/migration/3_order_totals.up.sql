@@ -0,0 +1,22 @@
+ALTER TABLE orders
+	ADD COLUMN subtotal numeric(50, 3) NOT NULL DEFAULT 0,
+	ADD COLUMN discount_total numeric(50, 3) NOT NULL DEFAULT 0,
+	ADD COLUMN item_count int4 NOT NULL DEFAULT 0;
+
+-- order_details.price holds the discounted line total, the undiscounted
+-- subtotal is rebuilt from the current product price
+UPDATE orders o SET
+	total = d.total,
+	subtotal = greatest(d.subtotal, d.total),
+	discount_total = greatest(d.subtotal - d.total, 0),
+	item_count = d.item_count
+FROM (
+	SELECT od.order_id,
+		sum(od.price) AS total,
+		sum(coalesce(p.price, 0) * od.qty) AS subtotal,
+		sum(od.qty) AS item_count
+	FROM order_details od
+	LEFT JOIN products p ON p.product_id = od.product_id
+	GROUP BY od.order_id
+) d
+WHERE d.order_id = o.order_id;
@@ -0,0 +1,2 @@
+ALTER TABLE products
+	DROP CONSTRAINT products_qty_check;
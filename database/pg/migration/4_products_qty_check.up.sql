@@ -0,0 +1,6 @@
+-- stock that already went negative through the old unconditional decrement
+-- has to be cleared before the constraint can be validated
+UPDATE products SET qty = 0 WHERE qty < 0;
+
+ALTER TABLE products
+	ADD CONSTRAINT products_qty_check CHECK (qty >= 0);
@@ -0,0 +1,6 @@
+DROP INDEX promos_product_id_priority_idx;
+
+ALTER TABLE promos
+	DROP COLUMN priority,
+	DROP COLUMN exclusive,
+	DROP COLUMN stack_group;
@@ -0,0 +1,6 @@
+ALTER TABLE promos
+	ADD COLUMN priority int4 NOT NULL DEFAULT 0,
+	ADD COLUMN exclusive bool NOT NULL DEFAULT false,
+	ADD COLUMN stack_group varchar(64) NOT NULL DEFAULT '';
+
+CREATE INDEX promos_product_id_priority_idx ON promos (product_id, priority DESC, promo_id);
@@ -0,0 +1,6 @@
+ALTER TABLE promos
+	DROP CONSTRAINT promos_window_check,
+	DROP COLUMN active,
+	DROP COLUMN starts_at,
+	DROP COLUMN ends_at,
+	DROP COLUMN timezone;
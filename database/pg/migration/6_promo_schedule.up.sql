@@ -0,0 +1,8 @@
+-- starts_at and ends_at are wall clock times in the promo's timezone, so a
+-- weekend sale starts at local midnight wherever the promo runs
+ALTER TABLE promos
+	ADD COLUMN active bool NOT NULL DEFAULT true,
+	ADD COLUMN starts_at timestamp NULL,
+	ADD COLUMN ends_at timestamp NULL,
+	ADD COLUMN timezone varchar(64) NOT NULL DEFAULT '',
+	ADD CONSTRAINT promos_window_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at);
@@ -0,0 +1,4 @@
+DROP INDEX products_sku_key;
+
+ALTER TABLE products
+	DROP COLUMN archived_at;
@@ -0,0 +1,6 @@
+ALTER TABLE products
+	ADD COLUMN archived_at timestamp NULL;
+
+-- skus were only unique by convention until products could be created
+-- through the API
+CREATE UNIQUE INDEX products_sku_key ON products (sku);
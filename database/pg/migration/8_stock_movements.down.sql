@@ -0,0 +1,5 @@
+DROP TRIGGER stock_movements_append_only ON stock_movements;
+
+DROP FUNCTION stock_movements_append_only();
+
+DROP TABLE stock_movements;
@@ -0,0 +1,28 @@
+CREATE TABLE stock_movements (
+	movement_id bigserial NOT NULL,
+	product_id int8 NOT NULL,
+	delta int4 NOT NULL,
+	reason varchar(32) NOT NULL,
+	order_id int8 NULL,
+	actor varchar(255) NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT movement_id_pkey PRIMARY KEY (movement_id),
+	CONSTRAINT stock_movements_delta_check CHECK (delta <> 0)
+);
+
+CREATE INDEX stock_movements_product_id_idx ON stock_movements (product_id, movement_id DESC);
+
+-- the ledger is append-only, a wrong movement is corrected by another one
+CREATE FUNCTION stock_movements_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_movements_append_only
+	BEFORE UPDATE OR DELETE ON stock_movements
+	FOR EACH ROW EXECUTE PROCEDURE stock_movements_append_only();
+
+-- the stock on hand when the ledger starts is its opening balance
+INSERT INTO stock_movements (product_id, delta, reason, actor)
+	SELECT product_id, qty, 'opening', 'migration' FROM products WHERE qty <> 0;
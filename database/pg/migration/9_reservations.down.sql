@@ -0,0 +1,8 @@
+DROP TABLE reservation_items;
+
+DROP TABLE reservations;
+
+ALTER TABLE products
+	DROP CONSTRAINT products_reserved_qty_check;
+ALTER TABLE products
+	DROP COLUMN reserved_qty;
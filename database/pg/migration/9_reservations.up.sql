@@ -0,0 +1,30 @@
+-- held stock stays on hand but can't be sold to anyone else until the hold
+-- is confirmed, released or expired
+ALTER TABLE products
+	ADD COLUMN reserved_qty int4 NOT NULL DEFAULT 0;
+ALTER TABLE products
+	ADD CONSTRAINT products_reserved_qty_check CHECK (reserved_qty >= 0 AND reserved_qty <= qty);
+
+CREATE TABLE reservations (
+	reservation_id bigserial NOT NULL,
+	status varchar(16) NOT NULL DEFAULT 'active',
+	expires_at timestamp NOT NULL,
+	order_id int8 NULL,
+	created_at timestamp NOT NULL DEFAULT now(),
+	updated_at timestamp NOT NULL DEFAULT now(),
+	CONSTRAINT reservation_id_pkey PRIMARY KEY (reservation_id)
+);
+
+-- the sweeper only looks for active holds past their expiry
+CREATE INDEX reservations_expires_at_idx ON reservations (expires_at) WHERE status = 'active';
+
+CREATE TABLE reservation_items (
+	reservation_item_id bigserial NOT NULL,
+	reservation_id int8 NOT NULL,
+	product_id int8 NOT NULL,
+	qty int4 NOT NULL,
+	CONSTRAINT reservation_item_id_pkey PRIMARY KEY (reservation_item_id),
+	CONSTRAINT reservation_items_qty_check CHECK (qty > 0)
+);
+
+CREATE INDEX reservation_items_reservation_id_idx ON reservation_items (reservation_id);
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+var bundleItemType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "BundleItem",
+	Fields: graphql.Fields{
+		"product_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"qty": &graphql.Field{
+			Type: graphql.Int,
+		},
+	},
+})
+
+var bundleType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Bundle",
+	Fields: graphql.Fields{
+		"bundle_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"bundle_type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"reward": &graphql.Field{
+			Type: decimalType,
+		},
+		"active": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"starts_at": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+		"ends_at": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+		"timezone": &graphql.Field{
+			Type: graphql.String,
+		},
+		"items": &graphql.Field{
+			Type: graphql.NewList(bundleItemType),
+		},
+	},
+})
+
+// appliedBundleType is shared by the checkout and the quote.
+var appliedBundleType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AppliedBundle",
+	Fields: graphql.Fields{
+		"bundle_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"bundle_type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"times": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"discount": &graphql.Field{
+			Type: decimalType,
+		},
+	},
+})
+
+func bundleQueryFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	return graphql.Fields{
+		"bundles": &graphql.Field{
+			Type: graphql.NewList(bundleType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.BundleSvc.GetBundles(p.Context)
+			},
+		},
+		"bundle": &graphql.Field{
+			Type: bundleType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				bundle, err := handler.BundleSvc.GetBundleByBundleID(p.Context, int64(p.Args["id"].(int)))
+				if err != nil || bundle.BundleID == 0 {
+					return nil, err
+				}
+
+				return bundle, nil
+			},
+		},
+	}
+}
+
+func bundleMutationFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	bundleItemInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "BundleItemInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"product_id": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"qty": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Int,
+				DefaultValue: 1,
+			},
+		},
+	})
+
+	// starts_at and ends_at are read as wall clock times in timezone, like
+	// the ones of a product promo.
+	bundleInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "BundleInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"bundle_type": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"reward": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(decimalType),
+			},
+			"items": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bundleItemInputType))),
+			},
+			"active": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: true,
+			},
+			"starts_at": &graphql.InputObjectFieldConfig{
+				Type: graphql.DateTime,
+			},
+			"ends_at": &graphql.InputObjectFieldConfig{
+				Type: graphql.DateTime,
+			},
+			"timezone": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"createBundle": &graphql.Field{
+			Type: bundleType,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(bundleInputType),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := bundleFromInput(p.Args["input"].(map[string]interface{}))
+				return handler.BundleSvc.CreateBundle(p.Context, form)
+			},
+		},
+		"deactivateBundle": &graphql.Field{
+			Type: bundleType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.BundleSvc.DeactivateBundle(p.Context, int64(p.Args["id"].(int)))
+			},
+		},
+	}
+}
+
+func bundleFromInput(input map[string]interface{}) repo.Bundle {
+	form := repo.Bundle{
+		Name:       input["name"].(string),
+		BundleType: input["bundle_type"].(string),
+		Reward:     input["reward"].(money.Decimal),
+		Active:     input["active"].(bool),
+	}
+	form.Timezone, _ = input["timezone"].(string)
+
+	for _, v := range input["items"].([]interface{}) {
+		item := v.(map[string]interface{})
+		form.Items = append(form.Items, repo.BundleItem{
+			ProductID: int64(item["product_id"].(int)),
+			Qty:       int64(item["qty"].(int)),
+		})
+	}
+
+	if startsAt, ok := input["starts_at"].(time.Time); ok {
+		form.StartsAt = &startsAt
+	}
+
+	if endsAt, ok := input["ends_at"].(time.Time); ok {
+		form.EndsAt = &endsAt
+	}
+
+	return form
+}
@@ -0,0 +1,121 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBundleAdmin(t *testing.T) {
+	smartHome := repo.Bundle{
+		BundleID:   2,
+		Name:       "Smart home",
+		BundleType: "discount",
+		Reward:     money.MustParse("15"),
+		Active:     true,
+		Items:      []repo.BundleItem{{BundleID: 2, ProductID: 1, Qty: 1}, {BundleID: 2, ProductID: 3, Qty: 1}},
+	}
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(bundleSvc *mockSvc.BundleUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "list running bundles",
+			requestString: `{ bundles { bundle_id name reward items { product_id qty } } }`,
+			mockSetupFunc: func(bundleSvc *mockSvc.BundleUsecase) {
+				bundleSvc.On("GetBundles", mock.Anything).Return([]repo.Bundle{smartHome}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"bundles": []interface{}{
+					map[string]interface{}{
+						"bundle_id": 2,
+						"name":      "Smart home",
+						"reward":    "15",
+						"items": []interface{}{
+							map[string]interface{}{"product_id": 1, "qty": 1},
+							map[string]interface{}{"product_id": 3, "qty": 1},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "unknown bundle is null",
+			requestString: `{ bundle(id: 99) { bundle_id } }`,
+			mockSetupFunc: func(bundleSvc *mockSvc.BundleUsecase) {
+				bundleSvc.On("GetBundleByBundleID", mock.Anything, int64(99)).Return(repo.Bundle{}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"bundle": nil,
+			},
+		},
+		{
+			name:          "create bundle with defaults",
+			requestString: `mutation { createBundle(input: {name: "Smart home", bundle_type: "discount", reward: "15", items: [{product_id: 1}, {product_id: 3}]}) { bundle_id active } }`,
+			mockSetupFunc: func(bundleSvc *mockSvc.BundleUsecase) {
+				form := repo.Bundle{Name: "Smart home", BundleType: "discount", Reward: money.MustParse("15"), Active: true, Items: []repo.BundleItem{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 1}}}
+				bundleSvc.On("CreateBundle", mock.Anything, form).Return(smartHome, nil)
+			},
+			expectedData: map[string]interface{}{
+				"createBundle": map[string]interface{}{"bundle_id": 2, "active": true},
+			},
+		},
+		{
+			name:          "invalid bundle",
+			requestString: `mutation { createBundle(input: {name: "Smart home", bundle_type: "discount", reward: "150", items: [{product_id: 1}, {product_id: 3}]}) { bundle_id } }`,
+			mockSetupFunc: func(bundleSvc *mockSvc.BundleUsecase) {
+				bundleSvc.On("CreateBundle", mock.Anything, mock.Anything).Return(repo.Bundle{}, service.ErrInvalidPromo)
+			},
+			wantErr: true,
+		},
+		{
+			name:          "deactivate bundle",
+			requestString: `mutation { deactivateBundle(id: 2) { bundle_id active } }`,
+			mockSetupFunc: func(bundleSvc *mockSvc.BundleUsecase) {
+				deactivated := smartHome
+				deactivated.Active = false
+				bundleSvc.On("DeactivateBundle", mock.Anything, int64(2)).Return(deactivated, nil)
+			},
+			expectedData: map[string]interface{}{
+				"deactivateBundle": map[string]interface{}{"bundle_id": 2, "active": false},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bundleSvc := new(mockSvc.BundleUsecase)
+			tc.mockSetupFunc(bundleSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				BundleSvc: bundleSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			bundleSvc.AssertExpectations(t)
+		})
+	}
+}
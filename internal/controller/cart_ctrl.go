@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+)
+
+func newCartType() *graphql.Object {
+	cartItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CartItem",
+		Fields: graphql.Fields{
+			"product_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"qty": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Cart",
+		Fields: graphql.Fields{
+			"cart_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"customer_id": &graphql.Field{
+				Type: graphql.String,
+			},
+			"status": &graphql.Field{
+				Type: graphql.String,
+			},
+			"order_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"created_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"updated_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"expires_at": &graphql.Field{
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cart := p.Source.(repo.Cart)
+					if cart.ExpiresAt.IsZero() {
+						return nil, nil
+					}
+
+					return cart.ExpiresAt, nil
+				},
+			},
+			"items": &graphql.Field{
+				Type: graphql.NewList(cartItemType),
+			},
+		},
+	})
+}
+
+func cartQueryFields(handler *CheckoutCntrlImpl, cartType *graphql.Object) graphql.Fields {
+	return graphql.Fields{
+		"cart": &graphql.Field{
+			Type: cartType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				cart, err := handler.CartSvc.GetCart(p.Context, int64(p.Args["id"].(int)))
+				if err != nil || cart.CartID == 0 {
+					return nil, err
+				}
+
+				return cart, nil
+			},
+		},
+	}
+}
+
+func cartMutationFields(handler *CheckoutCntrlImpl, cartType, checkoutType *graphql.Object) graphql.Fields {
+	cartItemArgs := graphql.FieldConfigArgument{
+		"cart_id": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+		"product_id": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+		"qty": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+	}
+
+	cartItemFromArgs := func(args map[string]interface{}) service.CartItemRequest {
+		return service.CartItemRequest{
+			CartID:    int64(args["cart_id"].(int)),
+			ProductID: int64(args["product_id"].(int)),
+			Qty:       int64(args["qty"].(int)),
+		}
+	}
+
+	return graphql.Fields{
+		"createCart": &graphql.Field{
+			Type: cartType,
+			Args: graphql.FieldConfigArgument{
+				"customer_id": &graphql.ArgumentConfig{
+					Type:         graphql.String,
+					DefaultValue: "",
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CartSvc.CreateCart(p.Context, p.Args["customer_id"].(string))
+			},
+		},
+		"addCartItem": &graphql.Field{
+			Type: cartType,
+			Args: cartItemArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CartSvc.AddCartItem(p.Context, cartItemFromArgs(p.Args))
+			},
+		},
+		"updateCartItem": &graphql.Field{
+			Type: cartType,
+			Args: cartItemArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CartSvc.UpdateCartItem(p.Context, cartItemFromArgs(p.Args))
+			},
+		},
+		"removeCartItem": &graphql.Field{
+			Type: cartType,
+			Args: graphql.FieldConfigArgument{
+				"cart_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"product_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CartSvc.RemoveCartItem(p.Context, int64(p.Args["cart_id"].(int)), int64(p.Args["product_id"].(int)))
+			},
+		},
+		"mergeCarts": &graphql.Field{
+			Type: cartType,
+			Args: graphql.FieldConfigArgument{
+				"guest_cart_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"customer_cart_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CartSvc.MergeCarts(p.Context, int64(p.Args["guest_cart_id"].(int)), int64(p.Args["customer_cart_id"].(int)))
+			},
+		},
+		"checkoutCart": &graphql.Field{
+			Type: checkoutType,
+			Args: graphql.FieldConfigArgument{
+				"cart_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"coupon_codes": &graphql.ArgumentConfig{
+					Type: graphql.NewList(graphql.NewNonNull(graphql.String)),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CartSvc.CheckoutCart(p.Context, int64(p.Args["cart_id"].(int)), stringsFromArgs(p.Args["coupon_codes"])...)
+			},
+		},
+	}
+}
@@ -0,0 +1,157 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCartMutations(t *testing.T) {
+	updatedAt := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	cart := repo.Cart{
+		CartID:     5,
+		CustomerID: "alice",
+		Status:     repo.CartStatusOpen,
+		CreatedAt:  updatedAt,
+		UpdatedAt:  updatedAt,
+		ExpiresAt:  updatedAt.Add(72 * time.Hour),
+		Items:      []repo.CartItem{{CartID: 5, ProductID: 3, Qty: 2}},
+	}
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(cartSvc *mockSvc.CartUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "create guest cart",
+			requestString: `mutation { createCart { cart_id customer_id status } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("CreateCart", mock.Anything, "").Return(repo.Cart{CartID: 6, Status: repo.CartStatusOpen}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"createCart": map[string]interface{}{"cart_id": 6, "customer_id": "", "status": "open"},
+			},
+		},
+		{
+			name:          "add cart item",
+			requestString: `mutation { addCartItem(cart_id: 5, product_id: 3, qty: 2) { cart_id expires_at items { product_id qty } } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("AddCartItem", mock.Anything, service.CartItemRequest{CartID: 5, ProductID: 3, Qty: 2}).Return(cart, nil)
+			},
+			expectedData: map[string]interface{}{
+				"addCartItem": map[string]interface{}{
+					"cart_id":    5,
+					"expires_at": "2023-06-06T10:00:00Z",
+					"items": []interface{}{
+						map[string]interface{}{"product_id": 3, "qty": 2},
+					},
+				},
+			},
+		},
+		{
+			name:          "update cart item",
+			requestString: `mutation { updateCartItem(cart_id: 5, product_id: 3, qty: 0) { cart_id } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("UpdateCartItem", mock.Anything, service.CartItemRequest{CartID: 5, ProductID: 3}).Return(repo.Cart{CartID: 5}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"updateCartItem": map[string]interface{}{"cart_id": 5},
+			},
+		},
+		{
+			name:          "remove cart item",
+			requestString: `mutation { removeCartItem(cart_id: 5, product_id: 3) { cart_id } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("RemoveCartItem", mock.Anything, int64(5), int64(3)).Return(repo.Cart{CartID: 5}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"removeCartItem": map[string]interface{}{"cart_id": 5},
+			},
+		},
+		{
+			name:          "merge carts",
+			requestString: `mutation { mergeCarts(guest_cart_id: 6, customer_cart_id: 5) { cart_id customer_id } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("MergeCarts", mock.Anything, int64(6), int64(5)).Return(cart, nil)
+			},
+			expectedData: map[string]interface{}{
+				"mergeCarts": map[string]interface{}{"cart_id": 5, "customer_id": "alice"},
+			},
+		},
+		{
+			name:          "checkout cart",
+			requestString: `mutation { checkoutCart(cart_id: 5) { order_id total } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("CheckoutCart", mock.Anything, int64(5)).Return(service.Checkout{OrderID: 11, TotalAmount: money.MustParse("219")}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"checkoutCart": map[string]interface{}{"order_id": 11, "total": "219"},
+			},
+		},
+		{
+			name:          "checkout an expired cart",
+			requestString: `mutation { checkoutCart(cart_id: 5) { order_id } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("CheckoutCart", mock.Anything, int64(5)).Return(service.Checkout{}, service.ErrCartExpired)
+			},
+			wantErr: true,
+		},
+		{
+			name:          "closed cart has no expiry",
+			requestString: `{ cart(id: 5) { status order_id expires_at } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("GetCart", mock.Anything, int64(5)).Return(repo.Cart{CartID: 5, Status: repo.CartStatusCheckedOut, OrderID: 11}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"cart": map[string]interface{}{"status": "checked_out", "order_id": 11, "expires_at": nil},
+			},
+		},
+		{
+			name:          "unknown cart",
+			requestString: `{ cart(id: 9) { cart_id } }`,
+			mockSetupFunc: func(cartSvc *mockSvc.CartUsecase) {
+				cartSvc.On("GetCart", mock.Anything, int64(9)).Return(repo.Cart{}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"cart": nil,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cartSvc := new(mockSvc.CartUsecase)
+			tc.mockSetupFunc(cartSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				CartSvc: cartSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			cartSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+)
+
+// newProductType builds the Product object shared by the catalog queries and
+// the product mutations.
+func newProductType(handler *CheckoutCntrlImpl) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Product",
+		Fields: graphql.Fields{
+			"product_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"sku": &graphql.Field{
+				Type: graphql.String,
+			},
+			"name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"price": &graphql.Field{
+				Type: decimalType,
+			},
+			"qty": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"reserved_qty": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"available_qty": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(repo.Product).Available(), nil
+				},
+			},
+			"archived_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"promos": &graphql.Field{
+				Type: graphql.NewList(promoType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					product := p.Source.(repo.Product)
+					return handler.CatalogSvc.GetPromosByProductID(p.Context, product.ProductID)
+				},
+			},
+		},
+	})
+}
+
+func catalogQueryFields(handler *CheckoutCntrlImpl, productType *graphql.Object) graphql.Fields {
+	productEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ProductEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type: graphql.String,
+			},
+			"node": &graphql.Field{
+				Type: productType,
+			},
+		},
+	})
+
+	productConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ProductConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewList(productEdgeType),
+			},
+			"page_info": &graphql.Field{
+				Type: pageInfoType,
+			},
+		},
+	})
+
+	productSortType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ProductSortField",
+		Values: graphql.EnumValueConfigMap{
+			"PRODUCT_ID": &graphql.EnumValueConfig{Value: repo.ProductSortByID},
+			"NAME":       &graphql.EnumValueConfig{Value: repo.ProductSortByName},
+			"SKU":        &graphql.EnumValueConfig{Value: repo.ProductSortBySku},
+			"PRICE":      &graphql.EnumValueConfig{Value: repo.ProductSortByPrice},
+		},
+	})
+
+	sortOrderType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SortOrder",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  &graphql.EnumValueConfig{Value: "asc"},
+			"DESC": &graphql.EnumValueConfig{Value: "desc"},
+		},
+	})
+
+	return graphql.Fields{
+		"products": &graphql.Field{
+			Type: productConnectionType,
+			Args: graphql.FieldConfigArgument{
+				"name": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"sku": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+				"sort_by": &graphql.ArgumentConfig{
+					Type:         productSortType,
+					DefaultValue: repo.ProductSortByID,
+				},
+				"sort_order": &graphql.ArgumentConfig{
+					Type:         sortOrderType,
+					DefaultValue: "asc",
+				},
+				"first": &graphql.ArgumentConfig{
+					Type:         graphql.Int,
+					DefaultValue: service.DefaultPageSize,
+				},
+				"after": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := service.ProductQuery{
+					SortBy: p.Args["sort_by"].(string),
+					Desc:   p.Args["sort_order"] == "desc",
+					First:  p.Args["first"].(int),
+				}
+				form.Name, _ = p.Args["name"].(string)
+				form.Sku, _ = p.Args["sku"].(string)
+				form.After, _ = p.Args["after"].(string)
+
+				return handler.CatalogSvc.GetProducts(p.Context, form)
+			},
+		},
+		"product": &graphql.Field{
+			Type: productType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				product, err := handler.CatalogSvc.GetProductByProductID(p.Context, int64(p.Args["id"].(int)))
+				if err != nil || product.ProductID == 0 {
+					return nil, err
+				}
+
+				return product, nil
+			},
+		},
+		"productBySku": &graphql.Field{
+			Type: productType,
+			Args: graphql.FieldConfigArgument{
+				"sku": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				product, err := handler.CatalogSvc.GetProductBySku(p.Context, p.Args["sku"].(string))
+				if err != nil || product.ProductID == 0 {
+					return nil, err
+				}
+
+				return product, nil
+			},
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package controller_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCatalogQueries(t *testing.T) {
+	googleHome := repo.Product{ProductID: 1, Sku: "120P90", Name: "Google Home", Price: money.MustParse("49.99"), Qty: 10}
+	startsAt := time.Date(2023, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(catalogSvc *mockSvc.CatalogUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "list products with promos",
+			requestString: `{ products(name: "home", sort_by: PRICE, sort_order: DESC, first: 1) { edges { cursor node { product_id name price promos { promo_id promo_type active starts_at ends_at timezone } } } page_info { end_cursor has_next_page } } }`,
+			mockSetupFunc: func(catalogSvc *mockSvc.CatalogUsecase) {
+				catalogSvc.On("GetProducts", mock.Anything, service.ProductQuery{Name: "home", SortBy: repo.ProductSortByPrice, Desc: true, First: 1}).
+					Return(service.ProductPage{
+						Edges:    []service.ProductEdge{{Cursor: "c1", Node: googleHome}},
+						PageInfo: service.PageInfo{EndCursor: "c1", HasNextPage: true},
+					}, nil)
+				catalogSvc.On("GetPromosByProductID", mock.Anything, int64(1)).
+					Return([]repo.Promo{{PromoID: 1, PromoType: "product", Active: true, StartsAt: &startsAt, Timezone: "Asia/Jakarta"}}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"products": map[string]interface{}{
+					"edges": []interface{}{
+						map[string]interface{}{
+							"cursor": "c1",
+							"node": map[string]interface{}{
+								"product_id": 1,
+								"name":       "Google Home",
+								"price":      "49.99",
+								"promos": []interface{}{
+									map[string]interface{}{"promo_id": 1, "promo_type": "product", "active": true, "starts_at": "2023-06-03T00:00:00Z", "ends_at": nil, "timezone": "Asia/Jakarta"},
+								},
+							},
+						},
+					},
+					"page_info": map[string]interface{}{
+						"end_cursor":    "c1",
+						"has_next_page": true,
+					},
+				},
+			},
+		},
+		{
+			name:          "product by id",
+			requestString: `{ product(id: 1) { product_id sku } }`,
+			mockSetupFunc: func(catalogSvc *mockSvc.CatalogUsecase) {
+				catalogSvc.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedData: map[string]interface{}{
+				"product": map[string]interface{}{"product_id": 1, "sku": "120P90"},
+			},
+		},
+		{
+			name:          "unknown product is null",
+			requestString: `{ product(id: 99) { product_id } }`,
+			mockSetupFunc: func(catalogSvc *mockSvc.CatalogUsecase) {
+				catalogSvc.On("GetProductByProductID", mock.Anything, int64(99)).Return(repo.Product{}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"product": nil,
+			},
+		},
+		{
+			name:          "product by sku",
+			requestString: `{ productBySku(sku: "120P90") { product_id name } }`,
+			mockSetupFunc: func(catalogSvc *mockSvc.CatalogUsecase) {
+				catalogSvc.On("GetProductBySku", mock.Anything, "120P90").Return(googleHome, nil)
+			},
+			expectedData: map[string]interface{}{
+				"productBySku": map[string]interface{}{"product_id": 1, "name": "Google Home"},
+			},
+		},
+		{
+			name:          "error while list products",
+			requestString: `{ products { edges { cursor } } }`,
+			mockSetupFunc: func(catalogSvc *mockSvc.CatalogUsecase) {
+				catalogSvc.On("GetProducts", mock.Anything, mock.Anything).Return(service.ProductPage{}, errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			catalogSvc := new(mockSvc.CatalogUsecase)
+			tc.mockSetupFunc(catalogSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				CatalogSvc: catalogSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			catalogSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -14,14 +14,22 @@ import (
 type (
 	CheckoutCntrlImpl struct {
 		dig.In
-		CheckoutSvc service.CheckoutUsecase
+		CheckoutSvc    service.CheckoutUsecase
+		CatalogSvc     service.CatalogUsecase
+		OrderSvc       service.OrderUsecase
+		OrderStatusSvc service.OrderStatusUsecase
+		PromoSvc       service.PromoUsecase
+		ProductSvc     service.ProductUsecase
+		StockSvc       service.StockUsecase
+		CartSvc        service.CartUsecase
+		CouponSvc      service.CouponUsecase
+		OrderPromoSvc  service.OrderPromoUsecase
+		BundleSvc      service.BundleUsecase
 	}
 )
 
-func NewCheckoutHandler(mux *http.ServeMux, checkoutSvc service.CheckoutUsecase) {
-	hc := &CheckoutCntrlImpl{
-		CheckoutSvc: checkoutSvc,
-	}
+func NewCheckoutHandler(mux *http.ServeMux, impl CheckoutCntrlImpl) {
+	hc := &impl
 
 	schema, err := CreateCheckoutSchema(hc)
 	if err != nil {
@@ -29,30 +37,133 @@ func NewCheckoutHandler(mux *http.ServeMux, checkoutSvc service.CheckoutUsecase)
 	}
 
 	h := handler.New(&handler.Config{
-		Schema:   &schema,
-		Pretty:   true,
-		GraphiQL: true,
+		Schema:        &schema,
+		Pretty:        true,
+		GraphiQL:      true,
+		FormatErrorFn: formatError,
 	})
 
 	mux.Handle("/graphql", hc.AdaptHTTPHandler(h))
 }
 
+// AdaptHTTPHandler tags the request context with the X-Actor header, which is
+// recorded on the stock movements the request makes, and with the
+// Idempotency-Key header checkout is retried with.
 func (cc CheckoutCntrlImpl) AdaptHTTPHandler(h http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if actor := r.Header.Get("X-Actor"); actor != "" {
+			r = r.WithContext(service.WithActor(r.Context(), actor))
+		}
+
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			r = r.WithContext(service.WithIdempotencyKey(r.Context(), key))
+		}
+
 		h.ServeHTTP(w, r)
 	}
 }
 
 func CreateCheckoutSchema(handler *CheckoutCntrlImpl) (graphql.Schema, error) {
+	appliedPromoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AppliedPromo",
+		Fields: graphql.Fields{
+			"promo_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"promo_type": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	checkoutLineType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CheckoutLine",
+		Fields: graphql.Fields{
+			"product_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"product_name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"qty": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"unit_price": &graphql.Field{
+				Type: decimalType,
+			},
+			"subtotal": &graphql.Field{
+				Type: decimalType,
+			},
+			"discount": &graphql.Field{
+				Type: decimalType,
+			},
+			"total": &graphql.Field{
+				Type: decimalType,
+			},
+			"promo_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"promo_type": &graphql.Field{
+				Type: graphql.String,
+			},
+			"promos": &graphql.Field{
+				Type: graphql.NewList(appliedPromoType),
+			},
+			"free": &graphql.Field{
+				Type: graphql.Boolean,
+			},
+			"bundled_qty": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"coupon_code": &graphql.Field{
+				Type: graphql.String,
+			},
+			"order_promo_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	})
+
 	// Define the Checkout type
 	checkoutType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Checkout",
 		Fields: graphql.Fields{
+			"order_id": &graphql.Field{
+				Type: graphql.Int,
+			},
 			"items": &graphql.Field{
 				Type: graphql.NewList(graphql.String),
 			},
+			"lines": &graphql.Field{
+				Type: graphql.NewList(checkoutLineType),
+			},
 			"total_amount": &graphql.Field{
-				Type: graphql.Float,
+				Type:              graphql.Float,
+				DeprecationReason: "Use total, which keeps the exact decimal amount.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(service.Checkout).TotalAmount.Float64(), nil
+				},
+			},
+			"total": &graphql.Field{
+				Type: decimalType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(service.Checkout).TotalAmount, nil
+				},
+			},
+			"currency": &graphql.Field{
+				Type: graphql.String,
+			},
+			"bundles": &graphql.Field{
+				Type: graphql.NewList(appliedBundleType),
+			},
+			"order_promos": &graphql.Field{
+				Type: graphql.NewList(appliedOrderPromoType),
+			},
+			"coupons": &graphql.Field{
+				Type: graphql.NewList(appliedCouponType),
+			},
+			"warnings": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
 			},
 		},
 	})
@@ -71,51 +182,90 @@ func CreateCheckoutSchema(handler *CheckoutCntrlImpl) (graphql.Schema, error) {
 		},
 	)
 
-	mutationType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Mutation",
-		Fields: graphql.Fields{
-			"checkout": &graphql.Field{
-				Type: checkoutType,
-				Args: graphql.FieldConfigArgument{
-					"items": &graphql.ArgumentConfig{
-						Type: graphql.NewList(inputItemType),
-					},
+	mutationFields := graphql.Fields{
+		"checkout": &graphql.Field{
+			Type: checkoutType,
+			Args: couponArgs(graphql.FieldConfigArgument{
+				"items": &graphql.ArgumentConfig{
+					Type: graphql.NewList(inputItemType),
 				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					items := p.Args["items"].([]interface{})
+				"idempotency_key": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				items, _ := p.Args["items"].([]interface{})
+				orderDetails := orderDetailsFromArgs(items)
 
-					orderDetails := make([]repo.OrderDetail, len(items))
-					for i, item := range items {
-						itemMap := item.(map[string]interface{})
-						orderDetails[i] = repo.OrderDetail{
-							ProductID: int64(itemMap["product_id"].(int)),
-							Qty:       int64(itemMap["qty"].(int)),
-						}
-					}
+				ctx := p.Context
 
-					ctx := p.Context
+				// the argument wins over the Idempotency-Key header
+				if key, _ := p.Args["idempotency_key"].(string); key != "" {
+					ctx = service.WithIdempotencyKey(ctx, key)
+				}
 
-					checkoutResult, err := handler.CheckoutSvc.Checkout(ctx, orderDetails)
-					if err != nil {
-						return nil, err
-					}
+				checkoutResult, err := handler.CheckoutSvc.Checkout(ctx, orderDetails, stringsFromArgs(p.Args["coupon_codes"])...)
+				if err != nil {
+					return nil, err
+				}
 
-					return checkoutResult, nil
-				},
+				return checkoutResult, nil
 			},
 		},
+	}
+
+	productType := newProductType(handler)
+	orderType := newOrderType(handler)
+	cartType := newCartType()
+
+	for _, fields := range []graphql.Fields{
+		promoMutationFields(handler),
+		productMutationFields(handler, productType),
+		reservationMutationFields(handler, checkoutType, inputItemType),
+		orderMutationFields(handler, orderType),
+		cartMutationFields(handler, cartType, checkoutType),
+		couponMutationFields(handler),
+		orderPromoMutationFields(handler),
+		bundleMutationFields(handler),
+	} {
+		for name, field := range fields {
+			mutationFields[name] = field
+		}
+	}
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Mutation",
+		Fields: mutationFields,
 	})
 
-	queryType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Query",
-		Fields: graphql.Fields{
-			"ping": &graphql.Field{
-				Type: graphql.String,
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return "pong", nil
-				},
+	queryFields := graphql.Fields{
+		"ping": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return "pong", nil
 			},
 		},
+	}
+
+	for _, fields := range []graphql.Fields{
+		catalogQueryFields(handler, productType),
+		orderQueryFields(handler, orderType),
+		promoQueryFields(handler),
+		stockQueryFields(handler),
+		quoteQueryFields(handler, checkoutLineType, inputItemType),
+		cartQueryFields(handler, cartType),
+		couponQueryFields(handler),
+		orderPromoQueryFields(handler),
+		bundleQueryFields(handler),
+	} {
+		for name, field := range fields {
+			queryFields[name] = field
+		}
+	}
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "Query",
+		Fields: queryFields,
 	})
 
 	schemaConfig := graphql.SchemaConfig{
@@ -130,3 +280,16 @@ func CreateCheckoutSchema(handler *CheckoutCntrlImpl) (graphql.Schema, error) {
 
 	return schema, nil
 }
+
+func orderDetailsFromArgs(items []interface{}) []repo.OrderDetail {
+	orderDetails := make([]repo.OrderDetail, len(items))
+	for i, item := range items {
+		itemMap := item.(map[string]interface{})
+		orderDetails[i] = repo.OrderDetail{
+			ProductID: int64(itemMap["product_id"].(int)),
+			Qty:       int64(itemMap["qty"].(int)),
+		}
+	}
+
+	return orderDetails
+}
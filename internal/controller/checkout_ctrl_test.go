@@ -2,7 +2,9 @@ package controller_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,7 +13,10 @@ import (
 	"github.com/graphql-go/graphql"
 	"github.com/learn/api-shop/internal/controller"
 	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
 	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/money"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -44,7 +49,7 @@ func TestNewCheckoutHandler(t *testing.T) {
 			mockSetupFunc: func(checkoutSvc *mockSvc.CheckoutUsecase) {
 				checkoutSvc.On("Checkout", mock.Anything, mock.AnythingOfType("[]repo.OrderDetail")).Return(service.Checkout{
 					Items:       []string{"Item1"},
-					TotalAmount: 10.0,
+					TotalAmount: money.MustParse("10.0"),
 				}, nil)
 			},
 		},
@@ -56,7 +61,7 @@ func TestNewCheckoutHandler(t *testing.T) {
 			tt.mockSetupFunc(checkoutSvc)
 
 			mux := http.NewServeMux()
-			controller.NewCheckoutHandler(mux, checkoutSvc)
+			controller.NewCheckoutHandler(mux, controller.CheckoutCntrlImpl{CheckoutSvc: checkoutSvc})
 
 			testServer := httptest.NewServer(mux)
 			defer testServer.Close()
@@ -95,7 +100,7 @@ func TestCreateCheckoutSchema(t *testing.T) {
 			requestString: `mutation { checkout(items: [{ product_id: 2, qty: 1 }]) { items total_amount }}`,
 			checkoutResult: service.Checkout{
 				Items:       []string{"Item1"},
-				TotalAmount: 10,
+				TotalAmount: money.MustParse("10"),
 			},
 			expectedData: map[string]interface{}{
 				"checkout": map[string]interface{}{
@@ -104,6 +109,102 @@ func TestCreateCheckoutSchema(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:          "Checkout with order id and line breakdown",
+			requestString: `mutation { checkout(items: [{ product_id: 2, qty: 1 }]) { order_id lines { product_id product_name qty unit_price subtotal discount total promo_id promo_type free } total_amount }}`,
+			checkoutResult: service.Checkout{
+				OrderID: 7,
+				Items:   []string{"MacBook Pro", "Raspberry Pi B"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 2, ProductName: "MacBook Pro", Qty: 1, UnitPrice: money.MustParse("5399.99"), Subtotal: money.MustParse("5399.99"), Total: money.MustParse("5399.99"), PromoID: 2, PromoType: "product"},
+					{ProductID: 4, ProductName: "Raspberry Pi B", Qty: 1, UnitPrice: money.MustParse("30"), Subtotal: money.MustParse("30"), Discount: money.MustParse("30"), PromoID: 2, PromoType: "product", Free: true},
+				},
+				TotalAmount: money.MustParse("5399.99"),
+			},
+			expectedData: map[string]interface{}{
+				"checkout": map[string]interface{}{
+					"order_id": 7,
+					"lines": []interface{}{
+						map[string]interface{}{
+							"product_id":   2,
+							"product_name": "MacBook Pro",
+							"qty":          1,
+							"unit_price":   "5399.99",
+							"subtotal":     "5399.99",
+							"discount":     "0",
+							"total":        "5399.99",
+							"promo_id":     2,
+							"promo_type":   "product",
+							"free":         false,
+						},
+						map[string]interface{}{
+							"product_id":   4,
+							"product_name": "Raspberry Pi B",
+							"qty":          1,
+							"unit_price":   "30",
+							"subtotal":     "30",
+							"discount":     "30",
+							"total":        "0",
+							"promo_id":     2,
+							"promo_type":   "product",
+							"free":         true,
+						},
+					},
+					"total_amount": 5399.99,
+				},
+			},
+		},
+		{
+			name:          "Checkout with every promo applied to a line",
+			requestString: `mutation { checkout(items: [{ product_id: 1, qty: 3 }]) { lines { product_id promo_id promos { promo_id promo_type } } }}`,
+			checkoutResult: service.Checkout{
+				Lines: []service.CheckoutLine{
+					{ProductID: 1, PromoID: 1, PromoType: "product", Promos: []service.AppliedPromo{{PromoID: 1, PromoType: "product"}, {PromoID: 7, PromoType: "product"}}},
+				},
+			},
+			expectedData: map[string]interface{}{
+				"checkout": map[string]interface{}{
+					"lines": []interface{}{
+						map[string]interface{}{
+							"product_id": 1,
+							"promo_id":   1,
+							"promos": []interface{}{
+								map[string]interface{}{"promo_id": 1, "promo_type": "product"},
+								map[string]interface{}{"promo_id": 7, "promo_type": "product"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "Checkout with warnings",
+			requestString: `mutation { checkout(items: [{ product_id: 2, qty: 1 }]) { items warnings }}`,
+			checkoutResult: service.Checkout{
+				Items:    []string{"MacBook Pro"},
+				Warnings: []string{"the free product Raspberry Pi B is out of stock and was not added"},
+			},
+			expectedData: map[string]interface{}{
+				"checkout": map[string]interface{}{
+					"items":    []interface{}{"MacBook Pro"},
+					"warnings": []interface{}{"the free product Raspberry Pi B is out of stock and was not added"},
+				},
+			},
+		},
+		{
+			name:          "Checkout with exact decimal total and currency",
+			requestString: `mutation { checkout(items: [{ product_id: 3, qty: 3 }]) { total currency }}`,
+			checkoutResult: service.Checkout{
+				TotalAmount: money.MustParse("295.65"),
+				Currency:    "USD",
+			},
+			expectedData: map[string]interface{}{
+				"checkout": map[string]interface{}{
+					"total":    "295.65",
+					"currency": "USD",
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -130,3 +231,133 @@ func TestCreateCheckoutSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckoutIdempotencyKey(t *testing.T) {
+	testCases := []struct {
+		name        string
+		query       string
+		header      string
+		expectedKey string
+	}{
+		{
+			name:        "key from the header",
+			query:       `mutation { checkout(items: [{product_id: 3, qty: 1}]) { order_id } }`,
+			header:      "retry-1",
+			expectedKey: "retry-1",
+		},
+		{
+			name:        "key from the argument",
+			query:       `mutation { checkout(items: [{product_id: 3, qty: 1}], idempotency_key: "retry-2") { order_id } }`,
+			expectedKey: "retry-2",
+		},
+		{
+			name:        "argument wins over the header",
+			query:       `mutation { checkout(items: [{product_id: 3, qty: 1}], idempotency_key: "retry-2") { order_id } }`,
+			header:      "retry-1",
+			expectedKey: "retry-2",
+		},
+		{
+			name:  "no key",
+			query: `mutation { checkout(items: [{product_id: 3, qty: 1}]) { order_id } }`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkoutSvc := new(mockSvc.CheckoutUsecase)
+			checkoutSvc.On("Checkout", mock.MatchedBy(func(ctx context.Context) bool {
+				return service.IdempotencyKeyFrom(ctx) == tc.expectedKey
+			}), mock.Anything).Return(service.Checkout{OrderID: 7}, nil)
+
+			mux := http.NewServeMux()
+			controller.NewCheckoutHandler(mux, controller.CheckoutCntrlImpl{CheckoutSvc: checkoutSvc})
+
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			request, err := http.NewRequest(http.MethodPost, testServer.URL+"/graphql", toJSONRequestBody(map[string]interface{}{
+				"query": tc.query,
+			}))
+			assert.NoError(t, err)
+			request.Header.Set("Content-Type", "application/json")
+			if tc.header != "" {
+				request.Header.Set("Idempotency-Key", tc.header)
+			}
+
+			response, err := http.DefaultClient.Do(request)
+			assert.NoError(t, err)
+			defer response.Body.Close()
+
+			var jsonResponse map[string]interface{}
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&jsonResponse))
+			assert.Equal(t, map[string]interface{}{
+				"data": map[string]interface{}{
+					"checkout": map[string]interface{}{"order_id": 7.0},
+				},
+			}, jsonResponse)
+
+			checkoutSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCheckoutCoupons(t *testing.T) {
+	checkoutSvc := new(mockSvc.CheckoutUsecase)
+	checkoutSvc.On("Checkout", mock.Anything, []repo.OrderDetail{{ProductID: 3, Qty: 1}}, "SAVE10").Return(service.Checkout{OrderID: 7}, nil)
+
+	schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{CheckoutSvc: checkoutSvc})
+	assert.NoError(t, err)
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `mutation { checkout(items: [{product_id: 3, qty: 1}], coupon_codes: ["SAVE10"]) { order_id } }`,
+		Context:       context.Background(),
+	})
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"checkout": map[string]interface{}{"order_id": 7}}, result.Data)
+	checkoutSvc.AssertExpectations(t)
+}
+
+func TestCheckoutValidationErrors(t *testing.T) {
+	testCases := []struct {
+		name               string
+		requestString      string
+		err                error
+		expectedExtensions map[string]interface{}
+	}{
+		{
+			name:               "unknown product",
+			requestString:      `mutation { checkout(items: [{ product_id: 9, qty: 1 }]) { order_id }}`,
+			err:                apperr.New(apperr.CodeProductNotFound, "product 9 doesn't exist").With("product_id", int64(9)),
+			expectedExtensions: map[string]interface{}{"code": "PRODUCT_NOT_FOUND", "product_id": float64(9)},
+		},
+		{
+			name:               "empty cart",
+			requestString:      `mutation { checkout(items: []) { order_id }}`,
+			err:                fmt.Errorf("%w: the cart is empty", service.ErrEmptyCart),
+			expectedExtensions: map[string]interface{}{"code": "EMPTY_CART"},
+		},
+		{
+			name:               "quote with a non positive qty",
+			requestString:      `{ quoteCart(items: [{ product_id: 3, qty: 0 }]) { total }}`,
+			err:                fmt.Errorf("%w: qty of product 3 must be at least 1", service.ErrInvalidQty.With("product_id", int64(3))),
+			expectedExtensions: map[string]interface{}{"code": "INVALID_QTY", "product_id": float64(3)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkoutSvc := new(mockSvc.CheckoutUsecase)
+			checkoutSvc.On("Checkout", mock.Anything, mock.Anything).Return(service.Checkout{}, tc.err).Maybe()
+			checkoutSvc.On("QuoteCart", mock.Anything, mock.Anything).Return(service.CartQuote{}, tc.err).Maybe()
+
+			// the errors come wrapped, so they go through the handler that finds
+			// their code in the chain
+			errs := postGraphQL(t, controller.CheckoutCntrlImpl{CheckoutSvc: checkoutSvc}, tc.requestString)
+			if assert.Len(t, errs, 1) {
+				assert.Equal(t, tc.err.Error(), errs[0]["message"])
+				assert.Equal(t, tc.expectedExtensions, errs[0]["extensions"])
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+var couponType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Coupon",
+	Fields: graphql.Fields{
+		"coupon_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"code": &graphql.Field{
+			Type: graphql.String,
+		},
+		"coupon_type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"reward": &graphql.Field{
+			Type: decimalType,
+		},
+		"min_basket": &graphql.Field{
+			Type: decimalType,
+		},
+		"usage_limit": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"active": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"expires_at": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+	},
+})
+
+// appliedCouponType is shared by the checkout and the quote.
+var appliedCouponType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AppliedCoupon",
+	Fields: graphql.Fields{
+		"code": &graphql.Field{
+			Type: graphql.String,
+		},
+		"coupon_type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"discount": &graphql.Field{
+			Type: decimalType,
+		},
+	},
+})
+
+// couponArgs are the arguments of the fields that price a cart with coupons.
+// There is no customer argument: a client could name any customer, so per
+// customer limits are only counted for the owner of a checked out cart.
+func couponArgs(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["coupon_codes"] = &graphql.ArgumentConfig{
+		Type: graphql.NewList(graphql.NewNonNull(graphql.String)),
+	}
+
+	return args
+}
+
+func stringsFromArgs(arg interface{}) []string {
+	values, _ := arg.([]interface{})
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		res = append(res, v.(string))
+	}
+
+	return res
+}
+
+func couponQueryFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	return graphql.Fields{
+		"coupon": &graphql.Field{
+			Type: couponType,
+			Args: graphql.FieldConfigArgument{
+				"code": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				coupon, err := handler.CouponSvc.GetCouponByCode(p.Context, p.Args["code"].(string))
+				if err != nil || coupon.CouponID == 0 {
+					return nil, err
+				}
+
+				return coupon, nil
+			},
+		},
+	}
+}
+
+func couponMutationFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	couponInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CouponInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"code": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"coupon_type": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"reward": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(decimalType),
+			},
+			"min_basket": &graphql.InputObjectFieldConfig{
+				Type: decimalType,
+			},
+			"usage_limit": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Int,
+				DefaultValue: 0,
+			},
+			"expires_at": &graphql.InputObjectFieldConfig{
+				Type: graphql.DateTime,
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"createCoupon": &graphql.Field{
+			Type: couponType,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(couponInputType),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := couponFromInput(p.Args["input"].(map[string]interface{}))
+				return handler.CouponSvc.CreateCoupon(p.Context, form)
+			},
+		},
+		"deactivateCoupon": &graphql.Field{
+			Type: couponType,
+			Args: graphql.FieldConfigArgument{
+				"code": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CouponSvc.DeactivateCoupon(p.Context, p.Args["code"].(string))
+			},
+		},
+	}
+}
+
+func couponFromInput(input map[string]interface{}) repo.Coupon {
+	form := repo.Coupon{
+		Code:       input["code"].(string),
+		CouponType: input["coupon_type"].(string),
+		Reward:     input["reward"].(money.Decimal),
+		UsageLimit: int64(input["usage_limit"].(int)),
+		Active:     true,
+	}
+	form.MinBasket, _ = input["min_basket"].(money.Decimal)
+
+	if expiresAt, ok := input["expires_at"].(time.Time); ok {
+		form.ExpiresAt = &expiresAt
+	}
+
+	return form
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+// decimalType carries money.Decimal values as strings so amounts never go
+// through a binary float on their way to the client. Numeric literals are
+// still accepted as input.
+var decimalType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Decimal",
+	Description: "Exact decimal number encoded as a string, e.g. \"49.99\".",
+	Serialize: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case money.Decimal:
+			return v.String()
+		case *money.Decimal:
+			if v == nil {
+				return nil
+			}
+			return v.String()
+		default:
+			return nil
+		}
+	},
+	ParseValue: func(value interface{}) interface{} {
+		var (
+			d   money.Decimal
+			err error
+		)
+
+		switch v := value.(type) {
+		case string:
+			d, err = money.Parse(v)
+		case float64:
+			d = money.NewFromFloat(v)
+		case int:
+			d = money.NewFromInt(int64(v))
+		default:
+			return nil
+		}
+
+		if err != nil {
+			return nil
+		}
+
+		return d
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		switch v := valueAST.(type) {
+		case *ast.StringValue, *ast.FloatValue, *ast.IntValue:
+			d, err := money.Parse(v.GetValue().(string))
+			if err != nil {
+				return nil
+			}
+			return d
+		default:
+			return nil
+		}
+	},
+})
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/learn/api-shop/pkg/apperr"
+)
+
+// internalErrorMessage replaces the message of every error without a code,
+// so clients never see what failed inside the shop.
+const internalErrorMessage = "internal error"
+
+// formatError renders a resolver error for clients. An error carrying a code
+// anywhere in its chain keeps its message and reports the code, and its
+// fields, in extensions. Any other error is logged under a correlation id and
+// reported as INTERNAL with that id only. Errors in the query itself are left
+// as graphql reports them.
+func formatError(err error) gqlerrors.FormattedError {
+	located, ok := err.(*gqlerrors.Error)
+	if !ok || located.OriginalError == nil {
+		return gqlerrors.FormatError(err)
+	}
+
+	presented := *located
+	presented.OriginalError = presentError(located.OriginalError)
+	presented.Message = presented.OriginalError.Error()
+
+	return gqlerrors.FormatError(&presented)
+}
+
+func presentError(err error) error {
+	var coded gqlerrors.ExtendedError
+	if errors.As(err, &coded) {
+		return &presentedError{message: err.Error(), extensions: coded.Extensions()}
+	}
+
+	id := newCorrelationID()
+	log.Printf("error while do resolve %s %+v", id, err)
+
+	return &presentedError{
+		message: internalErrorMessage,
+		extensions: map[string]interface{}{
+			"code":           string(apperr.CodeInternal),
+			"correlation_id": id,
+		},
+	}
+}
+
+type presentedError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+func (e *presentedError) Error() string {
+	return e.message
+}
+
+func (e *presentedError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
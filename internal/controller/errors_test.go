@@ -0,0 +1,111 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGraphQLErrorExtensions(t *testing.T) {
+	tests := []struct {
+		name               string
+		query              string
+		err                error
+		expectedMessage    string
+		expectedExtensions map[string]interface{}
+	}{
+		{
+			name:            "out of stock",
+			query:           `mutation { checkout(items: [{product_id: 3, qty: 11}]) { order_id } }`,
+			err:             apperr.New(apperr.CodeOutOfStock, "the product Alexa Speaker qty is not enough to fulfill the request").With("product_id", int64(3)).With("available_qty", int64(10)),
+			expectedMessage: "the product Alexa Speaker qty is not enough to fulfill the request",
+			expectedExtensions: map[string]interface{}{
+				"code":          "OUT_OF_STOCK",
+				"product_id":    3.0,
+				"available_qty": 10.0,
+			},
+		},
+		{
+			name:               "wrapped sentinel",
+			query:              `mutation { confirmReservation(id: 8) { order_id } }`,
+			err:                fmt.Errorf("%w: 8", service.ErrReservationClosed),
+			expectedMessage:    "reservation is closed: 8",
+			expectedExtensions: map[string]interface{}{"code": "CONFLICT"},
+		},
+		{
+			name:               "checkout validation",
+			query:              `mutation { checkout(items: []) { order_id } }`,
+			err:                fmt.Errorf("%w: the cart is empty", service.ErrEmptyCart),
+			expectedMessage:    "invalid checkout: the cart is empty",
+			expectedExtensions: map[string]interface{}{"code": "EMPTY_CART"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkoutSvc := new(mockSvc.CheckoutUsecase)
+			checkoutSvc.On("Checkout", mock.Anything, mock.Anything).Return(service.Checkout{}, tt.err).Maybe()
+			checkoutSvc.On("ConfirmReservation", mock.Anything, mock.Anything).Return(service.Checkout{}, tt.err).Maybe()
+
+			errs := postGraphQL(t, controller.CheckoutCntrlImpl{CheckoutSvc: checkoutSvc}, tt.query)
+			if assert.Len(t, errs, 1) {
+				assert.Equal(t, tt.expectedMessage, errs[0]["message"])
+				assert.Equal(t, tt.expectedExtensions, errs[0]["extensions"])
+			}
+		})
+	}
+
+	t.Run("internal errors are hidden", func(t *testing.T) {
+		orderSvc := new(mockSvc.OrderUsecase)
+		orderSvc.On("GetOrderByOrderID", mock.Anything, int64(7)).Return(repo.Order{}, errors.New("pq: connection refused"))
+
+		errs := postGraphQL(t, controller.CheckoutCntrlImpl{OrderSvc: orderSvc}, `{ order(id: 7) { order_id } }`)
+		if assert.Len(t, errs, 1) {
+			assert.Equal(t, "internal error", errs[0]["message"])
+
+			extensions, _ := errs[0]["extensions"].(map[string]interface{})
+			assert.Equal(t, "INTERNAL", extensions["code"])
+			assert.Regexp(t, "^[0-9a-f]{16}$", extensions["correlation_id"])
+		}
+	})
+
+	t.Run("query errors are left alone", func(t *testing.T) {
+		errs := postGraphQL(t, controller.CheckoutCntrlImpl{}, `{ order { order_id } }`)
+		if assert.Len(t, errs, 1) {
+			assert.Contains(t, errs[0]["message"], `argument "id" of type "Int!" is required`)
+			assert.Nil(t, errs[0]["extensions"])
+		}
+	})
+}
+
+func postGraphQL(t *testing.T, impl controller.CheckoutCntrlImpl, query string) []map[string]interface{} {
+	mux := http.NewServeMux()
+	controller.NewCheckoutHandler(mux, impl)
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	response, err := http.Post(testServer.URL+"/graphql", "application/json", toJSONRequestBody(map[string]interface{}{
+		"query": query,
+	}))
+	assert.NoError(t, err)
+	defer response.Body.Close()
+
+	var jsonResponse struct {
+		Errors []map[string]interface{} `json:"errors"`
+	}
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&jsonResponse))
+
+	return jsonResponse.Errors
+}
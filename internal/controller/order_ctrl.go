@@ -0,0 +1,280 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+)
+
+func newOrderType(handler *CheckoutCntrlImpl) *graphql.Object {
+	orderDetailType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderDetail",
+		Fields: graphql.Fields{
+			"order_detail_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"product_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"product_name": &graphql.Field{
+				Type: graphql.String,
+			},
+			"promo_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"promo_type": &graphql.Field{
+				Type: graphql.String,
+			},
+			"promo_reward": &graphql.Field{
+				Type: decimalType,
+			},
+			"order_promo_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"coupon_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"price": &graphql.Field{
+				Type: decimalType,
+			},
+			"qty": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"refunded_qty": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	})
+
+	orderStatusChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderStatusChange",
+		Fields: graphql.Fields{
+			"from_status": &graphql.Field{
+				Type: graphql.String,
+			},
+			"to_status": &graphql.Field{
+				Type: graphql.String,
+			},
+			"actor": &graphql.Field{
+				Type: graphql.String,
+			},
+			"note": &graphql.Field{
+				Type: graphql.String,
+			},
+			"created_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"order_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"date": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"subtotal": &graphql.Field{
+				Type: decimalType,
+			},
+			"discount_total": &graphql.Field{
+				Type: decimalType,
+			},
+			"total": &graphql.Field{
+				Type: decimalType,
+			},
+			"item_count": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"status": &graphql.Field{
+				Type: graphql.String,
+			},
+			"refunded_total": &graphql.Field{
+				Type: decimalType,
+			},
+			"details": &graphql.Field{
+				Type: graphql.NewList(orderDetailType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					order := p.Source.(repo.Order)
+					return handler.OrderSvc.GetOrderLinesByOrderID(p.Context, order.OrderID)
+				},
+			},
+			"discounts": &graphql.Field{
+				Type: graphql.NewList(orderDiscountType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					order := p.Source.(repo.Order)
+					return handler.OrderSvc.GetOrderDiscountsByOrderID(p.Context, order.OrderID)
+				},
+			},
+			"status_history": &graphql.Field{
+				Type: graphql.NewList(orderStatusChangeType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					order := p.Source.(repo.Order)
+					return handler.OrderStatusSvc.GetOrderStatusHistory(p.Context, order.OrderID)
+				},
+			},
+		},
+	})
+}
+
+func orderQueryFields(handler *CheckoutCntrlImpl, orderType *graphql.Object) graphql.Fields {
+	orderEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type: graphql.String,
+			},
+			"node": &graphql.Field{
+				Type: orderType,
+			},
+		},
+	})
+
+	orderConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewList(orderEdgeType),
+			},
+			"page_info": &graphql.Field{
+				Type: pageInfoType,
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"order": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				order, err := handler.OrderSvc.GetOrderByOrderID(p.Context, int64(p.Args["id"].(int)))
+				if err != nil || order.OrderID == 0 {
+					return nil, err
+				}
+
+				return order, nil
+			},
+		},
+		"orders": &graphql.Field{
+			Type: orderConnectionType,
+			Args: graphql.FieldConfigArgument{
+				"from": &graphql.ArgumentConfig{
+					Type: graphql.DateTime,
+				},
+				"to": &graphql.ArgumentConfig{
+					Type: graphql.DateTime,
+				},
+				"first": &graphql.ArgumentConfig{
+					Type:         graphql.Int,
+					DefaultValue: service.DefaultPageSize,
+				},
+				"after": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := service.OrderQuery{
+					First: p.Args["first"].(int),
+				}
+				form.From, _ = p.Args["from"].(time.Time)
+				form.To, _ = p.Args["to"].(time.Time)
+				form.After, _ = p.Args["after"].(string)
+
+				return handler.OrderSvc.GetOrders(p.Context, form)
+			},
+		},
+	}
+}
+
+func orderMutationFields(handler *CheckoutCntrlImpl, orderType *graphql.Object) graphql.Fields {
+	refundLineInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "RefundLineInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"order_detail_id": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"qty": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"transitionOrder": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"order_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"status": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.String),
+				},
+				"note": &graphql.ArgumentConfig{
+					Type:         graphql.String,
+					DefaultValue: "",
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.OrderStatusSvc.TransitionOrder(p.Context, service.OrderTransition{
+					OrderID: int64(p.Args["order_id"].(int)),
+					Status:  p.Args["status"].(string),
+					Note:    p.Args["note"].(string),
+				})
+			},
+		},
+		"cancelOrder": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"order_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"reason": &graphql.ArgumentConfig{
+					Type:         graphql.String,
+					DefaultValue: "",
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.OrderSvc.CancelOrder(p.Context, int64(p.Args["order_id"].(int)), p.Args["reason"].(string))
+			},
+		},
+		"refundOrder": &graphql.Field{
+			Type: orderType,
+			Args: graphql.FieldConfigArgument{
+				"order_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"lines": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(refundLineInputType))),
+				},
+				"reason": &graphql.ArgumentConfig{
+					Type:         graphql.String,
+					DefaultValue: "",
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := service.OrderRefundRequest{
+					OrderID: int64(p.Args["order_id"].(int)),
+					Reason:  p.Args["reason"].(string),
+				}
+
+				for _, line := range p.Args["lines"].([]interface{}) {
+					lineMap := line.(map[string]interface{})
+					form.Lines = append(form.Lines, service.RefundLine{
+						OrderDetailID: int64(lineMap["order_detail_id"].(int)),
+						Qty:           int64(lineMap["qty"].(int)),
+					})
+				}
+
+				return handler.OrderSvc.RefundOrder(p.Context, form)
+			},
+		},
+	}
+}
@@ -0,0 +1,317 @@
+package controller_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOrderQueries(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	order := repo.Order{OrderID: 1, Date: date, Total: money.MustParse("295.65")}
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(orderSvc *mockSvc.OrderUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "order with details",
+			requestString: `{ order(id: 1) { order_id date total details { product_id product_name promo_id promo_type price qty } } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase) {
+				orderSvc.On("GetOrderByOrderID", mock.Anything, int64(1)).Return(order, nil)
+				orderSvc.On("GetOrderLinesByOrderID", mock.Anything, int64(1)).Return([]repo.OrderLine{
+					{OrderDetailID: 1, OrderID: 1, ProductID: 3, ProductName: "Alexa Speaker", PromoID: 3, PromoType: "discount", Price: money.MustParse("295.65"), Qty: 3},
+				}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"order": map[string]interface{}{
+					"order_id": 1,
+					"date":     "2023-06-01T10:00:00Z",
+					"total":    "295.65",
+					"details": []interface{}{
+						map[string]interface{}{
+							"product_id":   3,
+							"product_name": "Alexa Speaker",
+							"promo_id":     3,
+							"promo_type":   "discount",
+							"price":        "295.65",
+							"qty":          3,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "order with an order promo",
+			requestString: `{ order(id: 1) { total details { product_id price order_promo_id } discounts { order_promo_id promo_type discount } } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase) {
+				orderSvc.On("GetOrderByOrderID", mock.Anything, int64(1)).Return(order, nil)
+				orderSvc.On("GetOrderLinesByOrderID", mock.Anything, int64(1)).Return([]repo.OrderLine{
+					{OrderDetailID: 2, OrderID: 1, ProductID: 4, PromoType: "gift", OrderPromoID: 3, Price: money.MustParse("0"), Qty: 1},
+				}, nil)
+				orderSvc.On("GetOrderDiscountsByOrderID", mock.Anything, int64(1)).Return([]repo.OrderDiscount{
+					{OrderDiscountID: 1, OrderID: 1, OrderPromoID: 1, PromoType: "discount", Discount: money.MustParse("29.57")},
+				}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"order": map[string]interface{}{
+					"total": "295.65",
+					"details": []interface{}{
+						map[string]interface{}{
+							"product_id":     4,
+							"price":          "0",
+							"order_promo_id": 3,
+						},
+					},
+					"discounts": []interface{}{
+						map[string]interface{}{
+							"order_promo_id": 1,
+							"promo_type":     "discount",
+							"discount":       "29.57",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:          "unknown order is null",
+			requestString: `{ order(id: 99) { order_id } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase) {
+				orderSvc.On("GetOrderByOrderID", mock.Anything, int64(99)).Return(repo.Order{}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"order": nil,
+			},
+		},
+		{
+			name:          "orders in a date range",
+			requestString: `{ orders(from: "2023-06-01T00:00:00Z", to: "2023-07-01T00:00:00Z", first: 1, after: "c0") { edges { cursor node { order_id total } } page_info { end_cursor has_next_page } } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase) {
+				orderSvc.On("GetOrders", mock.Anything, service.OrderQuery{
+					From:  time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+					To:    time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+					First: 1,
+					After: "c0",
+				}).Return(service.OrderPage{
+					Edges:    []service.OrderEdge{{Cursor: "c1", Node: order}},
+					PageInfo: service.PageInfo{EndCursor: "c1"},
+				}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"orders": map[string]interface{}{
+					"edges": []interface{}{
+						map[string]interface{}{
+							"cursor": "c1",
+							"node":   map[string]interface{}{"order_id": 1, "total": "295.65"},
+						},
+					},
+					"page_info": map[string]interface{}{
+						"end_cursor":    "c1",
+						"has_next_page": false,
+					},
+				},
+			},
+		},
+		{
+			name:          "error while get order",
+			requestString: `{ order(id: 1) { order_id } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase) {
+				orderSvc.On("GetOrderByOrderID", mock.Anything, int64(1)).Return(repo.Order{}, errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orderSvc := new(mockSvc.OrderUsecase)
+			tc.mockSetupFunc(orderSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				OrderSvc: orderSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			orderSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderMutations(t *testing.T) {
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(orderSvc *mockSvc.OrderUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "cancel order",
+			requestString: `mutation { cancelOrder(order_id: 1, reason: "changed my mind") { order_id status refunded_total } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase) {
+				orderSvc.On("CancelOrder", mock.Anything, int64(1), "changed my mind").
+					Return(repo.Order{OrderID: 1, Status: service.OrderStatusCancelled, RefundedTotal: money.MustParse("295.65")}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"cancelOrder": map[string]interface{}{"order_id": 1, "status": "cancelled", "refunded_total": "295.65"},
+			},
+		},
+		{
+			name:          "refund part of a line",
+			requestString: `mutation { refundOrder(order_id: 1, lines: [{order_detail_id: 4, qty: 1}], reason: "damaged") { status refunded_total } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase) {
+				orderSvc.On("RefundOrder", mock.Anything, service.OrderRefundRequest{
+					OrderID: 1,
+					Lines:   []service.RefundLine{{OrderDetailID: 4, Qty: 1}},
+					Reason:  "damaged",
+				}).Return(repo.Order{OrderID: 1, Status: service.OrderStatusPartiallyRefunded, RefundedTotal: money.MustParse("98.55")}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"refundOrder": map[string]interface{}{"status": "partially_refunded", "refunded_total": "98.55"},
+			},
+		},
+		{
+			name:          "cancel a cancelled order",
+			requestString: `mutation { cancelOrder(order_id: 1) { status } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase) {
+				orderSvc.On("CancelOrder", mock.Anything, int64(1), "").Return(repo.Order{}, service.ErrInvalidOrderTransition)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orderSvc := new(mockSvc.OrderUsecase)
+			tc.mockSetupFunc(orderSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				OrderSvc: orderSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			orderSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderTransitions(t *testing.T) {
+	at := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(orderSvc *mockSvc.OrderUsecase, orderStatusSvc *mockSvc.OrderStatusUsecase)
+		expectedData  map[string]interface{}
+		expectedErr   string
+	}{
+		{
+			name:          "transition order",
+			requestString: `mutation { transitionOrder(order_id: 1, status: "fulfilled", note: "shipped") { order_id status } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase, orderStatusSvc *mockSvc.OrderStatusUsecase) {
+				orderStatusSvc.On("TransitionOrder", mock.Anything, service.OrderTransition{OrderID: 1, Status: "fulfilled", Note: "shipped"}).
+					Return(repo.Order{OrderID: 1, Status: service.OrderStatusFulfilled}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"transitionOrder": map[string]interface{}{"order_id": 1, "status": "fulfilled"},
+			},
+		},
+		{
+			name:          "illegal transition",
+			requestString: `mutation { transitionOrder(order_id: 1, status: "paid") { status } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase, orderStatusSvc *mockSvc.OrderStatusUsecase) {
+				orderStatusSvc.On("TransitionOrder", mock.Anything, service.OrderTransition{OrderID: 1, Status: "paid"}).
+					Return(repo.Order{}, fmt.Errorf("%w: a delivered order can't become paid", service.ErrInvalidOrderTransition))
+			},
+			expectedErr: "invalid order transition: a delivered order can't become paid",
+		},
+		{
+			name:          "order status history",
+			requestString: `{ order(id: 1) { status status_history { from_status to_status actor note created_at } } }`,
+			mockSetupFunc: func(orderSvc *mockSvc.OrderUsecase, orderStatusSvc *mockSvc.OrderStatusUsecase) {
+				orderSvc.On("GetOrderByOrderID", mock.Anything, int64(1)).Return(repo.Order{OrderID: 1, Status: service.OrderStatusPaid}, nil)
+				orderStatusSvc.On("GetOrderStatusHistory", mock.Anything, int64(1)).Return([]repo.OrderStatusChange{
+					{OrderID: 1, ToStatus: service.OrderStatusPendingPayment, CreatedAt: at},
+					{OrderID: 1, FromStatus: service.OrderStatusPendingPayment, ToStatus: service.OrderStatusPaid, Actor: "alice", Note: "card", CreatedAt: at},
+				}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"order": map[string]interface{}{
+					"status": "paid",
+					"status_history": []interface{}{
+						map[string]interface{}{"from_status": "", "to_status": "pending_payment", "actor": "", "note": "", "created_at": "2023-06-01T10:00:00Z"},
+						map[string]interface{}{"from_status": "pending_payment", "to_status": "paid", "actor": "alice", "note": "card", "created_at": "2023-06-01T10:00:00Z"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orderSvc := new(mockSvc.OrderUsecase)
+			orderStatusSvc := new(mockSvc.OrderStatusUsecase)
+			tc.mockSetupFunc(orderSvc, orderStatusSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				OrderSvc:       orderSvc,
+				OrderStatusSvc: orderStatusSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.expectedErr != "" {
+				if assert.True(t, result.HasErrors()) {
+					assert.Equal(t, tc.expectedErr, result.Errors[0].Message)
+				}
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			orderSvc.AssertExpectations(t)
+			orderStatusSvc.AssertExpectations(t)
+		})
+	}
+}
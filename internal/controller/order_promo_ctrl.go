@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+var orderPromoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "OrderPromo",
+	Fields: graphql.Fields{
+		"order_promo_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"promo_type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"reward": &graphql.Field{
+			Type: decimalType,
+		},
+		"min_subtotal": &graphql.Field{
+			Type: decimalType,
+		},
+		"min_items": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"priority": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"stack_group": &graphql.Field{
+			Type: graphql.String,
+		},
+		"active": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"starts_at": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+		"ends_at": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+		"timezone": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+// appliedOrderPromoType is shared by the checkout and the quote.
+var appliedOrderPromoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AppliedOrderPromo",
+	Fields: graphql.Fields{
+		"order_promo_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"promo_type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"discount": &graphql.Field{
+			Type: decimalType,
+		},
+	},
+})
+
+// orderDiscountType is what an order promo took off a placed order.
+var orderDiscountType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "OrderDiscount",
+	Fields: graphql.Fields{
+		"order_promo_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"promo_type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"discount": &graphql.Field{
+			Type: decimalType,
+		},
+	},
+})
+
+func orderPromoQueryFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	return graphql.Fields{
+		"orderPromos": &graphql.Field{
+			Type: graphql.NewList(orderPromoType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.OrderPromoSvc.GetOrderPromos(p.Context)
+			},
+		},
+		"orderPromo": &graphql.Field{
+			Type: orderPromoType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				promo, err := handler.OrderPromoSvc.GetOrderPromoByOrderPromoID(p.Context, int64(p.Args["id"].(int)))
+				if err != nil || promo.OrderPromoID == 0 {
+					return nil, err
+				}
+
+				return promo, nil
+			},
+		},
+	}
+}
+
+func orderPromoMutationFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	// starts_at and ends_at are read as wall clock times in timezone, like
+	// the ones of a product promo.
+	orderPromoInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "OrderPromoInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"promo_type": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"reward": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(decimalType),
+			},
+			"min_subtotal": &graphql.InputObjectFieldConfig{
+				Type: decimalType,
+			},
+			"min_items": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Int,
+				DefaultValue: 0,
+			},
+			"priority": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Int,
+				DefaultValue: 0,
+			},
+			"stack_group": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"active": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: true,
+			},
+			"starts_at": &graphql.InputObjectFieldConfig{
+				Type: graphql.DateTime,
+			},
+			"ends_at": &graphql.InputObjectFieldConfig{
+				Type: graphql.DateTime,
+			},
+			"timezone": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"createOrderPromo": &graphql.Field{
+			Type: orderPromoType,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(orderPromoInputType),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := orderPromoFromInput(p.Args["input"].(map[string]interface{}))
+				return handler.OrderPromoSvc.CreateOrderPromo(p.Context, form)
+			},
+		},
+		"deactivateOrderPromo": &graphql.Field{
+			Type: orderPromoType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.OrderPromoSvc.DeactivateOrderPromo(p.Context, int64(p.Args["id"].(int)))
+			},
+		},
+	}
+}
+
+func orderPromoFromInput(input map[string]interface{}) repo.OrderPromo {
+	form := repo.OrderPromo{
+		PromoType: input["promo_type"].(string),
+		Reward:    input["reward"].(money.Decimal),
+		MinItems:  int64(input["min_items"].(int)),
+		Priority:  int64(input["priority"].(int)),
+		Active:    input["active"].(bool),
+	}
+	form.MinSubtotal, _ = input["min_subtotal"].(money.Decimal)
+	form.StackGroup, _ = input["stack_group"].(string)
+	form.Timezone, _ = input["timezone"].(string)
+
+	if startsAt, ok := input["starts_at"].(time.Time); ok {
+		form.StartsAt = &startsAt
+	}
+
+	if endsAt, ok := input["ends_at"].(time.Time); ok {
+		form.EndsAt = &endsAt
+	}
+
+	return form
+}
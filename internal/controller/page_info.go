@@ -0,0 +1,16 @@
+package controller
+
+import "github.com/graphql-go/graphql"
+
+// pageInfoType is shared by every connection type in the schema.
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"end_cursor": &graphql.Field{
+			Type: graphql.String,
+		},
+		"has_next_page": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+	},
+})
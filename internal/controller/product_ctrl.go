@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+func productMutationFields(handler *CheckoutCntrlImpl, productType *graphql.Object) graphql.Fields {
+	productInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ProductInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"sku": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"name": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"price": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(decimalType),
+			},
+		},
+	})
+
+	stockReasonType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "StockReason",
+		Values: graphql.EnumValueConfigMap{
+			"RESTOCK":    &graphql.EnumValueConfig{Value: service.StockReasonRestock},
+			"CORRECTION": &graphql.EnumValueConfig{Value: service.StockReasonCorrection},
+			"DAMAGED":    &graphql.EnumValueConfig{Value: service.StockReasonDamaged},
+			"LOST":       &graphql.EnumValueConfig{Value: service.StockReasonLost},
+			"RETURNED":   &graphql.EnumValueConfig{Value: service.StockReasonReturned},
+		},
+	})
+
+	return graphql.Fields{
+		"createProduct": &graphql.Field{
+			Type: productType,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(productInputType),
+				},
+				"qty": &graphql.ArgumentConfig{
+					Type:         graphql.Int,
+					DefaultValue: 0,
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := productFromInput(p.Args["input"].(map[string]interface{}))
+				form.Qty = int64(p.Args["qty"].(int))
+				return handler.ProductSvc.CreateProduct(p.Context, form)
+			},
+		},
+		"updateProduct": &graphql.Field{
+			Type: productType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(productInputType),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := productFromInput(p.Args["input"].(map[string]interface{}))
+				form.ProductID = int64(p.Args["id"].(int))
+				return handler.ProductSvc.UpdateProduct(p.Context, form)
+			},
+		},
+		"archiveProduct": &graphql.Field{
+			Type: productType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.ProductSvc.ArchiveProduct(p.Context, int64(p.Args["id"].(int)))
+			},
+		},
+		"adjustStock": &graphql.Field{
+			Type: productType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"delta": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"reason": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(stockReasonType),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.ProductSvc.AdjustStock(p.Context, service.StockAdjustment{
+					ProductID: int64(p.Args["id"].(int)),
+					Delta:     int64(p.Args["delta"].(int)),
+					Reason:    p.Args["reason"].(string),
+				})
+			},
+		},
+	}
+}
+
+func productFromInput(input map[string]interface{}) repo.Product {
+	return repo.Product{
+		Sku:   input["sku"].(string),
+		Name:  input["name"].(string),
+		Price: input["price"].(money.Decimal),
+	}
+}
@@ -0,0 +1,115 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProductMutations(t *testing.T) {
+	headphones := repo.Product{ProductID: 5, Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9"), Qty: 4}
+	archivedAt := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(productSvc *mockSvc.ProductUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "create product",
+			requestString: `mutation { createProduct(input: {sku: "HP01", name: "Headphones", price: "79.9"}, qty: 4) { product_id sku price qty archived_at } }`,
+			mockSetupFunc: func(productSvc *mockSvc.ProductUsecase) {
+				productSvc.On("CreateProduct", mock.Anything, repo.Product{Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9"), Qty: 4}).Return(headphones, nil)
+			},
+			expectedData: map[string]interface{}{
+				"createProduct": map[string]interface{}{"product_id": 5, "sku": "HP01", "price": "79.9", "qty": 4, "archived_at": nil},
+			},
+		},
+		{
+			name:          "create product with a taken sku",
+			requestString: `mutation { createProduct(input: {sku: "120P90", name: "Headphones", price: "79.9"}) { product_id } }`,
+			mockSetupFunc: func(productSvc *mockSvc.ProductUsecase) {
+				productSvc.On("CreateProduct", mock.Anything, mock.Anything).Return(repo.Product{}, repo.ErrDuplicateSku)
+			},
+			wantErr: true,
+		},
+		{
+			name:          "update product",
+			requestString: `mutation { updateProduct(id: 5, input: {sku: "HP01", name: "Headphones", price: 69}) { product_id name price } }`,
+			mockSetupFunc: func(productSvc *mockSvc.ProductUsecase) {
+				updated := headphones
+				updated.Price = money.MustParse("69")
+				productSvc.On("UpdateProduct", mock.Anything, repo.Product{ProductID: 5, Sku: "HP01", Name: "Headphones", Price: money.MustParse("69")}).Return(updated, nil)
+			},
+			expectedData: map[string]interface{}{
+				"updateProduct": map[string]interface{}{"product_id": 5, "name": "Headphones", "price": "69"},
+			},
+		},
+		{
+			name:          "archive product",
+			requestString: `mutation { archiveProduct(id: 5) { product_id archived_at } }`,
+			mockSetupFunc: func(productSvc *mockSvc.ProductUsecase) {
+				archived := headphones
+				archived.ArchivedAt = &archivedAt
+				productSvc.On("ArchiveProduct", mock.Anything, int64(5)).Return(archived, nil)
+			},
+			expectedData: map[string]interface{}{
+				"archiveProduct": map[string]interface{}{"product_id": 5, "archived_at": "2023-06-03T10:00:00Z"},
+			},
+		},
+		{
+			name:          "adjust stock",
+			requestString: `mutation { adjustStock(id: 5, delta: -2, reason: DAMAGED) { product_id qty } }`,
+			mockSetupFunc: func(productSvc *mockSvc.ProductUsecase) {
+				adjusted := headphones
+				adjusted.Qty = 2
+				productSvc.On("AdjustStock", mock.Anything, service.StockAdjustment{ProductID: 5, Delta: -2, Reason: service.StockReasonDamaged}).Return(adjusted, nil)
+			},
+			expectedData: map[string]interface{}{
+				"adjustStock": map[string]interface{}{"product_id": 5, "qty": 2},
+			},
+		},
+		{
+			name:          "adjust stock with an unknown reason",
+			requestString: `mutation { adjustStock(id: 5, delta: 2, reason: GIFT) { product_id } }`,
+			mockSetupFunc: func(productSvc *mockSvc.ProductUsecase) {},
+			wantErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			productSvc := new(mockSvc.ProductUsecase)
+			tc.mockSetupFunc(productSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				ProductSvc: productSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			productSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+var promoTierType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PromoTier",
+	Fields: graphql.Fields{
+		"min_qty": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"percent": &graphql.Field{
+			Type: decimalType,
+		},
+	},
+})
+
+// promoType is shared by the catalog and the promo administration fields.
+var promoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Promo",
+	Fields: graphql.Fields{
+		"promo_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"product_id": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"promo_type": &graphql.Field{
+			Type: graphql.String,
+		},
+		"reward": &graphql.Field{
+			Type: decimalType,
+		},
+		"min_qty": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"free_qty": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"tiers": &graphql.Field{
+			Type: graphql.NewList(promoTierType),
+		},
+		"priority": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"exclusive": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"stack_group": &graphql.Field{
+			Type: graphql.String,
+		},
+		"active": &graphql.Field{
+			Type: graphql.Boolean,
+		},
+		"starts_at": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+		"ends_at": &graphql.Field{
+			Type: graphql.DateTime,
+		},
+		"timezone": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+// promoKindType describes a promo_type and the promo fields it reads.
+var promoKindType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PromoType",
+	Fields: graphql.Fields{
+		"name": &graphql.Field{
+			Type: graphql.String,
+		},
+		"params": &graphql.Field{
+			Type: graphql.NewList(graphql.NewObject(graphql.ObjectConfig{
+				Name: "PromoParam",
+				Fields: graphql.Fields{
+					"name": &graphql.Field{
+						Type: graphql.String,
+					},
+					"type": &graphql.Field{
+						Type: graphql.String,
+					},
+					"description": &graphql.Field{
+						Type: graphql.String,
+					},
+				},
+			})),
+		},
+	},
+})
+
+func promoQueryFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	return graphql.Fields{
+		"promoTypes": &graphql.Field{
+			Type: graphql.NewList(promoKindType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.PromoSvc.GetPromoTypes(p.Context)
+			},
+		},
+		"promos": &graphql.Field{
+			Type: graphql.NewList(promoType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.PromoSvc.GetPromos(p.Context)
+			},
+		},
+		"promo": &graphql.Field{
+			Type: promoType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				promo, err := handler.PromoSvc.GetPromoByPromoID(p.Context, int64(p.Args["id"].(int)))
+				if err != nil || promo.PromoID == 0 {
+					return nil, err
+				}
+
+				return promo, nil
+			},
+		},
+	}
+}
+
+func promoMutationFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	promoTierInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "PromoTierInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"min_qty": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"percent": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(decimalType),
+			},
+		},
+	})
+
+	// starts_at and ends_at are read as wall clock times in timezone, the
+	// offset they are sent with is dropped when stored. reward, free_qty and
+	// tiers are only read by the promo types that use them.
+	promoInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "PromoInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"product_id": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.Int),
+			},
+			"promo_type": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.String),
+			},
+			"reward": &graphql.InputObjectFieldConfig{
+				Type:         decimalType,
+				DefaultValue: money.Decimal{},
+			},
+			"min_qty": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Int,
+				DefaultValue: 1,
+			},
+			"free_qty": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Int,
+				DefaultValue: 0,
+			},
+			"tiers": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewList(graphql.NewNonNull(promoTierInputType)),
+			},
+			"priority": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Int,
+				DefaultValue: 0,
+			},
+			"exclusive": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: false,
+			},
+			"stack_group": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"active": &graphql.InputObjectFieldConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: true,
+			},
+			"starts_at": &graphql.InputObjectFieldConfig{
+				Type: graphql.DateTime,
+			},
+			"ends_at": &graphql.InputObjectFieldConfig{
+				Type: graphql.DateTime,
+			},
+			"timezone": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"createPromo": &graphql.Field{
+			Type: promoType,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(promoInputType),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := promoFromInput(p.Args["input"].(map[string]interface{}))
+				return handler.PromoSvc.CreatePromo(p.Context, form)
+			},
+		},
+		"updatePromo": &graphql.Field{
+			Type:        promoType,
+			Description: "Replaces every field of the promo, omitted fields take their defaults.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(promoInputType),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := promoFromInput(p.Args["input"].(map[string]interface{}))
+				form.PromoID = int64(p.Args["id"].(int))
+				return handler.PromoSvc.UpdatePromo(p.Context, form)
+			},
+		},
+		"deactivatePromo": &graphql.Field{
+			Type: promoType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.PromoSvc.DeactivatePromo(p.Context, int64(p.Args["id"].(int)))
+			},
+		},
+		"deletePromo": &graphql.Field{
+			Type: graphql.Boolean,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				err := handler.PromoSvc.DeletePromo(p.Context, int64(p.Args["id"].(int)))
+				if err != nil {
+					return nil, err
+				}
+
+				return true, nil
+			},
+		},
+	}
+}
+
+func promoFromInput(input map[string]interface{}) repo.Promo {
+	form := repo.Promo{
+		ProductID: int64(input["product_id"].(int)),
+		PromoType: input["promo_type"].(string),
+		Reward:    input["reward"].(money.Decimal),
+		MinQty:    int64(input["min_qty"].(int)),
+		FreeQty:   int64(input["free_qty"].(int)),
+		Priority:  int64(input["priority"].(int)),
+		Exclusive: input["exclusive"].(bool),
+		Active:    input["active"].(bool),
+	}
+	form.StackGroup, _ = input["stack_group"].(string)
+	form.Timezone, _ = input["timezone"].(string)
+
+	tiers, _ := input["tiers"].([]interface{})
+	for _, v := range tiers {
+		tier := v.(map[string]interface{})
+		form.Tiers = append(form.Tiers, repo.PromoTier{
+			MinQty:  int64(tier["min_qty"].(int)),
+			Percent: tier["percent"].(money.Decimal),
+		})
+	}
+
+	if startsAt, ok := input["starts_at"].(time.Time); ok {
+		form.StartsAt = &startsAt
+	}
+
+	if endsAt, ok := input["ends_at"].(time.Time); ok {
+		form.EndsAt = &endsAt
+	}
+
+	return form
+}
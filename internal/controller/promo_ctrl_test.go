@@ -0,0 +1,187 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPromoAdmin(t *testing.T) {
+	discount := repo.Promo{PromoID: 3, ProductID: 3, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 3, Active: true}
+	startsAt := time.Date(2023, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(promoSvc *mockSvc.PromoUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "list running promos",
+			requestString: `{ promos { promo_id promo_type reward min_qty } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				promoSvc.On("GetPromos", mock.Anything).Return([]repo.Promo{discount}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"promos": []interface{}{
+					map[string]interface{}{"promo_id": 3, "promo_type": "discount", "reward": "10", "min_qty": 3},
+				},
+			},
+		},
+		{
+			name:          "list promo types",
+			requestString: `{ promoTypes { name params { name type } } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				promoSvc.On("GetPromoTypes", mock.Anything).Return([]service.PromoType{
+					{Name: "free_unit"},
+					{Name: "gift", Params: []service.PromoParam{{Name: "reward", Type: service.PromoParamProductID, Description: "the product given away"}}},
+				}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"promoTypes": []interface{}{
+					map[string]interface{}{"name": "free_unit", "params": []interface{}{}},
+					map[string]interface{}{"name": "gift", "params": []interface{}{map[string]interface{}{"name": "reward", "type": "product_id"}}},
+				},
+			},
+		},
+		{
+			name:          "unknown promo is null",
+			requestString: `{ promo(id: 99) { promo_id } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				promoSvc.On("GetPromoByPromoID", mock.Anything, int64(99)).Return(repo.Promo{}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"promo": nil,
+			},
+		},
+		{
+			name:          "create promo with defaults",
+			requestString: `mutation { createPromo(input: {product_id: 3, promo_type: "discount", reward: "10", starts_at: "2023-06-03T00:00:00Z", timezone: "Asia/Jakarta"}) { promo_id active } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				form := repo.Promo{ProductID: 3, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 1, Active: true, StartsAt: &startsAt, Timezone: "Asia/Jakarta"}
+				created := form
+				created.PromoID = 4
+				promoSvc.On("CreatePromo", mock.Anything, form).Return(created, nil)
+			},
+			expectedData: map[string]interface{}{
+				"createPromo": map[string]interface{}{"promo_id": 4, "active": true},
+			},
+		},
+		{
+			name:          "update promo",
+			requestString: `mutation { updatePromo(id: 3, input: {product_id: 3, promo_type: "discount", reward: 15, min_qty: 3, priority: 2}) { promo_id reward priority } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				form := repo.Promo{PromoID: 3, ProductID: 3, PromoType: "discount", Reward: money.MustParse("15"), MinQty: 3, Priority: 2, Active: true}
+				promoSvc.On("UpdatePromo", mock.Anything, form).Return(form, nil)
+			},
+			expectedData: map[string]interface{}{
+				"updatePromo": map[string]interface{}{"promo_id": 3, "reward": "15", "priority": 2},
+			},
+		},
+		{
+			name:          "create tiered promo without a reward",
+			requestString: `mutation { createPromo(input: {product_id: 1, promo_type: "tiered", min_qty: 2, tiers: [{min_qty: 2, percent: "10"}, {min_qty: 5, percent: 20}]}) { promo_id tiers { min_qty percent } } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				form := repo.Promo{ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("10")}, {MinQty: 5, Percent: money.MustParse("20")}}, Active: true}
+				created := form
+				created.PromoID = 6
+				promoSvc.On("CreatePromo", mock.Anything, form).Return(created, nil)
+			},
+			expectedData: map[string]interface{}{
+				"createPromo": map[string]interface{}{
+					"promo_id": 6,
+					"tiers": []interface{}{
+						map[string]interface{}{"min_qty": 2, "percent": "10"},
+						map[string]interface{}{"min_qty": 5, "percent": "20"},
+					},
+				},
+			},
+		},
+		{
+			name:          "create buy get promo",
+			requestString: `mutation { createPromo(input: {product_id: 1, promo_type: "buy_get", min_qty: 5, free_qty: 2}) { promo_id free_qty } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				form := repo.Promo{ProductID: 1, PromoType: "buy_get", MinQty: 5, FreeQty: 2, Active: true}
+				created := form
+				created.PromoID = 7
+				promoSvc.On("CreatePromo", mock.Anything, form).Return(created, nil)
+			},
+			expectedData: map[string]interface{}{
+				"createPromo": map[string]interface{}{"promo_id": 7, "free_qty": 2},
+			},
+		},
+		{
+			name:          "invalid promo",
+			requestString: `mutation { createPromo(input: {product_id: 3, promo_type: "discount", reward: "150"}) { promo_id } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				promoSvc.On("CreatePromo", mock.Anything, mock.Anything).Return(repo.Promo{}, service.ErrInvalidPromo)
+			},
+			wantErr: true,
+		},
+		{
+			name:          "deactivate promo",
+			requestString: `mutation { deactivatePromo(id: 3) { promo_id active } }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				deactivated := discount
+				deactivated.Active = false
+				promoSvc.On("DeactivatePromo", mock.Anything, int64(3)).Return(deactivated, nil)
+			},
+			expectedData: map[string]interface{}{
+				"deactivatePromo": map[string]interface{}{"promo_id": 3, "active": false},
+			},
+		},
+		{
+			name:          "delete promo",
+			requestString: `mutation { deletePromo(id: 3) }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				promoSvc.On("DeletePromo", mock.Anything, int64(3)).Return(nil)
+			},
+			expectedData: map[string]interface{}{
+				"deletePromo": true,
+			},
+		},
+		{
+			name:          "delete unknown promo",
+			requestString: `mutation { deletePromo(id: 9) }`,
+			mockSetupFunc: func(promoSvc *mockSvc.PromoUsecase) {
+				promoSvc.On("DeletePromo", mock.Anything, int64(9)).Return(service.ErrPromoNotFound)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			promoSvc := new(mockSvc.PromoUsecase)
+			tc.mockSetupFunc(promoSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				PromoSvc: promoSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			promoSvc.AssertExpectations(t)
+		})
+	}
+}
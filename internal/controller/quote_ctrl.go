@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/service"
+)
+
+func quoteQueryFields(handler *CheckoutCntrlImpl, checkoutLineType *graphql.Object, inputItemType *graphql.InputObject) graphql.Fields {
+	cartQuoteType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CartQuote",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
+			},
+			"lines": &graphql.Field{
+				Type: graphql.NewList(checkoutLineType),
+			},
+			"total": &graphql.Field{
+				Type: decimalType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(service.CartQuote).TotalAmount, nil
+				},
+			},
+			"currency": &graphql.Field{
+				Type: graphql.String,
+			},
+			"bundles": &graphql.Field{
+				Type: graphql.NewList(appliedBundleType),
+			},
+			"order_promos": &graphql.Field{
+				Type: graphql.NewList(appliedOrderPromoType),
+			},
+			"coupons": &graphql.Field{
+				Type: graphql.NewList(appliedCouponType),
+			},
+			"warnings": &graphql.Field{
+				Type: graphql.NewList(graphql.String),
+			},
+			"checkoutable": &graphql.Field{
+				Type: graphql.Boolean,
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"quoteCart": &graphql.Field{
+			Type: cartQuoteType,
+			Args: couponArgs(graphql.FieldConfigArgument{
+				"items": &graphql.ArgumentConfig{
+					Type: graphql.NewList(inputItemType),
+				},
+			}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				items, _ := p.Args["items"].([]interface{})
+				return handler.CheckoutSvc.QuoteCart(p.Context, orderDetailsFromArgs(items), stringsFromArgs(p.Args["coupon_codes"])...)
+			},
+		},
+	}
+}
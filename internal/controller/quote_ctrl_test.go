@@ -0,0 +1,55 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQuoteCart(t *testing.T) {
+	checkoutSvc := new(mockSvc.CheckoutUsecase)
+	checkoutSvc.On("QuoteCart", mock.Anything, []repo.OrderDetail{{ProductID: 2, Qty: 1}, {ProductID: 3, Qty: 20}}).Return(service.CartQuote{
+		Items: []string{"MacBook Pro", "Raspberry Pi B"},
+		Lines: []service.CheckoutLine{
+			{ProductID: 2, ProductName: "MacBook Pro", Qty: 1, Total: money.MustParse("5399.99"), PromoID: 2, PromoType: "product"},
+			{ProductID: 4, ProductName: "Raspberry Pi B", Qty: 1, Discount: money.MustParse("30"), PromoID: 2, PromoType: "product", Free: true},
+		},
+		TotalAmount: money.MustParse("5399.99"),
+		Currency:    "USD",
+		Warnings:    []string{"the product Alexa Speaker qty is not enough to fulfill the request"},
+	}, nil)
+
+	schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+		CheckoutSvc: checkoutSvc,
+	})
+	assert.NoError(t, err)
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ quoteCart(items: [{product_id: 2, qty: 1}, {product_id: 3, qty: 20}]) { items lines { product_name total free } total currency warnings checkoutable } }`,
+	})
+
+	assert.False(t, result.HasErrors(), result.Errors)
+	assert.Equal(t, map[string]interface{}{
+		"quoteCart": map[string]interface{}{
+			"items": []interface{}{"MacBook Pro", "Raspberry Pi B"},
+			"lines": []interface{}{
+				map[string]interface{}{"product_name": "MacBook Pro", "total": "5399.99", "free": false},
+				map[string]interface{}{"product_name": "Raspberry Pi B", "total": "0", "free": true},
+			},
+			"total":        "5399.99",
+			"currency":     "USD",
+			"warnings":     []interface{}{"the product Alexa Speaker qty is not enough to fulfill the request"},
+			"checkoutable": false,
+		},
+	}, result.Data)
+
+	checkoutSvc.AssertExpectations(t)
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+func reservationMutationFields(handler *CheckoutCntrlImpl, checkoutType *graphql.Object, inputItemType *graphql.InputObject) graphql.Fields {
+	reservationItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ReservationItem",
+		Fields: graphql.Fields{
+			"product_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"qty": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	})
+
+	reservationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Reservation",
+		Fields: graphql.Fields{
+			"reservation_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"status": &graphql.Field{
+				Type: graphql.String,
+			},
+			"expires_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"order_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"created_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+			"items": &graphql.Field{
+				Type: graphql.NewList(reservationItemType),
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"reserveCart": &graphql.Field{
+			Type: reservationType,
+			Args: graphql.FieldConfigArgument{
+				"items": &graphql.ArgumentConfig{
+					Type: graphql.NewList(inputItemType),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				items, _ := p.Args["items"].([]interface{})
+				return handler.CheckoutSvc.ReserveCart(p.Context, orderDetailsFromArgs(items))
+			},
+		},
+		"confirmReservation": &graphql.Field{
+			Type: checkoutType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CheckoutSvc.ConfirmReservation(p.Context, int64(p.Args["id"].(int)))
+			},
+		},
+		"releaseReservation": &graphql.Field{
+			Type: reservationType,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return handler.CheckoutSvc.ReleaseReservation(p.Context, int64(p.Args["id"].(int)))
+			},
+		},
+	}
+}
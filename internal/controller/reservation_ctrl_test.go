@@ -0,0 +1,108 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReservationMutations(t *testing.T) {
+	createdAt := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	reservation := repo.Reservation{
+		ReservationID: 8,
+		Status:        repo.ReservationStatusActive,
+		ExpiresAt:     createdAt.Add(15 * time.Minute),
+		CreatedAt:     createdAt,
+		Items:         []repo.ReservationItem{{ReservationID: 8, ProductID: 3, Qty: 2}},
+	}
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(checkoutSvc *mockSvc.CheckoutUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "reserve cart",
+			requestString: `mutation { reserveCart(items: [{product_id: 3, qty: 2}]) { reservation_id status expires_at items { product_id qty } } }`,
+			mockSetupFunc: func(checkoutSvc *mockSvc.CheckoutUsecase) {
+				checkoutSvc.On("ReserveCart", mock.Anything, []repo.OrderDetail{{ProductID: 3, Qty: 2}}).Return(reservation, nil)
+			},
+			expectedData: map[string]interface{}{
+				"reserveCart": map[string]interface{}{
+					"reservation_id": 8,
+					"status":         "active",
+					"expires_at":     "2023-06-03T10:15:00Z",
+					"items": []interface{}{
+						map[string]interface{}{"product_id": 3, "qty": 2},
+					},
+				},
+			},
+		},
+		{
+			name:          "confirm reservation",
+			requestString: `mutation { confirmReservation(id: 8) { order_id total } }`,
+			mockSetupFunc: func(checkoutSvc *mockSvc.CheckoutUsecase) {
+				checkoutSvc.On("ConfirmReservation", mock.Anything, int64(8)).Return(service.Checkout{OrderID: 11, TotalAmount: money.MustParse("219")}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"confirmReservation": map[string]interface{}{"order_id": 11, "total": "219"},
+			},
+		},
+		{
+			name:          "release reservation",
+			requestString: `mutation { releaseReservation(id: 8) { reservation_id status } }`,
+			mockSetupFunc: func(checkoutSvc *mockSvc.CheckoutUsecase) {
+				released := reservation
+				released.Status = repo.ReservationStatusReleased
+				checkoutSvc.On("ReleaseReservation", mock.Anything, int64(8)).Return(released, nil)
+			},
+			expectedData: map[string]interface{}{
+				"releaseReservation": map[string]interface{}{"reservation_id": 8, "status": "released"},
+			},
+		},
+		{
+			name:          "confirm a closed reservation",
+			requestString: `mutation { confirmReservation(id: 8) { order_id } }`,
+			mockSetupFunc: func(checkoutSvc *mockSvc.CheckoutUsecase) {
+				checkoutSvc.On("ConfirmReservation", mock.Anything, int64(8)).Return(service.Checkout{}, service.ErrReservationClosed)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkoutSvc := new(mockSvc.CheckoutUsecase)
+			tc.mockSetupFunc(checkoutSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				CheckoutSvc: checkoutSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			checkoutSvc.AssertExpectations(t)
+		})
+	}
+}
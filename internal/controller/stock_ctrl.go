@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/service"
+)
+
+func stockQueryFields(handler *CheckoutCntrlImpl) graphql.Fields {
+	stockMovementType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StockMovement",
+		Fields: graphql.Fields{
+			"movement_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"product_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"delta": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"reason": &graphql.Field{
+				Type: graphql.String,
+			},
+			"order_id": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"actor": &graphql.Field{
+				Type: graphql.String,
+			},
+			"created_at": &graphql.Field{
+				Type: graphql.DateTime,
+			},
+		},
+	})
+
+	stockMovementEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StockMovementEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type: graphql.String,
+			},
+			"node": &graphql.Field{
+				Type: stockMovementType,
+			},
+		},
+	})
+
+	stockMovementConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StockMovementConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewList(stockMovementEdgeType),
+			},
+			"page_info": &graphql.Field{
+				Type: pageInfoType,
+			},
+		},
+	})
+
+	return graphql.Fields{
+		"stockMovements": &graphql.Field{
+			Type: stockMovementConnectionType,
+			Args: graphql.FieldConfigArgument{
+				"product_id": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.Int),
+				},
+				"first": &graphql.ArgumentConfig{
+					Type:         graphql.Int,
+					DefaultValue: service.DefaultPageSize,
+				},
+				"after": &graphql.ArgumentConfig{
+					Type: graphql.String,
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				form := service.StockMovementQuery{
+					ProductID: int64(p.Args["product_id"].(int)),
+					First:     p.Args["first"].(int),
+				}
+				form.After, _ = p.Args["after"].(string)
+
+				return handler.StockSvc.GetStockMovements(p.Context, form)
+			},
+		},
+	}
+}
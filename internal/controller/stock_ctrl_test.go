@@ -0,0 +1,134 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/learn/api-shop/internal/controller"
+	mockSvc "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStockQueries(t *testing.T) {
+	createdAt := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		requestString string
+		mockSetupFunc func(stockSvc *mockSvc.StockUsecase)
+		expectedData  map[string]interface{}
+		wantErr       bool
+	}{
+		{
+			name:          "movement history of a product",
+			requestString: `{ stockMovements(product_id: 1, first: 1) { edges { cursor node { movement_id delta reason order_id actor created_at } } page_info { end_cursor has_next_page } } }`,
+			mockSetupFunc: func(stockSvc *mockSvc.StockUsecase) {
+				stockSvc.On("GetStockMovements", mock.Anything, service.StockMovementQuery{ProductID: 1, First: 1}).
+					Return(service.StockMovementPage{
+						Edges: []service.StockMovementEdge{{Cursor: "c9", Node: repo.StockMovement{
+							MovementID: 9, ProductID: 1, Delta: -3, Reason: "sale", OrderID: 7, Actor: "system", CreatedAt: createdAt,
+						}}},
+						PageInfo: service.PageInfo{EndCursor: "c9", HasNextPage: true},
+					}, nil)
+			},
+			expectedData: map[string]interface{}{
+				"stockMovements": map[string]interface{}{
+					"edges": []interface{}{
+						map[string]interface{}{
+							"cursor": "c9",
+							"node": map[string]interface{}{
+								"movement_id": 9,
+								"delta":       -3,
+								"reason":      "sale",
+								"order_id":    7,
+								"actor":       "system",
+								"created_at":  "2023-06-03T10:00:00Z",
+							},
+						},
+					},
+					"page_info": map[string]interface{}{
+						"end_cursor":    "c9",
+						"has_next_page": true,
+					},
+				},
+			},
+		},
+		{
+			name:          "error while get movements",
+			requestString: `{ stockMovements(product_id: 1) { edges { cursor } } }`,
+			mockSetupFunc: func(stockSvc *mockSvc.StockUsecase) {
+				stockSvc.On("GetStockMovements", mock.Anything, mock.Anything).Return(service.StockMovementPage{}, errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stockSvc := new(mockSvc.StockUsecase)
+			tc.mockSetupFunc(stockSvc)
+
+			schema, err := controller.CreateCheckoutSchema(&controller.CheckoutCntrlImpl{
+				StockSvc: stockSvc,
+			})
+			assert.NoError(t, err)
+
+			result := graphql.Do(graphql.Params{
+				Schema:        schema,
+				RequestString: tc.requestString,
+			})
+
+			if tc.wantErr {
+				assert.True(t, result.HasErrors())
+			} else {
+				assert.False(t, result.HasErrors(), result.Errors)
+				assert.Equal(t, tc.expectedData, result.Data)
+			}
+
+			stockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestActorHeader(t *testing.T) {
+	productSvc := new(mockSvc.ProductUsecase)
+	productSvc.On("AdjustStock", mock.MatchedBy(func(ctx context.Context) bool {
+		return service.ActorFrom(ctx) == "alice"
+	}), mock.Anything).Return(repo.Product{ProductID: 4, Qty: 12}, nil)
+
+	mux := http.NewServeMux()
+	controller.NewCheckoutHandler(mux, controller.CheckoutCntrlImpl{ProductSvc: productSvc})
+
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	request, err := http.NewRequest(http.MethodPost, testServer.URL+"/graphql", toJSONRequestBody(map[string]interface{}{
+		"query": `mutation { adjustStock(id: 4, delta: 10, reason: RESTOCK) { qty } }`,
+	}))
+	assert.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Actor", "alice")
+
+	response, err := http.DefaultClient.Do(request)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+
+	var jsonResponse map[string]interface{}
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&jsonResponse))
+	assert.Equal(t, map[string]interface{}{
+		"data": map[string]interface{}{
+			"adjustStock": map[string]interface{}{"qty": 12.0},
+		},
+	}, jsonResponse)
+
+	productSvc.AssertExpectations(t)
+}
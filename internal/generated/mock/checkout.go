@@ -5,10 +5,10 @@ package mock
 import (
 	context "context"
 
+	sqlx "github.com/jmoiron/sqlx"
 	repo "github.com/learn/api-shop/internal/repo"
-	mock "github.com/stretchr/testify/mock"
-
 	service "github.com/learn/api-shop/internal/service"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // CheckoutUsecase is an autogenerated mock type for the CheckoutUsecase type
@@ -16,19 +16,184 @@ type CheckoutUsecase struct {
 	mock.Mock
 }
 
-// Checkout provides a mock function with given fields: ctx, form
-func (_m *CheckoutUsecase) Checkout(ctx context.Context, form []repo.OrderDetail) (service.Checkout, error) {
-	ret := _m.Called(ctx, form)
+// Checkout provides a mock function with given fields: ctx, form, couponCodes
+func (_m *CheckoutUsecase) Checkout(ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (service.Checkout, error) {
+	_va := make([]interface{}, len(couponCodes))
+	for _i := range couponCodes {
+		_va[_i] = couponCodes[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, form)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 service.Checkout
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []repo.OrderDetail, ...string) (service.Checkout, error)); ok {
+		return rf(ctx, form, couponCodes...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []repo.OrderDetail, ...string) service.Checkout); ok {
+		r0 = rf(ctx, form, couponCodes...)
+	} else {
+		r0 = ret.Get(0).(service.Checkout)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []repo.OrderDetail, ...string) error); ok {
+		r1 = rf(ctx, form, couponCodes...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CheckoutTx provides a mock function with given fields: tx, ctx, form, couponCodes
+func (_m *CheckoutUsecase) CheckoutTx(tx *sqlx.Tx, ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (service.Checkout, error) {
+	_va := make([]interface{}, len(couponCodes))
+	for _i := range couponCodes {
+		_va[_i] = couponCodes[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, tx, ctx, form)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 service.Checkout
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, []repo.OrderDetail, ...string) (service.Checkout, error)); ok {
+		return rf(tx, ctx, form, couponCodes...)
+	}
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, []repo.OrderDetail, ...string) service.Checkout); ok {
+		r0 = rf(tx, ctx, form, couponCodes...)
+	} else {
+		r0 = ret.Get(0).(service.Checkout)
+	}
+
+	if rf, ok := ret.Get(1).(func(*sqlx.Tx, context.Context, []repo.OrderDetail, ...string) error); ok {
+		r1 = rf(tx, ctx, form, couponCodes...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConfirmReservation provides a mock function with given fields: ctx, reservationID
+func (_m *CheckoutUsecase) ConfirmReservation(ctx context.Context, reservationID int64) (service.Checkout, error) {
+	ret := _m.Called(ctx, reservationID)
 
 	var r0 service.Checkout
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []repo.OrderDetail) (service.Checkout, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (service.Checkout, error)); ok {
+		return rf(ctx, reservationID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) service.Checkout); ok {
+		r0 = rf(ctx, reservationID)
+	} else {
+		r0 = ret.Get(0).(service.Checkout)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, reservationID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExpireReservations provides a mock function with given fields: ctx
+func (_m *CheckoutUsecase) ExpireReservations(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QuoteCart provides a mock function with given fields: ctx, form, couponCodes
+func (_m *CheckoutUsecase) QuoteCart(ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (service.CartQuote, error) {
+	_va := make([]interface{}, len(couponCodes))
+	for _i := range couponCodes {
+		_va[_i] = couponCodes[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, form)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 service.CartQuote
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []repo.OrderDetail, ...string) (service.CartQuote, error)); ok {
+		return rf(ctx, form, couponCodes...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []repo.OrderDetail, ...string) service.CartQuote); ok {
+		r0 = rf(ctx, form, couponCodes...)
+	} else {
+		r0 = ret.Get(0).(service.CartQuote)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []repo.OrderDetail, ...string) error); ok {
+		r1 = rf(ctx, form, couponCodes...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseReservation provides a mock function with given fields: ctx, reservationID
+func (_m *CheckoutUsecase) ReleaseReservation(ctx context.Context, reservationID int64) (repo.Reservation, error) {
+	ret := _m.Called(ctx, reservationID)
+
+	var r0 repo.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (repo.Reservation, error)); ok {
+		return rf(ctx, reservationID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) repo.Reservation); ok {
+		r0 = rf(ctx, reservationID)
+	} else {
+		r0 = ret.Get(0).(repo.Reservation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, reservationID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReserveCart provides a mock function with given fields: ctx, form
+func (_m *CheckoutUsecase) ReserveCart(ctx context.Context, form []repo.OrderDetail) (repo.Reservation, error) {
+	ret := _m.Called(ctx, form)
+
+	var r0 repo.Reservation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []repo.OrderDetail) (repo.Reservation, error)); ok {
 		return rf(ctx, form)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []repo.OrderDetail) service.Checkout); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, []repo.OrderDetail) repo.Reservation); ok {
 		r0 = rf(ctx, form)
 	} else {
-		r0 = ret.Get(0).(service.Checkout)
+		r0 = ret.Get(0).(repo.Reservation)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, []repo.OrderDetail) error); ok {
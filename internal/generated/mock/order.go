@@ -5,10 +5,9 @@ package mock
 import (
 	context "context"
 
+	sqlx "github.com/jmoiron/sqlx"
 	repo "github.com/learn/api-shop/internal/repo"
 	mock "github.com/stretchr/testify/mock"
-
-	sqlx "github.com/jmoiron/sqlx"
 )
 
 // OrderRepository is an autogenerated mock type for the OrderRepository type
@@ -94,6 +93,226 @@ func (_m *OrderRepository) CreateOrderDetails(tx *sqlx.Tx, ctx context.Context,
 	return r0
 }
 
+// CreateOrderDiscounts provides a mock function with given fields: tx, ctx, form
+func (_m *OrderRepository) CreateOrderDiscounts(tx *sqlx.Tx, ctx context.Context, form []repo.OrderDiscount) error {
+	ret := _m.Called(tx, ctx, form)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, []repo.OrderDiscount) error); ok {
+		r0 = rf(tx, ctx, form)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateOrderRefunds provides a mock function with given fields: tx, ctx, form
+func (_m *OrderRepository) CreateOrderRefunds(tx *sqlx.Tx, ctx context.Context, form []repo.OrderRefund) error {
+	ret := _m.Called(tx, ctx, form)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, []repo.OrderRefund) error); ok {
+		r0 = rf(tx, ctx, form)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateOrderStatusChange provides a mock function with given fields: tx, ctx, form
+func (_m *OrderRepository) CreateOrderStatusChange(tx *sqlx.Tx, ctx context.Context, form repo.OrderStatusChange) error {
+	ret := _m.Called(tx, ctx, form)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, repo.OrderStatusChange) error); ok {
+		r0 = rf(tx, ctx, form)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetOrderByOrderID provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) GetOrderByOrderID(ctx context.Context, orderID int64) (repo.Order, error) {
+	ret := _m.Called(ctx, orderID)
+
+	var r0 repo.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (repo.Order, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) repo.Order); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		r0 = ret.Get(0).(repo.Order)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderByOrderIDForUpdate provides a mock function with given fields: tx, ctx, orderID
+func (_m *OrderRepository) GetOrderByOrderIDForUpdate(tx *sqlx.Tx, ctx context.Context, orderID int64) (repo.Order, error) {
+	ret := _m.Called(tx, ctx, orderID)
+
+	var r0 repo.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64) (repo.Order, error)); ok {
+		return rf(tx, ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64) repo.Order); ok {
+		r0 = rf(tx, ctx, orderID)
+	} else {
+		r0 = ret.Get(0).(repo.Order)
+	}
+
+	if rf, ok := ret.Get(1).(func(*sqlx.Tx, context.Context, int64) error); ok {
+		r1 = rf(tx, ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderDetailsByOrderID provides a mock function with given fields: tx, ctx, orderID
+func (_m *OrderRepository) GetOrderDetailsByOrderID(tx *sqlx.Tx, ctx context.Context, orderID int64) ([]repo.OrderDetail, error) {
+	ret := _m.Called(tx, ctx, orderID)
+
+	var r0 []repo.OrderDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64) ([]repo.OrderDetail, error)); ok {
+		return rf(tx, ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64) []repo.OrderDetail); ok {
+		r0 = rf(tx, ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.OrderDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*sqlx.Tx, context.Context, int64) error); ok {
+		r1 = rf(tx, ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderDiscountsByOrderID provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) GetOrderDiscountsByOrderID(ctx context.Context, orderID int64) ([]repo.OrderDiscount, error) {
+	ret := _m.Called(ctx, orderID)
+
+	var r0 []repo.OrderDiscount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]repo.OrderDiscount, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []repo.OrderDiscount); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.OrderDiscount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderLinesByOrderID provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) GetOrderLinesByOrderID(ctx context.Context, orderID int64) ([]repo.OrderLine, error) {
+	ret := _m.Called(ctx, orderID)
+
+	var r0 []repo.OrderLine
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]repo.OrderLine, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []repo.OrderLine); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.OrderLine)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderStatusHistoryByOrderID provides a mock function with given fields: ctx, orderID
+func (_m *OrderRepository) GetOrderStatusHistoryByOrderID(ctx context.Context, orderID int64) ([]repo.OrderStatusChange, error) {
+	ret := _m.Called(ctx, orderID)
+
+	var r0 []repo.OrderStatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]repo.OrderStatusChange, error)); ok {
+		return rf(ctx, orderID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []repo.OrderStatusChange); ok {
+		r0 = rf(ctx, orderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.OrderStatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrders provides a mock function with given fields: ctx, filter
+func (_m *OrderRepository) GetOrders(ctx context.Context, filter repo.OrderFilter) ([]repo.Order, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []repo.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repo.OrderFilter) ([]repo.Order, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repo.OrderFilter) []repo.Order); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repo.OrderFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RollbackTx provides a mock function with given fields: tx
 func (_m *OrderRepository) RollbackTx(tx *sqlx.Tx) error {
 	ret := _m.Called(tx)
@@ -108,6 +327,34 @@ func (_m *OrderRepository) RollbackTx(tx *sqlx.Tx) error {
 	return r0
 }
 
+// UpdateOrderDetailRefund provides a mock function with given fields: tx, ctx, form
+func (_m *OrderRepository) UpdateOrderDetailRefund(tx *sqlx.Tx, ctx context.Context, form repo.OrderDetail) error {
+	ret := _m.Called(tx, ctx, form)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, repo.OrderDetail) error); ok {
+		r0 = rf(tx, ctx, form)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateOrderStatus provides a mock function with given fields: tx, ctx, form
+func (_m *OrderRepository) UpdateOrderStatus(tx *sqlx.Tx, ctx context.Context, form repo.Order) error {
+	ret := _m.Called(tx, ctx, form)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, repo.Order) error); ok {
+		r0 = rf(tx, ctx, form)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewOrderRepository interface {
 	mock.TestingT
 	Cleanup(func())
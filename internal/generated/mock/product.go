@@ -4,11 +4,11 @@ package mock
 
 import (
 	context "context"
+	time "time"
 
+	sqlx "github.com/jmoiron/sqlx"
 	repo "github.com/learn/api-shop/internal/repo"
 	mock "github.com/stretchr/testify/mock"
-
-	sqlx "github.com/jmoiron/sqlx"
 )
 
 // ProductRepository is an autogenerated mock type for the ProductRepository type
@@ -16,6 +16,132 @@ type ProductRepository struct {
 	mock.Mock
 }
 
+// AdjustProductQty provides a mock function with given fields: tx, ctx, productID, delta
+func (_m *ProductRepository) AdjustProductQty(tx *sqlx.Tx, ctx context.Context, productID int64, delta int64) (repo.Product, error) {
+	ret := _m.Called(tx, ctx, productID, delta)
+
+	var r0 repo.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64, int64) (repo.Product, error)); ok {
+		return rf(tx, ctx, productID, delta)
+	}
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64, int64) repo.Product); ok {
+		r0 = rf(tx, ctx, productID, delta)
+	} else {
+		r0 = ret.Get(0).(repo.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(*sqlx.Tx, context.Context, int64, int64) error); ok {
+		r1 = rf(tx, ctx, productID, delta)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AdjustProductReservedQty provides a mock function with given fields: tx, ctx, productID, delta
+func (_m *ProductRepository) AdjustProductReservedQty(tx *sqlx.Tx, ctx context.Context, productID int64, delta int64) error {
+	ret := _m.Called(tx, ctx, productID, delta)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64, int64) error); ok {
+		r0 = rf(tx, ctx, productID, delta)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ArchiveProduct provides a mock function with given fields: ctx, productID, at
+func (_m *ProductRepository) ArchiveProduct(ctx context.Context, productID int64, at time.Time) (repo.Product, error) {
+	ret := _m.Called(ctx, productID, at)
+
+	var r0 repo.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) (repo.Product, error)); ok {
+		return rf(ctx, productID, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) repo.Product); ok {
+		r0 = rf(ctx, productID, at)
+	} else {
+		r0 = ret.Get(0).(repo.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, time.Time) error); ok {
+		r1 = rf(ctx, productID, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BeginTx provides a mock function with given fields:
+func (_m *ProductRepository) BeginTx() (*sqlx.Tx, error) {
+	ret := _m.Called()
+
+	var r0 *sqlx.Tx
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (*sqlx.Tx, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() *sqlx.Tx); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*sqlx.Tx)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CommitTx provides a mock function with given fields: tx
+func (_m *ProductRepository) CommitTx(tx *sqlx.Tx) error {
+	ret := _m.Called(tx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx) error); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateProduct provides a mock function with given fields: tx, ctx, form
+func (_m *ProductRepository) CreateProduct(tx *sqlx.Tx, ctx context.Context, form repo.Product) (repo.Product, error) {
+	ret := _m.Called(tx, ctx, form)
+
+	var r0 repo.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, repo.Product) (repo.Product, error)); ok {
+		return rf(tx, ctx, form)
+	}
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, repo.Product) repo.Product); ok {
+		r0 = rf(tx, ctx, form)
+	} else {
+		r0 = ret.Get(0).(repo.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(*sqlx.Tx, context.Context, repo.Product) error); ok {
+		r1 = rf(tx, ctx, form)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllProduct provides a mock function with given fields: ctx
 func (_m *ProductRepository) GetAllProduct(ctx context.Context) ([]repo.Product, error) {
 	ret := _m.Called(ctx)
@@ -66,6 +192,142 @@ func (_m *ProductRepository) GetProductByProductID(ctx context.Context, id int64
 	return r0, r1
 }
 
+// GetProductByProductIDForUpdate provides a mock function with given fields: tx, ctx, id
+func (_m *ProductRepository) GetProductByProductIDForUpdate(tx *sqlx.Tx, ctx context.Context, id int64) (repo.Product, error) {
+	ret := _m.Called(tx, ctx, id)
+
+	var r0 repo.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64) (repo.Product, error)); ok {
+		return rf(tx, ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64) repo.Product); ok {
+		r0 = rf(tx, ctx, id)
+	} else {
+		r0 = ret.Get(0).(repo.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(*sqlx.Tx, context.Context, int64) error); ok {
+		r1 = rf(tx, ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductByProductIDTx provides a mock function with given fields: tx, ctx, productID
+func (_m *ProductRepository) GetProductByProductIDTx(tx *sqlx.Tx, ctx context.Context, productID int64) (repo.Product, error) {
+	ret := _m.Called(tx, ctx, productID)
+
+	var r0 repo.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64) (repo.Product, error)); ok {
+		return rf(tx, ctx, productID)
+	}
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64) repo.Product); ok {
+		r0 = rf(tx, ctx, productID)
+	} else {
+		r0 = ret.Get(0).(repo.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(*sqlx.Tx, context.Context, int64) error); ok {
+		r1 = rf(tx, ctx, productID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProductBySku provides a mock function with given fields: ctx, sku
+func (_m *ProductRepository) GetProductBySku(ctx context.Context, sku string) (repo.Product, error) {
+	ret := _m.Called(ctx, sku)
+
+	var r0 repo.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (repo.Product, error)); ok {
+		return rf(ctx, sku)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) repo.Product); ok {
+		r0 = rf(ctx, sku)
+	} else {
+		r0 = ret.Get(0).(repo.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sku)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProducts provides a mock function with given fields: ctx, filter
+func (_m *ProductRepository) GetProducts(ctx context.Context, filter repo.ProductFilter) ([]repo.Product, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []repo.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repo.ProductFilter) ([]repo.Product, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repo.ProductFilter) []repo.Product); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repo.ProductFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RollbackTx provides a mock function with given fields: tx
+func (_m *ProductRepository) RollbackTx(tx *sqlx.Tx) error {
+	ret := _m.Called(tx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx) error); ok {
+		r0 = rf(tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateProduct provides a mock function with given fields: ctx, form
+func (_m *ProductRepository) UpdateProduct(ctx context.Context, form repo.Product) (repo.Product, error) {
+	ret := _m.Called(ctx, form)
+
+	var r0 repo.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repo.Product) (repo.Product, error)); ok {
+		return rf(ctx, form)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repo.Product) repo.Product); ok {
+		r0 = rf(ctx, form)
+	} else {
+		r0 = ret.Get(0).(repo.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repo.Product) error); ok {
+		r1 = rf(ctx, form)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateProductQtyByProductID provides a mock function with given fields: tx, ctx, form
 func (_m *ProductRepository) UpdateProductQtyByProductID(tx *sqlx.Tx, ctx context.Context, form repo.Product) error {
 	ret := _m.Called(tx, ctx, form)
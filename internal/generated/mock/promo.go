@@ -4,7 +4,9 @@ package mock
 
 import (
 	context "context"
+	time "time"
 
+	sqlx "github.com/jmoiron/sqlx"
 	repo "github.com/learn/api-shop/internal/repo"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -14,25 +16,101 @@ type PromoRepository struct {
 	mock.Mock
 }
 
-// GetAllPromo provides a mock function with given fields: ctx
-func (_m *PromoRepository) GetAllPromo(ctx context.Context) ([]repo.Promo, error) {
-	ret := _m.Called(ctx)
+// CreatePromo provides a mock function with given fields: ctx, form
+func (_m *PromoRepository) CreatePromo(ctx context.Context, form repo.Promo) (int64, error) {
+	ret := _m.Called(ctx, form)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repo.Promo) (int64, error)); ok {
+		return rf(ctx, form)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repo.Promo) int64); ok {
+		r0 = rf(ctx, form)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repo.Promo) error); ok {
+		r1 = rf(ctx, form)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeactivatePromo provides a mock function with given fields: ctx, promoID
+func (_m *PromoRepository) DeactivatePromo(ctx context.Context, promoID int64) error {
+	ret := _m.Called(ctx, promoID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, promoID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeletePromo provides a mock function with given fields: ctx, promoID
+func (_m *PromoRepository) DeletePromo(ctx context.Context, promoID int64) error {
+	ret := _m.Called(ctx, promoID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, promoID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAllPromo provides a mock function with given fields: ctx, at
+func (_m *PromoRepository) GetAllPromo(ctx context.Context, at time.Time) ([]repo.Promo, error) {
+	ret := _m.Called(ctx, at)
 
 	var r0 []repo.Promo
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]repo.Promo, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) ([]repo.Promo, error)); ok {
+		return rf(ctx, at)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []repo.Promo); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []repo.Promo); ok {
+		r0 = rf(ctx, at)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]repo.Promo)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPromoByProductID provides a mock function with given fields: ctx, productID, at
+func (_m *PromoRepository) GetPromoByProductID(ctx context.Context, productID int64, at time.Time) (repo.Promo, error) {
+	ret := _m.Called(ctx, productID, at)
+
+	var r0 repo.Promo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) (repo.Promo, error)); ok {
+		return rf(ctx, productID, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) repo.Promo); ok {
+		r0 = rf(ctx, productID, at)
+	} else {
+		r0 = ret.Get(0).(repo.Promo)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, time.Time) error); ok {
+		r1 = rf(ctx, productID, at)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -40,23 +118,23 @@ func (_m *PromoRepository) GetAllPromo(ctx context.Context) ([]repo.Promo, error
 	return r0, r1
 }
 
-// GetPromoByProductID provides a mock function with given fields: ctx, productID
-func (_m *PromoRepository) GetPromoByProductID(ctx context.Context, productID int64) (repo.Promo, error) {
-	ret := _m.Called(ctx, productID)
+// GetPromoByPromoID provides a mock function with given fields: ctx, promoID
+func (_m *PromoRepository) GetPromoByPromoID(ctx context.Context, promoID int64) (repo.Promo, error) {
+	ret := _m.Called(ctx, promoID)
 
 	var r0 repo.Promo
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, int64) (repo.Promo, error)); ok {
-		return rf(ctx, productID)
+		return rf(ctx, promoID)
 	}
 	if rf, ok := ret.Get(0).(func(context.Context, int64) repo.Promo); ok {
-		r0 = rf(ctx, productID)
+		r0 = rf(ctx, promoID)
 	} else {
 		r0 = ret.Get(0).(repo.Promo)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
-		r1 = rf(ctx, productID)
+		r1 = rf(ctx, promoID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -64,6 +142,72 @@ func (_m *PromoRepository) GetPromoByProductID(ctx context.Context, productID in
 	return r0, r1
 }
 
+// GetPromosByProductID provides a mock function with given fields: ctx, productID, at
+func (_m *PromoRepository) GetPromosByProductID(ctx context.Context, productID int64, at time.Time) ([]repo.Promo, error) {
+	ret := _m.Called(ctx, productID, at)
+
+	var r0 []repo.Promo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) ([]repo.Promo, error)); ok {
+		return rf(ctx, productID, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, time.Time) []repo.Promo); ok {
+		r0 = rf(ctx, productID, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.Promo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, time.Time) error); ok {
+		r1 = rf(ctx, productID, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPromosByProductIDTx provides a mock function with given fields: tx, ctx, productID, at
+func (_m *PromoRepository) GetPromosByProductIDTx(tx *sqlx.Tx, ctx context.Context, productID int64, at time.Time) ([]repo.Promo, error) {
+	ret := _m.Called(tx, ctx, productID, at)
+
+	var r0 []repo.Promo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64, time.Time) ([]repo.Promo, error)); ok {
+		return rf(tx, ctx, productID, at)
+	}
+	if rf, ok := ret.Get(0).(func(*sqlx.Tx, context.Context, int64, time.Time) []repo.Promo); ok {
+		r0 = rf(tx, ctx, productID, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repo.Promo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*sqlx.Tx, context.Context, int64, time.Time) error); ok {
+		r1 = rf(tx, ctx, productID, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdatePromo provides a mock function with given fields: ctx, form
+func (_m *PromoRepository) UpdatePromo(ctx context.Context, form repo.Promo) error {
+	ret := _m.Called(ctx, form)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repo.Promo) error); ok {
+		r0 = rf(ctx, form)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewPromoRepository interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,9 @@
+package infra
+
+import "time"
+
+type (
+	CartCfg struct {
+		IdleTTL time.Duration `envconfig:"IDLE_TTL" default:"72h"`
+	}
+)
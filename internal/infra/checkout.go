@@ -0,0 +1,9 @@
+package infra
+
+type (
+	CheckoutCfg struct {
+		RewardOutOfStock string `envconfig:"REWARD_OUT_OF_STOCK" default:"fail"`
+		MaxLines         int    `envconfig:"MAX_LINES" default:"50"`
+		MaxQty           int64  `envconfig:"MAX_QTY" default:"1000"`
+	}
+)
@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/money"
 	"go.uber.org/dig"
 )
 
@@ -27,6 +29,87 @@ func LoadMuxCfg() (*MuxCfg, error) {
 	return &cfg, nil
 }
 
+// LoadCurrency reads the currency every amount is priced in and how amounts
+// are rounded to its minor unit.
+func LoadCurrency() (money.Currency, error) {
+	var cfg CurrencyCfg
+	prefix := "MONEY"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return money.Currency{}, fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	rounding, err := money.ParseRoundingMode(cfg.Rounding)
+	if err != nil {
+		return money.Currency{}, fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	return money.Currency{
+		Code:     cfg.Currency,
+		Places:   cfg.Places,
+		Rounding: rounding,
+	}, nil
+}
+
+// LoadRewardStockPolicy reads what checkout does when a free reward product
+// is out of stock, "fail" or "skip".
+func LoadRewardStockPolicy() (service.RewardStockPolicy, error) {
+	var cfg CheckoutCfg
+	prefix := "CHECKOUT"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return "", fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	policy, err := service.ParseRewardStockPolicy(cfg.RewardOutOfStock)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	return policy, nil
+}
+
+// LoadCheckoutLimits reads how many distinct products one checkout may hold
+// and how many units of each.
+func LoadCheckoutLimits() (service.CheckoutLimits, error) {
+	var cfg CheckoutCfg
+	prefix := "CHECKOUT"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return service.CheckoutLimits{}, fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	return service.CheckoutLimits{
+		MaxLines: cfg.MaxLines,
+		MaxQty:   cfg.MaxQty,
+	}, nil
+}
+
+// LoadReservationPolicy reads how long reservations hold stock and how often
+// the expired ones are swept.
+func LoadReservationPolicy() (service.ReservationPolicy, error) {
+	var cfg ReservationCfg
+	prefix := "RESERVATION"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return service.ReservationPolicy{}, fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	return service.ReservationPolicy{
+		TTL:           cfg.TTL,
+		SweepInterval: cfg.SweepInterval,
+	}, nil
+}
+
+// LoadCartPolicy reads how long an open cart may sit idle before it expires.
+func LoadCartPolicy() (service.CartPolicy, error) {
+	var cfg CartCfg
+	prefix := "CART"
+	if err := envconfig.Process(prefix, &cfg); err != nil {
+		return service.CartPolicy{}, fmt.Errorf("%s: %w", prefix, err)
+	}
+
+	return service.CartPolicy{
+		IdleTTL: cfg.IdleTTL,
+	}, nil
+}
+
 func LoadHttpServer(p struct {
 	dig.In
 	Cfg *MuxCfg
@@ -0,0 +1,9 @@
+package infra
+
+type (
+	CurrencyCfg struct {
+		Currency string `envconfig:"CURRENCY" default:"USD" required:"true"`
+		Places   int32  `envconfig:"PLACES" default:"2"`
+		Rounding string `envconfig:"ROUNDING" default:"half_up"`
+	}
+)
@@ -0,0 +1,10 @@
+package infra
+
+import "time"
+
+type (
+	ReservationCfg struct {
+		TTL           time.Duration `envconfig:"TTL" default:"15m"`
+		SweepInterval time.Duration `envconfig:"SWEEP_INTERVAL" default:"1m"`
+	}
+)
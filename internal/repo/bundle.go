@@ -0,0 +1,212 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/learn/api-shop/pkg/sqlkit"
+	"go.uber.org/dig"
+)
+
+type (
+	// Bundle is a promo on a set of products bought together. Each time the
+	// cart holds every item of the bundle, those units are priced as one.
+	Bundle struct {
+		BundleID   int64  `json:"bundle_id" db:"bundle_id"`
+		Name       string `json:"name" db:"name"`
+		BundleType string `json:"bundle_type" db:"bundle_type"`
+		// Reward is the bundle's price or a percentage off its items,
+		// depending on BundleType.
+		Reward   money.Decimal `json:"reward" db:"reward"`
+		Active   bool          `json:"active" db:"active"`
+		StartsAt *time.Time    `json:"starts_at" db:"starts_at"`
+		EndsAt   *time.Time    `json:"ends_at" db:"ends_at"`
+		Timezone string        `json:"timezone" db:"timezone"`
+		Items    []BundleItem  `json:"items" db:"-"`
+	}
+
+	// BundleItem is how many units of a product one application of a bundle
+	// takes.
+	BundleItem struct {
+		BundleID  int64 `json:"bundle_id" db:"bundle_id"`
+		ProductID int64 `json:"product_id" db:"product_id"`
+		Qty       int64 `json:"qty" db:"qty"`
+	}
+
+	BundleRepository interface {
+		GetBundles(ctx context.Context, at time.Time) (res []Bundle, err error)
+		GetBundlesTx(tx *sqlx.Tx, ctx context.Context, at time.Time) (res []Bundle, err error)
+		GetBundleByBundleID(ctx context.Context, bundleID int64) (res Bundle, err error)
+		CreateBundle(tx *sqlx.Tx, ctx context.Context, form Bundle) (bundleID int64, err error)
+		CreateBundleItems(tx *sqlx.Tx, ctx context.Context, form []BundleItem) (err error)
+		DeactivateBundle(ctx context.Context, bundleID int64) (err error)
+		BeginTx() (tx *sqlx.Tx, err error)
+		RollbackTx(tx *sqlx.Tx) (err error)
+		CommitTx(tx *sqlx.Tx) (err error)
+	}
+
+	BundleRepoImpl struct {
+		dig.In
+		*sqlx.DB
+	}
+)
+
+const (
+	bundleColumns     = "bundle_id, name, bundle_type, reward, active, starts_at, ends_at, timezone"
+	bundleItemColumns = "bundle_id, product_id, qty"
+)
+
+func NewBundleRepository(impl BundleRepoImpl) BundleRepository {
+	return &impl
+}
+
+// GetBundles returns the bundles running at the given time with their items,
+// by bundle id.
+func (r *BundleRepoImpl) GetBundles(ctx context.Context, at time.Time) (res []Bundle, err error) {
+	return getBundles(ctx, r.DB, at)
+}
+
+// GetBundlesTx is GetBundles inside tx.
+func (r *BundleRepoImpl) GetBundlesTx(tx *sqlx.Tx, ctx context.Context, at time.Time) (res []Bundle, err error) {
+	return getBundles(ctx, tx, at)
+}
+
+func getBundles(ctx context.Context, q sqlx.QueryerContext, at time.Time) (res []Bundle, err error) {
+	rows, err := q.QueryxContext(ctx, "select "+bundleColumns+" from bundles where "+promoEffectiveAt(1)+" order by bundle_id asc", at)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := Bundle{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	for i := range res {
+		res[i].Items, err = getBundleItems(ctx, q, res[i].BundleID)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *BundleRepoImpl) GetBundleByBundleID(ctx context.Context, bundleID int64) (res Bundle, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+bundleColumns+" from bundles where bundle_id = $1", bundleID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	if res.BundleID == 0 {
+		return res, nil
+	}
+
+	res.Items, err = getBundleItems(ctx, r.DB, res.BundleID)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func getBundleItems(ctx context.Context, q sqlx.QueryerContext, bundleID int64) (res []BundleItem, err error) {
+	rows, err := q.QueryxContext(ctx, "select "+bundleItemColumns+" from bundle_items where bundle_id = $1 order by product_id asc", bundleID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := BundleItem{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+func (r *BundleRepoImpl) CreateBundle(tx *sqlx.Tx, ctx context.Context, form Bundle) (bundleID int64, err error) {
+	err = tx.QueryRowxContext(ctx, "insert into bundles(name, bundle_type, reward, active, starts_at, ends_at, timezone) values($1, $2, $3, $4, $5, $6, $7) RETURNING bundle_id",
+		form.Name, form.BundleType, form.Reward, form.Active, form.StartsAt, form.EndsAt, form.Timezone).Scan(&bundleID)
+	if err != nil {
+		return bundleID, err
+	}
+
+	return bundleID, nil
+}
+
+func (r *BundleRepoImpl) CreateBundleItems(tx *sqlx.Tx, ctx context.Context, form []BundleItem) (err error) {
+	sqlInsert := "insert into bundle_items(bundle_id, product_id, qty) values"
+	rowSQL := "(?, ?, ?)"
+
+	vals := []interface{}{}
+	var inserts []string
+
+	for _, val := range form {
+		vals = append(vals, val.BundleID, val.ProductID, val.Qty)
+		inserts = append(inserts, rowSQL)
+	}
+
+	sqlInsert = sqlInsert + strings.Join(inserts, ",")
+	sqlInsert = sqlkit.ReplaceSQL(sqlInsert, "?")
+
+	stmt, err := tx.PrepareContext(ctx, sqlInsert)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, vals...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *BundleRepoImpl) DeactivateBundle(ctx context.Context, bundleID int64) (err error) {
+	_, err = r.DB.ExecContext(ctx, "update bundles set active = false where bundle_id = $1", bundleID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *BundleRepoImpl) BeginTx() (tx *sqlx.Tx, err error) {
+	return r.DB.Beginx()
+}
+
+func (r *BundleRepoImpl) RollbackTx(tx *sqlx.Tx) (err error) {
+	return tx.Rollback()
+}
+
+func (r *BundleRepoImpl) CommitTx(tx *sqlx.Tx) (err error) {
+	return tx.Commit()
+}
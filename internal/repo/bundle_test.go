@@ -0,0 +1,108 @@
+package repo_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundleRepoImpl(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	columns := []string{"bundle_id", "name", "bundle_type", "reward", "active", "starts_at", "ends_at", "timezone"}
+	itemColumns := []string{"bundle_id", "product_id", "qty"}
+	selectSQL := "select bundle_id, name, bundle_type, reward, active, starts_at, ends_at, timezone from bundles"
+	itemsSQL := "select bundle_id, product_id, qty from bundle_items where bundle_id = $1 order by product_id asc"
+	bundle := repo.Bundle{
+		BundleID:   2,
+		Name:       "Smart home",
+		BundleType: "discount",
+		Reward:     money.MustParse("15"),
+		Active:     true,
+		Items: []repo.BundleItem{
+			{BundleID: 2, ProductID: 1, Qty: 1},
+			{BundleID: 2, ProductID: 3, Qty: 1},
+		},
+	}
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("insert into bundles(name, bundle_type, reward, active, starts_at, ends_at, timezone) values($1, $2, $3, $4, $5, $6, $7) RETURNING bundle_id")).
+		WithArgs("Smart home", "discount", bundle.Reward, true, nil, nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"bundle_id"}).AddRow(2))
+	mock.ExpectPrepare(regexp.QuoteMeta("insert into bundle_items(bundle_id, product_id, qty) values($1, $2, $3),($4, $5, $6)")).
+		ExpectExec().
+		WithArgs(2, 1, 1, 2, 3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " where active and")).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Smart home", "discount", "15", true, nil, nil, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(itemsSQL)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(itemColumns).AddRow(2, 1, 1).AddRow(2, 3, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " where bundle_id = $1")).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Smart home", "discount", "15", true, nil, nil, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(itemsSQL)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(itemColumns).AddRow(2, 1, 1).AddRow(2, 3, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " where bundle_id = $1")).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectExec(regexp.QuoteMeta("update bundles set active = false where bundle_id = $1")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " where active and")).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Smart home", "discount", "15", true, nil, nil, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(itemsSQL)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(itemColumns).AddRow(2, 1, 1).AddRow(2, 3, 1))
+	mock.ExpectRollback()
+
+	bundleRepo := repo.NewBundleRepository(repo.BundleRepoImpl{DB: sqlx.NewDb(db, "sqlmock")})
+	ctx := context.Background()
+
+	tx, err := bundleRepo.BeginTx()
+	assert.NoError(t, err)
+
+	bundleID, err := bundleRepo.CreateBundle(tx, ctx, bundle)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), bundleID)
+	assert.NoError(t, bundleRepo.CreateBundleItems(tx, ctx, bundle.Items))
+	assert.NoError(t, bundleRepo.CommitTx(tx))
+
+	bundles, err := bundleRepo.GetBundles(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.Bundle{bundle}, bundles)
+
+	res, err := bundleRepo.GetBundleByBundleID(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, bundle, res)
+
+	res, err = bundleRepo.GetBundleByBundleID(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Bundle{}, res)
+
+	assert.NoError(t, bundleRepo.DeactivateBundle(ctx, 2))
+
+	tx, err = bundleRepo.BeginTx()
+	assert.NoError(t, err)
+	bundles, err = bundleRepo.GetBundlesTx(tx, ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.Bundle{bundle}, bundles)
+	assert.NoError(t, bundleRepo.RollbackTx(tx))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,206 @@
+//go:generate mockery --dir=$PROJECT_DIR/internal/repo  --name=CartRepository --filename=$GOFILE --output=$PROJECT_DIR/internal/generated/mock --outpkg=mock
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/dig"
+)
+
+type (
+	// Cart is a customer's, or a guest's when CustomerID is empty, list of
+	// products to check out later.
+	Cart struct {
+		CartID     int64  `json:"cart_id" db:"cart_id"`
+		CustomerID string `json:"customer_id" db:"customer_id"`
+		Status     string `json:"status" db:"status"`
+		// OrderID is zero until the cart is checked out.
+		OrderID   int64      `json:"order_id" db:"order_id"`
+		CreatedAt time.Time  `json:"created_at" db:"created_at"`
+		UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+		Items     []CartItem `json:"items" db:"-"`
+		// ExpiresAt is set by the service for open carts only.
+		ExpiresAt time.Time `json:"expires_at" db:"-"`
+	}
+
+	CartItem struct {
+		CartItemID int64 `json:"cart_item_id" db:"cart_item_id"`
+		CartID     int64 `json:"cart_id" db:"cart_id"`
+		ProductID  int64 `json:"product_id" db:"product_id"`
+		Qty        int64 `json:"qty" db:"qty"`
+	}
+
+	CartRepository interface {
+		CreateCart(tx *sqlx.Tx, ctx context.Context, form Cart) (cartID int64, err error)
+		GetCartByCartID(ctx context.Context, cartID int64) (res Cart, err error)
+		GetCartByCartIDForUpdate(tx *sqlx.Tx, ctx context.Context, cartID int64) (res Cart, err error)
+		GetCartItemsByCartID(ctx context.Context, cartID int64) (res []CartItem, err error)
+		GetCartItemsByCartIDTx(tx *sqlx.Tx, ctx context.Context, cartID int64) (res []CartItem, err error)
+		UpsertCartItem(tx *sqlx.Tx, ctx context.Context, form CartItem) (err error)
+		DeleteCartItem(tx *sqlx.Tx, ctx context.Context, cartID, productID int64) (err error)
+		UpdateCart(tx *sqlx.Tx, ctx context.Context, form Cart) (err error)
+		BeginTx() (tx *sqlx.Tx, err error)
+		RollbackTx(tx *sqlx.Tx) (err error)
+		CommitTx(tx *sqlx.Tx) (err error)
+	}
+
+	CartRepoImpl struct {
+		dig.In
+		*sqlx.DB
+	}
+)
+
+const (
+	CartStatusOpen       = "open"
+	CartStatusCheckedOut = "checked_out"
+	CartStatusMerged     = "merged"
+)
+
+const (
+	cartColumns     = "cart_id, customer_id, status, coalesce(order_id, 0) as order_id, created_at, updated_at"
+	cartItemColumns = "cart_item_id, cart_id, product_id, qty"
+)
+
+func NewCartRepository(impl CartRepoImpl) CartRepository {
+	return &impl
+}
+
+func (r *CartRepoImpl) CreateCart(tx *sqlx.Tx, ctx context.Context, form Cart) (cartID int64, err error) {
+	err = tx.QueryRowxContext(ctx, "insert into carts(customer_id, status, created_at, updated_at) values($1, $2, $3, $3) RETURNING cart_id",
+		form.CustomerID, form.Status, form.CreatedAt).Scan(&cartID)
+	if err != nil {
+		return cartID, err
+	}
+
+	return cartID, nil
+}
+
+func (r *CartRepoImpl) GetCartByCartID(ctx context.Context, cartID int64) (res Cart, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+cartColumns+" from carts where cart_id = $1", cartID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// GetCartByCartIDForUpdate reads the cart inside tx and locks its row until tx
+// ends, so changes to the same cart are serialized. A zero CartID in res
+// means the cart doesn't exist.
+func (r *CartRepoImpl) GetCartByCartIDForUpdate(tx *sqlx.Tx, ctx context.Context, cartID int64) (res Cart, err error) {
+	rows, err := tx.QueryxContext(ctx, "select "+cartColumns+" from carts where cart_id = $1 for update", cartID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *CartRepoImpl) GetCartItemsByCartID(ctx context.Context, cartID int64) (res []CartItem, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+cartItemColumns+" from cart_items where cart_id = $1 order by cart_item_id asc", cartID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := CartItem{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+// GetCartItemsByCartIDTx is GetCartItemsByCartID inside tx, so it sees the
+// changes tx made.
+func (r *CartRepoImpl) GetCartItemsByCartIDTx(tx *sqlx.Tx, ctx context.Context, cartID int64) (res []CartItem, err error) {
+	rows, err := tx.QueryxContext(ctx, "select "+cartItemColumns+" from cart_items where cart_id = $1 order by cart_item_id asc", cartID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := CartItem{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+// UpsertCartItem sets the qty of the product in the cart, adding the product
+// when it isn't in the cart yet.
+func (r *CartRepoImpl) UpsertCartItem(tx *sqlx.Tx, ctx context.Context, form CartItem) (err error) {
+	_, err = tx.ExecContext(ctx, "insert into cart_items(cart_id, product_id, qty) values($1, $2, $3) on conflict (cart_id, product_id) do update set qty = excluded.qty",
+		form.CartID, form.ProductID, form.Qty)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CartRepoImpl) DeleteCartItem(tx *sqlx.Tx, ctx context.Context, cartID, productID int64) (err error) {
+	_, err = tx.ExecContext(ctx, "delete from cart_items where cart_id = $1 and product_id = $2", cartID, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateCart stores the cart's owner, status and order, and when it was last
+// touched.
+func (r *CartRepoImpl) UpdateCart(tx *sqlx.Tx, ctx context.Context, form Cart) (err error) {
+	_, err = tx.ExecContext(ctx, "update carts set customer_id = $1, status = $2, order_id = nullif(cast($3 as bigint), 0), updated_at = $4 where cart_id = $5",
+		form.CustomerID, form.Status, form.OrderID, form.UpdatedAt, form.CartID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CartRepoImpl) BeginTx() (tx *sqlx.Tx, err error) {
+	return r.DB.Beginx()
+}
+
+func (r *CartRepoImpl) RollbackTx(tx *sqlx.Tx) (err error) {
+	return tx.Rollback()
+}
+
+func (r *CartRepoImpl) CommitTx(tx *sqlx.Tx) (err error) {
+	return tx.Commit()
+}
@@ -0,0 +1,80 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCartRepoImpl(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	cartColumns := []string{"cart_id", "customer_id", "status", "order_id", "created_at", "updated_at"}
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("insert into carts(customer_id, status, created_at, updated_at) values($1, $2, $3, $3) RETURNING cart_id")).
+		WithArgs("alice", "open", now).
+		WillReturnRows(sqlmock.NewRows([]string{"cart_id"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta("select cart_id, customer_id, status, coalesce(order_id, 0) as order_id, created_at, updated_at from carts where cart_id = $1 for update")).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(cartColumns).AddRow(5, "alice", "open", 0, now, now))
+	mock.ExpectExec(regexp.QuoteMeta("insert into cart_items(cart_id, product_id, qty) values($1, $2, $3) on conflict (cart_id, product_id) do update set qty = excluded.qty")).
+		WithArgs(5, 3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("delete from cart_items where cart_id = $1 and product_id = $2")).
+		WithArgs(5, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("select cart_item_id, cart_id, product_id, qty from cart_items where cart_id = $1 order by cart_item_id asc")).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"cart_item_id", "cart_id", "product_id", "qty"}).AddRow(1, 5, 3, 2))
+	mock.ExpectExec(regexp.QuoteMeta("update carts set customer_id = $1, status = $2, order_id = nullif(cast($3 as bigint), 0), updated_at = $4 where cart_id = $5")).
+		WithArgs("alice", "checked_out", 11, now, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("update carts").WillReturnError(errors.New("update error"))
+	mock.ExpectQuery(regexp.QuoteMeta("select cart_id, customer_id, status, coalesce(order_id, 0) as order_id, created_at, updated_at from carts where cart_id = $1")).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(cartColumns))
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	cartRepo := repo.NewCartRepository(repo.CartRepoImpl{DB: sqlxDB})
+	ctx := context.Background()
+
+	cartID, err := cartRepo.CreateCart(tx, ctx, repo.Cart{CustomerID: "alice", Status: repo.CartStatusOpen, CreatedAt: now})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), cartID)
+
+	cart, err := cartRepo.GetCartByCartIDForUpdate(tx, ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Cart{CartID: 5, CustomerID: "alice", Status: "open", CreatedAt: now, UpdatedAt: now}, cart)
+
+	assert.NoError(t, cartRepo.UpsertCartItem(tx, ctx, repo.CartItem{CartID: 5, ProductID: 3, Qty: 2}))
+	assert.NoError(t, cartRepo.DeleteCartItem(tx, ctx, 5, 4))
+
+	items, err := cartRepo.GetCartItemsByCartIDTx(tx, ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.CartItem{{CartItemID: 1, CartID: 5, ProductID: 3, Qty: 2}}, items)
+
+	cart.Status = repo.CartStatusCheckedOut
+	cart.OrderID = 11
+	assert.NoError(t, cartRepo.UpdateCart(tx, ctx, cart))
+	assert.EqualError(t, cartRepo.UpdateCart(tx, ctx, cart), "update error")
+
+	missing, err := cartRepo.GetCartByCartID(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), missing.CartID)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
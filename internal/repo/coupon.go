@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/learn/api-shop/pkg/sqlkit"
+	"github.com/lib/pq"
+	"go.uber.org/dig"
+)
+
+type (
+	// Coupon is a code a customer redeems at checkout for a discount on the
+	// whole order or a free product.
+	Coupon struct {
+		CouponID   int64  `json:"coupon_id" db:"coupon_id"`
+		Code       string `json:"code" db:"code"`
+		CouponType string `json:"coupon_type" db:"coupon_type"`
+		// Reward is a percentage, an amount or a product id depending on
+		// CouponType.
+		Reward money.Decimal `json:"reward" db:"reward"`
+		// MinBasket is what the order must come to, after promos, for the
+		// coupon to apply.
+		MinBasket money.Decimal `json:"min_basket" db:"min_basket"`
+		// UsageLimit caps the redemptions of the coupon, zero is unlimited.
+		UsageLimit int64      `json:"usage_limit" db:"usage_limit"`
+		Active     bool       `json:"active" db:"active"`
+		ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+		CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	}
+
+	// CouponRedemption records a coupon used by an order.
+	CouponRedemption struct {
+		RedemptionID int64         `json:"redemption_id" db:"redemption_id"`
+		CouponID     int64         `json:"coupon_id" db:"coupon_id"`
+		OrderID      int64         `json:"order_id" db:"order_id"`
+		Discount     money.Decimal `json:"discount" db:"discount"`
+		CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+	}
+
+	CouponRepository interface {
+		CreateCoupon(ctx context.Context, form Coupon) (couponID int64, err error)
+		GetCouponByCode(ctx context.Context, code string) (res Coupon, err error)
+		GetCouponByCodeForUpdate(tx *sqlx.Tx, ctx context.Context, code string) (res Coupon, err error)
+		DeactivateCoupon(ctx context.Context, couponID int64) (err error)
+		CountCouponRedemptions(ctx context.Context, couponID int64) (uses int64, err error)
+		CountCouponRedemptionsTx(tx *sqlx.Tx, ctx context.Context, couponID int64) (uses int64, err error)
+		CreateCouponRedemptions(tx *sqlx.Tx, ctx context.Context, form []CouponRedemption) (err error)
+	}
+
+	CouponRepoImpl struct {
+		dig.In
+		*sqlx.DB
+	}
+)
+
+// ErrDuplicateCoupon is returned when a coupon write would reuse a code.
+var ErrDuplicateCoupon = apperr.New(apperr.CodeConflict, "coupon code already exists")
+
+const (
+	couponColumns = "coupon_id, code, coupon_type, reward, min_basket, usage_limit, active, expires_at, created_at"
+
+	// countRedemptionsSQL counts the redemptions of coupon $1.
+	countRedemptionsSQL = "select count(*) from coupon_redemptions where coupon_id = $1"
+)
+
+func NewCouponRepository(impl CouponRepoImpl) CouponRepository {
+	return &impl
+}
+
+func (r *CouponRepoImpl) CreateCoupon(ctx context.Context, form Coupon) (couponID int64, err error) {
+	err = r.DB.QueryRowxContext(ctx, "insert into coupons(code, coupon_type, reward, min_basket, usage_limit, active, expires_at, created_at) values($1, $2, $3, $4, $5, $6, $7, $8) RETURNING coupon_id",
+		form.Code, form.CouponType, form.Reward, form.MinBasket, form.UsageLimit, form.Active, form.ExpiresAt, form.CreatedAt).Scan(&couponID)
+	if err != nil {
+		return couponID, couponWriteError(err)
+	}
+
+	return couponID, nil
+}
+
+// GetCouponByCode returns the coupon with the code, a zero CouponID when there
+// is none.
+func (r *CouponRepoImpl) GetCouponByCode(ctx context.Context, code string) (res Coupon, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+couponColumns+" from coupons where code = $1", code)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// GetCouponByCodeForUpdate reads the coupon inside tx and locks its row until
+// tx ends, so concurrent checkouts can't both take its last redemption.
+func (r *CouponRepoImpl) GetCouponByCodeForUpdate(tx *sqlx.Tx, ctx context.Context, code string) (res Coupon, err error) {
+	rows, err := tx.QueryxContext(ctx, "select "+couponColumns+" from coupons where code = $1 for update", code)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *CouponRepoImpl) DeactivateCoupon(ctx context.Context, couponID int64) (err error) {
+	_, err = r.DB.ExecContext(ctx, "update coupons set active = false where coupon_id = $1", couponID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CouponRepoImpl) CountCouponRedemptions(ctx context.Context, couponID int64) (uses int64, err error) {
+	err = r.DB.QueryRowxContext(ctx, countRedemptionsSQL, couponID).Scan(&uses)
+	if err != nil {
+		return 0, err
+	}
+
+	return uses, nil
+}
+
+func (r *CouponRepoImpl) CountCouponRedemptionsTx(tx *sqlx.Tx, ctx context.Context, couponID int64) (uses int64, err error) {
+	err = tx.QueryRowxContext(ctx, countRedemptionsSQL, couponID).Scan(&uses)
+	if err != nil {
+		return 0, err
+	}
+
+	return uses, nil
+}
+
+func (r *CouponRepoImpl) CreateCouponRedemptions(tx *sqlx.Tx, ctx context.Context, form []CouponRedemption) (err error) {
+	if len(form) == 0 {
+		return nil
+	}
+
+	sqlInsert := "insert into coupon_redemptions(coupon_id, order_id, discount, created_at) values"
+	rowSQL := "(?, ?, ?, ?)"
+
+	vals := []interface{}{}
+	var inserts []string
+
+	for _, val := range form {
+		vals = append(vals, val.CouponID, val.OrderID, val.Discount, val.CreatedAt)
+		inserts = append(inserts, rowSQL)
+	}
+
+	sqlInsert = sqlInsert + strings.Join(inserts, ",")
+	sqlInsert = sqlkit.ReplaceSQL(sqlInsert, "?")
+
+	stmt, err := tx.PrepareContext(ctx, sqlInsert)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, vals...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func couponWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "coupons_code_key" {
+		return ErrDuplicateCoupon
+	}
+
+	return err
+}
@@ -0,0 +1,111 @@
+package repo_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCouponRepoImpl(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(24 * time.Hour)
+	columns := []string{"coupon_id", "code", "coupon_type", "reward", "min_basket", "usage_limit", "active", "expires_at", "created_at"}
+	selectSQL := "select coupon_id, code, coupon_type, reward, min_basket, usage_limit, active, expires_at, created_at from coupons where code = $1"
+	countSQL := regexp.QuoteMeta("select count(*) from coupon_redemptions where coupon_id = $1")
+	coupon := repo.Coupon{
+		CouponID:   3,
+		Code:       "SAVE10",
+		CouponType: "percent",
+		Reward:     money.MustParse("10"),
+		MinBasket:  money.MustParse("50"),
+		UsageLimit: 100,
+		Active:     true,
+		ExpiresAt:  &expiresAt,
+		CreatedAt:  now,
+	}
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("insert into coupons(code, coupon_type, reward, min_basket, usage_limit, active, expires_at, created_at) values($1, $2, $3, $4, $5, $6, $7, $8) RETURNING coupon_id")).
+		WithArgs("SAVE10", "percent", coupon.Reward, coupon.MinBasket, 100, true, &expiresAt, now).
+		WillReturnRows(sqlmock.NewRows([]string{"coupon_id"}).AddRow(3))
+	mock.ExpectQuery("insert into coupons").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "coupons_code_key"})
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL)).
+		WithArgs("SAVE10").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "SAVE10", "percent", "10", "50", 100, true, expiresAt, now))
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL)).
+		WithArgs("NOPE").
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery(countSQL).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " for update")).
+		WithArgs("SAVE10").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "SAVE10", "percent", "10", "50", 100, true, expiresAt, now))
+	mock.ExpectQuery(countSQL).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	mock.ExpectPrepare(regexp.QuoteMeta("insert into coupon_redemptions(coupon_id, order_id, discount, created_at) values($1, $2, $3, $4),($5, $6, $7, $8)")).
+		ExpectExec().
+		WithArgs(3, 11, money.MustParse("5.5"), now, 4, 11, money.MustParse("0"), now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("update coupons set active = false where coupon_id = $1")).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	couponRepo := repo.NewCouponRepository(repo.CouponRepoImpl{DB: sqlxDB})
+	ctx := context.Background()
+
+	couponID, err := couponRepo.CreateCoupon(ctx, coupon)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), couponID)
+
+	_, err = couponRepo.CreateCoupon(ctx, coupon)
+	assert.ErrorIs(t, err, repo.ErrDuplicateCoupon)
+
+	res, err := couponRepo.GetCouponByCode(ctx, "SAVE10")
+	assert.NoError(t, err)
+	assert.Equal(t, coupon, res)
+
+	res, err = couponRepo.GetCouponByCode(ctx, "NOPE")
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Coupon{}, res)
+
+	uses, err := couponRepo.CountCouponRedemptions(ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), uses)
+
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	res, err = couponRepo.GetCouponByCodeForUpdate(tx, ctx, "SAVE10")
+	assert.NoError(t, err)
+	assert.Equal(t, coupon, res)
+
+	uses, err = couponRepo.CountCouponRedemptionsTx(tx, ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), uses)
+
+	assert.NoError(t, couponRepo.CreateCouponRedemptions(tx, ctx, []repo.CouponRedemption{
+		{CouponID: 3, OrderID: 11, Discount: money.MustParse("5.5"), CreatedAt: now},
+		{CouponID: 4, OrderID: 11, Discount: money.MustParse("0"), CreatedAt: now},
+	}))
+	assert.NoError(t, couponRepo.CreateCouponRedemptions(tx, ctx, nil))
+
+	assert.NoError(t, couponRepo.DeactivateCoupon(ctx, 3))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
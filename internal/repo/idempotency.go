@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/dig"
+)
+
+type (
+	// IdempotencyKey remembers the request a client key was first used with
+	// and the response it got, so a retry can be answered the same way.
+	IdempotencyKey struct {
+		Key         string `json:"idempotency_key" db:"idempotency_key"`
+		RequestHash string `json:"request_hash" db:"request_hash"`
+		// Response is the JSON encoded response, nil until it is stored.
+		Response  []byte    `json:"response" db:"response"`
+		OrderID   int64     `json:"order_id" db:"order_id"`
+		CreatedAt time.Time `json:"created_at" db:"created_at"`
+	}
+
+	IdempotencyRepository interface {
+		CreateIdempotencyKey(tx *sqlx.Tx, ctx context.Context, form IdempotencyKey) (created bool, err error)
+		GetIdempotencyKey(tx *sqlx.Tx, ctx context.Context, key string) (res IdempotencyKey, err error)
+		UpdateIdempotencyKeyResponse(tx *sqlx.Tx, ctx context.Context, form IdempotencyKey) (err error)
+	}
+
+	IdempotencyRepoImpl struct {
+		dig.In
+		*sqlx.DB
+	}
+)
+
+func NewIdempotencyRepository(impl IdempotencyRepoImpl) IdempotencyRepository {
+	return &impl
+}
+
+// CreateIdempotencyKey claims the key inside tx. created is false when the key
+// is already taken; a concurrent claim of the same key waits for the other
+// transaction to end first.
+func (r *IdempotencyRepoImpl) CreateIdempotencyKey(tx *sqlx.Tx, ctx context.Context, form IdempotencyKey) (created bool, err error) {
+	result, err := tx.ExecContext(ctx, "insert into idempotency_keys(idempotency_key, request_hash, created_at) values($1, $2, $3) on conflict (idempotency_key) do nothing",
+		form.Key, form.RequestHash, form.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *IdempotencyRepoImpl) GetIdempotencyKey(tx *sqlx.Tx, ctx context.Context, key string) (res IdempotencyKey, err error) {
+	rows, err := tx.QueryxContext(ctx, "select idempotency_key, request_hash, response, coalesce(order_id, 0) as order_id, created_at from idempotency_keys where idempotency_key = $1", key)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *IdempotencyRepoImpl) UpdateIdempotencyKeyResponse(tx *sqlx.Tx, ctx context.Context, form IdempotencyKey) (err error) {
+	_, err = tx.ExecContext(ctx, "update idempotency_keys set response = $1, order_id = $2 where idempotency_key = $3",
+		form.Response, form.OrderID, form.Key)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
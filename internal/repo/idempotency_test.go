@@ -0,0 +1,66 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyRepoImpl(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	insertSQL := regexp.QuoteMeta("insert into idempotency_keys(idempotency_key, request_hash, created_at) values($1, $2, $3) on conflict (idempotency_key) do nothing")
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(insertSQL).
+		WithArgs("retry-1", "abc", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insertSQL).
+		WithArgs("retry-1", "abc", now).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("update idempotency_keys set response = $1, order_id = $2 where idempotency_key = $3")).
+		WithArgs([]byte(`{"order_id":11}`), 11, "retry-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("select idempotency_key, request_hash, response, coalesce(order_id, 0) as order_id, created_at from idempotency_keys where idempotency_key = $1")).
+		WithArgs("retry-1").
+		WillReturnRows(sqlmock.NewRows([]string{"idempotency_key", "request_hash", "response", "order_id", "created_at"}).
+			AddRow("retry-1", "abc", []byte(`{"order_id":11}`), 11, now))
+	mock.ExpectExec("insert into idempotency_keys").WillReturnError(errors.New("insert error"))
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	idempotencyRepo := repo.NewIdempotencyRepository(repo.IdempotencyRepoImpl{DB: sqlxDB})
+	ctx := context.Background()
+	key := repo.IdempotencyKey{Key: "retry-1", RequestHash: "abc", CreatedAt: now}
+
+	created, err := idempotencyRepo.CreateIdempotencyKey(tx, ctx, key)
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	created, err = idempotencyRepo.CreateIdempotencyKey(tx, ctx, key)
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	assert.NoError(t, idempotencyRepo.UpdateIdempotencyKeyResponse(tx, ctx, repo.IdempotencyKey{Key: "retry-1", Response: []byte(`{"order_id":11}`), OrderID: 11}))
+
+	res, err := idempotencyRepo.GetIdempotencyKey(tx, ctx, "retry-1")
+	assert.NoError(t, err)
+	assert.Equal(t, repo.IdempotencyKey{Key: "retry-1", RequestHash: "abc", Response: []byte(`{"order_id":11}`), OrderID: 11, CreatedAt: now}, res)
+
+	_, err = idempotencyRepo.CreateIdempotencyKey(tx, ctx, key)
+	assert.EqualError(t, err, "insert error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -7,29 +7,113 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/pkg/money"
 	"github.com/learn/api-shop/pkg/sqlkit"
 	"go.uber.org/dig"
 )
 
 type (
 	Order struct {
-		OrderID int64     `json:"order_id" db:"order_id"`
-		Date    time.Time `json:"date" db:"date"`
-		Total   float64   `json:"total" db:"total"`
+		OrderID       int64         `json:"order_id" db:"order_id"`
+		Date          time.Time     `json:"date" db:"date"`
+		Subtotal      money.Decimal `json:"subtotal" db:"subtotal"`
+		DiscountTotal money.Decimal `json:"discount_total" db:"discount_total"`
+		Total         money.Decimal `json:"total" db:"total"`
+		ItemCount     int64         `json:"item_count" db:"item_count"`
+		Status        string        `json:"status" db:"status"`
+		RefundedTotal money.Decimal `json:"refunded_total" db:"refunded_total"`
 	}
 
 	OrderDetail struct {
-		OrderDetailID int64   `json:"order_detail_id" db:"order_detail_id"`
-		OrderID       int64   `json:"order_id" db:"order_id"`
-		ProductID     int64   `json:"product_id" db:"product_id"`
-		PromoID       int64   `json:"promo_id" db:"promo_id"`
-		Price         float64 `json:"price" db:"price"`
-		Qty           int64   `json:"qty" db:"qty"`
+		OrderDetailID int64         `json:"order_detail_id" db:"order_detail_id"`
+		OrderID       int64         `json:"order_id" db:"order_id"`
+		ProductID     int64         `json:"product_id" db:"product_id"`
+		PromoID       int64         `json:"promo_id" db:"promo_id"`
+		Price         money.Decimal `json:"price" db:"price"`
+		Qty           int64         `json:"qty" db:"qty"`
+		// RefundedQty and RefundedAmount add up what was refunded of the
+		// line so far.
+		RefundedQty    int64         `json:"refunded_qty" db:"refunded_qty"`
+		RefundedAmount money.Decimal `json:"refunded_amount" db:"refunded_amount"`
+		// OrderPromoID or CouponID is the order promo or coupon that gave
+		// the line away, both zero for lines sold or given by a product
+		// promo.
+		OrderPromoID int64 `json:"order_promo_id" db:"order_promo_id"`
+		CouponID     int64 `json:"coupon_id" db:"coupon_id"`
+	}
+
+	OrderLine struct {
+		OrderDetailID int64         `json:"order_detail_id" db:"order_detail_id"`
+		OrderID       int64         `json:"order_id" db:"order_id"`
+		ProductID     int64         `json:"product_id" db:"product_id"`
+		ProductName   string        `json:"product_name" db:"product_name"`
+		PromoID       int64         `json:"promo_id" db:"promo_id"`
+		PromoType     string        `json:"promo_type" db:"promo_type"`
+		PromoReward   money.Decimal `json:"promo_reward" db:"promo_reward"`
+		OrderPromoID  int64         `json:"order_promo_id" db:"order_promo_id"`
+		CouponID      int64         `json:"coupon_id" db:"coupon_id"`
+		Price         money.Decimal `json:"price" db:"price"`
+		Qty           int64         `json:"qty" db:"qty"`
+		RefundedQty   int64         `json:"refunded_qty" db:"refunded_qty"`
+	}
+
+	// OrderRefund records qty of an order line given back and the amount
+	// refunded for it.
+	OrderRefund struct {
+		RefundID      int64         `json:"refund_id" db:"refund_id"`
+		OrderID       int64         `json:"order_id" db:"order_id"`
+		OrderDetailID int64         `json:"order_detail_id" db:"order_detail_id"`
+		ProductID     int64         `json:"product_id" db:"product_id"`
+		Qty           int64         `json:"qty" db:"qty"`
+		Amount        money.Decimal `json:"amount" db:"amount"`
+		Reason        string        `json:"reason" db:"reason"`
+		Actor         string        `json:"actor" db:"actor"`
+		CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+	}
+
+	// OrderDiscount records an amount an order promo took off an order.
+	OrderDiscount struct {
+		OrderDiscountID int64         `json:"order_discount_id" db:"order_discount_id"`
+		OrderID         int64         `json:"order_id" db:"order_id"`
+		OrderPromoID    int64         `json:"order_promo_id" db:"order_promo_id"`
+		PromoType       string        `json:"promo_type" db:"promo_type"`
+		Discount        money.Decimal `json:"discount" db:"discount"`
+	}
+
+	// OrderStatusChange records one move of an order from a status to
+	// another. FromStatus is empty for the status the order was created with.
+	OrderStatusChange struct {
+		HistoryID  int64     `json:"history_id" db:"history_id"`
+		OrderID    int64     `json:"order_id" db:"order_id"`
+		FromStatus string    `json:"from_status" db:"from_status"`
+		ToStatus   string    `json:"to_status" db:"to_status"`
+		Actor      string    `json:"actor" db:"actor"`
+		Note       string    `json:"note" db:"note"`
+		CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	}
+
+	OrderFilter struct {
+		From    time.Time
+		To      time.Time
+		AfterID int64
+		Limit   int
 	}
 
 	OrderRepository interface {
 		CreateOrder(tx *sqlx.Tx, ctx context.Context, form Order) (orderID int64, err error)
 		CreateOrderDetails(tx *sqlx.Tx, ctx context.Context, form []OrderDetail) (err error)
+		CreateOrderDiscounts(tx *sqlx.Tx, ctx context.Context, form []OrderDiscount) (err error)
+		GetOrderDiscountsByOrderID(ctx context.Context, orderID int64) (res []OrderDiscount, err error)
+		GetOrderByOrderID(ctx context.Context, orderID int64) (res Order, err error)
+		GetOrders(ctx context.Context, filter OrderFilter) (res []Order, err error)
+		GetOrderLinesByOrderID(ctx context.Context, orderID int64) (res []OrderLine, err error)
+		GetOrderByOrderIDForUpdate(tx *sqlx.Tx, ctx context.Context, orderID int64) (res Order, err error)
+		GetOrderDetailsByOrderID(tx *sqlx.Tx, ctx context.Context, orderID int64) (res []OrderDetail, err error)
+		UpdateOrderDetailRefund(tx *sqlx.Tx, ctx context.Context, form OrderDetail) (err error)
+		CreateOrderRefunds(tx *sqlx.Tx, ctx context.Context, form []OrderRefund) (err error)
+		UpdateOrderStatus(tx *sqlx.Tx, ctx context.Context, form Order) (err error)
+		CreateOrderStatusChange(tx *sqlx.Tx, ctx context.Context, form OrderStatusChange) (err error)
+		GetOrderStatusHistoryByOrderID(ctx context.Context, orderID int64) (res []OrderStatusChange, err error)
 		BeginTx() (tx *sqlx.Tx, err error)
 		RollbackTx(tx *sqlx.Tx) (err error)
 		CommitTx(tx *sqlx.Tx) (err error)
@@ -41,12 +125,15 @@ type (
 	}
 )
 
+const orderColumns = "order_id, date, subtotal, discount_total, total, item_count, status, refunded_total"
+
 func NewOrderRepository(impl OrderRepoImpl) OrderRepository {
 	return &impl
 }
 
 func (r *OrderRepoImpl) CreateOrder(tx *sqlx.Tx, ctx context.Context, form Order) (orderID int64, err error) {
-	err = tx.QueryRowxContext(ctx, "insert into orders(date, total) values($1, $2) RETURNING order_id", form.Date, form.Total).Scan(&orderID)
+	err = tx.QueryRowxContext(ctx, "insert into orders(date, subtotal, discount_total, total, item_count, status) values($1, $2, $3, $4, $5, $6) RETURNING order_id",
+		form.Date, form.Subtotal, form.DiscountTotal, form.Total, form.ItemCount, form.Status).Scan(&orderID)
 	if err != nil {
 		return orderID, err
 	}
@@ -55,14 +142,14 @@ func (r *OrderRepoImpl) CreateOrder(tx *sqlx.Tx, ctx context.Context, form Order
 }
 
 func (r *OrderRepoImpl) CreateOrderDetails(tx *sqlx.Tx, ctx context.Context, form []OrderDetail) (err error) {
-	sqlInsert := "insert into order_details(order_id, product_id, promo_id, price, qty) values"
-	rowSQL := "(?, ?, ?, ?, ?)"
+	sqlInsert := "insert into order_details(order_id, product_id, promo_id, order_promo_id, coupon_id, price, qty) values"
+	rowSQL := "(?, ?, ?, ?, ?, ?, ?)"
 
 	vals := []interface{}{}
 	var inserts []string
 
 	for _, val := range form {
-		vals = append(vals, val.OrderID, val.ProductID, val.PromoID, val.Price, val.Qty)
+		vals = append(vals, val.OrderID, val.ProductID, val.PromoID, val.OrderPromoID, val.CouponID, val.Price, val.Qty)
 		inserts = append(inserts, rowSQL)
 	}
 
@@ -84,6 +171,290 @@ func (r *OrderRepoImpl) CreateOrderDetails(tx *sqlx.Tx, ctx context.Context, for
 	return nil
 }
 
+func (r *OrderRepoImpl) CreateOrderDiscounts(tx *sqlx.Tx, ctx context.Context, form []OrderDiscount) (err error) {
+	if len(form) == 0 {
+		return nil
+	}
+
+	sqlInsert := "insert into order_discounts(order_id, order_promo_id, promo_type, discount) values"
+	rowSQL := "(?, ?, ?, ?)"
+
+	vals := []interface{}{}
+	var inserts []string
+
+	for _, val := range form {
+		vals = append(vals, val.OrderID, val.OrderPromoID, val.PromoType, val.Discount)
+		inserts = append(inserts, rowSQL)
+	}
+
+	sqlInsert = sqlInsert + strings.Join(inserts, ",")
+	sqlInsert = sqlkit.ReplaceSQL(sqlInsert, "?")
+
+	stmt, err := tx.PrepareContext(ctx, sqlInsert)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, vals...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrderRepoImpl) GetOrderDiscountsByOrderID(ctx context.Context, orderID int64) (res []OrderDiscount, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select order_discount_id, order_id, order_promo_id, promo_type, discount from order_discounts where order_id = $1 order by order_discount_id asc", orderID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := OrderDiscount{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+func (r *OrderRepoImpl) GetOrderByOrderID(ctx context.Context, orderID int64) (res Order, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+orderColumns+" from orders where order_id = $1", orderID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// GetOrders returns orders newest first, paginated by order_id.
+func (r *OrderRepoImpl) GetOrders(ctx context.Context, filter OrderFilter) (res []Order, err error) {
+	var conds []string
+	var vals []interface{}
+
+	if !filter.From.IsZero() {
+		conds = append(conds, "date >= ?")
+		vals = append(vals, filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		conds = append(conds, "date < ?")
+		vals = append(vals, filter.To)
+	}
+
+	if filter.AfterID > 0 {
+		conds = append(conds, "order_id < ?")
+		vals = append(vals, filter.AfterID)
+	}
+
+	query := "select " + orderColumns + " from orders"
+	if len(conds) > 0 {
+		query += " where " + strings.Join(conds, " and ")
+	}
+
+	query += " order by order_id desc"
+
+	if filter.Limit > 0 {
+		query += " limit ?"
+		vals = append(vals, filter.Limit)
+	}
+
+	rows, err := r.DB.QueryxContext(ctx, sqlkit.ReplaceSQL(query, "?"), vals...)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := Order{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+func (r *OrderRepoImpl) GetOrderLinesByOrderID(ctx context.Context, orderID int64) (res []OrderLine, err error) {
+	rows, err := r.DB.QueryxContext(ctx, `select od.order_detail_id, od.order_id, od.product_id, coalesce(p.name, '') as product_name,
+		od.promo_id, coalesce(pr.promo_type::text, op.promo_type, '') as promo_type, coalesce(pr.reward, op.reward, 0) as promo_reward,
+		od.order_promo_id, od.coupon_id, od.price, od.qty, od.refunded_qty
+		from order_details od
+		left join products p on p.product_id = od.product_id
+		left join promos pr on pr.promo_id = od.promo_id
+		left join order_promos op on op.order_promo_id = od.order_promo_id
+		where od.order_id = $1 order by od.order_detail_id asc`, orderID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := OrderLine{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+// GetOrderByOrderIDForUpdate reads the order inside tx and locks its row
+// until tx ends, so refunds of the same order are serialized. A zero OrderID
+// in res means the order doesn't exist.
+func (r *OrderRepoImpl) GetOrderByOrderIDForUpdate(tx *sqlx.Tx, ctx context.Context, orderID int64) (res Order, err error) {
+	rows, err := tx.QueryxContext(ctx, "select "+orderColumns+" from orders where order_id = $1 for update", orderID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *OrderRepoImpl) GetOrderDetailsByOrderID(tx *sqlx.Tx, ctx context.Context, orderID int64) (res []OrderDetail, err error) {
+	rows, err := tx.QueryxContext(ctx, "select order_detail_id, order_id, product_id, promo_id, price, qty, refunded_qty, refunded_amount from order_details where order_id = $1 order by order_detail_id asc", orderID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := OrderDetail{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+// UpdateOrderDetailRefund stores the refunded qty and amount of the line.
+func (r *OrderRepoImpl) UpdateOrderDetailRefund(tx *sqlx.Tx, ctx context.Context, form OrderDetail) (err error) {
+	_, err = tx.ExecContext(ctx, "update order_details set refunded_qty = $1, refunded_amount = $2 where order_detail_id = $3",
+		form.RefundedQty, form.RefundedAmount, form.OrderDetailID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrderRepoImpl) CreateOrderRefunds(tx *sqlx.Tx, ctx context.Context, form []OrderRefund) (err error) {
+	if len(form) == 0 {
+		return nil
+	}
+
+	sqlInsert := "insert into order_refunds(order_id, order_detail_id, product_id, qty, amount, reason, actor, created_at) values"
+	rowSQL := "(?, ?, ?, ?, ?, ?, ?, ?)"
+
+	vals := []interface{}{}
+	var inserts []string
+
+	for _, val := range form {
+		vals = append(vals, val.OrderID, val.OrderDetailID, val.ProductID, val.Qty, val.Amount, val.Reason, val.Actor, val.CreatedAt)
+		inserts = append(inserts, rowSQL)
+	}
+
+	sqlInsert = sqlInsert + strings.Join(inserts, ",")
+	sqlInsert = sqlkit.ReplaceSQL(sqlInsert, "?")
+
+	stmt, err := tx.PrepareContext(ctx, sqlInsert)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, vals...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateOrderStatus stores the order's status and refunded total.
+func (r *OrderRepoImpl) UpdateOrderStatus(tx *sqlx.Tx, ctx context.Context, form Order) (err error) {
+	_, err = tx.ExecContext(ctx, "update orders set status = $1, refunded_total = $2 where order_id = $3",
+		form.Status, form.RefundedTotal, form.OrderID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrderRepoImpl) CreateOrderStatusChange(tx *sqlx.Tx, ctx context.Context, form OrderStatusChange) (err error) {
+	_, err = tx.ExecContext(ctx, "insert into order_status_history(order_id, from_status, to_status, actor, note, created_at) values($1, $2, $3, $4, $5, $6)",
+		form.OrderID, form.FromStatus, form.ToStatus, form.Actor, form.Note, form.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetOrderStatusHistoryByOrderID lists the status changes of the order,
+// oldest first.
+func (r *OrderRepoImpl) GetOrderStatusHistoryByOrderID(ctx context.Context, orderID int64) (res []OrderStatusChange, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select history_id, order_id, from_status, to_status, actor, note, created_at from order_status_history where order_id = $1 order by history_id asc", orderID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := OrderStatusChange{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
 func (r *OrderRepoImpl) BeginTx() (tx *sqlx.Tx, err error) {
 	return r.DB.Beginx()
 }
@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/pkg/money"
+	"go.uber.org/dig"
+)
+
+type (
+	// OrderPromo is a promo on the whole order rather than on one product. It
+	// runs once every line is priced and applies when the order reaches its
+	// thresholds.
+	OrderPromo struct {
+		OrderPromoID int64  `json:"order_promo_id" db:"order_promo_id"`
+		PromoType    string `json:"promo_type" db:"promo_type"`
+		// Reward is a percentage, an amount or a product id depending on
+		// PromoType.
+		Reward money.Decimal `json:"reward" db:"reward"`
+		// MinSubtotal and MinItems are what the order must come to, after
+		// line promos, and how many items it must hold. Zero is not checked.
+		MinSubtotal money.Decimal `json:"min_subtotal" db:"min_subtotal"`
+		MinItems    int64         `json:"min_items" db:"min_items"`
+		// Priority orders the promos, higher first.
+		Priority int64 `json:"priority" db:"priority"`
+		// StackGroup names the promos of which only the first that applies is
+		// given, like tiers of the same discount. Empty stacks with any promo.
+		StackGroup string     `json:"stack_group" db:"stack_group"`
+		Active     bool       `json:"active" db:"active"`
+		StartsAt   *time.Time `json:"starts_at" db:"starts_at"`
+		EndsAt     *time.Time `json:"ends_at" db:"ends_at"`
+		Timezone   string     `json:"timezone" db:"timezone"`
+	}
+
+	OrderPromoRepository interface {
+		GetOrderPromos(ctx context.Context, at time.Time) (res []OrderPromo, err error)
+		GetOrderPromosTx(tx *sqlx.Tx, ctx context.Context, at time.Time) (res []OrderPromo, err error)
+		GetOrderPromoByOrderPromoID(ctx context.Context, orderPromoID int64) (res OrderPromo, err error)
+		CreateOrderPromo(ctx context.Context, form OrderPromo) (orderPromoID int64, err error)
+		DeactivateOrderPromo(ctx context.Context, orderPromoID int64) (err error)
+	}
+
+	OrderPromoRepoImpl struct {
+		dig.In
+		*sqlx.DB
+	}
+)
+
+const orderPromoColumns = "order_promo_id, promo_type, reward, min_subtotal, min_items, priority, stack_group, active, starts_at, ends_at, timezone"
+
+func NewOrderPromoRepository(impl OrderPromoRepoImpl) OrderPromoRepository {
+	return &impl
+}
+
+// GetOrderPromos returns the order promos running at the given time, highest
+// priority first.
+func (r *OrderPromoRepoImpl) GetOrderPromos(ctx context.Context, at time.Time) (res []OrderPromo, err error) {
+	return getOrderPromos(ctx, r.DB, at)
+}
+
+// GetOrderPromosTx is GetOrderPromos inside tx.
+func (r *OrderPromoRepoImpl) GetOrderPromosTx(tx *sqlx.Tx, ctx context.Context, at time.Time) (res []OrderPromo, err error) {
+	return getOrderPromos(ctx, tx, at)
+}
+
+func getOrderPromos(ctx context.Context, q sqlx.QueryerContext, at time.Time) (res []OrderPromo, err error) {
+	rows, err := q.QueryxContext(ctx, "select "+orderPromoColumns+" from order_promos where "+promoEffectiveAt(1)+" order by priority desc, order_promo_id asc", at)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := OrderPromo{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+func (r *OrderPromoRepoImpl) GetOrderPromoByOrderPromoID(ctx context.Context, orderPromoID int64) (res OrderPromo, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+orderPromoColumns+" from order_promos where order_promo_id = $1", orderPromoID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *OrderPromoRepoImpl) CreateOrderPromo(ctx context.Context, form OrderPromo) (orderPromoID int64, err error) {
+	err = r.DB.QueryRowxContext(ctx, "insert into order_promos(promo_type, reward, min_subtotal, min_items, priority, stack_group, active, starts_at, ends_at, timezone) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING order_promo_id",
+		form.PromoType, form.Reward, form.MinSubtotal, form.MinItems, form.Priority, form.StackGroup, form.Active, form.StartsAt, form.EndsAt, form.Timezone).Scan(&orderPromoID)
+	if err != nil {
+		return orderPromoID, err
+	}
+
+	return orderPromoID, nil
+}
+
+func (r *OrderPromoRepoImpl) DeactivateOrderPromo(ctx context.Context, orderPromoID int64) (err error) {
+	_, err = r.DB.ExecContext(ctx, "update order_promos set active = false where order_promo_id = $1", orderPromoID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package repo_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderPromoRepoImpl(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	columns := []string{"order_promo_id", "promo_type", "reward", "min_subtotal", "min_items", "priority", "stack_group", "active", "starts_at", "ends_at", "timezone"}
+	selectSQL := "select order_promo_id, promo_type, reward, min_subtotal, min_items, priority, stack_group, active, starts_at, ends_at, timezone from order_promos"
+	promo := repo.OrderPromo{
+		OrderPromoID: 2,
+		PromoType:    "discount",
+		Reward:       money.MustParse("10"),
+		MinSubtotal:  money.MustParse("500"),
+		Priority:     1,
+		StackGroup:   "spend",
+		Active:       true,
+	}
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("insert into order_promos(promo_type, reward, min_subtotal, min_items, priority, stack_group, active, starts_at, ends_at, timezone) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING order_promo_id")).
+		WithArgs("discount", promo.Reward, promo.MinSubtotal, 0, 1, "spend", true, nil, nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"order_promo_id"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " where active and")).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "discount", "10", "500", 0, 1, "spend", true, nil, nil, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " where order_promo_id = $1")).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "discount", "10", "500", 0, 1, "spend", true, nil, nil, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " where order_promo_id = $1")).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectExec(regexp.QuoteMeta("update order_promos set active = false where order_promo_id = $1")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(selectSQL + " where active and")).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "discount", "10", "500", 0, 1, "spend", true, nil, nil, ""))
+	mock.ExpectRollback()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	orderPromoRepo := repo.NewOrderPromoRepository(repo.OrderPromoRepoImpl{DB: sqlxDB})
+	ctx := context.Background()
+
+	orderPromoID, err := orderPromoRepo.CreateOrderPromo(ctx, promo)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), orderPromoID)
+
+	promos, err := orderPromoRepo.GetOrderPromos(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.OrderPromo{promo}, promos)
+
+	res, err := orderPromoRepo.GetOrderPromoByOrderPromoID(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, promo, res)
+
+	res, err = orderPromoRepo.GetOrderPromoByOrderPromoID(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.OrderPromo{}, res)
+
+	assert.NoError(t, orderPromoRepo.DeactivateOrderPromo(ctx, 2))
+
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+	promos, err = orderPromoRepo.GetOrderPromosTx(tx, ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.OrderPromo{promo}, promos)
+	assert.NoError(t, tx.Rollback())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
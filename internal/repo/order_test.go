@@ -4,12 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,8 +33,12 @@ func TestOrderRepoImpl_CreateOrder(t *testing.T) {
 		{
 			name: "successful insert",
 			args: repo.Order{
-				Date:  time.Now(),
-				Total: 1000.0,
+				Date:          time.Now(),
+				Subtotal:      money.MustParse("1100.0"),
+				DiscountTotal: money.MustParse("100.0"),
+				Total:         money.MustParse("1000.0"),
+				ItemCount:     3,
+				Status:        "pending_payment",
 			},
 			wantOrderID: 1,
 			wantErr:     false,
@@ -41,7 +47,7 @@ func TestOrderRepoImpl_CreateOrder(t *testing.T) {
 			name: "insert error",
 			args: repo.Order{
 				Date:  time.Now(),
-				Total: 1000.0,
+				Total: money.MustParse("1000.0"),
 			},
 			wantOrderID: 0,
 			wantErr:     true,
@@ -55,7 +61,7 @@ func TestOrderRepoImpl_CreateOrder(t *testing.T) {
 			if tt.wantErr {
 				mock.ExpectQuery("insert into orders").WillReturnError(errors.New("insert error"))
 			} else {
-				mock.ExpectQuery("insert into orders").WithArgs(tt.args.Date, tt.args.Total).WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow(tt.wantOrderID))
+				mock.ExpectQuery("insert into orders").WithArgs(tt.args.Date, tt.args.Subtotal, tt.args.DiscountTotal, tt.args.Total, tt.args.ItemCount, tt.args.Status).WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow(tt.wantOrderID))
 			}
 
 			tx, err := sqlxDB.Beginx()
@@ -84,7 +90,7 @@ func TestOrderRepoImpl_CreateOrderDetails(t *testing.T) {
 		OrderID:   1,
 		ProductID: 1,
 		PromoID:   0,
-		Price:     10.0,
+		Price:     money.MustParse("10.0"),
 		Qty:       1,
 	}
 
@@ -101,7 +107,7 @@ func TestOrderRepoImpl_CreateOrderDetails(t *testing.T) {
 				mock.ExpectBegin()
 				mock.ExpectPrepare("insert into order_details").
 					ExpectExec().
-					WithArgs(orderDetail.OrderID, orderDetail.ProductID, orderDetail.PromoID, orderDetail.Price, orderDetail.Qty).
+					WithArgs(orderDetail.OrderID, orderDetail.ProductID, orderDetail.PromoID, orderDetail.OrderPromoID, orderDetail.CouponID, orderDetail.Price, orderDetail.Qty).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			wantErr: false,
@@ -261,3 +267,342 @@ func TestOrderRepoImpl_CommitTx(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderRepoImpl_GetOrderByOrderID(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		orderID      int64
+		expectedResp repo.Order
+		expectedErr  error
+		mockFunc     func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:         "success",
+			orderID:      1,
+			expectedResp: repo.Order{OrderID: 1, Date: date, Subtotal: money.MustParse("328.5"), DiscountTotal: money.MustParse("32.85"), Total: money.MustParse("295.65"), ItemCount: 3, Status: "placed"},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"order_id", "date", "subtotal", "discount_total", "total", "item_count", "status", "refunded_total"}).AddRow(1, date, 328.5, 32.85, 295.65, 3, "placed", 0)
+				mock.ExpectQuery("select order_id, date, subtotal, discount_total, total, item_count, status, refunded_total from orders where order_id = \\$1").
+					WithArgs(1).WillReturnRows(rows)
+			},
+		},
+		{
+			name:        "database error",
+			orderID:     1,
+			expectedErr: errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select order_id, date, subtotal, discount_total, total, item_count, status, refunded_total from orders where order_id = \\$1").
+					WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+		},
+		{
+			name:        "error scanning order rows",
+			orderID:     1,
+			expectedErr: errors.New("sql: Scan error on column index 4, name \"total\": money: invalid decimal: \"not a float\""),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"order_id", "date", "subtotal", "discount_total", "total", "item_count"}).AddRow(1, date, 328.5, 32.85, "not a float", 3)
+				mock.ExpectQuery("select order_id, date, subtotal, discount_total, total, item_count, status, refunded_total from orders where order_id = \\$1").
+					WithArgs(1).WillReturnRows(rows)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			orderRepo := repo.NewOrderRepository(repo.OrderRepoImpl{DB: sqlx.NewDb(db, "sqlmock")})
+
+			resp, err := orderRepo.GetOrderByOrderID(context.Background(), tc.orderID)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, resp)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOrderRepoImpl_GetOrders(t *testing.T) {
+	from := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		filter       repo.OrderFilter
+		expectedResp []repo.Order
+		expectedErr  error
+		mockFunc     func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:         "without filter",
+			filter:       repo.OrderFilter{Limit: 2},
+			expectedResp: []repo.Order{{OrderID: 2, Date: from, Subtotal: money.MustParse("10"), Total: money.MustParse("10"), ItemCount: 1}, {OrderID: 1, Date: from, Subtotal: money.MustParse("20"), Total: money.MustParse("20"), ItemCount: 2}},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"order_id", "date", "subtotal", "discount_total", "total", "item_count"}).
+					AddRow(2, from, 10, 0, 10, 1).
+					AddRow(1, from, 20, 0, 20, 2)
+				mock.ExpectQuery(regexp.QuoteMeta("select order_id, date, subtotal, discount_total, total, item_count, status, refunded_total from orders order by order_id desc limit $1")).
+					WithArgs(2).WillReturnRows(rows)
+			},
+		},
+		{
+			name:   "date range and cursor",
+			filter: repo.OrderFilter{From: from, To: to, AfterID: 10, Limit: 5},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select order_id, date, subtotal, discount_total, total, item_count, status, refunded_total from orders where date >= $1 and date < $2 and order_id < $3 order by order_id desc limit $4")).
+					WithArgs(from, to, 10, 5).WillReturnRows(sqlmock.NewRows([]string{"order_id", "date", "subtotal", "discount_total", "total", "item_count"}))
+			},
+		},
+		{
+			name:        "database error",
+			filter:      repo.OrderFilter{},
+			expectedErr: errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select order_id, date, subtotal, discount_total, total, item_count, status, refunded_total from orders").WillReturnError(errors.New("database error"))
+			},
+		},
+		{
+			name:        "error scanning order rows",
+			filter:      repo.OrderFilter{},
+			expectedErr: errors.New("sql: Scan error on column index 4, name \"total\": money: invalid decimal: \"not a float\""),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"order_id", "date", "subtotal", "discount_total", "total", "item_count"}).AddRow(1, from, 20, 0, "not a float", 1)
+				mock.ExpectQuery("select order_id, date, subtotal, discount_total, total, item_count, status, refunded_total from orders").WillReturnRows(rows)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			orderRepo := repo.NewOrderRepository(repo.OrderRepoImpl{DB: sqlx.NewDb(db, "sqlmock")})
+
+			resp, err := orderRepo.GetOrders(context.Background(), tc.filter)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, resp)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOrderRepoImpl_GetOrderLinesByOrderID(t *testing.T) {
+	columns := []string{"order_detail_id", "order_id", "product_id", "product_name", "promo_id", "promo_type", "promo_reward", "order_promo_id", "coupon_id", "price", "qty", "refunded_qty"}
+
+	testCases := []struct {
+		name         string
+		orderID      int64
+		expectedResp []repo.OrderLine
+		expectedErr  error
+		mockFunc     func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:    "success",
+			orderID: 1,
+			expectedResp: []repo.OrderLine{
+				{OrderDetailID: 1, OrderID: 1, ProductID: 3, ProductName: "Alexa Speaker", PromoID: 3, PromoType: "discount", PromoReward: money.MustParse("10"), Price: money.MustParse("295.65"), Qty: 3, RefundedQty: 1},
+				{OrderDetailID: 2, OrderID: 1, ProductID: 4, ProductName: "Raspberry Pi B", Price: money.MustParse("30"), Qty: 1},
+				{OrderDetailID: 3, OrderID: 1, ProductID: 4, ProductName: "Raspberry Pi B", PromoType: "gift", PromoReward: money.MustParse("4"), OrderPromoID: 2, Price: money.MustParse("0"), Qty: 1},
+				{OrderDetailID: 4, OrderID: 1, ProductID: 1, ProductName: "Google Home", CouponID: 5, Price: money.MustParse("0"), Qty: 1},
+			},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(1, 1, 3, "Alexa Speaker", 3, "discount", 10, 0, 0, 295.65, 3, 1).
+					AddRow(2, 1, 4, "Raspberry Pi B", 0, "", 0, 0, 0, 30, 1, 0).
+					AddRow(3, 1, 4, "Raspberry Pi B", 0, "gift", 4, 2, 0, 0, 1, 0).
+					AddRow(4, 1, 1, "Google Home", 0, "", 0, 0, 5, 0, 1, 0)
+				mock.ExpectQuery("from order_details od").WithArgs(1).WillReturnRows(rows)
+			},
+		},
+		{
+			name:        "database error",
+			orderID:     1,
+			expectedErr: errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("from order_details od").WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+		},
+		{
+			name:        "error scanning order detail rows",
+			orderID:     1,
+			expectedErr: errors.New("sql: Scan error on column index 9, name \"price\": money: invalid decimal: \"not a float\""),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).AddRow(1, 1, 3, "Alexa Speaker", 3, "discount", 10, 0, 0, "not a float", 3, 0)
+				mock.ExpectQuery("from order_details od").WithArgs(1).WillReturnRows(rows)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			orderRepo := repo.NewOrderRepository(repo.OrderRepoImpl{DB: sqlx.NewDb(db, "sqlmock")})
+
+			resp, err := orderRepo.GetOrderLinesByOrderID(context.Background(), tc.orderID)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, resp)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOrderRepoImpl_RefundOrder(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("select order_id, date, subtotal, discount_total, total, item_count, status, refunded_total from orders where order_id = $1 for update")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "date", "subtotal", "discount_total", "total", "item_count", "status", "refunded_total"}).
+			AddRow(1, date, 328.5, 32.85, 295.65, 3, "paid", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("select order_detail_id, order_id, product_id, promo_id, price, qty, refunded_qty, refunded_amount from order_details where order_id = $1 order by order_detail_id asc")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"order_detail_id", "order_id", "product_id", "promo_id", "price", "qty", "refunded_qty", "refunded_amount"}).
+			AddRow(1, 1, 3, 3, 295.65, 3, 0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("update order_details set refunded_qty = $1, refunded_amount = $2 where order_detail_id = $3")).
+		WithArgs(1, money.MustParse("98.55"), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(regexp.QuoteMeta("insert into order_refunds(order_id, order_detail_id, product_id, qty, amount, reason, actor, created_at) values($1, $2, $3, $4, $5, $6, $7, $8)")).
+		ExpectExec().
+		WithArgs(1, 1, 3, 1, money.MustParse("98.55"), "damaged", "alice", date).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("update orders set status = $1, refunded_total = $2 where order_id = $3")).
+		WithArgs("partially_refunded", money.MustParse("98.55"), 1).
+		WillReturnError(errors.New("update error"))
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	orderRepo := repo.NewOrderRepository(repo.OrderRepoImpl{DB: sqlxDB})
+	ctx := context.Background()
+
+	order, err := orderRepo.GetOrderByOrderIDForUpdate(tx, ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Order{OrderID: 1, Date: date, Subtotal: money.MustParse("328.5"), DiscountTotal: money.MustParse("32.85"), Total: money.MustParse("295.65"), ItemCount: 3, Status: "paid"}, order)
+
+	details, err := orderRepo.GetOrderDetailsByOrderID(tx, ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.OrderDetail{{OrderDetailID: 1, OrderID: 1, ProductID: 3, PromoID: 3, Price: money.MustParse("295.65"), Qty: 3}}, details)
+
+	assert.NoError(t, orderRepo.UpdateOrderDetailRefund(tx, ctx, repo.OrderDetail{OrderDetailID: 1, RefundedQty: 1, RefundedAmount: money.MustParse("98.55")}))
+	assert.NoError(t, orderRepo.CreateOrderRefunds(tx, ctx, []repo.OrderRefund{
+		{OrderID: 1, OrderDetailID: 1, ProductID: 3, Qty: 1, Amount: money.MustParse("98.55"), Reason: "damaged", Actor: "alice", CreatedAt: date},
+	}))
+	assert.NoError(t, orderRepo.CreateOrderRefunds(tx, ctx, nil))
+	assert.EqualError(t, orderRepo.UpdateOrderStatus(tx, ctx, repo.Order{OrderID: 1, Status: "partially_refunded", RefundedTotal: money.MustParse("98.55")}), "update error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderRepoImpl_OrderStatusHistory(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("insert into order_status_history(order_id, from_status, to_status, actor, note, created_at) values($1, $2, $3, $4, $5, $6)")).
+		WithArgs(1, "paid", "fulfilled", "alice", "shipped with DHL", date).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("select history_id, order_id, from_status, to_status, actor, note, created_at from order_status_history where order_id = $1 order by history_id asc")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"history_id", "order_id", "from_status", "to_status", "actor", "note", "created_at"}).
+			AddRow(1, 1, "", "pending_payment", "", "", date).
+			AddRow(2, 1, "pending_payment", "paid", "alice", "", date))
+	mock.ExpectQuery("from order_status_history").WillReturnError(errors.New("database error"))
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	orderRepo := repo.NewOrderRepository(repo.OrderRepoImpl{DB: sqlxDB})
+	ctx := context.Background()
+
+	assert.NoError(t, orderRepo.CreateOrderStatusChange(tx, ctx, repo.OrderStatusChange{OrderID: 1, FromStatus: "paid", ToStatus: "fulfilled", Actor: "alice", Note: "shipped with DHL", CreatedAt: date}))
+
+	history, err := orderRepo.GetOrderStatusHistoryByOrderID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.OrderStatusChange{
+		{HistoryID: 1, OrderID: 1, ToStatus: "pending_payment", CreatedAt: date},
+		{HistoryID: 2, OrderID: 1, FromStatus: "pending_payment", ToStatus: "paid", Actor: "alice", CreatedAt: date},
+	}, history)
+
+	_, err = orderRepo.GetOrderStatusHistoryByOrderID(ctx, 1)
+	assert.EqualError(t, err, "database error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOrderRepoImpl_OrderDiscounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(regexp.QuoteMeta("insert into order_discounts(order_id, order_promo_id, promo_type, discount) values($1, $2, $3, $4),($5, $6, $7, $8)")).
+		ExpectExec().
+		WithArgs(1, 2, "discount", money.MustParse("55.5"), 1, 3, "amount", money.MustParse("20")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(regexp.QuoteMeta("select order_discount_id, order_id, order_promo_id, promo_type, discount from order_discounts where order_id = $1 order by order_discount_id asc")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"order_discount_id", "order_id", "order_promo_id", "promo_type", "discount"}).
+			AddRow(1, 1, 2, "discount", "55.5").
+			AddRow(2, 1, 3, "amount", "20"))
+	mock.ExpectQuery("from order_discounts").WillReturnError(errors.New("database error"))
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	orderRepo := repo.NewOrderRepository(repo.OrderRepoImpl{DB: sqlxDB})
+	ctx := context.Background()
+
+	discounts := []repo.OrderDiscount{
+		{OrderID: 1, OrderPromoID: 2, PromoType: "discount", Discount: money.MustParse("55.5")},
+		{OrderID: 1, OrderPromoID: 3, PromoType: "amount", Discount: money.MustParse("20")},
+	}
+	assert.NoError(t, orderRepo.CreateOrderDiscounts(tx, ctx, discounts))
+	assert.NoError(t, orderRepo.CreateOrderDiscounts(tx, ctx, nil))
+
+	res, err := orderRepo.GetOrderDiscountsByOrderID(ctx, 1)
+	assert.NoError(t, err)
+	discounts[0].OrderDiscountID = 1
+	discounts[1].OrderDiscountID = 2
+	assert.Equal(t, discounts, res)
+
+	_, err = orderRepo.GetOrderDiscountsByOrderID(ctx, 1)
+	assert.EqualError(t, err, "database error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,24 +2,62 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/learn/api-shop/pkg/sqlkit"
+	"github.com/lib/pq"
 	"go.uber.org/dig"
 )
 
 type (
 	Product struct {
-		ProductID int64   `json:"product_id" db:"product_id"`
-		Sku       string  `json:"sku" db:"sku"`
-		Name      string  `json:"name" db:"name"`
-		Price     float64 `json:"price" db:"price"`
-		Qty       int64   `json:"qty" db:"qty"`
+		ProductID int64         `json:"product_id" db:"product_id"`
+		Sku       string        `json:"sku" db:"sku"`
+		Name      string        `json:"name" db:"name"`
+		Price     money.Decimal `json:"price" db:"price"`
+		Qty       int64         `json:"qty" db:"qty"`
+		// ReservedQty is the part of Qty held by active reservations. It's
+		// still on hand but can only be sold by confirming the reservation.
+		ReservedQty int64 `json:"reserved_qty" db:"reserved_qty"`
+		// ArchivedAt is set once the product is retired. Archived products
+		// stay readable for order history but can't be listed or sold.
+		ArchivedAt *time.Time `json:"archived_at" db:"archived_at"`
+	}
+
+	ProductFilter struct {
+		Name       string
+		Sku        string
+		SortBy     string
+		Desc       bool
+		AfterValue string
+		AfterID    int64
+		Limit      int
+		// IncludeArchived lists archived products too.
+		IncludeArchived bool
 	}
 
 	ProductRepository interface {
 		GetProductByProductID(ctx context.Context, id int64) (res Product, err error)
+		GetProductByProductIDTx(tx *sqlx.Tx, ctx context.Context, id int64) (res Product, err error)
+		GetProductByProductIDForUpdate(tx *sqlx.Tx, ctx context.Context, id int64) (res Product, err error)
+		GetProductBySku(ctx context.Context, sku string) (res Product, err error)
 		GetAllProduct(ctx context.Context) (res []Product, err error)
+		GetProducts(ctx context.Context, filter ProductFilter) (res []Product, err error)
 		UpdateProductQtyByProductID(tx *sqlx.Tx, ctx context.Context, form Product) (err error)
+		CreateProduct(tx *sqlx.Tx, ctx context.Context, form Product) (res Product, err error)
+		UpdateProduct(ctx context.Context, form Product) (res Product, err error)
+		ArchiveProduct(ctx context.Context, productID int64, at time.Time) (res Product, err error)
+		AdjustProductQty(tx *sqlx.Tx, ctx context.Context, productID int64, delta int64) (res Product, err error)
+		AdjustProductReservedQty(tx *sqlx.Tx, ctx context.Context, productID int64, delta int64) (err error)
+		BeginTx() (tx *sqlx.Tx, err error)
+		RollbackTx(tx *sqlx.Tx) (err error)
+		CommitTx(tx *sqlx.Tx) (err error)
 	}
 
 	ProductRepoImpl struct {
@@ -28,12 +66,81 @@ type (
 	}
 )
 
+// ErrInsufficientStock is returned when a stock decrement would take a
+// product's qty below zero.
+var ErrInsufficientStock = apperr.New(apperr.CodeOutOfStock, "insufficient stock")
+
+// ErrDuplicateSku is returned when a product write would reuse the sku of
+// another product.
+var ErrDuplicateSku = apperr.New(apperr.CodeConflict, "sku already exists")
+
+const productColumns = "product_id, sku, name, price, qty, reserved_qty, archived_at"
+
+const (
+	ProductSortByID    = "product_id"
+	ProductSortByName  = "name"
+	ProductSortBySku   = "sku"
+	ProductSortByPrice = "price"
+)
+
 func NewProductRepository(impl ProductRepoImpl) ProductRepository {
 	return &impl
 }
 
+// Available is the stock that can still be sold or reserved.
+func (p Product) Available() int64 {
+	return p.Qty - p.ReservedQty
+}
+
 func (r *ProductRepoImpl) GetProductByProductID(ctx context.Context, productID int64) (res Product, err error) {
-	rows, err := r.DB.QueryxContext(ctx, "select product_id, sku, name, price, qty from products where product_id = $1", productID)
+	return getProductByProductID(ctx, r.DB, productID)
+}
+
+// GetProductByProductIDTx reads the product inside tx without locking it.
+func (r *ProductRepoImpl) GetProductByProductIDTx(tx *sqlx.Tx, ctx context.Context, productID int64) (res Product, err error) {
+	return getProductByProductID(ctx, tx, productID)
+}
+
+func getProductByProductID(ctx context.Context, q sqlx.QueryerContext, productID int64) (res Product, err error) {
+	rows, err := q.QueryxContext(ctx, "select "+productColumns+" from products where product_id = $1", productID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// GetProductByProductIDForUpdate reads the product inside tx and locks its row
+// until tx ends, so concurrent checkouts of the same product are serialized.
+func (r *ProductRepoImpl) GetProductByProductIDForUpdate(tx *sqlx.Tx, ctx context.Context, productID int64) (res Product, err error) {
+	rows, err := tx.QueryxContext(ctx, "select "+productColumns+" from products where product_id = $1 for update", productID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *ProductRepoImpl) GetProductBySku(ctx context.Context, sku string) (res Product, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+productColumns+" from products where sku = $1", sku)
 	if err != nil {
 		return res, err
 	}
@@ -51,7 +158,7 @@ func (r *ProductRepoImpl) GetProductByProductID(ctx context.Context, productID i
 }
 
 func (r *ProductRepoImpl) GetAllProduct(ctx context.Context) (res []Product, err error) {
-	rows, err := r.DB.QueryxContext(ctx, "select product_id, sku, name, price, qty from products order by product_id asc")
+	rows, err := r.DB.QueryxContext(ctx, "select "+productColumns+" from products order by product_id asc")
 	if err != nil {
 		return res, err
 	}
@@ -71,11 +178,198 @@ func (r *ProductRepoImpl) GetAllProduct(ctx context.Context) (res []Product, err
 	return res, nil
 }
 
+// GetProducts returns one page of products using keyset pagination on
+// (SortBy, product_id), so AfterValue must hold the SortBy column of the last
+// row of the previous page.
+func (r *ProductRepoImpl) GetProducts(ctx context.Context, filter ProductFilter) (res []Product, err error) {
+	var conds []string
+	var vals []interface{}
+
+	if !filter.IncludeArchived {
+		conds = append(conds, "archived_at is null")
+	}
+
+	if filter.Name != "" {
+		conds = append(conds, "name ilike ?")
+		vals = append(vals, "%"+sqlkit.EscapeLike(filter.Name)+"%")
+	}
+
+	if filter.Sku != "" {
+		conds = append(conds, "sku ilike ?")
+		vals = append(vals, sqlkit.EscapeLike(filter.Sku)+"%")
+	}
+
+	sortBy, cmp, dir := productSortColumn(filter.SortBy), ">", "asc"
+	if filter.Desc {
+		cmp, dir = "<", "desc"
+	}
+
+	if filter.AfterID > 0 {
+		switch sortBy {
+		case ProductSortByID:
+			conds = append(conds, "product_id "+cmp+" ?")
+			vals = append(vals, filter.AfterID)
+		case ProductSortByPrice:
+			conds = append(conds, "(price, product_id) "+cmp+" (cast(? as numeric), ?)")
+			vals = append(vals, filter.AfterValue, filter.AfterID)
+		default:
+			conds = append(conds, "("+sortBy+", product_id) "+cmp+" (?, ?)")
+			vals = append(vals, filter.AfterValue, filter.AfterID)
+		}
+	}
+
+	query := "select " + productColumns + " from products"
+	if len(conds) > 0 {
+		query += " where " + strings.Join(conds, " and ")
+	}
+
+	if sortBy == ProductSortByID {
+		query += " order by product_id " + dir
+	} else {
+		query += " order by " + sortBy + " " + dir + ", product_id " + dir
+	}
+
+	if filter.Limit > 0 {
+		query += " limit ?"
+		vals = append(vals, filter.Limit)
+	}
+
+	rows, err := r.DB.QueryxContext(ctx, sqlkit.ReplaceSQL(query, "?"), vals...)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := Product{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+func productSortColumn(sortBy string) string {
+	switch sortBy {
+	case ProductSortByName, ProductSortBySku, ProductSortByPrice:
+		return sortBy
+	default:
+		return ProductSortByID
+	}
+}
+
+// UpdateProductQtyByProductID takes form.Qty off the product's stock. The
+// decrement only applies while enough unreserved stock is left; otherwise
+// ErrInsufficientStock is returned and nothing changes.
 func (r *ProductRepoImpl) UpdateProductQtyByProductID(tx *sqlx.Tx, ctx context.Context, form Product) (err error) {
-	_, err = tx.ExecContext(ctx, "UPDATE products SET qty = qty - $1 WHERE product_id = $2", form.Qty, form.ProductID)
+	var qty int64
+	err = tx.QueryRowxContext(ctx, "UPDATE products SET qty = qty - $1 WHERE product_id = $2 AND qty - reserved_qty >= $1 RETURNING qty", form.Qty, form.ProductID).Scan(&qty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInsufficientStock
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ProductRepoImpl) CreateProduct(tx *sqlx.Tx, ctx context.Context, form Product) (res Product, err error) {
+	err = tx.QueryRowxContext(ctx, "insert into products(sku, name, price, qty) values($1, $2, $3, $4) RETURNING "+productColumns,
+		form.Sku, form.Name, form.Price, form.Qty).StructScan(&res)
+	if err != nil {
+		return res, productWriteError(err)
+	}
+
+	return res, nil
+}
+
+// UpdateProduct changes the sku, name and price of a product. Stock only moves
+// through AdjustProductQty and checkout. A zero ProductID in res means the
+// product doesn't exist.
+func (r *ProductRepoImpl) UpdateProduct(ctx context.Context, form Product) (res Product, err error) {
+	err = r.DB.QueryRowxContext(ctx, "update products set sku = $1, name = $2, price = $3 where product_id = $4 RETURNING "+productColumns,
+		form.Sku, form.Name, form.Price, form.ProductID).StructScan(&res)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Product{}, nil
+	}
+	if err != nil {
+		return res, productWriteError(err)
+	}
+
+	return res, nil
+}
+
+// ArchiveProduct retires the product at the given time. Archiving an archived
+// product keeps its original archived_at.
+func (r *ProductRepoImpl) ArchiveProduct(ctx context.Context, productID int64, at time.Time) (res Product, err error) {
+	err = r.DB.QueryRowxContext(ctx, "update products set archived_at = coalesce(archived_at, $1) where product_id = $2 RETURNING "+productColumns,
+		at, productID).StructScan(&res)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Product{}, nil
+	}
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// AdjustProductQty adds delta, which may be negative, to the product's stock.
+// ErrInsufficientStock is returned when the stock would go below zero or
+// below what is reserved.
+func (r *ProductRepoImpl) AdjustProductQty(tx *sqlx.Tx, ctx context.Context, productID int64, delta int64) (res Product, err error) {
+	err = tx.QueryRowxContext(ctx, "update products set qty = qty + $1 where product_id = $2 and qty + $1 >= reserved_qty RETURNING "+productColumns,
+		delta, productID).StructScan(&res)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Product{}, ErrInsufficientStock
+	}
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// AdjustProductReservedQty holds delta more of the product's stock, or gives
+// it back when delta is negative. ErrInsufficientStock is returned when the
+// hold would exceed the stock on hand.
+func (r *ProductRepoImpl) AdjustProductReservedQty(tx *sqlx.Tx, ctx context.Context, productID int64, delta int64) (err error) {
+	var reservedQty int64
+	err = tx.QueryRowxContext(ctx, "update products set reserved_qty = reserved_qty + $1 where product_id = $2 and reserved_qty + $1 between 0 and qty RETURNING reserved_qty",
+		delta, productID).Scan(&reservedQty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInsufficientStock
+	}
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (r *ProductRepoImpl) BeginTx() (tx *sqlx.Tx, err error) {
+	return r.DB.Beginx()
+}
+
+func (r *ProductRepoImpl) RollbackTx(tx *sqlx.Tx) (err error) {
+	return tx.Rollback()
+}
+
+func (r *ProductRepoImpl) CommitTx(tx *sqlx.Tx) (err error) {
+	return tx.Commit()
+}
+
+func productWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "products_sku_key" {
+		return ErrDuplicateSku
+	}
+
+	return err
+}
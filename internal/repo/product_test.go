@@ -3,11 +3,15 @@ package repo_test
 import (
 	"context"
 	"errors"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,14 +30,14 @@ func TestProductRepoImpl_GetProductByProductID(t *testing.T) {
 				ProductID: 1,
 				Sku:       "abc",
 				Name:      "sepatu",
-				Price:     2.2,
+				Price:     money.MustParse("2.2"),
 				Qty:       10,
 			},
 			expectedErr: nil,
 			mockFunc: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"}).
 					AddRow(1, "abc", "sepatu", 2.2, 10)
-				mock.ExpectQuery("select product_id, sku, name, price, qty from products where product_id = \\$1").
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where product_id = \\$1").
 					WillReturnRows(rows)
 			},
 		},
@@ -43,7 +47,7 @@ func TestProductRepoImpl_GetProductByProductID(t *testing.T) {
 			expectedResp: repo.Product{},
 			expectedErr:  errors.New("database error"),
 			mockFunc: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("select product_id, sku, name, price, qty from products where product_id = \\$1").
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where product_id = \\$1").
 					WithArgs(1).WillReturnError(errors.New("database error"))
 			},
 		},
@@ -51,11 +55,11 @@ func TestProductRepoImpl_GetProductByProductID(t *testing.T) {
 			name:         "error scanning product rows",
 			productID:    1,
 			expectedResp: repo.Product{},
-			expectedErr:  errors.New("sql: Scan error on column index 3, name \"price\": converting driver.Value type string (\"not a float\") to a float64: invalid syntax"),
+			expectedErr:  errors.New("sql: Scan error on column index 3, name \"price\": money: invalid decimal: \"not a float\""),
 			mockFunc: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"}).
 					AddRow(1, "abc", "sepatu", "not a float", 10)
-				mock.ExpectQuery("select product_id, sku, name, price, qty from products where product_id = \\$1").
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where product_id = \\$1").
 					WithArgs(1).WillReturnRows(rows).WillReturnError(nil)
 			},
 		},
@@ -102,14 +106,14 @@ func TestProductRepoImpl_GetAllProduct(t *testing.T) {
 					ProductID: 1,
 					Sku:       "abc",
 					Name:      "sepatu",
-					Price:     2.2,
+					Price:     money.MustParse("2.2"),
 					Qty:       10,
 				},
 				{
 					ProductID: 2,
 					Sku:       "cda",
 					Name:      "jam",
-					Price:     20,
+					Price:     money.MustParse("20"),
 					Qty:       20,
 				},
 			},
@@ -118,7 +122,7 @@ func TestProductRepoImpl_GetAllProduct(t *testing.T) {
 				rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"}).
 					AddRow(1, "abc", "sepatu", 2.2, 10).
 					AddRow(2, "cda", "jam", 20, 20)
-				mock.ExpectQuery("select product_id, sku, name, price, qty from products order by product_id asc").
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products order by product_id asc").
 					WillReturnRows(rows)
 			},
 		},
@@ -127,18 +131,18 @@ func TestProductRepoImpl_GetAllProduct(t *testing.T) {
 			expectedResp: []repo.Product{},
 			expectedErr:  errors.New("database error"),
 			mockFunc: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("select product_id, sku, name, price, qty from products order by product_id asc").
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products order by product_id asc").
 					WillReturnError(errors.New("database error"))
 			},
 		},
 		{
 			name:         "error scanning product rows",
 			expectedResp: []repo.Product{},
-			expectedErr:  errors.New("sql: Scan error on column index 3, name \"price\": converting driver.Value type string (\"not a float\") to a float64: invalid syntax"),
+			expectedErr:  errors.New("sql: Scan error on column index 3, name \"price\": money: invalid decimal: \"not a float\""),
 			mockFunc: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"}).
 					AddRow(1, "abc", "sepatu", "not a float", 10)
-				mock.ExpectQuery("select product_id, sku, name, price, qty from products order by product_id asc").
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products order by product_id asc").
 					WillReturnRows(rows).WillReturnError(nil)
 			},
 		},
@@ -186,9 +190,9 @@ func TestProductRepoImpl_UpdateProductQtyByProductID(t *testing.T) {
 			name: "success",
 			mockSQL: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectExec("UPDATE products SET qty = qty - \\$1 WHERE product_id = \\$2").
+				mock.ExpectQuery("UPDATE products SET qty = qty - \\$1 WHERE product_id = \\$2 AND qty - reserved_qty >= \\$1 RETURNING qty").
 					WithArgs(10, 1).
-					WillReturnResult(sqlmock.NewResult(0, 1))
+					WillReturnRows(sqlmock.NewRows([]string{"qty"}).AddRow(5))
 			},
 			args: args{
 				form: repo.Product{
@@ -202,7 +206,7 @@ func TestProductRepoImpl_UpdateProductQtyByProductID(t *testing.T) {
 			name: "db error",
 			mockSQL: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectExec("UPDATE products SET qty = qty - \\$1 WHERE product_id = \\$2").
+				mock.ExpectQuery("UPDATE products SET qty = qty - \\$1 WHERE product_id = \\$2 AND qty - reserved_qty >= \\$1 RETURNING qty").
 					WithArgs(10, 1).
 					WillReturnError(errors.New("db error"))
 			},
@@ -214,6 +218,22 @@ func TestProductRepoImpl_UpdateProductQtyByProductID(t *testing.T) {
 			},
 			expectedResult: errors.New("db error"),
 		},
+		{
+			name: "not enough stock",
+			mockSQL: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE products SET qty = qty - \\$1 WHERE product_id = \\$2 AND qty - reserved_qty >= \\$1 RETURNING qty").
+					WithArgs(10, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"qty"}))
+			},
+			args: args{
+				form: repo.Product{
+					ProductID: 1,
+					Qty:       10,
+				},
+			},
+			expectedResult: repo.ErrInsufficientStock,
+		},
 	}
 
 	for _, tt := range testCases {
@@ -248,3 +268,437 @@ func TestProductRepoImpl_UpdateProductQtyByProductID(t *testing.T) {
 		})
 	}
 }
+
+func TestProductRepoImpl_GetProductByProductIDForUpdate(t *testing.T) {
+	query := regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where product_id = $1 for update")
+
+	testCases := []struct {
+		name         string
+		productID    int64
+		expectedResp repo.Product
+		expectedErr  error
+		mockFunc     func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:      "success",
+			productID: 1,
+			expectedResp: repo.Product{
+				ProductID: 1,
+				Sku:       "abc",
+				Name:      "sepatu",
+				Price:     money.MustParse("2.2"),
+				Qty:       10,
+			},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"}).
+					AddRow(1, "abc", "sepatu", 2.2, 10)
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(rows)
+			},
+		},
+		{
+			name:         "database error",
+			productID:    1,
+			expectedResp: repo.Product{},
+			expectedErr:  errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(errors.New("database error"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+			repo := repo.NewProductRepository(repo.ProductRepoImpl{DB: sqlxDB})
+
+			tx, err := sqlxDB.Beginx()
+			assert.NoError(t, err)
+
+			resp, err := repo.GetProductByProductIDForUpdate(tx, context.Background(), tc.productID)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, resp)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProductRepoImpl_GetProductByProductIDTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"}).
+		AddRow(1, "abc", "sepatu", 2.2, 10)
+	mock.ExpectQuery(regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where product_id = $1")).
+		WithArgs(1).WillReturnRows(rows)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	productRepo := repo.NewProductRepository(repo.ProductRepoImpl{DB: sqlxDB})
+
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	resp, err := productRepo.GetProductByProductIDTx(tx, context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Product{ProductID: 1, Sku: "abc", Name: "sepatu", Price: money.MustParse("2.2"), Qty: 10}, resp)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProductRepoImpl_GetProductBySku(t *testing.T) {
+	testCases := []struct {
+		name         string
+		sku          string
+		expectedResp repo.Product
+		expectedErr  error
+		mockFunc     func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name: "success",
+			sku:  "abc",
+			expectedResp: repo.Product{
+				ProductID: 1,
+				Sku:       "abc",
+				Name:      "sepatu",
+				Price:     money.MustParse("2.2"),
+				Qty:       10,
+			},
+			expectedErr: nil,
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"}).
+					AddRow(1, "abc", "sepatu", 2.2, 10)
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where sku = \\$1").
+					WithArgs("abc").WillReturnRows(rows)
+			},
+		},
+		{
+			name:         "not found",
+			sku:          "zzz",
+			expectedResp: repo.Product{},
+			expectedErr:  nil,
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"})
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where sku = \\$1").
+					WithArgs("zzz").WillReturnRows(rows)
+			},
+		},
+		{
+			name:         "database error",
+			sku:          "abc",
+			expectedResp: repo.Product{},
+			expectedErr:  errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where sku = \\$1").
+					WithArgs("abc").WillReturnError(errors.New("database error"))
+			},
+		},
+		{
+			name:         "error scanning product rows",
+			sku:          "abc",
+			expectedResp: repo.Product{},
+			expectedErr:  errors.New("sql: Scan error on column index 3, name \"price\": money: invalid decimal: \"not a float\""),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"product_id", "sku", "name", "price", "qty"}).
+					AddRow(1, "abc", "sepatu", "not a float", 10)
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where sku = \\$1").
+					WithArgs("abc").WillReturnRows(rows)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			repoImpl := repo.ProductRepoImpl{DB: sqlx.NewDb(db, "sqlmock")}
+			repo := repo.NewProductRepository(repoImpl)
+
+			resp, err := repo.GetProductBySku(context.Background(), tc.sku)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, resp)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProductRepoImpl_GetProducts(t *testing.T) {
+	columns := []string{"product_id", "sku", "name", "price", "qty"}
+
+	testCases := []struct {
+		name         string
+		filter       repo.ProductFilter
+		expectedResp []repo.Product
+		expectedErr  error
+		mockFunc     func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:   "default sort with limit",
+			filter: repo.ProductFilter{Limit: 3},
+			expectedResp: []repo.Product{
+				{ProductID: 1, Sku: "abc", Name: "sepatu", Price: money.MustParse("2.2"), Qty: 10},
+			},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where archived_at is null order by product_id asc limit $1")).
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "abc", "sepatu", 2.2, 10))
+			},
+		},
+		{
+			name:   "filter by name and sku escapes wildcards",
+			filter: repo.ProductFilter{Name: "50%", Sku: "A_", Limit: 2},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where archived_at is null and name ilike $1 and sku ilike $2 order by product_id asc limit $3")).
+					WithArgs(`%50\%%`, `A\_%`, 2).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name:   "after cursor on product id descending",
+			filter: repo.ProductFilter{SortBy: repo.ProductSortByID, Desc: true, AfterID: 5, Limit: 2},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where archived_at is null and product_id < $1 order by product_id desc limit $2")).
+					WithArgs(5, 2).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name:   "after cursor on name",
+			filter: repo.ProductFilter{SortBy: repo.ProductSortByName, AfterValue: "jam", AfterID: 2, Limit: 2},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where archived_at is null and (name, product_id) > ($1, $2) order by name asc, product_id asc limit $3")).
+					WithArgs("jam", 2, 2).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name:   "after cursor on price",
+			filter: repo.ProductFilter{SortBy: repo.ProductSortByPrice, AfterValue: "20", AfterID: 2},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where archived_at is null and (price, product_id) > (cast($1 as numeric), $2) order by price asc, product_id asc")).
+					WithArgs("20", 2).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name:   "unknown sort falls back to product id",
+			filter: repo.ProductFilter{SortBy: "qty; drop table products"},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products where archived_at is null order by product_id asc")).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name:   "include archived products",
+			filter: repo.ProductFilter{IncludeArchived: true, Limit: 1},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select product_id, sku, name, price, qty, reserved_qty, archived_at from products order by product_id asc limit $1")).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name:        "database error",
+			filter:      repo.ProductFilter{},
+			expectedErr: errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products").
+					WillReturnError(errors.New("database error"))
+			},
+		},
+		{
+			name:        "error scanning product rows",
+			filter:      repo.ProductFilter{},
+			expectedErr: errors.New("sql: Scan error on column index 3, name \"price\": money: invalid decimal: \"not a float\""),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("select product_id, sku, name, price, qty, reserved_qty, archived_at from products").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "abc", "sepatu", "not a float", 10))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			repoImpl := repo.ProductRepoImpl{DB: sqlx.NewDb(db, "sqlmock")}
+			repo := repo.NewProductRepository(repoImpl)
+
+			resp, err := repo.GetProducts(context.Background(), tc.filter)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, resp)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProductRepoImpl_WriteProduct(t *testing.T) {
+	archivedAt := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	columns := []string{"product_id", "sku", "name", "price", "qty", "reserved_qty", "archived_at"}
+	product := repo.Product{ProductID: 5, Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9"), Qty: 4}
+
+	testCases := []struct {
+		name         string
+		mockFunc     func(mock sqlmock.Sqlmock)
+		call         func(r repo.ProductRepository, tx *sqlx.Tx) (repo.Product, error)
+		expectedResp repo.Product
+		expectedErr  error
+	}{
+		{
+			name: "create product",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("insert into products(sku, name, price, qty) values($1, $2, $3, $4) RETURNING product_id, sku, name, price, qty, reserved_qty, archived_at")).
+					WithArgs("HP01", "Headphones", product.Price, 4).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "HP01", "Headphones", "79.9", 4, 0, nil))
+			},
+			call: func(r repo.ProductRepository, tx *sqlx.Tx) (repo.Product, error) {
+				return r.CreateProduct(tx, context.Background(), repo.Product{Sku: "HP01", Name: "Headphones", Price: product.Price, Qty: 4})
+			},
+			expectedResp: product,
+		},
+		{
+			name: "create product with a taken sku",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("insert into products").
+					WillReturnError(&pq.Error{Code: "23505", Constraint: "products_sku_key"})
+			},
+			call: func(r repo.ProductRepository, tx *sqlx.Tx) (repo.Product, error) {
+				return r.CreateProduct(tx, context.Background(), product)
+			},
+			expectedErr: repo.ErrDuplicateSku,
+		},
+		{
+			name: "update product",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("update products set sku = $1, name = $2, price = $3 where product_id = $4 RETURNING product_id, sku, name, price, qty, reserved_qty, archived_at")).
+					WithArgs("HP01", "Headphones", product.Price, 5).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "HP01", "Headphones", "79.9", 4, 0, nil))
+			},
+			call: func(r repo.ProductRepository, tx *sqlx.Tx) (repo.Product, error) {
+				return r.UpdateProduct(context.Background(), product)
+			},
+			expectedResp: product,
+		},
+		{
+			name: "update unknown product",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("update products set sku").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(r repo.ProductRepository, tx *sqlx.Tx) (repo.Product, error) {
+				return r.UpdateProduct(context.Background(), product)
+			},
+		},
+		{
+			name: "archive product",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("update products set archived_at = coalesce(archived_at, $1) where product_id = $2 RETURNING product_id, sku, name, price, qty, reserved_qty, archived_at")).
+					WithArgs(archivedAt, 5).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "HP01", "Headphones", "79.9", 4, 0, archivedAt))
+			},
+			call: func(r repo.ProductRepository, tx *sqlx.Tx) (repo.Product, error) {
+				return r.ArchiveProduct(context.Background(), 5, archivedAt)
+			},
+			expectedResp: repo.Product{ProductID: 5, Sku: "HP01", Name: "Headphones", Price: product.Price, Qty: 4, ArchivedAt: &archivedAt},
+		},
+		{
+			name: "adjust product qty",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("update products set qty = qty + $1 where product_id = $2 and qty + $1 >= reserved_qty RETURNING product_id, sku, name, price, qty, reserved_qty, archived_at")).
+					WithArgs(-2, 5).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "HP01", "Headphones", "79.9", 2, 0, nil))
+			},
+			call: func(r repo.ProductRepository, tx *sqlx.Tx) (repo.Product, error) {
+				return r.AdjustProductQty(tx, context.Background(), 5, -2)
+			},
+			expectedResp: repo.Product{ProductID: 5, Sku: "HP01", Name: "Headphones", Price: product.Price, Qty: 2},
+		},
+		{
+			name: "adjust product qty below zero",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("update products set qty = qty").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			call: func(r repo.ProductRepository, tx *sqlx.Tx) (repo.Product, error) {
+				return r.AdjustProductQty(tx, context.Background(), 5, -10)
+			},
+			expectedErr: repo.ErrInsufficientStock,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tc.mockFunc(mock)
+
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+			tx, err := sqlxDB.Beginx()
+			assert.NoError(t, err)
+
+			res, err := tc.call(repo.NewProductRepository(repo.ProductRepoImpl{DB: sqlxDB}), tx)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, res)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProductRepoImpl_AdjustProductReservedQty(t *testing.T) {
+	query := regexp.QuoteMeta("update products set reserved_qty = reserved_qty + $1 where product_id = $2 and reserved_qty + $1 between 0 and qty RETURNING reserved_qty")
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(2, 3).WillReturnRows(sqlmock.NewRows([]string{"reserved_qty"}).AddRow(2))
+	mock.ExpectQuery(query).WithArgs(20, 3).WillReturnRows(sqlmock.NewRows([]string{"reserved_qty"}))
+	mock.ExpectQuery(query).WithArgs(-2, 3).WillReturnError(errors.New("db error"))
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	productRepo := repo.NewProductRepository(repo.ProductRepoImpl{DB: sqlxDB})
+
+	assert.NoError(t, productRepo.AdjustProductReservedQty(tx, context.Background(), 3, 2))
+	assert.ErrorIs(t, productRepo.AdjustProductReservedQty(tx, context.Background(), 3, 20), repo.ErrInsufficientStock)
+	assert.EqualError(t, productRepo.AdjustProductReservedQty(tx, context.Background(), 3, -2), "db error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,23 +2,64 @@ package repo
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/pkg/money"
 	"go.uber.org/dig"
 )
 
 type (
 	Promo struct {
-		PromoID   int64   `json:"promo_id" db:"promo_id"`
-		ProductID int64   `json:"product_id" db:"product_id"`
-		PromoType string  `json:"promo_type" db:"promo_type"`
-		Reward    float64 `json:"reward" db:"reward"`
-		MinQty    int64   `json:"min_qty" db:"min_qty"`
+		PromoID   int64         `json:"promo_id" db:"promo_id"`
+		ProductID int64         `json:"product_id" db:"product_id"`
+		PromoType string        `json:"promo_type" db:"promo_type"`
+		Reward    money.Decimal `json:"reward" db:"reward"`
+		MinQty    int64         `json:"min_qty" db:"min_qty"`
+		// FreeQty is how many units of every MinQty a buy_get promo gives
+		// away.
+		FreeQty int64 `json:"free_qty" db:"free_qty"`
+		// Tiers are the qty break points of a tiered promo.
+		Tiers PromoTiers `json:"tiers" db:"tiers"`
+		// Priority orders promos of a product, higher first. It decides which
+		// promo is applied first and breaks ties between equally good picks.
+		Priority int64 `json:"priority" db:"priority"`
+		// Exclusive promos are never combined with another promo.
+		Exclusive bool `json:"exclusive" db:"exclusive"`
+		// StackGroup names the promos that can't be combined with each other.
+		// Empty means the default group of the promo's kind.
+		StackGroup string `json:"stack_group" db:"stack_group"`
+		// Active switches the promo off regardless of its window.
+		Active bool `json:"active" db:"active"`
+		// StartsAt and EndsAt bound the window the promo runs in, either may
+		// be open. They are wall clock times in Timezone, UTC when empty.
+		StartsAt *time.Time `json:"starts_at" db:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at" db:"ends_at"`
+		Timezone string     `json:"timezone" db:"timezone"`
 	}
 
+	// PromoTier takes Percent off a line of at least MinQty units.
+	PromoTier struct {
+		MinQty  int64         `json:"min_qty"`
+		Percent money.Decimal `json:"percent"`
+	}
+
+	// PromoTiers is stored as a JSON array, lowest MinQty first.
+	PromoTiers []PromoTier
+
 	PromoRepository interface {
-		GetPromoByProductID(ctx context.Context, productID int64) (res Promo, err error)
-		GetAllPromo(ctx context.Context) (res []Promo, err error)
+		GetPromoByProductID(ctx context.Context, productID int64, at time.Time) (res Promo, err error)
+		GetPromosByProductID(ctx context.Context, productID int64, at time.Time) (res []Promo, err error)
+		GetPromosByProductIDTx(tx *sqlx.Tx, ctx context.Context, productID int64, at time.Time) (res []Promo, err error)
+		GetAllPromo(ctx context.Context, at time.Time) (res []Promo, err error)
+		GetPromoByPromoID(ctx context.Context, promoID int64) (res Promo, err error)
+		CreatePromo(ctx context.Context, form Promo) (promoID int64, err error)
+		UpdatePromo(ctx context.Context, form Promo) (err error)
+		DeactivatePromo(ctx context.Context, promoID int64) (err error)
+		DeletePromo(ctx context.Context, promoID int64) (err error)
 	}
 
 	PromoRepoImpl struct {
@@ -27,12 +68,69 @@ type (
 	}
 )
 
+const promoColumns = "promo_id, product_id, promo_type, reward, min_qty, free_qty, tiers, priority, exclusive, stack_group, active, starts_at, ends_at, timezone"
+
 func NewPromoRepository(impl PromoRepoImpl) PromoRepository {
 	return &impl
 }
 
-func (r *PromoRepoImpl) GetPromoByProductID(ctx context.Context, productID int64) (res Promo, err error) {
-	rows, err := r.DB.QueryxContext(ctx, "select promo_id, product_id, promo_type, reward, min_qty from promos where product_id = $1", productID)
+// Scan implements sql.Scanner for the jsonb tiers column.
+func (t *PromoTiers) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		return t.unmarshal(v)
+	case string:
+		return t.unmarshal([]byte(v))
+	default:
+		return fmt.Errorf("cannot scan %T into promo tiers", src)
+	}
+}
+
+func (t *PromoTiers) unmarshal(b []byte) error {
+	var tiers PromoTiers
+	err := json.Unmarshal(b, &tiers)
+	if err != nil {
+		return err
+	}
+
+	if len(tiers) == 0 {
+		tiers = nil
+	}
+
+	*t = tiers
+	return nil
+}
+
+// Value implements driver.Valuer, writing an empty array for no tiers so the
+// column never holds null.
+func (t PromoTiers) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "[]", nil
+	}
+
+	b, err := json.Marshal([]PromoTier(t))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// promoEffectiveAt filters the promos running at the instant bound to $n. The
+// instant is turned into the promo's local time before it's compared with the
+// window.
+func promoEffectiveAt(n int) string {
+	local := fmt.Sprintf("($%d::timestamptz at time zone coalesce(nullif(timezone, ''), 'UTC'))", n)
+	return "active and (starts_at is null or starts_at <= " + local + ") and (ends_at is null or ends_at > " + local + ")"
+}
+
+// GetPromoByProductID returns the product's highest priority promo running at
+// the given time.
+func (r *PromoRepoImpl) GetPromoByProductID(ctx context.Context, productID int64, at time.Time) (res Promo, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+promoColumns+" from promos where product_id = $1 and "+promoEffectiveAt(2)+" order by priority desc, promo_id asc limit 1", productID, at)
 	if err != nil {
 		return res, err
 	}
@@ -49,8 +147,42 @@ func (r *PromoRepoImpl) GetPromoByProductID(ctx context.Context, productID int64
 	return res, nil
 }
 
-func (r *PromoRepoImpl) GetAllPromo(ctx context.Context) (res []Promo, err error) {
-	rows, err := r.DB.QueryxContext(ctx, "select promo_id, product_id, promo_type, reward, min_qty from promos order by promo_id asc")
+// GetPromosByProductID returns every promo of the product running at the given
+// time, highest priority first.
+func (r *PromoRepoImpl) GetPromosByProductID(ctx context.Context, productID int64, at time.Time) (res []Promo, err error) {
+	return getPromosByProductID(ctx, r.DB, productID, at)
+}
+
+// GetPromosByProductIDTx is GetPromosByProductID inside tx, so a checkout
+// reads its promos on the connection it already holds.
+func (r *PromoRepoImpl) GetPromosByProductIDTx(tx *sqlx.Tx, ctx context.Context, productID int64, at time.Time) (res []Promo, err error) {
+	return getPromosByProductID(ctx, tx, productID, at)
+}
+
+func getPromosByProductID(ctx context.Context, q sqlx.QueryerContext, productID int64, at time.Time) (res []Promo, err error) {
+	rows, err := q.QueryxContext(ctx, "select "+promoColumns+" from promos where product_id = $1 and "+promoEffectiveAt(2)+" order by priority desc, promo_id asc", productID, at)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := Promo{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+// GetAllPromo returns every promo running at the given time.
+func (r *PromoRepoImpl) GetAllPromo(ctx context.Context, at time.Time) (res []Promo, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+promoColumns+" from promos where "+promoEffectiveAt(1)+" order by promo_id asc", at)
 	if err != nil {
 		return res, err
 	}
@@ -69,3 +201,60 @@ func (r *PromoRepoImpl) GetAllPromo(ctx context.Context) (res []Promo, err error
 
 	return res, nil
 }
+
+// GetPromoByPromoID returns the promo whether it's running or not.
+func (r *PromoRepoImpl) GetPromoByPromoID(ctx context.Context, promoID int64) (res Promo, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select "+promoColumns+" from promos where promo_id = $1", promoID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *PromoRepoImpl) CreatePromo(ctx context.Context, form Promo) (promoID int64, err error) {
+	err = r.DB.QueryRowxContext(ctx, "insert into promos(product_id, promo_type, reward, min_qty, free_qty, tiers, priority, exclusive, stack_group, active, starts_at, ends_at, timezone) values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING promo_id",
+		form.ProductID, form.PromoType, form.Reward, form.MinQty, form.FreeQty, form.Tiers, form.Priority, form.Exclusive, form.StackGroup, form.Active, form.StartsAt, form.EndsAt, form.Timezone).Scan(&promoID)
+	if err != nil {
+		return promoID, err
+	}
+
+	return promoID, nil
+}
+
+func (r *PromoRepoImpl) UpdatePromo(ctx context.Context, form Promo) (err error) {
+	_, err = r.DB.ExecContext(ctx, "update promos set product_id = $1, promo_type = $2, reward = $3, min_qty = $4, free_qty = $5, tiers = $6, priority = $7, exclusive = $8, stack_group = $9, active = $10, starts_at = $11, ends_at = $12, timezone = $13 where promo_id = $14",
+		form.ProductID, form.PromoType, form.Reward, form.MinQty, form.FreeQty, form.Tiers, form.Priority, form.Exclusive, form.StackGroup, form.Active, form.StartsAt, form.EndsAt, form.Timezone, form.PromoID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *PromoRepoImpl) DeactivatePromo(ctx context.Context, promoID int64) (err error) {
+	_, err = r.DB.ExecContext(ctx, "update promos set active = false where promo_id = $1", promoID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *PromoRepoImpl) DeletePromo(ctx context.Context, promoID int64) (err error) {
+	_, err = r.DB.ExecContext(ctx, "delete from promos where promo_id = $1", promoID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -2,15 +2,30 @@ package repo_test
 
 import (
 	"context"
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
 	"github.com/stretchr/testify/assert"
 )
 
+const promoEffectiveAt = "active and (starts_at is null or starts_at <= ($%[1]d::timestamptz at time zone coalesce(nullif(timezone, ''), 'UTC'))) and (ends_at is null or ends_at > ($%[1]d::timestamptz at time zone coalesce(nullif(timezone, ''), 'UTC')))"
+
+var (
+	promoAt = time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	promoByProductQuery  = regexp.QuoteMeta("select promo_id, product_id, promo_type, reward, min_qty, free_qty, tiers, priority, exclusive, stack_group, active, starts_at, ends_at, timezone from promos where product_id = $1 and " + fmt.Sprintf(promoEffectiveAt, 2) + " order by priority desc, promo_id asc limit 1")
+	promosByProductQuery = regexp.QuoteMeta("select promo_id, product_id, promo_type, reward, min_qty, free_qty, tiers, priority, exclusive, stack_group, active, starts_at, ends_at, timezone from promos where product_id = $1 and " + fmt.Sprintf(promoEffectiveAt, 2) + " order by priority desc, promo_id asc")
+	allPromoQuery        = regexp.QuoteMeta("select promo_id, product_id, promo_type, reward, min_qty, free_qty, tiers, priority, exclusive, stack_group, active, starts_at, ends_at, timezone from promos where " + fmt.Sprintf(promoEffectiveAt, 1) + " order by promo_id asc")
+)
+
 func TestPromoRepoImpl_GetPromoByProductID(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -26,15 +41,16 @@ func TestPromoRepoImpl_GetPromoByProductID(t *testing.T) {
 				PromoID:   1,
 				ProductID: 1,
 				PromoType: "type1",
-				Reward:    1.23,
+				Reward:    money.MustParse("1.23"),
 				MinQty:    1,
+				Active:    true,
 			},
 			expectedErr: nil,
 			mockFunc: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty"}).
-					AddRow(1, 1, "type1", 1.23, 1)
-				mock.ExpectQuery("select promo_id, product_id, promo_type, reward, min_qty from promos where product_id = \\$1").
-					WithArgs(1).WillReturnRows(rows)
+				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty", "free_qty", "tiers", "priority", "exclusive", "stack_group", "active", "starts_at", "ends_at", "timezone"}).
+					AddRow(1, 1, "type1", 1.23, 1, 0, "[]", 0, false, "", true, nil, nil, "")
+				mock.ExpectQuery(promoByProductQuery).
+					WithArgs(1, promoAt).WillReturnRows(rows)
 			},
 		},
 		{
@@ -43,20 +59,20 @@ func TestPromoRepoImpl_GetPromoByProductID(t *testing.T) {
 			expectedPromo: repo.Promo{},
 			expectedErr:   errors.New("database error"),
 			mockFunc: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("select promo_id, product_id, promo_type, reward, min_qty from promos where product_id = \\$1").
-					WithArgs(1).WillReturnError(errors.New("database error"))
+				mock.ExpectQuery(promoByProductQuery).
+					WithArgs(1, promoAt).WillReturnError(errors.New("database error"))
 			},
 		},
 		{
 			name:          "error scanning promo rows",
 			productID:     1,
 			expectedPromo: repo.Promo{},
-			expectedErr:   errors.New("sql: Scan error on column index 3, name \"reward\": converting driver.Value type string (\"not a float\") to a float64: invalid syntax"),
+			expectedErr:   errors.New("sql: Scan error on column index 3, name \"reward\": money: invalid decimal: \"not a float\""),
 			mockFunc: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty"}).
-					AddRow(1, 1, "discount", "not a float", 2)
-				mock.ExpectQuery("select promo_id, product_id, promo_type, reward, min_qty from promos where product_id = \\$1").
-					WithArgs(1).WillReturnRows(rows).WillReturnError(nil)
+				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty", "free_qty", "tiers", "priority", "exclusive", "stack_group", "active", "starts_at", "ends_at", "timezone"}).
+					AddRow(1, 1, "discount", "not a float", 2, 0, "[]", 0, false, "", true, nil, nil, "")
+				mock.ExpectQuery(promoByProductQuery).
+					WithArgs(1, promoAt).WillReturnRows(rows).WillReturnError(nil)
 			},
 		},
 	}
@@ -76,7 +92,7 @@ func TestPromoRepoImpl_GetPromoByProductID(t *testing.T) {
 			repoImpl := repo.PromoRepoImpl{DB: sqlx.NewDb(db, "sqlmock")}
 			repo := repo.NewPromoRepository(repoImpl)
 
-			promo, err := repo.GetPromoByProductID(context.Background(), tc.productID)
+			promo, err := repo.GetPromoByProductID(context.Background(), tc.productID, promoAt)
 			if err != nil {
 				if err.Error() != tc.expectedErr.Error() {
 					t.Errorf("expected error '%s', but got '%s'", tc.expectedErr, err)
@@ -97,13 +113,13 @@ func TestPromoRepoImpl_GetAllPromo(t *testing.T) {
 	}{
 		{
 			name:          "successfully get all promos",
-			expectedPromo: []repo.Promo{{PromoID: 1, ProductID: 1, PromoType: "discount", Reward: 10.0, MinQty: 2}, {PromoID: 2, ProductID: 2, PromoType: "free gift", Reward: 0.0, MinQty: 5}},
+			expectedPromo: []repo.Promo{{PromoID: 1, ProductID: 1, PromoType: "discount", Reward: money.MustParse("10.0"), MinQty: 2, Active: true}, {PromoID: 2, ProductID: 2, PromoType: "free gift", Reward: money.MustParse("0.0"), MinQty: 5, Active: true}},
 			expectedErr:   nil,
 			mockFunc: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty"}).
-					AddRow(1, 1, "discount", 10.0, 2).
-					AddRow(2, 2, "free gift", 0.0, 5)
-				mock.ExpectQuery("select promo_id, product_id, promo_type, reward, min_qty from promos order by promo_id asc").
+				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty", "free_qty", "tiers", "priority", "exclusive", "stack_group", "active", "starts_at", "ends_at", "timezone"}).
+					AddRow(1, 1, "discount", 10.0, 2, 0, "[]", 0, false, "", true, nil, nil, "").
+					AddRow(2, 2, "free gift", 0.0, 5, 0, "[]", 0, false, "", true, nil, nil, "")
+				mock.ExpectQuery(allPromoQuery).WithArgs(promoAt).
 					WillReturnRows(rows)
 			},
 		},
@@ -112,18 +128,18 @@ func TestPromoRepoImpl_GetAllPromo(t *testing.T) {
 			expectedPromo: []repo.Promo{},
 			expectedErr:   errors.New("database error"),
 			mockFunc: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery("select promo_id, product_id, promo_type, reward, min_qty from promos order by promo_id asc").
+				mock.ExpectQuery(allPromoQuery).WithArgs(promoAt).
 					WillReturnError(errors.New("database error"))
 			},
 		},
 		{
 			name:          "error scanning promo rows",
 			expectedPromo: []repo.Promo{},
-			expectedErr:   errors.New("sql: Scan error on column index 3, name \"reward\": converting driver.Value type string (\"not a float\") to a float64: invalid syntax"),
+			expectedErr:   errors.New("sql: Scan error on column index 3, name \"reward\": money: invalid decimal: \"not a float\""),
 			mockFunc: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty"}).
-					AddRow(1, 1, "discount", "not a float", 2)
-				mock.ExpectQuery("select promo_id, product_id, promo_type, reward, min_qty from promos order by promo_id asc").
+				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty", "free_qty", "tiers", "priority", "exclusive", "stack_group", "active", "starts_at", "ends_at", "timezone"}).
+					AddRow(1, 1, "discount", "not a float", 2, 0, "[]", 0, false, "", true, nil, nil, "")
+				mock.ExpectQuery(allPromoQuery).WithArgs(promoAt).
 					WillReturnRows(rows).WillReturnError(nil)
 			},
 		},
@@ -144,7 +160,7 @@ func TestPromoRepoImpl_GetAllPromo(t *testing.T) {
 			repoImpl := repo.PromoRepoImpl{DB: sqlx.NewDb(db, "sqlmock")}
 			repo := repo.NewPromoRepository(repoImpl)
 
-			promo, err := repo.GetAllPromo(context.Background())
+			promo, err := repo.GetAllPromo(context.Background(), promoAt)
 			if err != nil {
 				if err.Error() != tc.expectedErr.Error() {
 					t.Errorf("expected error '%s', but got '%s'", tc.expectedErr, err)
@@ -155,3 +171,318 @@ func TestPromoRepoImpl_GetAllPromo(t *testing.T) {
 		})
 	}
 }
+
+func TestPromoRepoImpl_GetPromosByProductID(t *testing.T) {
+	startsAt := time.Date(2023, 6, 3, 0, 0, 0, 0, time.UTC)
+	endsAt := time.Date(2023, 6, 5, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		productID     int64
+		expectedPromo []repo.Promo
+		expectedErr   error
+		mockFunc      func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:          "successfully get promos of a product",
+			productID:     1,
+			expectedPromo: []repo.Promo{{PromoID: 4, ProductID: 1, PromoType: "product", Reward: money.MustParse("4"), MinQty: 1, Priority: 10, Exclusive: true, StackGroup: "gift", Active: true, StartsAt: &startsAt, EndsAt: &endsAt, Timezone: "Asia/Jakarta"}, {PromoID: 1, ProductID: 1, PromoType: "discount", Reward: money.MustParse("10.0"), MinQty: 2, Active: true}, {PromoID: 6, ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("10")}, {MinQty: 5, Percent: money.MustParse("20")}}, Active: true}},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty", "free_qty", "tiers", "priority", "exclusive", "stack_group", "active", "starts_at", "ends_at", "timezone"}).
+					AddRow(4, 1, "product", 4, 1, 0, "[]", 10, true, "gift", true, startsAt, endsAt, "Asia/Jakarta").
+					AddRow(1, 1, "discount", 10.0, 2, 0, "[]", 0, false, "", true, nil, nil, "").
+					AddRow(6, 1, "tiered", 0, 2, 0, []byte(`[{"min_qty": 2, "percent": "10"}, {"min_qty": 5, "percent": 20}]`), 0, false, "", true, nil, nil, "")
+				mock.ExpectQuery(promosByProductQuery).
+					WithArgs(1, promoAt).WillReturnRows(rows)
+			},
+		},
+		{
+			name:        "database error",
+			productID:   1,
+			expectedErr: errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(promosByProductQuery).
+					WithArgs(1, promoAt).WillReturnError(errors.New("database error"))
+			},
+		},
+		{
+			name:        "error scanning promo rows",
+			productID:   1,
+			expectedErr: errors.New("sql: Scan error on column index 3, name \"reward\": money: invalid decimal: \"not a float\""),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty", "free_qty", "tiers", "priority", "exclusive", "stack_group", "active", "starts_at", "ends_at", "timezone"}).
+					AddRow(1, 1, "discount", "not a float", 2, 0, "[]", 0, false, "", true, nil, nil, "")
+				mock.ExpectQuery(promosByProductQuery).
+					WithArgs(1, promoAt).WillReturnRows(rows)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			repoImpl := repo.PromoRepoImpl{DB: sqlx.NewDb(db, "sqlmock")}
+			repo := repo.NewPromoRepository(repoImpl)
+
+			promos, err := repo.GetPromosByProductID(context.Background(), tc.productID, promoAt)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedPromo, promos)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPromoRepoImpl_GetPromosByProductIDTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty", "free_qty", "tiers", "priority", "exclusive", "stack_group", "active", "starts_at", "ends_at", "timezone"}).
+		AddRow(1, 1, "discount", 10.0, 2, 0, "[]", 0, false, "", true, nil, nil, "")
+	mock.ExpectQuery(promosByProductQuery).
+		WithArgs(1, promoAt).WillReturnRows(rows)
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	promoRepo := repo.NewPromoRepository(repo.PromoRepoImpl{DB: sqlxDB})
+	promos, err := promoRepo.GetPromosByProductIDTx(tx, context.Background(), 1, promoAt)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.Promo{{PromoID: 1, ProductID: 1, PromoType: "discount", Reward: money.MustParse("10.0"), MinQty: 2, Active: true}}, promos)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPromoRepoImpl_GetPromoByPromoID(t *testing.T) {
+	query := regexp.QuoteMeta("select promo_id, product_id, promo_type, reward, min_qty, free_qty, tiers, priority, exclusive, stack_group, active, starts_at, ends_at, timezone from promos where promo_id = $1")
+
+	testCases := []struct {
+		name          string
+		promoID       int64
+		expectedPromo repo.Promo
+		expectedErr   error
+		mockFunc      func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:          "inactive promo is returned too",
+			promoID:       2,
+			expectedPromo: repo.Promo{PromoID: 2, ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 1},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"promo_id", "product_id", "promo_type", "reward", "min_qty", "free_qty", "tiers", "priority", "exclusive", "stack_group", "active", "starts_at", "ends_at", "timezone"}).
+					AddRow(2, 1, "discount", 10, 1, 0, "[]", 0, false, "", false, nil, nil, "")
+				mock.ExpectQuery(query).WithArgs(2).WillReturnRows(rows)
+			},
+		},
+		{
+			name:        "database error",
+			promoID:     2,
+			expectedErr: errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(2).WillReturnError(errors.New("database error"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			repo := repo.NewPromoRepository(repo.PromoRepoImpl{DB: sqlx.NewDb(db, "sqlmock")})
+
+			promo, err := repo.GetPromoByPromoID(context.Background(), tc.promoID)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedPromo, promo)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPromoRepoImpl_WritePromo(t *testing.T) {
+	startsAt := time.Date(2023, 6, 3, 0, 0, 0, 0, time.UTC)
+	promo := repo.Promo{
+		PromoID:   5,
+		ProductID: 1,
+		PromoType: "tiered",
+		MinQty:    2,
+		Tiers:     repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("10")}, {MinQty: 5, Percent: money.MustParse("20")}},
+		Priority:  1,
+		Active:    true,
+		StartsAt:  &startsAt,
+		Timezone:  "Asia/Jakarta",
+	}
+	columns := []driver.Value{promo.ProductID, promo.PromoType, promo.Reward, promo.MinQty, promo.FreeQty, `[{"min_qty":2,"percent":"10"},{"min_qty":5,"percent":"20"}]`, promo.Priority, promo.Exclusive, promo.StackGroup, promo.Active, promo.StartsAt, promo.EndsAt, promo.Timezone}
+
+	testCases := []struct {
+		name        string
+		mockFunc    func(mock sqlmock.Sqlmock)
+		call        func(r repo.PromoRepository) error
+		expectedErr error
+	}{
+		{
+			name: "create promo",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("insert into promos").WithArgs(columns...).
+					WillReturnRows(sqlmock.NewRows([]string{"promo_id"}).AddRow(5))
+			},
+			call: func(r repo.PromoRepository) error {
+				promoID, err := r.CreatePromo(context.Background(), promo)
+				if promoID != 5 {
+					return errors.New("unexpected promo id")
+				}
+				return err
+			},
+		},
+		{
+			name: "create promo error",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("insert into promos").WillReturnError(errors.New("insert error"))
+			},
+			call: func(r repo.PromoRepository) error {
+				_, err := r.CreatePromo(context.Background(), promo)
+				return err
+			},
+			expectedErr: errors.New("insert error"),
+		},
+		{
+			name: "update promo",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("update promos set product_id = \\$1").WithArgs(append(columns, promo.PromoID)...).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(r repo.PromoRepository) error {
+				return r.UpdatePromo(context.Background(), promo)
+			},
+		},
+		{
+			name: "update promo error",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("update promos set product_id = \\$1").WillReturnError(errors.New("update error"))
+			},
+			call: func(r repo.PromoRepository) error {
+				return r.UpdatePromo(context.Background(), promo)
+			},
+			expectedErr: errors.New("update error"),
+		},
+		{
+			name: "deactivate promo",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("update promos set active = false where promo_id = $1")).WithArgs(5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(r repo.PromoRepository) error {
+				return r.DeactivatePromo(context.Background(), 5)
+			},
+		},
+		{
+			name: "delete promo",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("delete from promos where promo_id = $1")).WithArgs(5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(r repo.PromoRepository) error {
+				return r.DeletePromo(context.Background(), 5)
+			},
+		},
+		{
+			name: "delete promo error",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("delete from promos where promo_id = $1")).WillReturnError(errors.New("delete error"))
+			},
+			call: func(r repo.PromoRepository) error {
+				return r.DeletePromo(context.Background(), 5)
+			},
+			expectedErr: errors.New("delete error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			err = tc.call(repo.NewPromoRepository(repo.PromoRepoImpl{DB: sqlx.NewDb(db, "sqlmock")}))
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPromoTiers(t *testing.T) {
+	testCases := []struct {
+		name          string
+		src           interface{}
+		expectedTiers repo.PromoTiers
+		expectedErr   bool
+	}{
+		{
+			name:          "json array",
+			src:           []byte(`[{"min_qty": 2, "percent": "10"}, {"min_qty": 5, "percent": 20.5}]`),
+			expectedTiers: repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("10")}, {MinQty: 5, Percent: money.MustParse("20.5")}},
+		},
+		{
+			name: "empty array",
+			src:  "[]",
+		},
+		{
+			name: "null",
+			src:  nil,
+		},
+		{
+			name:        "not an array",
+			src:         `{"min_qty": 2}`,
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported type",
+			src:         int64(2),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var tiers repo.PromoTiers
+			err := tiers.Scan(tc.src)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedTiers, tiers)
+		})
+	}
+
+	t.Run("no tiers are written as an empty array", func(t *testing.T) {
+		value, err := repo.PromoTiers(nil).Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", value)
+	})
+}
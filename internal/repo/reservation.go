@@ -0,0 +1,193 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/pkg/sqlkit"
+	"go.uber.org/dig"
+)
+
+type (
+	// Reservation holds stock for a cart until it's confirmed into an order,
+	// released, or it expires.
+	Reservation struct {
+		ReservationID int64     `json:"reservation_id" db:"reservation_id"`
+		Status        string    `json:"status" db:"status"`
+		ExpiresAt     time.Time `json:"expires_at" db:"expires_at"`
+		// OrderID is zero until the reservation is confirmed.
+		OrderID   int64             `json:"order_id" db:"order_id"`
+		CreatedAt time.Time         `json:"created_at" db:"created_at"`
+		Items     []ReservationItem `json:"items" db:"-"`
+	}
+
+	ReservationItem struct {
+		ReservationItemID int64 `json:"reservation_item_id" db:"reservation_item_id"`
+		ReservationID     int64 `json:"reservation_id" db:"reservation_id"`
+		ProductID         int64 `json:"product_id" db:"product_id"`
+		Qty               int64 `json:"qty" db:"qty"`
+	}
+
+	ReservationRepository interface {
+		CreateReservation(tx *sqlx.Tx, ctx context.Context, form Reservation) (reservationID int64, err error)
+		CreateReservationItems(tx *sqlx.Tx, ctx context.Context, form []ReservationItem) (err error)
+		GetReservationByReservationIDForUpdate(tx *sqlx.Tx, ctx context.Context, reservationID int64) (res Reservation, err error)
+		GetReservationItemsByReservationID(tx *sqlx.Tx, ctx context.Context, reservationID int64) (res []ReservationItem, err error)
+		UpdateReservationStatus(tx *sqlx.Tx, ctx context.Context, form Reservation, at time.Time) (err error)
+		GetExpiredReservationIDs(ctx context.Context, now time.Time, afterID int64, limit int) (res []int64, err error)
+		BeginTx() (tx *sqlx.Tx, err error)
+		RollbackTx(tx *sqlx.Tx) (err error)
+		CommitTx(tx *sqlx.Tx) (err error)
+	}
+
+	ReservationRepoImpl struct {
+		dig.In
+		*sqlx.DB
+	}
+)
+
+const (
+	ReservationStatusActive    = "active"
+	ReservationStatusConfirmed = "confirmed"
+	ReservationStatusReleased  = "released"
+	ReservationStatusExpired   = "expired"
+)
+
+const reservationColumns = "reservation_id, status, expires_at, coalesce(order_id, 0) as order_id, created_at"
+
+func NewReservationRepository(impl ReservationRepoImpl) ReservationRepository {
+	return &impl
+}
+
+func (r *ReservationRepoImpl) CreateReservation(tx *sqlx.Tx, ctx context.Context, form Reservation) (reservationID int64, err error) {
+	err = tx.QueryRowxContext(ctx, "insert into reservations(status, expires_at, created_at, updated_at) values($1, $2, $3, $3) RETURNING reservation_id",
+		form.Status, form.ExpiresAt, form.CreatedAt).Scan(&reservationID)
+	if err != nil {
+		return reservationID, err
+	}
+
+	return reservationID, nil
+}
+
+func (r *ReservationRepoImpl) CreateReservationItems(tx *sqlx.Tx, ctx context.Context, form []ReservationItem) (err error) {
+	sqlInsert := "insert into reservation_items(reservation_id, product_id, qty) values"
+	rowSQL := "(?, ?, ?)"
+
+	vals := []interface{}{}
+	var inserts []string
+
+	for _, val := range form {
+		vals = append(vals, val.ReservationID, val.ProductID, val.Qty)
+		inserts = append(inserts, rowSQL)
+	}
+
+	sqlInsert = sqlInsert + strings.Join(inserts, ",")
+	sqlInsert = sqlkit.ReplaceSQL(sqlInsert, "?")
+
+	stmt, err := tx.PrepareContext(ctx, sqlInsert)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, vals...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetReservationByReservationIDForUpdate reads the reservation inside tx and
+// locks its row until tx ends, so it's confirmed, released or expired once.
+// A zero ReservationID in res means the reservation doesn't exist.
+func (r *ReservationRepoImpl) GetReservationByReservationIDForUpdate(tx *sqlx.Tx, ctx context.Context, reservationID int64) (res Reservation, err error) {
+	rows, err := tx.QueryxContext(ctx, "select "+reservationColumns+" from reservations where reservation_id = $1 for update", reservationID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&res)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func (r *ReservationRepoImpl) GetReservationItemsByReservationID(tx *sqlx.Tx, ctx context.Context, reservationID int64) (res []ReservationItem, err error) {
+	rows, err := tx.QueryxContext(ctx, "select reservation_item_id, reservation_id, product_id, qty from reservation_items where reservation_id = $1 order by reservation_item_id asc", reservationID)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var item ReservationItem
+		err = rows.StructScan(&item)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, item)
+	}
+
+	return res, nil
+}
+
+// UpdateReservationStatus moves the reservation to form.Status and records
+// form.OrderID, zero for none.
+func (r *ReservationRepoImpl) UpdateReservationStatus(tx *sqlx.Tx, ctx context.Context, form Reservation, at time.Time) (err error) {
+	_, err = tx.ExecContext(ctx, "update reservations set status = $1, order_id = nullif(cast($2 as bigint), 0), updated_at = $3 where reservation_id = $4",
+		form.Status, form.OrderID, at, form.ReservationID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetExpiredReservationIDs lists up to limit active reservations that
+// expired at now, by id after afterID, so a caller can page past the ones it
+// couldn't expire.
+func (r *ReservationRepoImpl) GetExpiredReservationIDs(ctx context.Context, now time.Time, afterID int64, limit int) (res []int64, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select reservation_id from reservations where status = $1 and expires_at <= $2 and reservation_id > $3 order by reservation_id asc limit $4",
+		ReservationStatusActive, now, afterID, limit)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var reservationID int64
+		err = rows.Scan(&reservationID)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, reservationID)
+	}
+
+	return res, nil
+}
+
+func (r *ReservationRepoImpl) BeginTx() (tx *sqlx.Tx, err error) {
+	return r.DB.Beginx()
+}
+
+func (r *ReservationRepoImpl) RollbackTx(tx *sqlx.Tx) (err error) {
+	return tx.Rollback()
+}
+
+func (r *ReservationRepoImpl) CommitTx(tx *sqlx.Tx) (err error) {
+	return tx.Commit()
+}
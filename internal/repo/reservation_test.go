@@ -0,0 +1,153 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservationRepoImpl_WriteReservation(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(15 * time.Minute)
+
+	testCases := []struct {
+		name        string
+		mockFunc    func(mock sqlmock.Sqlmock)
+		call        func(r repo.ReservationRepository, tx *sqlx.Tx) error
+		expectedErr error
+	}{
+		{
+			name: "create reservation",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("insert into reservations(status, expires_at, created_at, updated_at) values($1, $2, $3, $3) RETURNING reservation_id")).
+					WithArgs("active", expiresAt, now).
+					WillReturnRows(sqlmock.NewRows([]string{"reservation_id"}).AddRow(8))
+			},
+			call: func(r repo.ReservationRepository, tx *sqlx.Tx) error {
+				reservationID, err := r.CreateReservation(tx, context.Background(), repo.Reservation{Status: repo.ReservationStatusActive, ExpiresAt: expiresAt, CreatedAt: now})
+				assert.Equal(t, int64(8), reservationID)
+				return err
+			},
+		},
+		{
+			name: "create reservation items",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare(regexp.QuoteMeta("insert into reservation_items(reservation_id, product_id, qty) values($1, $2, $3),($4, $5, $6)")).
+					ExpectExec().
+					WithArgs(8, 3, 2, 8, 4, 1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			call: func(r repo.ReservationRepository, tx *sqlx.Tx) error {
+				return r.CreateReservationItems(tx, context.Background(), []repo.ReservationItem{
+					{ReservationID: 8, ProductID: 3, Qty: 2},
+					{ReservationID: 8, ProductID: 4, Qty: 1},
+				})
+			},
+		},
+		{
+			name: "confirm reservation",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("update reservations set status = $1, order_id = nullif(cast($2 as bigint), 0), updated_at = $3 where reservation_id = $4")).
+					WithArgs("confirmed", 11, now, 8).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			call: func(r repo.ReservationRepository, tx *sqlx.Tx) error {
+				return r.UpdateReservationStatus(tx, context.Background(), repo.Reservation{ReservationID: 8, Status: repo.ReservationStatusConfirmed, OrderID: 11}, now)
+			},
+		},
+		{
+			name: "update error",
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("update reservations").WillReturnError(errors.New("update error"))
+			},
+			call: func(r repo.ReservationRepository, tx *sqlx.Tx) error {
+				return r.UpdateReservationStatus(tx, context.Background(), repo.Reservation{ReservationID: 8, Status: repo.ReservationStatusReleased}, now)
+			},
+			expectedErr: errors.New("update error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tc.mockFunc(mock)
+
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+			tx, err := sqlxDB.Beginx()
+			assert.NoError(t, err)
+
+			err = tc.call(repo.NewReservationRepository(repo.ReservationRepoImpl{DB: sqlxDB}), tx)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReservationRepoImpl_ReadReservation(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("select reservation_id, status, expires_at, coalesce(order_id, 0) as order_id, created_at from reservations where reservation_id = $1 for update")).
+		WithArgs(8).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_id", "status", "expires_at", "order_id", "created_at"}).
+			AddRow(8, "active", now.Add(15*time.Minute), 0, now))
+	mock.ExpectQuery(regexp.QuoteMeta("select reservation_item_id, reservation_id, product_id, qty from reservation_items where reservation_id = $1 order by reservation_item_id asc")).
+		WithArgs(8).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_item_id", "reservation_id", "product_id", "qty"}).
+			AddRow(1, 8, 3, 2))
+	mock.ExpectQuery("from reservations where reservation_id").
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_id", "status", "expires_at", "order_id", "created_at"}))
+	mock.ExpectQuery(regexp.QuoteMeta("select reservation_id from reservations where status = $1 and expires_at <= $2 and reservation_id > $3 order by reservation_id asc limit $4")).
+		WithArgs("active", now, 0, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"reservation_id"}).AddRow(4).AddRow(8))
+	mock.ExpectQuery("select reservation_id from reservations").WillReturnError(errors.New("database error"))
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	tx, err := sqlxDB.Beginx()
+	assert.NoError(t, err)
+
+	reservationRepo := repo.NewReservationRepository(repo.ReservationRepoImpl{DB: sqlxDB})
+	ctx := context.Background()
+
+	res, err := reservationRepo.GetReservationByReservationIDForUpdate(tx, ctx, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Reservation{ReservationID: 8, Status: "active", ExpiresAt: now.Add(15 * time.Minute), CreatedAt: now}, res)
+
+	items, err := reservationRepo.GetReservationItemsByReservationID(tx, ctx, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.ReservationItem{{ReservationItemID: 1, ReservationID: 8, ProductID: 3, Qty: 2}}, items)
+
+	res, err = reservationRepo.GetReservationByReservationIDForUpdate(tx, ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.Reservation{}, res)
+
+	reservationIDs, err := reservationRepo.GetExpiredReservationIDs(ctx, now, 0, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4, 8}, reservationIDs)
+
+	_, err = reservationRepo.GetExpiredReservationIDs(ctx, now, 0, 100)
+	assert.EqualError(t, err, "database error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
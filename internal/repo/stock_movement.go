@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/pkg/sqlkit"
+	"go.uber.org/dig"
+)
+
+type (
+	// StockMovement is one entry of the append-only inventory ledger. A
+	// product's qty always equals the sum of its movements' Delta.
+	StockMovement struct {
+		MovementID int64  `json:"movement_id" db:"movement_id"`
+		ProductID  int64  `json:"product_id" db:"product_id"`
+		Delta      int64  `json:"delta" db:"delta"`
+		Reason     string `json:"reason" db:"reason"`
+		// OrderID is zero for movements that don't come from an order.
+		OrderID   int64     `json:"order_id" db:"order_id"`
+		Actor     string    `json:"actor" db:"actor"`
+		CreatedAt time.Time `json:"created_at" db:"created_at"`
+	}
+
+	StockMovementFilter struct {
+		ProductID int64
+		AfterID   int64
+		Limit     int
+	}
+
+	// StockDrift is a product whose qty doesn't match its ledger.
+	StockDrift struct {
+		ProductID int64  `json:"product_id" db:"product_id"`
+		Sku       string `json:"sku" db:"sku"`
+		Name      string `json:"name" db:"name"`
+		Qty       int64  `json:"qty" db:"qty"`
+		LedgerQty int64  `json:"ledger_qty" db:"ledger_qty"`
+	}
+
+	StockMovementRepository interface {
+		CreateStockMovements(tx *sqlx.Tx, ctx context.Context, form []StockMovement) (err error)
+		GetStockMovements(ctx context.Context, filter StockMovementFilter) (res []StockMovement, err error)
+		GetStockDrift(ctx context.Context) (res []StockDrift, err error)
+	}
+
+	StockMovementRepoImpl struct {
+		dig.In
+		*sqlx.DB
+	}
+)
+
+func NewStockMovementRepository(impl StockMovementRepoImpl) StockMovementRepository {
+	return &impl
+}
+
+// CreateStockMovements appends form to the ledger inside tx, which must be
+// the transaction that changes the products' qty.
+func (r *StockMovementRepoImpl) CreateStockMovements(tx *sqlx.Tx, ctx context.Context, form []StockMovement) (err error) {
+	if len(form) == 0 {
+		return nil
+	}
+
+	sqlInsert := "insert into stock_movements(product_id, delta, reason, order_id, actor, created_at) values"
+	rowSQL := "(?, ?, ?, nullif(cast(? as bigint), 0), ?, ?)"
+
+	vals := []interface{}{}
+	var inserts []string
+
+	for _, val := range form {
+		vals = append(vals, val.ProductID, val.Delta, val.Reason, val.OrderID, val.Actor, val.CreatedAt)
+		inserts = append(inserts, rowSQL)
+	}
+
+	sqlInsert = sqlInsert + strings.Join(inserts, ",")
+	sqlInsert = sqlkit.ReplaceSQL(sqlInsert, "?")
+
+	stmt, err := tx.PrepareContext(ctx, sqlInsert)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, vals...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetStockMovements returns one page of a product's movements, newest first.
+func (r *StockMovementRepoImpl) GetStockMovements(ctx context.Context, filter StockMovementFilter) (res []StockMovement, err error) {
+	query := "select movement_id, product_id, delta, reason, coalesce(order_id, 0) as order_id, actor, created_at from stock_movements where product_id = ?"
+	vals := []interface{}{filter.ProductID}
+
+	if filter.AfterID > 0 {
+		query += " and movement_id < ?"
+		vals = append(vals, filter.AfterID)
+	}
+
+	query += " order by movement_id desc"
+
+	if filter.Limit > 0 {
+		query += " limit ?"
+		vals = append(vals, filter.Limit)
+	}
+
+	rows, err := r.DB.QueryxContext(ctx, sqlkit.ReplaceSQL(query, "?"), vals...)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := StockMovement{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
+
+// GetStockDrift recomputes every product's qty from the ledger and returns
+// the products where it differs from the stored qty.
+func (r *StockMovementRepoImpl) GetStockDrift(ctx context.Context) (res []StockDrift, err error) {
+	rows, err := r.DB.QueryxContext(ctx, "select p.product_id, p.sku, p.name, p.qty, coalesce(sum(m.delta), 0) as ledger_qty from products p left join stock_movements m on m.product_id = p.product_id group by p.product_id having p.qty <> coalesce(sum(m.delta), 0) order by p.product_id asc")
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tmp := StockDrift{}
+		err = rows.StructScan(&tmp)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, tmp)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,173 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockMovementRepoImpl_CreateStockMovements(t *testing.T) {
+	createdAt := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		form        []repo.StockMovement
+		expectedErr error
+		mockFunc    func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name: "sale and restock",
+			form: []repo.StockMovement{
+				{ProductID: 1, Delta: -3, Reason: "sale", OrderID: 7, Actor: "system", CreatedAt: createdAt},
+				{ProductID: 2, Delta: 5, Reason: "restock", Actor: "alice", CreatedAt: createdAt},
+			},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare(regexp.QuoteMeta("insert into stock_movements(product_id, delta, reason, order_id, actor, created_at) values($1, $2, $3, nullif(cast($4 as bigint), 0), $5, $6),($7, $8, $9, nullif(cast($10 as bigint), 0), $11, $12)")).
+					ExpectExec().
+					WithArgs(1, -3, "sale", 7, "system", createdAt, 2, 5, "restock", 0, "alice", createdAt).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+		},
+		{
+			name:     "nothing to record",
+			mockFunc: func(mock sqlmock.Sqlmock) {},
+		},
+		{
+			name: "insert error",
+			form: []repo.StockMovement{
+				{ProductID: 1, Delta: -3, Reason: "sale", OrderID: 7, CreatedAt: createdAt},
+			},
+			expectedErr: errors.New("insert error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare("insert into stock_movements").
+					ExpectExec().
+					WillReturnError(errors.New("insert error"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			tc.mockFunc(mock)
+
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+			tx, err := sqlxDB.Beginx()
+			assert.NoError(t, err)
+
+			stockRepo := repo.NewStockMovementRepository(repo.StockMovementRepoImpl{DB: sqlxDB})
+
+			err = stockRepo.CreateStockMovements(tx, context.Background(), tc.form)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStockMovementRepoImpl_GetStockMovements(t *testing.T) {
+	createdAt := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	columns := []string{"movement_id", "product_id", "delta", "reason", "order_id", "actor", "created_at"}
+
+	testCases := []struct {
+		name         string
+		filter       repo.StockMovementFilter
+		expectedResp []repo.StockMovement
+		expectedErr  error
+		mockFunc     func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name:   "first page",
+			filter: repo.StockMovementFilter{ProductID: 1, Limit: 2},
+			expectedResp: []repo.StockMovement{
+				{MovementID: 9, ProductID: 1, Delta: -3, Reason: "sale", OrderID: 7, Actor: "system", CreatedAt: createdAt},
+				{MovementID: 4, ProductID: 1, Delta: 10, Reason: "opening", Actor: "migration", CreatedAt: createdAt},
+			},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("select movement_id, product_id, delta, reason, coalesce(order_id, 0) as order_id, actor, created_at from stock_movements where product_id = $1 order by movement_id desc limit $2")).
+					WithArgs(1, 2).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(9, 1, -3, "sale", 7, "system", createdAt).
+						AddRow(4, 1, 10, "opening", 0, "migration", createdAt))
+			},
+		},
+		{
+			name:   "after cursor",
+			filter: repo.StockMovementFilter{ProductID: 1, AfterID: 4, Limit: 2},
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta("from stock_movements where product_id = $1 and movement_id < $2 order by movement_id desc limit $3")).
+					WithArgs(1, 4, 2).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+		{
+			name:        "database error",
+			filter:      repo.StockMovementFilter{ProductID: 1},
+			expectedErr: errors.New("database error"),
+			mockFunc: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("from stock_movements").WillReturnError(errors.New("database error"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+
+			tc.mockFunc(mock)
+
+			stockRepo := repo.NewStockMovementRepository(repo.StockMovementRepoImpl{DB: sqlx.NewDb(db, "sqlmock")})
+
+			res, err := stockRepo.GetStockMovements(context.Background(), tc.filter)
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedResp, res)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStockMovementRepoImpl_GetStockDrift(t *testing.T) {
+	query := regexp.QuoteMeta("select p.product_id, p.sku, p.name, p.qty, coalesce(sum(m.delta), 0) as ledger_qty from products p left join stock_movements m on m.product_id = p.product_id group by p.product_id having p.qty <> coalesce(sum(m.delta), 0) order by p.product_id asc")
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(query).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "sku", "name", "qty", "ledger_qty"}).
+			AddRow(4, "234234", "Raspberry Pi B", 2, 1))
+	mock.ExpectQuery(query).WillReturnError(errors.New("database error"))
+
+	stockRepo := repo.NewStockMovementRepository(repo.StockMovementRepoImpl{DB: sqlx.NewDb(db, "sqlmock")})
+
+	res, err := stockRepo.GetStockDrift(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.StockDrift{{ProductID: 4, Sku: "234234", Name: "Raspberry Pi B", Qty: 2, LedgerQty: 1}}, res)
+
+	_, err = stockRepo.GetStockDrift(context.Background())
+	assert.EqualError(t, err, "database error")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,23 @@
+package service
+
+import "context"
+
+// DefaultActor is recorded when a change comes without an actor.
+const DefaultActor = "system"
+
+type actorKey struct{}
+
+// WithActor tags ctx with who is making the change, e.g. an admin's user name.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor ctx was tagged with, or DefaultActor.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	if actor == "" {
+		return DefaultActor
+	}
+
+	return actor
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/clock"
+	"go.uber.org/dig"
+)
+
+// Types of bundle, each reading its reward differently.
+const (
+	// BundleTypePrice sells the bundle's items for the Reward amount.
+	BundleTypePrice = "price"
+	// BundleTypeDiscount takes Reward percent off the bundle's items.
+	BundleTypeDiscount = "discount"
+)
+
+type (
+	BundleUsecase interface {
+		GetBundles(ctx context.Context) (res []repo.Bundle, err error)
+		GetBundleByBundleID(ctx context.Context, bundleID int64) (res repo.Bundle, err error)
+		CreateBundle(ctx context.Context, form repo.Bundle) (res repo.Bundle, err error)
+		DeactivateBundle(ctx context.Context, bundleID int64) (res repo.Bundle, err error)
+	}
+
+	BundleUsecaseImpl struct {
+		dig.In
+		ProductRepo repo.ProductRepository
+		BundleRepo  repo.BundleRepository
+		Clock       clock.Clock        `optional:"true"`
+		Promotions  *PromotionRegistry `optional:"true"`
+	}
+)
+
+func NewBundleUsecase(impl BundleUsecaseImpl) BundleUsecase {
+	return &impl
+}
+
+// GetBundles returns the bundles running right now.
+func (c *BundleUsecaseImpl) GetBundles(ctx context.Context) (res []repo.Bundle, err error) {
+	res, err = c.BundleRepo.GetBundles(ctx, c.now())
+	if err != nil {
+		log.Printf("error while do GetBundles %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *BundleUsecaseImpl) GetBundleByBundleID(ctx context.Context, bundleID int64) (res repo.Bundle, err error) {
+	res, err = c.BundleRepo.GetBundleByBundleID(ctx, bundleID)
+	if err != nil {
+		log.Printf("error while do GetBundleByBundleID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+// CreateBundle writes the bundle and its items in one transaction.
+func (c *BundleUsecaseImpl) CreateBundle(ctx context.Context, form repo.Bundle) (res repo.Bundle, err error) {
+	form.Name = strings.TrimSpace(form.Name)
+
+	err = c.validateBundle(ctx, form)
+	if err != nil {
+		return res, err
+	}
+
+	tx, err := c.BundleRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.BundleRepo.RollbackTx(tx)
+
+	form.BundleID, err = c.BundleRepo.CreateBundle(tx, ctx, form)
+	if err != nil {
+		log.Printf("error while do CreateBundle %+v", err)
+		return res, err
+	}
+
+	for i := range form.Items {
+		form.Items[i].BundleID = form.BundleID
+	}
+
+	err = c.BundleRepo.CreateBundleItems(tx, ctx, form.Items)
+	if err != nil {
+		log.Printf("error while do CreateBundleItems %+v", err)
+		return res, err
+	}
+
+	err = c.BundleRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return res, err
+	}
+
+	return form, nil
+}
+
+// DeactivateBundle switches the bundle off but keeps it and its items.
+func (c *BundleUsecaseImpl) DeactivateBundle(ctx context.Context, bundleID int64) (res repo.Bundle, err error) {
+	res, err = c.GetBundleByBundleID(ctx, bundleID)
+	if err != nil {
+		return res, err
+	}
+
+	if res.BundleID == 0 {
+		return res, fmt.Errorf("%w: %d", ErrPromoNotFound, bundleID)
+	}
+
+	err = c.BundleRepo.DeactivateBundle(ctx, bundleID)
+	if err != nil {
+		log.Printf("error while do DeactivateBundle %+v", err)
+		return res, err
+	}
+
+	res.Active = false
+
+	return res, nil
+}
+
+func (c *BundleUsecaseImpl) validateBundle(ctx context.Context, form repo.Bundle) error {
+	if form.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPromo)
+	}
+
+	// a single product bought in numbers is what product promos are for
+	if len(form.Items) < 2 {
+		return fmt.Errorf("%w: a bundle needs at least two products", ErrInvalidPromo)
+	}
+
+	seen := make(map[int64]bool, len(form.Items))
+	for _, item := range form.Items {
+		if item.Qty < 1 {
+			return fmt.Errorf("%w: qty of product %d must be at least 1", ErrInvalidPromo, item.ProductID)
+		}
+
+		if seen[item.ProductID] {
+			return fmt.Errorf("%w: product %d is listed twice", ErrInvalidPromo, item.ProductID)
+		}
+		seen[item.ProductID] = true
+
+		product, err := c.ProductRepo.GetProductByProductID(ctx, item.ProductID)
+		if err != nil {
+			log.Printf("error while do GetProductByProductID %+v", err)
+			return err
+		}
+
+		if product.ProductID == 0 || product.ArchivedAt != nil {
+			return fmt.Errorf("%w: product %d doesn't exist", ErrInvalidPromo, item.ProductID)
+		}
+	}
+
+	kind, ok := c.promotions().BundleKind(form.BundleType)
+	if !ok {
+		return fmt.Errorf("%w: unknown bundle_type %q", ErrInvalidPromo, form.BundleType)
+	}
+
+	err := kind.Validate(ctx, form, promoCheck{ProductRepo: c.ProductRepo})
+	if err != nil {
+		return err
+	}
+
+	return validatePromoWindow(form.StartsAt, form.EndsAt, form.Timezone)
+}
+
+func (c *BundleUsecaseImpl) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Clock.Now()
+}
+
+func (c *BundleUsecaseImpl) promotions() *PromotionRegistry {
+	if c.Promotions == nil {
+		return builtinPromotions
+	}
+
+	return c.Promotions
+}
@@ -0,0 +1,199 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBundleCreateBundle(t *testing.T) {
+	archivedAt := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	startsAt := time.Date(2023, 6, 10, 0, 0, 0, 0, time.UTC)
+	endsAt := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	items := []repo.BundleItem{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 1}}
+
+	tests := []struct {
+		name          string
+		form          repo.Bundle
+		mockSetupFunc func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository)
+		expectedResp  repo.Bundle
+		expectedErr   error
+	}{
+		{
+			name: "discount bundle",
+			form: repo.Bundle{Name: " Smart home ", BundleType: "discount", Reward: money.MustParse("15"), Active: true, Items: []repo.BundleItem{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 1}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				productRepo.On("GetProductByProductID", mock.Anything, int64(3)).Return(alexaSpeaker, nil)
+				bundleRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				bundleRepo.On("RollbackTx", mock.Anything).Return(nil)
+				bundleRepo.On("CreateBundle", mock.Anything, mock.Anything, mock.Anything).Return(int64(2), nil)
+				bundleRepo.On("CreateBundleItems", mock.Anything, mock.Anything, []repo.BundleItem{{BundleID: 2, ProductID: 1, Qty: 1}, {BundleID: 2, ProductID: 3, Qty: 1}}).Return(nil)
+				bundleRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Bundle{BundleID: 2, Name: "Smart home", BundleType: "discount", Reward: money.MustParse("15"), Active: true, Items: []repo.BundleItem{{BundleID: 2, ProductID: 1, Qty: 1}, {BundleID: 2, ProductID: 3, Qty: 1}}},
+		},
+		{
+			name:          "no name",
+			form:          repo.Bundle{Name: " ", BundleType: "price", Reward: money.MustParse("100"), Items: items},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name:          "a single product",
+			form:          repo.Bundle{Name: "Two homes", BundleType: "price", Reward: money.MustParse("90"), Items: []repo.BundleItem{{ProductID: 1, Qty: 2}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name: "product listed twice",
+			form: repo.Bundle{Name: "Smart home", BundleType: "price", Reward: money.MustParse("100"), Items: []repo.BundleItem{{ProductID: 1, Qty: 1}, {ProductID: 1, Qty: 1}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name:          "qty below one",
+			form:          repo.Bundle{Name: "Smart home", BundleType: "price", Reward: money.MustParse("100"), Items: []repo.BundleItem{{ProductID: 1, Qty: 0}, {ProductID: 3, Qty: 1}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name: "archived product",
+			form: repo.Bundle{Name: "Smart home", BundleType: "price", Reward: money.MustParse("100"), Items: items},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {
+				archived := googleHome
+				archived.ArchivedAt = &archivedAt
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(archived, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "discount above 100",
+			form: repo.Bundle{Name: "Smart home", BundleType: "discount", Reward: money.MustParse("100.5"), Items: items},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				productRepo.On("GetProductByProductID", mock.Anything, int64(3)).Return(alexaSpeaker, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "price of zero",
+			form: repo.Bundle{Name: "Smart home", BundleType: "price", Items: items},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				productRepo.On("GetProductByProductID", mock.Anything, int64(3)).Return(alexaSpeaker, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "unknown bundle type",
+			form: repo.Bundle{Name: "Smart home", BundleType: "cashback", Reward: money.MustParse("10"), Items: items},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				productRepo.On("GetProductByProductID", mock.Anything, int64(3)).Return(alexaSpeaker, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "window ends before it starts",
+			form: repo.Bundle{Name: "Smart home", BundleType: "price", Reward: money.MustParse("100"), Items: items, StartsAt: &startsAt, EndsAt: &endsAt},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				productRepo.On("GetProductByProductID", mock.Anything, int64(3)).Return(alexaSpeaker, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "repository error",
+			form: repo.Bundle{Name: "Smart home", BundleType: "price", Reward: money.MustParse("100"), Items: []repo.BundleItem{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 1}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, bundleRepo *mockRepo.BundleRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				productRepo.On("GetProductByProductID", mock.Anything, int64(3)).Return(alexaSpeaker, nil)
+				bundleRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				bundleRepo.On("RollbackTx", mock.Anything).Return(nil)
+				bundleRepo.On("CreateBundle", mock.Anything, mock.Anything, mock.Anything).Return(int64(2), nil)
+				bundleRepo.On("CreateBundleItems", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error"))
+			},
+			expectedErr: errors.New("error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := new(mockRepo.ProductRepository)
+			bundleRepo := new(mockRepo.BundleRepository)
+			tt.mockSetupFunc(productRepo, bundleRepo)
+
+			bundleUsecase := service.NewBundleUsecase(service.BundleUsecaseImpl{
+				ProductRepo: productRepo,
+				BundleRepo:  bundleRepo,
+			})
+
+			res, err := bundleUsecase.CreateBundle(context.Background(), tt.form)
+			if tt.expectedErr != nil {
+				if errors.Is(tt.expectedErr, service.ErrInvalidPromo) {
+					assert.ErrorIs(t, err, service.ErrInvalidPromo)
+				} else {
+					assert.EqualError(t, err, tt.expectedErr.Error())
+				}
+				bundleRepo.AssertNotCalled(t, "CommitTx", mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			productRepo.AssertExpectations(t)
+			bundleRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBundleWrites(t *testing.T) {
+	bundle := repo.Bundle{BundleID: 3, Name: "Smart home", BundleType: "discount", Reward: money.MustParse("15"), Active: true}
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	bundleRepo := new(mockRepo.BundleRepository)
+	bundleRepo.On("GetBundles", mock.Anything, now).Return([]repo.Bundle{bundle}, nil)
+	bundleRepo.On("GetBundleByBundleID", mock.Anything, int64(3)).Return(bundle, nil)
+	bundleRepo.On("GetBundleByBundleID", mock.Anything, int64(9)).Return(repo.Bundle{}, nil)
+	bundleRepo.On("DeactivateBundle", mock.Anything, int64(3)).Return(nil)
+
+	bundleUsecase := service.NewBundleUsecase(service.BundleUsecaseImpl{
+		ProductRepo: new(mockRepo.ProductRepository),
+		BundleRepo:  bundleRepo,
+		Clock:       clock.Fixed(now),
+	})
+
+	ctx := context.Background()
+
+	t.Run("lists the bundles running now", func(t *testing.T) {
+		res, err := bundleUsecase.GetBundles(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []repo.Bundle{bundle}, res)
+	})
+
+	t.Run("deactivate keeps the bundle", func(t *testing.T) {
+		res, err := bundleUsecase.DeactivateBundle(ctx, 3)
+		assert.NoError(t, err)
+		assert.False(t, res.Active)
+		bundleRepo.AssertCalled(t, "DeactivateBundle", mock.Anything, int64(3))
+	})
+
+	t.Run("deactivate unknown bundle", func(t *testing.T) {
+		_, err := bundleUsecase.DeactivateBundle(ctx, 9)
+		assert.ErrorIs(t, err, service.ErrPromoNotFound)
+		bundleRepo.AssertNotCalled(t, "DeactivateBundle", mock.Anything, int64(9))
+	})
+}
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"go.uber.org/dig"
+)
+
+var (
+	// ErrInvalidCart wraps every validation failure of a cart change.
+	ErrInvalidCart = apperr.New(apperr.CodeInvalidInput, "invalid cart")
+	// ErrCartNotFound is returned for an unknown cart.
+	ErrCartNotFound = apperr.New(apperr.CodeNotFound, "cart not found")
+	// ErrCartClosed is returned when a cart was already checked out or merged
+	// into another one.
+	ErrCartClosed = apperr.New(apperr.CodeConflict, "cart is closed")
+	// ErrCartExpired is returned when an open cart sat idle for longer than
+	// CartPolicy.IdleTTL.
+	ErrCartExpired = apperr.New(apperr.CodeConflict, "cart has expired")
+)
+
+// DefaultCartIdleTTL is how long an open cart may sit idle when no TTL is
+// configured.
+const DefaultCartIdleTTL = 72 * time.Hour
+
+// CartPolicy is how long an open cart may sit idle before it expires. Every
+// change to the cart starts the period again.
+type CartPolicy struct {
+	IdleTTL time.Duration
+}
+
+type (
+	CartItemRequest struct {
+		CartID    int64
+		ProductID int64
+		Qty       int64
+	}
+
+	CartUsecase interface {
+		CreateCart(ctx context.Context, customerID string) (res repo.Cart, err error)
+		GetCart(ctx context.Context, cartID int64) (res repo.Cart, err error)
+		AddCartItem(ctx context.Context, form CartItemRequest) (res repo.Cart, err error)
+		UpdateCartItem(ctx context.Context, form CartItemRequest) (res repo.Cart, err error)
+		RemoveCartItem(ctx context.Context, cartID, productID int64) (res repo.Cart, err error)
+		MergeCarts(ctx context.Context, guestCartID, customerCartID int64) (res repo.Cart, err error)
+		CheckoutCart(ctx context.Context, cartID int64, couponCodes ...string) (res Checkout, err error)
+	}
+
+	CartUsecaseImpl struct {
+		dig.In
+		CartRepo    repo.CartRepository
+		ProductRepo repo.ProductRepository
+		CheckoutSvc CheckoutUsecase
+		Policy      CartPolicy  `optional:"true"`
+		Clock       clock.Clock `optional:"true"`
+	}
+)
+
+func NewCartUsecase(impl CartUsecaseImpl) CartUsecase {
+	return &impl
+}
+
+// CreateCart opens an empty cart for customerID, or for a guest when it is
+// empty.
+func (c *CartUsecaseImpl) CreateCart(ctx context.Context, customerID string) (res repo.Cart, err error) {
+	tx, err := c.CartRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.CartRepo.RollbackTx(tx)
+
+	res = repo.Cart{
+		CustomerID: customerID,
+		Status:     repo.CartStatusOpen,
+		CreatedAt:  c.now(),
+	}
+	res.UpdatedAt = res.CreatedAt
+
+	res.CartID, err = c.CartRepo.CreateCart(tx, ctx, res)
+	if err != nil {
+		log.Printf("error while do CreateCart %+v", err)
+		return res, err
+	}
+
+	err = c.CartRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return res, err
+	}
+
+	return c.withExpiry(res), nil
+}
+
+func (c *CartUsecaseImpl) GetCart(ctx context.Context, cartID int64) (res repo.Cart, err error) {
+	res, err = c.CartRepo.GetCartByCartID(ctx, cartID)
+	if err != nil {
+		log.Printf("error while do GetCartByCartID %+v", err)
+		return res, err
+	}
+
+	if res.CartID == 0 {
+		return res, nil
+	}
+
+	res.Items, err = c.CartRepo.GetCartItemsByCartID(ctx, cartID)
+	if err != nil {
+		log.Printf("error while do GetCartItemsByCartID %+v", err)
+		return res, err
+	}
+
+	return c.withExpiry(res), nil
+}
+
+// AddCartItem adds qty of the product to the cart, on top of what the cart
+// already holds of it.
+func (c *CartUsecaseImpl) AddCartItem(ctx context.Context, form CartItemRequest) (res repo.Cart, err error) {
+	if form.Qty < 1 {
+		return res, fmt.Errorf("%w: qty must be at least 1", ErrInvalidCart)
+	}
+
+	return c.changeCart(ctx, form.CartID, func(tx *sqlx.Tx, cart repo.Cart) error {
+		err := c.checkProduct(ctx, form.ProductID)
+		if err != nil {
+			return err
+		}
+
+		items, err := c.CartRepo.GetCartItemsByCartIDTx(tx, ctx, cart.CartID)
+		if err != nil {
+			log.Printf("error while do GetCartItemsByCartIDTx %+v", err)
+			return err
+		}
+
+		qty := form.Qty
+		for _, v := range items {
+			if v.ProductID == form.ProductID {
+				qty += v.Qty
+			}
+		}
+
+		return c.setItemQty(tx, ctx, cart.CartID, form.ProductID, qty)
+	})
+}
+
+// UpdateCartItem sets the qty of the product in the cart. A zero qty removes
+// the product.
+func (c *CartUsecaseImpl) UpdateCartItem(ctx context.Context, form CartItemRequest) (res repo.Cart, err error) {
+	if form.Qty < 0 {
+		return res, fmt.Errorf("%w: qty can't be negative", ErrInvalidCart)
+	}
+
+	return c.changeCart(ctx, form.CartID, func(tx *sqlx.Tx, cart repo.Cart) error {
+		if form.Qty > 0 {
+			err := c.checkProduct(ctx, form.ProductID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return c.setItemQty(tx, ctx, cart.CartID, form.ProductID, form.Qty)
+	})
+}
+
+func (c *CartUsecaseImpl) RemoveCartItem(ctx context.Context, cartID, productID int64) (res repo.Cart, err error) {
+	return c.changeCart(ctx, cartID, func(tx *sqlx.Tx, cart repo.Cart) error {
+		return c.setItemQty(tx, ctx, cartID, productID, 0)
+	})
+}
+
+// MergeCarts moves the items of a guest cart into a customer's cart, adding
+// up the qty of products in both, and closes the guest cart. It returns the
+// customer's cart.
+func (c *CartUsecaseImpl) MergeCarts(ctx context.Context, guestCartID, customerCartID int64) (res repo.Cart, err error) {
+	if guestCartID == customerCartID {
+		return res, fmt.Errorf("%w: a cart can't be merged into itself", ErrInvalidCart)
+	}
+
+	tx, err := c.CartRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.CartRepo.RollbackTx(tx)
+
+	now := c.now()
+
+	// both carts are locked in id order so two merges of the same carts can't
+	// deadlock
+	first, second := guestCartID, customerCartID
+	if first > second {
+		first, second = second, first
+	}
+
+	carts := make(map[int64]repo.Cart, 2)
+	for _, id := range []int64{first, second} {
+		carts[id], err = c.openCart(tx, ctx, id, now)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	guest, res := carts[guestCartID], carts[customerCartID]
+	if guest.CustomerID != "" {
+		return res, fmt.Errorf("%w: cart %d isn't a guest cart", ErrInvalidCart, guestCartID)
+	}
+
+	if res.CustomerID == "" {
+		return res, fmt.Errorf("%w: cart %d isn't a customer cart", ErrInvalidCart, customerCartID)
+	}
+
+	guestItems, err := c.CartRepo.GetCartItemsByCartIDTx(tx, ctx, guestCartID)
+	if err != nil {
+		log.Printf("error while do GetCartItemsByCartIDTx %+v", err)
+		return res, err
+	}
+
+	items, err := c.CartRepo.GetCartItemsByCartIDTx(tx, ctx, customerCartID)
+	if err != nil {
+		log.Printf("error while do GetCartItemsByCartIDTx %+v", err)
+		return res, err
+	}
+
+	qty := make(map[int64]int64, len(items))
+	for _, v := range items {
+		qty[v.ProductID] = v.Qty
+	}
+
+	for _, v := range guestItems {
+		err = c.setItemQty(tx, ctx, customerCartID, v.ProductID, qty[v.ProductID]+v.Qty)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	guest.Status = repo.CartStatusMerged
+	guest.UpdatedAt = now
+	err = c.CartRepo.UpdateCart(tx, ctx, guest)
+	if err != nil {
+		log.Printf("error while do UpdateCart %+v", err)
+		return res, err
+	}
+
+	return c.commitCart(tx, ctx, res, now)
+}
+
+// CheckoutCart checks the cart's items out through CheckoutUsecase, with
+// couponCodes, and closes the cart with the order it placed. The order and the
+// closed cart are written in the same transaction, under the cart's lock, so
+// checking the same cart out again fails with ErrCartClosed.
+func (c *CartUsecaseImpl) CheckoutCart(ctx context.Context, cartID int64, couponCodes ...string) (res Checkout, err error) {
+	tx, err := c.CartRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.CartRepo.RollbackTx(tx)
+
+	now := c.now()
+
+	cart, err := c.openCart(tx, ctx, cartID, now)
+	if err != nil {
+		return res, err
+	}
+
+	items, err := c.CartRepo.GetCartItemsByCartIDTx(tx, ctx, cartID)
+	if err != nil {
+		log.Printf("error while do GetCartItemsByCartIDTx %+v", err)
+		return res, err
+	}
+
+	if len(items) == 0 {
+		return res, fmt.Errorf("%w: the cart is empty", ErrInvalidCart)
+	}
+
+	form := make([]repo.OrderDetail, len(items))
+	for i, v := range items {
+		form[i] = repo.OrderDetail{ProductID: v.ProductID, Qty: v.Qty}
+	}
+
+	// the cart's lock already makes its checkout happen once, a client key
+	// sent along would only be claimed for nothing
+	ctx = WithIdempotencyKey(ctx, "")
+
+	res, err = c.CheckoutSvc.CheckoutTx(tx, ctx, form, couponCodes...)
+	if err != nil {
+		return res, err
+	}
+
+	cart.Status = repo.CartStatusCheckedOut
+	cart.OrderID = res.OrderID
+	cart.UpdatedAt = now
+	err = c.CartRepo.UpdateCart(tx, ctx, cart)
+	if err != nil {
+		log.Printf("error while do UpdateCart %+v", err)
+		return res, err
+	}
+
+	err = c.CartRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+// changeCart runs change on the locked, open cart and returns the cart as it
+// is after the change.
+func (c *CartUsecaseImpl) changeCart(ctx context.Context, cartID int64, change func(tx *sqlx.Tx, cart repo.Cart) error) (res repo.Cart, err error) {
+	tx, err := c.CartRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.CartRepo.RollbackTx(tx)
+
+	now := c.now()
+
+	res, err = c.openCart(tx, ctx, cartID, now)
+	if err != nil {
+		return res, err
+	}
+
+	err = change(tx, res)
+	if err != nil {
+		return res, err
+	}
+
+	return c.commitCart(tx, ctx, res, now)
+}
+
+// openCart locks the cart and checks it can still change.
+func (c *CartUsecaseImpl) openCart(tx *sqlx.Tx, ctx context.Context, cartID int64, now time.Time) (res repo.Cart, err error) {
+	res, err = c.CartRepo.GetCartByCartIDForUpdate(tx, ctx, cartID)
+	if err != nil {
+		log.Printf("error while do GetCartByCartIDForUpdate %+v", err)
+		return res, err
+	}
+
+	if res.CartID == 0 {
+		return res, ErrCartNotFound
+	}
+
+	if res.Status != repo.CartStatusOpen {
+		return res, ErrCartClosed
+	}
+
+	if now.After(res.UpdatedAt.Add(c.idleTTL())) {
+		return res, ErrCartExpired
+	}
+
+	return res, nil
+}
+
+// commitCart marks the cart as touched at now, commits tx and returns the cart
+// with its items.
+func (c *CartUsecaseImpl) commitCart(tx *sqlx.Tx, ctx context.Context, cart repo.Cart, now time.Time) (res repo.Cart, err error) {
+	cart.UpdatedAt = now
+	err = c.CartRepo.UpdateCart(tx, ctx, cart)
+	if err != nil {
+		log.Printf("error while do UpdateCart %+v", err)
+		return res, err
+	}
+
+	cart.Items, err = c.CartRepo.GetCartItemsByCartIDTx(tx, ctx, cart.CartID)
+	if err != nil {
+		log.Printf("error while do GetCartItemsByCartIDTx %+v", err)
+		return res, err
+	}
+
+	err = c.CartRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return res, err
+	}
+
+	return c.withExpiry(cart), nil
+}
+
+func (c *CartUsecaseImpl) setItemQty(tx *sqlx.Tx, ctx context.Context, cartID, productID, qty int64) error {
+	if qty == 0 {
+		err := c.CartRepo.DeleteCartItem(tx, ctx, cartID, productID)
+		if err != nil {
+			log.Printf("error while do DeleteCartItem %+v", err)
+			return err
+		}
+
+		return nil
+	}
+
+	err := c.CartRepo.UpsertCartItem(tx, ctx, repo.CartItem{
+		CartID:    cartID,
+		ProductID: productID,
+		Qty:       qty,
+	})
+	if err != nil {
+		log.Printf("error while do UpsertCartItem %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// checkProduct makes sure the product exists and can still be sold. Stock
+// isn't checked, it is only taken at checkout.
+func (c *CartUsecaseImpl) checkProduct(ctx context.Context, productID int64) error {
+	product, err := c.ProductRepo.GetProductByProductID(ctx, productID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductID %+v", err)
+		return err
+	}
+
+	if product.ProductID == 0 {
+		return productNotFoundError(productID)
+	}
+
+	if product.ArchivedAt != nil {
+		return productUnavailableError(product)
+	}
+
+	return nil
+}
+
+// withExpiry sets when an open cart expires if it is left as it is.
+func (c *CartUsecaseImpl) withExpiry(cart repo.Cart) repo.Cart {
+	if cart.Status == repo.CartStatusOpen {
+		cart.ExpiresAt = cart.UpdatedAt.Add(c.idleTTL())
+	}
+
+	return cart
+}
+
+func (c *CartUsecaseImpl) idleTTL() time.Duration {
+	if c.Policy.IdleTTL <= 0 {
+		return DefaultCartIdleTTL
+	}
+
+	return c.Policy.IdleTTL
+}
+
+func (c *CartUsecaseImpl) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Clock.Now()
+}
@@ -0,0 +1,214 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCartUsecase(t *testing.T) {
+	touchedAt := checkoutAt.Add(-time.Hour)
+	archived := checkoutAt.Add(-24 * time.Hour)
+	openCart := func(id int64, customerID string) repo.Cart {
+		return repo.Cart{CartID: id, CustomerID: customerID, Status: repo.CartStatusOpen, CreatedAt: touchedAt, UpdatedAt: touchedAt}
+	}
+
+	type mocks struct {
+		cart     *mockRepo.CartRepository
+		product  *mockRepo.ProductRepository
+		checkout *mockRepo.CheckoutUsecase
+	}
+
+	newCartUsecase := func(policy service.CartPolicy) (service.CartUsecase, mocks) {
+		m := mocks{
+			cart:     new(mockRepo.CartRepository),
+			product:  new(mockRepo.ProductRepository),
+			checkout: new(mockRepo.CheckoutUsecase),
+		}
+
+		m.cart.On("BeginTx").Return(&sqlx.Tx{}, nil)
+		m.cart.On("RollbackTx", mock.Anything).Return(nil)
+		m.cart.On("CommitTx", mock.Anything).Return(nil).Maybe()
+		m.product.On("GetProductByProductID", mock.Anything, int64(3)).Return(alexaSpeaker, nil).Maybe()
+		m.product.On("GetProductByProductID", mock.Anything, int64(4)).Return(repo.Product{ProductID: 4, Name: "Raspberry Pi B", ArchivedAt: &archived}, nil).Maybe()
+		m.product.On("GetProductByProductID", mock.Anything, int64(9)).Return(repo.Product{}, nil).Maybe()
+
+		return service.NewCartUsecase(service.CartUsecaseImpl{
+			CartRepo:    m.cart,
+			ProductRepo: m.product,
+			CheckoutSvc: m.checkout,
+			Policy:      policy,
+			Clock:       clock.Fixed(checkoutAt),
+		}), m
+	}
+
+	t.Run("adding a product adds up its qty", func(t *testing.T) {
+		cartUsecase, m := newCartUsecase(service.CartPolicy{})
+		m.cart.On("GetCartByCartIDForUpdate", mock.Anything, mock.Anything, int64(5)).Return(openCart(5, "alice"), nil)
+		m.cart.On("GetCartItemsByCartIDTx", mock.Anything, mock.Anything, int64(5)).Return([]repo.CartItem{{CartID: 5, ProductID: 3, Qty: 2}}, nil).Once()
+		m.cart.On("UpsertCartItem", mock.Anything, mock.Anything, repo.CartItem{CartID: 5, ProductID: 3, Qty: 3}).Return(nil)
+		m.cart.On("UpdateCart", mock.Anything, mock.Anything, mock.MatchedBy(func(c repo.Cart) bool { return c.UpdatedAt.Equal(checkoutAt) })).Return(nil)
+		m.cart.On("GetCartItemsByCartIDTx", mock.Anything, mock.Anything, int64(5)).Return([]repo.CartItem{{CartID: 5, ProductID: 3, Qty: 3}}, nil).Once()
+
+		res, err := cartUsecase.AddCartItem(context.Background(), service.CartItemRequest{CartID: 5, ProductID: 3, Qty: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []repo.CartItem{{CartID: 5, ProductID: 3, Qty: 3}}, res.Items)
+		assert.Equal(t, checkoutAt.Add(service.DefaultCartIdleTTL), res.ExpiresAt)
+		m.cart.AssertExpectations(t)
+	})
+
+	t.Run("a zero qty removes the product", func(t *testing.T) {
+		cartUsecase, m := newCartUsecase(service.CartPolicy{})
+		m.cart.On("GetCartByCartIDForUpdate", mock.Anything, mock.Anything, int64(5)).Return(openCart(5, "alice"), nil)
+		m.cart.On("DeleteCartItem", mock.Anything, mock.Anything, int64(5), int64(3)).Return(nil)
+		m.cart.On("UpdateCart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.cart.On("GetCartItemsByCartIDTx", mock.Anything, mock.Anything, int64(5)).Return([]repo.CartItem{}, nil)
+
+		_, err := cartUsecase.UpdateCartItem(context.Background(), service.CartItemRequest{CartID: 5, ProductID: 3})
+		assert.NoError(t, err)
+		m.cart.AssertCalled(t, "DeleteCartItem", mock.Anything, mock.Anything, int64(5), int64(3))
+		m.product.AssertNotCalled(t, "GetProductByProductID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("merging sums the qty and closes the guest cart", func(t *testing.T) {
+		cartUsecase, m := newCartUsecase(service.CartPolicy{})
+		m.cart.On("GetCartByCartIDForUpdate", mock.Anything, mock.Anything, int64(5)).Return(openCart(5, "alice"), nil)
+		m.cart.On("GetCartByCartIDForUpdate", mock.Anything, mock.Anything, int64(6)).Return(openCart(6, ""), nil)
+		m.cart.On("GetCartItemsByCartIDTx", mock.Anything, mock.Anything, int64(6)).Return([]repo.CartItem{{CartID: 6, ProductID: 3, Qty: 1}, {CartID: 6, ProductID: 1, Qty: 2}}, nil)
+		m.cart.On("GetCartItemsByCartIDTx", mock.Anything, mock.Anything, int64(5)).Return([]repo.CartItem{{CartID: 5, ProductID: 3, Qty: 2}}, nil)
+		m.cart.On("UpsertCartItem", mock.Anything, mock.Anything, repo.CartItem{CartID: 5, ProductID: 3, Qty: 3}).Return(nil)
+		m.cart.On("UpsertCartItem", mock.Anything, mock.Anything, repo.CartItem{CartID: 5, ProductID: 1, Qty: 2}).Return(nil)
+		m.cart.On("UpdateCart", mock.Anything, mock.Anything, mock.MatchedBy(func(c repo.Cart) bool { return c.CartID == 6 && c.Status == repo.CartStatusMerged })).Return(nil).Once()
+		m.cart.On("UpdateCart", mock.Anything, mock.Anything, mock.MatchedBy(func(c repo.Cart) bool { return c.CartID == 5 && c.Status == repo.CartStatusOpen })).Return(nil).Once()
+
+		res, err := cartUsecase.MergeCarts(context.Background(), 6, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), res.CartID)
+		m.cart.AssertExpectations(t)
+	})
+
+	t.Run("checkout closes the cart in the checkout's transaction", func(t *testing.T) {
+		cartUsecase, m := newCartUsecase(service.CartPolicy{})
+		m.cart.On("GetCartByCartIDForUpdate", mock.Anything, mock.Anything, int64(5)).Return(openCart(5, "alice"), nil)
+		m.cart.On("GetCartItemsByCartIDTx", mock.Anything, mock.Anything, int64(5)).Return([]repo.CartItem{{CartID: 5, ProductID: 3, Qty: 2}}, nil)
+		m.checkout.On("CheckoutTx", mock.Anything, mock.Anything, []repo.OrderDetail{{ProductID: 3, Qty: 2}}, "SAVE10").
+			Return(service.Checkout{OrderID: 11, TotalAmount: money.MustParse("219")}, nil)
+		m.cart.On("UpdateCart", mock.Anything, mock.Anything, mock.MatchedBy(func(c repo.Cart) bool {
+			return c.Status == repo.CartStatusCheckedOut && c.OrderID == 11
+		})).Return(nil)
+
+		res, err := cartUsecase.CheckoutCart(context.Background(), 5, "SAVE10")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), res.OrderID)
+		m.cart.AssertCalled(t, "CommitTx", mock.Anything)
+	})
+
+	errCases := []struct {
+		name   string
+		policy service.CartPolicy
+		cart   repo.Cart
+		call   func(cartUsecase service.CartUsecase) error
+		err    error
+		code   apperr.Code
+		errMsg string
+	}{
+		{
+			name: "unknown product",
+			cart: openCart(5, "alice"),
+			call: func(cartUsecase service.CartUsecase) error {
+				_, err := cartUsecase.AddCartItem(context.Background(), service.CartItemRequest{CartID: 5, ProductID: 9, Qty: 1})
+				return err
+			},
+			code:   apperr.CodeProductNotFound,
+			errMsg: "product 9 doesn't exist",
+		},
+		{
+			name: "archived product",
+			cart: openCart(5, "alice"),
+			call: func(cartUsecase service.CartUsecase) error {
+				_, err := cartUsecase.UpdateCartItem(context.Background(), service.CartItemRequest{CartID: 5, ProductID: 4, Qty: 1})
+				return err
+			},
+			code:   apperr.CodeProductUnavailable,
+			errMsg: "the product Raspberry Pi B is no longer available",
+		},
+		{
+			name:   "idle cart expires",
+			policy: service.CartPolicy{IdleTTL: 30 * time.Minute},
+			cart:   openCart(5, "alice"),
+			call: func(cartUsecase service.CartUsecase) error {
+				_, err := cartUsecase.AddCartItem(context.Background(), service.CartItemRequest{CartID: 5, ProductID: 3, Qty: 1})
+				return err
+			},
+			err: service.ErrCartExpired,
+		},
+		{
+			name: "checked out cart is closed",
+			cart: repo.Cart{CartID: 5, Status: repo.CartStatusCheckedOut, UpdatedAt: touchedAt},
+			call: func(cartUsecase service.CartUsecase) error {
+				_, err := cartUsecase.RemoveCartItem(context.Background(), 5, 3)
+				return err
+			},
+			err: service.ErrCartClosed,
+		},
+		{
+			name: "unknown cart",
+			call: func(cartUsecase service.CartUsecase) error {
+				_, err := cartUsecase.CheckoutCart(context.Background(), 5)
+				return err
+			},
+			err: service.ErrCartNotFound,
+		},
+		{
+			name: "merge into a guest cart",
+			cart: openCart(5, ""),
+			call: func(cartUsecase service.CartUsecase) error {
+				_, err := cartUsecase.MergeCarts(context.Background(), 6, 5)
+				return err
+			},
+			err:    service.ErrInvalidCart,
+			errMsg: "invalid cart: cart 5 isn't a customer cart",
+		},
+		{
+			name: "empty qty",
+			call: func(cartUsecase service.CartUsecase) error {
+				_, err := cartUsecase.AddCartItem(context.Background(), service.CartItemRequest{CartID: 5, ProductID: 3})
+				return err
+			},
+			err: service.ErrInvalidCart,
+		},
+	}
+
+	for _, tt := range errCases {
+		t.Run(tt.name, func(t *testing.T) {
+			cartUsecase, m := newCartUsecase(tt.policy)
+			m.cart.On("GetCartByCartIDForUpdate", mock.Anything, mock.Anything, int64(5)).Return(tt.cart, nil).Maybe()
+			m.cart.On("GetCartByCartIDForUpdate", mock.Anything, mock.Anything, int64(6)).Return(openCart(6, ""), nil).Maybe()
+			m.cart.On("GetCartItemsByCartIDTx", mock.Anything, mock.Anything, int64(5)).Return([]repo.CartItem{}, nil).Maybe()
+
+			err := tt.call(cartUsecase)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+			if tt.code != "" {
+				assert.Equal(t, tt.code, apperr.CodeOf(err))
+			}
+			if tt.errMsg != "" {
+				assert.EqualError(t, err, tt.errMsg)
+			}
+
+			m.cart.AssertNotCalled(t, "CommitTx", mock.Anything)
+			m.checkout.AssertNotCalled(t, "CheckoutTx", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
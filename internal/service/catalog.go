@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/cursor"
+	"go.uber.org/dig"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+type (
+	ProductQuery struct {
+		Name   string
+		Sku    string
+		SortBy string
+		Desc   bool
+		First  int
+		After  string
+	}
+
+	ProductEdge struct {
+		Cursor string       `json:"cursor"`
+		Node   repo.Product `json:"node"`
+	}
+
+	PageInfo struct {
+		EndCursor   string `json:"end_cursor"`
+		HasNextPage bool   `json:"has_next_page"`
+	}
+
+	ProductPage struct {
+		Edges    []ProductEdge `json:"edges"`
+		PageInfo PageInfo      `json:"page_info"`
+	}
+
+	CatalogUsecase interface {
+		GetProducts(ctx context.Context, form ProductQuery) (res ProductPage, err error)
+		GetProductByProductID(ctx context.Context, productID int64) (res repo.Product, err error)
+		GetProductBySku(ctx context.Context, sku string) (res repo.Product, err error)
+		GetPromosByProductID(ctx context.Context, productID int64) (res []repo.Promo, err error)
+	}
+
+	CatalogUsecaseImpl struct {
+		dig.In
+		ProductRepo repo.ProductRepository
+		PromoRepo   repo.PromoRepository
+		Clock       clock.Clock `optional:"true"`
+	}
+)
+
+func NewCatalogUsecase(impl CatalogUsecaseImpl) CatalogUsecase {
+	return &impl
+}
+
+func (c *CatalogUsecaseImpl) GetProducts(ctx context.Context, form ProductQuery) (res ProductPage, err error) {
+	filter := repo.ProductFilter{
+		Name:   form.Name,
+		Sku:    form.Sku,
+		SortBy: form.SortBy,
+		Desc:   form.Desc,
+		Limit:  pageSize(form.First) + 1,
+	}
+
+	if form.After != "" {
+		filter.AfterValue, filter.AfterID, err = cursor.Decode(form.After)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	products, err := c.ProductRepo.GetProducts(ctx, filter)
+	if err != nil {
+		log.Printf("error while do GetProducts %+v", err)
+		return res, err
+	}
+
+	if len(products) > pageSize(form.First) {
+		products = products[:pageSize(form.First)]
+		res.PageInfo.HasNextPage = true
+	}
+
+	res.Edges = make([]ProductEdge, len(products))
+	for i, v := range products {
+		res.Edges[i] = ProductEdge{
+			Cursor: cursor.Encode(productSortValue(v, form.SortBy), v.ProductID),
+			Node:   v,
+		}
+	}
+
+	if len(res.Edges) > 0 {
+		res.PageInfo.EndCursor = res.Edges[len(res.Edges)-1].Cursor
+	}
+
+	return res, nil
+}
+
+func (c *CatalogUsecaseImpl) GetProductByProductID(ctx context.Context, productID int64) (res repo.Product, err error) {
+	res, err = c.ProductRepo.GetProductByProductID(ctx, productID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *CatalogUsecaseImpl) GetProductBySku(ctx context.Context, sku string) (res repo.Product, err error) {
+	res, err = c.ProductRepo.GetProductBySku(ctx, sku)
+	if err != nil {
+		log.Printf("error while do GetProductBySku %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *CatalogUsecaseImpl) GetPromosByProductID(ctx context.Context, productID int64) (res []repo.Promo, err error) {
+	res, err = c.PromoRepo.GetPromosByProductID(ctx, productID, c.now())
+	if err != nil {
+		log.Printf("error while do GetPromosByProductID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *CatalogUsecaseImpl) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Clock.Now()
+}
+
+func pageSize(first int) int {
+	if first <= 0 {
+		return DefaultPageSize
+	}
+
+	if first > MaxPageSize {
+		return MaxPageSize
+	}
+
+	return first
+}
+
+func productSortValue(p repo.Product, sortBy string) string {
+	switch sortBy {
+	case repo.ProductSortByName:
+		return p.Name
+	case repo.ProductSortBySku:
+		return p.Sku
+	case repo.ProductSortByPrice:
+		return p.Price.String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,155 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/cursor"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCatalogGetProducts(t *testing.T) {
+	googleHome := repo.Product{ProductID: 1, Sku: "120P90", Name: "Google Home", Price: money.MustParse("49.99"), Qty: 10}
+	macbook := repo.Product{ProductID: 2, Sku: "43N23P", Name: "MacBook Pro", Price: money.MustParse("5399.99"), Qty: 5}
+
+	tests := []struct {
+		name          string
+		form          service.ProductQuery
+		mockSetupFunc func(productRepo *mockRepo.ProductRepository)
+		expectedResp  service.ProductPage
+		wantErr       bool
+	}{
+		{
+			name: "first page has a next page",
+			form: service.ProductQuery{SortBy: repo.ProductSortByName, First: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository) {
+				productRepo.On("GetProducts", mock.Anything, repo.ProductFilter{SortBy: repo.ProductSortByName, Limit: 2}).
+					Return([]repo.Product{googleHome, macbook}, nil)
+			},
+			expectedResp: service.ProductPage{
+				Edges: []service.ProductEdge{
+					{Cursor: cursor.Encode("Google Home", 1), Node: googleHome},
+				},
+				PageInfo: service.PageInfo{EndCursor: cursor.Encode("Google Home", 1), HasNextPage: true},
+			},
+		},
+		{
+			name: "after cursor is passed to the repository",
+			form: service.ProductQuery{SortBy: repo.ProductSortByPrice, Desc: true, First: 5, After: cursor.Encode("5399.99", 2)},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository) {
+				productRepo.On("GetProducts", mock.Anything, repo.ProductFilter{SortBy: repo.ProductSortByPrice, Desc: true, AfterValue: "5399.99", AfterID: 2, Limit: 6}).
+					Return([]repo.Product{googleHome}, nil)
+			},
+			expectedResp: service.ProductPage{
+				Edges: []service.ProductEdge{
+					{Cursor: cursor.Encode("49.99", 1), Node: googleHome},
+				},
+				PageInfo: service.PageInfo{EndCursor: cursor.Encode("49.99", 1)},
+			},
+		},
+		{
+			name: "page size is capped",
+			form: service.ProductQuery{Name: "home", First: 1000},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository) {
+				productRepo.On("GetProducts", mock.Anything, repo.ProductFilter{Name: "home", Limit: service.MaxPageSize + 1}).
+					Return([]repo.Product{}, nil)
+			},
+			expectedResp: service.ProductPage{Edges: []service.ProductEdge{}},
+		},
+		{
+			name:    "invalid cursor",
+			form:    service.ProductQuery{After: "!!"},
+			wantErr: true,
+		},
+		{
+			name: "error while GetProducts",
+			form: service.ProductQuery{},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository) {
+				productRepo.On("GetProducts", mock.Anything, mock.Anything).Return(nil, errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+
+			if tt.mockSetupFunc != nil {
+				tt.mockSetupFunc(productRepo)
+			}
+
+			catalogUsecase := service.NewCatalogUsecase(service.CatalogUsecaseImpl{
+				ProductRepo: productRepo,
+				PromoRepo:   promoRepo,
+			})
+
+			res, err := catalogUsecase.GetProducts(context.Background(), tt.form)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			productRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCatalogLookups(t *testing.T) {
+	googleHome := repo.Product{ProductID: 1, Sku: "120P90", Name: "Google Home", Price: money.MustParse("49.99"), Qty: 10}
+	promos := []repo.Promo{{PromoID: 1, ProductID: 1, PromoType: "product", Reward: money.MustParse("1"), MinQty: 3}}
+
+	productRepo := new(mockRepo.ProductRepository)
+	promoRepo := new(mockRepo.PromoRepository)
+
+	productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+	productRepo.On("GetProductByProductID", mock.Anything, int64(9)).Return(repo.Product{}, errors.New("error"))
+	productRepo.On("GetProductBySku", mock.Anything, "120P90").Return(googleHome, nil)
+	productRepo.On("GetProductBySku", mock.Anything, "broken").Return(repo.Product{}, errors.New("error"))
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	promoRepo.On("GetPromosByProductID", mock.Anything, int64(1), now).Return(promos, nil)
+	promoRepo.On("GetPromosByProductID", mock.Anything, int64(9), now).Return(nil, errors.New("error"))
+
+	catalogUsecase := service.NewCatalogUsecase(service.CatalogUsecaseImpl{
+		ProductRepo: productRepo,
+		PromoRepo:   promoRepo,
+		Clock:       clock.Fixed(now),
+	})
+
+	ctx := context.Background()
+
+	product, err := catalogUsecase.GetProductByProductID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, googleHome, product)
+
+	_, err = catalogUsecase.GetProductByProductID(ctx, 9)
+	assert.Error(t, err)
+
+	product, err = catalogUsecase.GetProductBySku(ctx, "120P90")
+	assert.NoError(t, err)
+	assert.Equal(t, googleHome, product)
+
+	_, err = catalogUsecase.GetProductBySku(ctx, "broken")
+	assert.Error(t, err)
+
+	res, err := catalogUsecase.GetPromosByProductID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, promos, res)
+
+	_, err = catalogUsecase.GetPromosByProductID(ctx, 9)
+	assert.Error(t, err)
+
+	productRepo.AssertExpectations(t)
+	promoRepo.AssertExpectations(t)
+}
@@ -2,38 +2,134 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
 	"go.uber.org/dig"
 )
 
 type (
 	Checkout struct {
-		Items       []string `json:"items"`
-		TotalAmount float64  `json:"total_amount"`
+		OrderID     int64          `json:"order_id"`
+		Items       []string       `json:"items"`
+		Lines       []CheckoutLine `json:"lines"`
+		TotalAmount money.Decimal  `json:"total_amount"`
+		Currency    string         `json:"currency"`
+		// Bundles are the bundles that fit in the cart, each with how many
+		// times it was applied.
+		Bundles []AppliedBundle `json:"bundles"`
+		// OrderPromos are the order promos applied once every line was
+		// priced, in the order they were applied.
+		OrderPromos []AppliedOrderPromo `json:"order_promos"`
+		// Coupons are the coupons redeemed by the checkout, in the order they
+		// were applied.
+		Coupons  []AppliedCoupon `json:"coupons"`
+		Warnings []string        `json:"warnings"`
+	}
+
+	// CheckoutLine is the priced breakdown of one ordered product, or of a
+	// reward given away by a promo when Free is set.
+	CheckoutLine struct {
+		ProductID   int64          `json:"product_id"`
+		ProductName string         `json:"product_name"`
+		Qty         int64          `json:"qty"`
+		UnitPrice   money.Decimal  `json:"unit_price"`
+		Subtotal    money.Decimal  `json:"subtotal"`
+		Discount    money.Decimal  `json:"discount"`
+		Total       money.Decimal  `json:"total"`
+		PromoID     int64          `json:"promo_id"`
+		PromoType   string         `json:"promo_type"`
+		Promos      []AppliedPromo `json:"promos"`
+		Free        bool           `json:"free"`
+		// BundledQty is how many units of Qty were priced by bundles rather
+		// than by the line's promos.
+		BundledQty int64 `json:"bundled_qty"`
+		// CouponCode and CouponID, or OrderPromoID, is the coupon or order
+		// promo that gave a Free line away, all empty when a product promo
+		// did.
+		CouponCode   string `json:"coupon_code"`
+		CouponID     int64  `json:"coupon_id"`
+		OrderPromoID int64  `json:"order_promo_id"`
+	}
+
+	// AppliedPromo is one promo applied to a checkout line, in the order it
+	// was applied.
+	AppliedPromo struct {
+		PromoID   int64  `json:"promo_id"`
+		PromoType string `json:"promo_type"`
 	}
 
 	CheckoutUsecase interface {
-		Checkout(ctx context.Context, form []repo.OrderDetail) (res Checkout, err error)
+		Checkout(ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (res Checkout, err error)
+		CheckoutTx(tx *sqlx.Tx, ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (res Checkout, err error)
+		QuoteCart(ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (res CartQuote, err error)
+		ReserveCart(ctx context.Context, form []repo.OrderDetail) (res repo.Reservation, err error)
+		ConfirmReservation(ctx context.Context, reservationID int64) (res Checkout, err error)
+		ReleaseReservation(ctx context.Context, reservationID int64) (res repo.Reservation, err error)
+		ExpireReservations(ctx context.Context) (count int, err error)
 	}
 
 	CheckoutUsecaseImpl struct {
 		dig.In
-		OrderRepo   repo.OrderRepository
-		ProductRepo repo.ProductRepository
-		PromoRepo   repo.PromoRepository
+		OrderRepo         repo.OrderRepository
+		ProductRepo       repo.ProductRepository
+		PromoRepo         repo.PromoRepository
+		StockMovementRepo repo.StockMovementRepository
+		ReservationRepo   repo.ReservationRepository
+		IdempotencyRepo   repo.IdempotencyRepository
+		CouponRepo        repo.CouponRepository
+		OrderPromoRepo    repo.OrderPromoRepository
+		BundleRepo        repo.BundleRepository
+		Currency          money.Currency     `optional:"true"`
+		StockPolicy       RewardStockPolicy  `optional:"true"`
+		ReservationPolicy ReservationPolicy  `optional:"true"`
+		Limits            CheckoutLimits     `optional:"true"`
+		Promotions        *PromotionRegistry `optional:"true"`
+		Clock             clock.Clock        `optional:"true"`
 	}
+
+	// RewardStockPolicy decides what happens when a free reward product is out
+	// of stock.
+	RewardStockPolicy string
 )
 
+const (
+	// RewardStockFail fails the whole checkout, it's the default.
+	RewardStockFail RewardStockPolicy = "fail"
+	// RewardStockSkip completes the checkout without the gift and reports it
+	// in Checkout.Warnings.
+	RewardStockSkip RewardStockPolicy = "skip"
+)
+
+func ParseRewardStockPolicy(s string) (RewardStockPolicy, error) {
+	switch p := RewardStockPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "", RewardStockFail:
+		return RewardStockFail, nil
+	case RewardStockSkip:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown reward stock policy %q", s)
+	}
+}
+
 func NewCheckoutUsecase(impl CheckoutUsecaseImpl) CheckoutUsecase {
 	return &impl
 }
 
-func (c *CheckoutUsecaseImpl) Checkout(ctx context.Context, form []repo.OrderDetail) (res Checkout, err error) {
+// Checkout places an order for form, once its lines of the same product are
+// merged, redeeming couponCodes. When ctx carries an idempotency key, a retry
+// with the same key, items and coupons gets the first response back without
+// placing another order. The key is claimed in the checkout's transaction, so
+// a failed checkout leaves it free for the retry.
+func (c *CheckoutUsecaseImpl) Checkout(ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (res Checkout, err error) {
 	tx, err := c.OrderRepo.BeginTx()
 	if err != nil {
 		log.Printf("error while do BeginTx %+v", err)
@@ -42,119 +138,392 @@ func (c *CheckoutUsecaseImpl) Checkout(ctx context.Context, form []repo.OrderDet
 
 	defer c.OrderRepo.RollbackTx(tx)
 
-	orderID, err := c.createOrder(tx, ctx, &res)
+	res, replay, err := c.checkout(tx, ctx, form, couponCodes)
+	if err != nil || replay {
+		return res, err
+	}
+
+	err = c.OrderRepo.CommitTx(tx)
 	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
 		return res, err
 	}
 
-	for i, v := range form {
-		err := c.processOrderItem(ctx, tx, &form[i], v, orderID, &res)
+	return res, nil
+}
+
+// CheckoutTx is Checkout inside tx, leaving the commit to the caller so the
+// order is written together with whatever else the caller changes in tx.
+func (c *CheckoutUsecaseImpl) CheckoutTx(tx *sqlx.Tx, ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (res Checkout, err error) {
+	res, _, err = c.checkout(tx, ctx, form, couponCodes)
+	return res, err
+}
+
+// checkout claims the idempotency key of ctx and places the order in tx,
+// telling whether it replayed the response of an earlier checkout instead.
+func (c *CheckoutUsecaseImpl) checkout(tx *sqlx.Tx, ctx context.Context, form []repo.OrderDetail, couponCodes []string) (res Checkout, replay bool, err error) {
+	form, err = c.normalizeCheckout(form)
+	if err != nil {
+		return res, false, err
+	}
+
+	couponCodes = normalizeCouponCodes(couponCodes)
+
+	key := IdempotencyKeyFrom(ctx)
+	if key != "" {
+		res, replay, err = c.claimIdempotencyKey(tx, ctx, key, checkoutRequestHash(form, couponCodes), c.now())
+		if err != nil || replay {
+			return res, replay, err
+		}
+	}
+
+	res, err = c.placeOrder(ctx, tx, form, couponCodes)
+	if err != nil {
+		return res, false, err
+	}
+
+	if key != "" {
+		err = c.storeIdempotentResponse(tx, ctx, key, res)
 		if err != nil {
-			return res, err
+			return res, false, err
 		}
 	}
 
-	err = c.OrderRepo.CreateOrderDetails(tx, ctx, form)
+	return res, false, nil
+}
+
+// placeOrder prices form, takes its stock, redeems its coupons and writes the
+// order with its discounts inside tx, leaving the commit to the caller.
+func (c *CheckoutUsecaseImpl) placeOrder(ctx context.Context, tx *sqlx.Tx, form []repo.OrderDetail, couponCodes []string) (res Checkout, err error) {
+	res.Currency = c.currency().Code
+
+	// one instant for the whole checkout: it picks the running promos and
+	// dates the order
+	now := c.now()
+
+	stock := &lockedStock{
+		ProductRepo:    c.ProductRepo,
+		CouponRepo:     c.CouponRepo,
+		PromoRepo:      c.PromoRepo,
+		BundleRepo:     c.BundleRepo,
+		OrderPromoRepo: c.OrderPromoRepo,
+	}
+
+	err = c.priceCart(ctx, tx, stock, form, couponCodes, now, &res)
+	if err != nil {
+		return res, err
+	}
+
+	// the header is written once every line is priced so it carries the real
+	// totals, still inside the same transaction as the details
+	orderID, err := c.createOrder(tx, ctx, now, &res)
+	if err != nil {
+		return res, err
+	}
+
+	res.OrderID = orderID
+	for i := range form {
+		form[i].OrderID = orderID
+	}
+
+	// the details carry what was paid for them, so refunds never give back
+	// a discount the whole order got
+	c.spreadOrderDiscount(form, orderPromoDiscount(&res).Add(couponDiscount(&res)))
+
+	// free rewards are stored as zero priced details under the promo, order
+	// promo or coupon that gave them away
+	details := append([]repo.OrderDetail(nil), form...)
+	for _, line := range res.Lines {
+		if !line.Free {
+			continue
+		}
+
+		details = append(details, repo.OrderDetail{
+			OrderID:      orderID,
+			ProductID:    line.ProductID,
+			PromoID:      line.PromoID,
+			OrderPromoID: line.OrderPromoID,
+			CouponID:     line.CouponID,
+			Qty:          line.Qty,
+		})
+	}
+
+	err = c.OrderRepo.CreateOrderDetails(tx, ctx, details)
 	if err != nil {
 		log.Printf("error while do CreateOrderDetails %+v", err)
 		return res, err
 	}
 
-	c.OrderRepo.CommitTx(tx)
+	err = c.recordStockMovements(tx, ctx, orderID, now, &res)
+	if err != nil {
+		return res, err
+	}
+
+	err = c.recordOrderDiscounts(tx, ctx, orderID, &res)
+	if err != nil {
+		return res, err
+	}
+
+	err = c.recordCouponRedemptions(tx, ctx, orderID, now, &res)
+	if err != nil {
+		return res, err
+	}
 
 	return res, nil
 }
 
-func (c *CheckoutUsecaseImpl) createOrder(tx *sqlx.Tx, ctx context.Context, res *Checkout) (int64, error) {
-	orderID, err := c.OrderRepo.CreateOrder(tx, ctx, repo.Order{
-		Date:  time.Now(),
-		Total: res.TotalAmount,
-	})
+func (c *CheckoutUsecaseImpl) createOrder(tx *sqlx.Tx, ctx context.Context, now time.Time, res *Checkout) (int64, error) {
+	order := repo.Order{
+		Date:   now,
+		Total:  res.TotalAmount,
+		Status: OrderStatusPendingPayment,
+	}
+
+	for _, line := range res.Lines {
+		order.ItemCount += line.Qty
+		if line.Free {
+			continue
+		}
+
+		order.Subtotal = order.Subtotal.Add(line.Subtotal)
+	}
+
+	order.DiscountTotal = order.Subtotal.Sub(order.Total)
+
+	orderID, err := c.OrderRepo.CreateOrder(tx, ctx, order)
 	if err != nil {
 		log.Printf("error while do CreateOrder %+v", err)
 		return 0, err
 	}
+
+	order.OrderID = orderID
+	err = recordOrderStatus(tx, ctx, c.OrderRepo, order, "", "", now)
+	if err != nil {
+		return 0, err
+	}
+
 	return orderID, nil
 }
 
-func (c *CheckoutUsecaseImpl) processOrderItem(ctx context.Context, tx *sqlx.Tx, item *repo.OrderDetail, v repo.OrderDetail, orderID int64, res *Checkout) error {
-	promo, err := c.PromoRepo.GetPromoByProductID(ctx, v.ProductID)
+// priceCart fits the bundles in form, runs the promo pipeline over every line
+// of form, taking the stock the cart needs from stock, then the order promos
+// over the whole order, then applies couponCodes to the result. Checkout and
+// quotes both price through here so they always come to the same numbers.
+func (c *CheckoutUsecaseImpl) priceCart(ctx context.Context, tx *sqlx.Tx, stock cartStock, form []repo.OrderDetail, couponCodes []string, now time.Time, res *Checkout) error {
+	shares, err := c.allocateBundles(ctx, tx, stock, form, now, res)
 	if err != nil {
-		log.Printf("error while do GetPromoByProductID %+v", err)
 		return err
 	}
 
-	productDetail, err := c.ProductRepo.GetProductByProductID(ctx, v.ProductID)
+	for i, v := range form {
+		err = c.processOrderItem(ctx, tx, stock, &form[i], v, shares[v.ProductID], now, res)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.applyOrderPromos(ctx, tx, stock, now, res)
 	if err != nil {
-		log.Printf("error while do GetProductByProductID %+v", err)
 		return err
 	}
 
-	if productDetail.Qty < v.Qty {
-		return fmt.Errorf("the product %s qty is not enough to fulfill the request", productDetail.Name)
+	return c.applyCoupons(ctx, tx, stock, couponCodes, now, res)
+}
+
+// processOrderItem prices line v, whose share went to bundles. The promos
+// of the product only see the units no bundle took.
+func (c *CheckoutUsecaseImpl) processOrderItem(ctx context.Context, tx *sqlx.Tx, stock cartStock, item *repo.OrderDetail, v repo.OrderDetail, share bundleShare, now time.Time, res *Checkout) error {
+	promos, err := stock.promos(ctx, tx, v.ProductID, now)
+	if err != nil {
+		return err
 	}
 
-	err = c.calculatePriceAndRewards(ctx, item, v, &productDetail, &promo, orderID, res)
+	productDetail, err := stock.product(ctx, tx, v.ProductID)
 	if err != nil {
 		return err
 	}
-	err = c.updateProductQty(ctx, tx, v)
+
+	if productDetail.ProductID == 0 {
+		return productNotFoundError(v.ProductID)
+	}
+
+	if productDetail.ArchivedAt != nil {
+		return stock.unavailable(res, productUnavailableError(productDetail))
+	}
+
+	if productDetail.Available() < v.Qty {
+		err = stock.unavailable(res, insufficientStockError(productDetail))
+		if err != nil {
+			return err
+		}
+	}
+
+	rest := v
+	rest.Qty -= share.Qty
+
+	promos, err = c.selectPromos(ctx, tx, stock, rest, &productDetail, promos)
 	if err != nil {
 		return err
 	}
 
-	res.TotalAmount += item.Price
+	err = c.calculatePriceAndRewards(ctx, tx, stock, item, v, share, &productDetail, promos, res)
+	if err != nil {
+		return err
+	}
+	err = stock.take(ctx, tx, v.ProductID, v.Qty)
+	if errors.Is(err, repo.ErrInsufficientStock) {
+		return insufficientStockError(productDetail)
+	}
+	if err != nil {
+		return err
+	}
+
+	res.TotalAmount = res.TotalAmount.Add(item.Price)
 	return nil
 }
 
-func (c *CheckoutUsecaseImpl) calculatePriceAndRewards(ctx context.Context, item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, orderID int64, res *Checkout) error {
-	item.Price = float64(v.Qty) * productDetail.Price
-	item.OrderID = orderID
+// calculatePriceAndRewards prices the line and applies promos in order to the
+// units share leaves, the bundled units costing share's price. The line and
+// its order detail are attributed to the first price promo applied, or to the
+// gift when only a gift was given.
+func (c *CheckoutUsecaseImpl) calculatePriceAndRewards(ctx context.Context, tx *sqlx.Tx, stock cartStock, item *repo.OrderDetail, v repo.OrderDetail, share bundleShare, productDetail *repo.Product, promos []repo.Promo, res *Checkout) error {
+	rest := v
+	rest.Qty -= share.Qty
+
+	item.Price = productDetail.Price.MulInt(rest.Qty)
 	for i := 0; i < int(v.Qty); i++ {
 		res.Items = append(res.Items, productDetail.Name)
 	}
 
-	var promotion Promotion
+	lineIdx := len(res.Lines)
+	res.Lines = append(res.Lines, CheckoutLine{
+		ProductID:   v.ProductID,
+		ProductName: productDetail.Name,
+		Qty:         v.Qty,
+		UnitPrice:   productDetail.Price,
+		Subtotal:    productDetail.Price.MulInt(v.Qty),
+		BundledQty:  share.Qty,
+	})
 
-	if v.Qty >= promo.MinQty {
-		switch promo.PromoType {
-		case "product":
-			promotion = c.calculateProductPromo(item, v, productDetail, promo, res)
-		case "discount":
-			promotion = &DiscountPromo{}
-		}
+	var primary *repo.Promo
+	for i := range promos {
+		promo := &promos[i]
 
-		item.PromoID = promo.PromoID
-	}
+		promotion := c.promotionFor(rest, promo, stock)
+		if promotion == nil {
+			continue
+		}
 
-	if promotion != nil {
-		err := promotion.ApplyPromotion(item, v, productDetail, promo, res)
+		err := promotion.ApplyPromotion(ctx, tx, item, rest, productDetail, promo, res)
+		if errors.Is(err, errPromotionSkipped) {
+			continue
+		}
 		if err != nil {
 			return err
 		}
+
+		res.Lines[lineIdx].Promos = append(res.Lines[lineIdx].Promos, AppliedPromo{
+			PromoID:   promo.PromoID,
+			PromoType: promo.PromoType,
+		})
+
+		if primary == nil || (c.promotions().isGift(*primary, v.ProductID) && !c.promotions().isGift(*promo, v.ProductID)) {
+			primary = promo
+		}
+	}
+
+	item.Price = item.Price.Add(share.Price)
+
+	if primary != nil {
+		item.PromoID = primary.PromoID
+		res.Lines[lineIdx].PromoID = primary.PromoID
+		res.Lines[lineIdx].PromoType = primary.PromoType
 	}
 
+	res.Lines[lineIdx].Total = item.Price
+	res.Lines[lineIdx].Discount = res.Lines[lineIdx].Subtotal.Sub(item.Price)
+
 	return nil
 }
 
-func (c *CheckoutUsecaseImpl) calculateProductPromo(item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) Promotion {
-	if v.ProductID == int64(promo.Reward) {
-		return &ProductPromoDiscount{}
-	}
+// promotionFor returns what applies promo to line v, nil when no registered
+// kind has the promo's type.
+func (c *CheckoutUsecaseImpl) promotionFor(v repo.OrderDetail, promo *repo.Promo, stock cartStock) Promotion {
+	return c.promotions().promotion(*promo, v.ProductID, PromotionEnv{
+		Stock:       stock,
+		StockPolicy: c.StockPolicy,
+		Currency:    c.currency(),
+	})
+}
 
-	return &ProductPromoFree{
-		ProductRepo: c.ProductRepo,
+func (c *CheckoutUsecaseImpl) promotions() *PromotionRegistry {
+	if c.Promotions == nil {
+		return builtinPromotions
 	}
+
+	return c.Promotions
 }
 
-func (c *CheckoutUsecaseImpl) updateProductQty(ctx context.Context, tx *sqlx.Tx, v repo.OrderDetail) error {
-	err := c.ProductRepo.UpdateProductQtyByProductID(tx, ctx, repo.Product{
-		ProductID: v.ProductID,
-		Qty:       v.Qty,
-	})
+// recordStockMovements writes one ledger entry per line, every line having
+// taken its qty off the stock while the checkout was priced.
+func (c *CheckoutUsecaseImpl) recordStockMovements(tx *sqlx.Tx, ctx context.Context, orderID int64, now time.Time, res *Checkout) error {
+	movements := make([]repo.StockMovement, len(res.Lines))
+	for i, line := range res.Lines {
+		reason := StockReasonSale
+		if line.Free {
+			reason = StockReasonReward
+		}
+
+		movements[i] = repo.StockMovement{
+			ProductID: line.ProductID,
+			Delta:     -line.Qty,
+			Reason:    reason,
+			OrderID:   orderID,
+			Actor:     ActorFrom(ctx),
+			CreatedAt: now,
+		}
+	}
+
+	err := c.StockMovementRepo.CreateStockMovements(tx, ctx, movements)
 	if err != nil {
-		log.Printf("error while do UpdateProductQtyByProductID %+v", err)
+		log.Printf("error while do CreateStockMovements %+v", err)
 		return err
 	}
+
 	return nil
 }
+
+// insufficientStockError reports that the stock of product can't cover what
+// was asked for.
+func insufficientStockError(product repo.Product) error {
+	return apperr.New(apperr.CodeOutOfStock, fmt.Sprintf("the product %s qty is not enough to fulfill the request", product.Name)).
+		With("product_id", product.ProductID).
+		With("available_qty", product.Available())
+}
+
+func productUnavailableError(product repo.Product) error {
+	return apperr.New(apperr.CodeProductUnavailable, fmt.Sprintf("the product %s is no longer available", product.Name)).
+		With("product_id", product.ProductID)
+}
+
+func productNotFoundError(productID int64) error {
+	return apperr.New(apperr.CodeProductNotFound, fmt.Sprintf("product %d doesn't exist", productID)).
+		With("product_id", productID)
+}
+
+func (c *CheckoutUsecaseImpl) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Clock.Now()
+}
+
+// currency falls back to money.DefaultCurrency when none is configured.
+func (c *CheckoutUsecaseImpl) currency() money.Currency {
+	if c.Currency.Code == "" {
+		return money.DefaultCurrency
+	}
+
+	return c.Currency
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+const (
+	// maxBundleCandidates bounds how many bundles are considered for one
+	// cart, the allocation tries every way of fitting them together.
+	maxBundleCandidates = 10
+
+	// maxBundleSteps bounds the counts the allocation tries. Past it the
+	// bundles left are fitted greedily, so large quantities can't make the
+	// search run away.
+	maxBundleSteps = 100000
+)
+
+// AppliedBundle is a bundle applied to a checkout. Times is how many times
+// it fit in the cart and Discount what it took off its items' prices in all.
+type AppliedBundle struct {
+	BundleID   int64         `json:"bundle_id"`
+	Name       string        `json:"name"`
+	BundleType string        `json:"bundle_type"`
+	Times      int64         `json:"times"`
+	Discount   money.Decimal `json:"discount"`
+}
+
+// bundleShare is what the applied bundles took of one product: Qty units,
+// costing Price together.
+type bundleShare struct {
+	Qty   int64
+	Price money.Decimal
+}
+
+// pricedBundle is a bundle with what one application of it costs.
+type pricedBundle struct {
+	bundle repo.Bundle
+	// shares is the part of the bundle's price each item pays, in the order
+	// of bundle.Items.
+	shares []money.Decimal
+	saving money.Decimal
+}
+
+// bundlePick is how many times each candidate bundle is applied.
+type bundlePick struct {
+	times  []int64
+	saving money.Decimal
+	count  int64
+}
+
+// better orders two allocations: the bigger saving for the customer wins,
+// then fewer bundles, which leaves more units to the product promos.
+func (p bundlePick) better(o bundlePick) bool {
+	if c := p.saving.Cmp(o.saving); c != 0 {
+		return c > 0
+	}
+
+	return p.count < o.count
+}
+
+// allocateBundles fits the running bundles in form, choosing the allocation
+// that saves the customer the most when bundles compete for the same units.
+// The bundles applied are listed in res and what they took of each product is
+// returned, the units left over are priced by their product promos.
+func (c *CheckoutUsecaseImpl) allocateBundles(ctx context.Context, tx *sqlx.Tx, stock cartStock, form []repo.OrderDetail, now time.Time, res *Checkout) (map[int64]bundleShare, error) {
+	bundles, err := stock.bundles(ctx, tx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	qty := make(map[int64]int64, len(form))
+	for _, v := range form {
+		qty[v.ProductID] += v.Qty
+	}
+
+	prices := make(map[int64]money.Decimal)
+	candidates := make([]pricedBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		if len(candidates) == maxBundleCandidates {
+			break
+		}
+
+		if bundleFits(bundle, qty) == 0 {
+			continue
+		}
+
+		priced, ok, err := c.priceBundle(ctx, tx, stock, bundle, prices)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			candidates = append(candidates, priced)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	search := bundleSearch{candidates: candidates, qty: qty, memo: make(map[string]bundlePick)}
+	best := search.best(0)
+
+	shares := make(map[int64]bundleShare)
+	for i, times := range best.times {
+		if times == 0 {
+			continue
+		}
+
+		priced := candidates[i]
+		for j, item := range priced.bundle.Items {
+			share := shares[item.ProductID]
+			share.Qty += item.Qty * times
+			share.Price = share.Price.Add(priced.shares[j].MulInt(times))
+			shares[item.ProductID] = share
+		}
+
+		res.Bundles = append(res.Bundles, AppliedBundle{
+			BundleID:   priced.bundle.BundleID,
+			Name:       priced.bundle.Name,
+			BundleType: priced.bundle.BundleType,
+			Times:      times,
+			Discount:   priced.saving.MulInt(times),
+		})
+	}
+
+	return shares, nil
+}
+
+// priceBundle works out what one application of bundle costs and splits it
+// over the items in proportion to their prices, the last item taking the
+// rounding. A bundle that saves nothing, or holds a product that is gone, is
+// not ok.
+func (c *CheckoutUsecaseImpl) priceBundle(ctx context.Context, tx *sqlx.Tx, stock cartStock, bundle repo.Bundle, prices map[int64]money.Decimal) (pricedBundle, bool, error) {
+	priced := pricedBundle{bundle: bundle, shares: make([]money.Decimal, len(bundle.Items))}
+
+	list := money.Decimal{}
+	values := make([]money.Decimal, len(bundle.Items))
+	for i, item := range bundle.Items {
+		price, ok := prices[item.ProductID]
+		if !ok {
+			product, err := stock.price(ctx, tx, item.ProductID)
+			if err != nil {
+				return priced, false, err
+			}
+
+			if product.ProductID == 0 || product.ArchivedAt != nil {
+				return priced, false, nil
+			}
+
+			price = product.Price
+			prices[item.ProductID] = price
+		}
+
+		values[i] = price.MulInt(item.Qty)
+		list = list.Add(values[i])
+	}
+
+	kind, ok := c.promotions().BundleKind(bundle.BundleType)
+	if !ok {
+		// bundle writes are validated, so this is a kind the shop dropped
+		return priced, false, nil
+	}
+
+	total := kind.BundlePrice(bundle, list, c.currency())
+	if total.Cmp(list) >= 0 {
+		return priced, false, nil
+	}
+
+	rest := total
+	for i := range values {
+		if i == len(values)-1 {
+			priced.shares[i] = rest
+			break
+		}
+
+		priced.shares[i] = c.currency().Round(total.Mul(values[i]).Div(list))
+		rest = rest.Sub(priced.shares[i])
+	}
+
+	priced.saving = list.Sub(total)
+
+	return priced, true, nil
+}
+
+type priceBundleKind struct{}
+
+// NewPriceBundle sells the bundle's items for the reward amount.
+func NewPriceBundle() BundleKind {
+	return priceBundleKind{}
+}
+
+func (priceBundleKind) Name() string {
+	return BundleTypePrice
+}
+
+func (priceBundleKind) Validate(ctx context.Context, bundle repo.Bundle, check PromoCheck) error {
+	if bundle.Reward.IsNegative() || bundle.Reward.IsZero() {
+		return fmt.Errorf("%w: price reward must be above 0", ErrInvalidPromo)
+	}
+
+	return nil
+}
+
+func (priceBundleKind) BundlePrice(bundle repo.Bundle, list money.Decimal, currency money.Currency) money.Decimal {
+	return currency.Round(bundle.Reward)
+}
+
+type discountBundleKind struct{}
+
+// NewDiscountBundle takes a percent off the bundle's items.
+func NewDiscountBundle() BundleKind {
+	return discountBundleKind{}
+}
+
+func (discountBundleKind) Name() string {
+	return BundleTypeDiscount
+}
+
+func (discountBundleKind) Validate(ctx context.Context, bundle repo.Bundle, check PromoCheck) error {
+	if bundle.Reward.IsNegative() || bundle.Reward.IsZero() || bundle.Reward.Cmp(money.NewFromInt(100)) > 0 {
+		return fmt.Errorf("%w: discount reward must be above 0 and at most 100", ErrInvalidPromo)
+	}
+
+	return nil
+}
+
+func (discountBundleKind) BundlePrice(bundle repo.Bundle, list money.Decimal, currency money.Currency) money.Decimal {
+	return list.Sub(currency.Round(list.Mul(bundle.Reward).Div(money.NewFromInt(100))))
+}
+
+// bundleFits returns how many times bundle fits in qty.
+func bundleFits(bundle repo.Bundle, qty map[int64]int64) int64 {
+	if len(bundle.Items) == 0 {
+		return 0
+	}
+
+	fits := int64(-1)
+	for _, item := range bundle.Items {
+		n := qty[item.ProductID] / item.Qty
+		if fits < 0 || n < fits {
+			fits = n
+		}
+	}
+
+	return fits
+}
+
+// bundleSearch finds the best allocation of candidates to the units in qty.
+// Every count of each bundle is tried, remembering the best allocation of the
+// later bundles for the units left, so overlapping bundles don't blow up.
+// Once maxBundleSteps counts were tried the rest is fitted greedily.
+type bundleSearch struct {
+	candidates []pricedBundle
+	qty        map[int64]int64
+	memo       map[string]bundlePick
+	steps      int
+}
+
+// best returns the best allocation of the candidates from i on. Between
+// equally good allocations the one applying earlier bundles more wins.
+func (s *bundleSearch) best(i int) bundlePick {
+	if i == len(s.candidates) {
+		return bundlePick{}
+	}
+
+	key := s.key(i)
+	if pick, ok := s.memo[key]; ok {
+		return pick
+	}
+
+	if s.steps >= maxBundleSteps {
+		return s.greedy(i)
+	}
+
+	priced := s.candidates[i]
+
+	var best bundlePick
+	for times := bundleFits(priced.bundle, s.qty); times >= 0; times-- {
+		s.steps++
+		s.take(priced.bundle, times)
+		rest := s.best(i + 1)
+		s.take(priced.bundle, -times)
+
+		pick := bundlePick{
+			times:  append([]int64{times}, rest.times...),
+			saving: rest.saving.Add(priced.saving.MulInt(times)),
+			count:  rest.count + times,
+		}
+
+		if best.times == nil || pick.better(best) {
+			best = pick
+		}
+
+		// the counts tried first are the biggest, the greedy choice
+		if s.steps >= maxBundleSteps {
+			break
+		}
+	}
+
+	s.memo[key] = best
+	return best
+}
+
+// greedy applies each candidate from i on as many times as it still fits, in
+// order.
+func (s *bundleSearch) greedy(i int) bundlePick {
+	var pick bundlePick
+	for _, priced := range s.candidates[i:] {
+		times := bundleFits(priced.bundle, s.qty)
+		s.take(priced.bundle, times)
+
+		pick.times = append(pick.times, times)
+		pick.saving = pick.saving.Add(priced.saving.MulInt(times))
+		pick.count += times
+	}
+
+	for j, priced := range s.candidates[i:] {
+		s.take(priced.bundle, -pick.times[j])
+	}
+
+	return pick
+}
+
+func (s *bundleSearch) take(bundle repo.Bundle, times int64) {
+	for _, item := range bundle.Items {
+		s.qty[item.ProductID] -= item.Qty * times
+	}
+}
+
+// key identifies the units the candidates from i on can still use.
+func (s *bundleSearch) key(i int) string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(i))
+	for _, priced := range s.candidates[i:] {
+		for _, item := range priced.bundle.Items {
+			b.WriteByte(' ')
+			b.WriteString(strconv.FormatInt(s.qty[item.ProductID], 10))
+		}
+	}
+
+	return b.String()
+}
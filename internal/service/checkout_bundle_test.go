@@ -0,0 +1,211 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckoutBundles(t *testing.T) {
+	// 15% off a Google Home with an Alexa Speaker saves 23.92, a Google Home
+	// with a Raspberry Pi for 60 saves 19.99 and 20% off two Google Homes with
+	// an Alexa Speaker saves 41.90
+	smartHome := repo.Bundle{BundleID: 1, Name: "Smart home", BundleType: "discount", Reward: money.MustParse("15"), Items: []repo.BundleItem{{BundleID: 1, ProductID: 1, Qty: 1}, {BundleID: 1, ProductID: 3, Qty: 1}}}
+	piStarter := repo.Bundle{BundleID: 2, Name: "Pi starter", BundleType: "price", Reward: money.MustParse("60"), Items: []repo.BundleItem{{BundleID: 2, ProductID: 1, Qty: 1}, {BundleID: 2, ProductID: 4, Qty: 1}}}
+	homeTrio := repo.Bundle{BundleID: 3, Name: "Home trio", BundleType: "discount", Reward: money.MustParse("20"), Items: []repo.BundleItem{{BundleID: 3, ProductID: 1, Qty: 2}, {BundleID: 3, ProductID: 3, Qty: 1}}}
+	pricey := repo.Bundle{BundleID: 4, Name: "Pricey", BundleType: "price", Reward: money.MustParse("200"), Items: []repo.BundleItem{{BundleID: 4, ProductID: 1, Qty: 1}, {BundleID: 4, ProductID: 4, Qty: 1}}}
+
+	tests := []struct {
+		name            string
+		cart            []repo.OrderDetail
+		bundles         []repo.Bundle
+		expectedTotal   money.Decimal
+		expectedBundles []service.AppliedBundle
+		// lineTotals and bundledQty are by product id
+		lineTotals map[int64]string
+		bundledQty map[int64]int64
+	}{
+		{
+			name:          "a bundle prices its items together",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 1}},
+			bundles:       []repo.Bundle{smartHome},
+			expectedTotal: money.MustParse("135.57"),
+			expectedBundles: []service.AppliedBundle{
+				{BundleID: 1, Name: "Smart home", BundleType: "discount", Times: 1, Discount: money.MustParse("23.92")},
+			},
+			lineTotals: map[int64]string{1: "42.49", 3: "93.08"},
+			bundledQty: map[int64]int64{1: 1, 3: 1},
+		},
+		{
+			name:          "a bundle fits as many times as the cart allows",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 2}, {ProductID: 3, Qty: 3}},
+			bundles:       []repo.Bundle{smartHome},
+			expectedTotal: money.MustParse("380.64"),
+			expectedBundles: []service.AppliedBundle{
+				{BundleID: 1, Name: "Smart home", BundleType: "discount", Times: 2, Discount: money.MustParse("47.84")},
+			},
+			lineTotals: map[int64]string{1: "84.98", 3: "295.66"},
+			bundledQty: map[int64]int64{1: 2, 3: 2},
+		},
+		{
+			name:          "units left over keep their promos",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 4}, {ProductID: 3, Qty: 1}},
+			bundles:       []repo.Bundle{smartHome},
+			expectedTotal: money.MustParse("235.55"),
+			expectedBundles: []service.AppliedBundle{
+				{BundleID: 1, Name: "Smart home", BundleType: "discount", Times: 1, Discount: money.MustParse("23.92")},
+			},
+			lineTotals: map[int64]string{1: "142.47", 3: "93.08"},
+			bundledQty: map[int64]int64{1: 1, 3: 1},
+		},
+		{
+			name:          "overlapping bundles take the best allocation",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 1}, {ProductID: 4, Qty: 1}},
+			bundles:       []repo.Bundle{piStarter, smartHome},
+			expectedTotal: money.MustParse("165.57"),
+			expectedBundles: []service.AppliedBundle{
+				{BundleID: 1, Name: "Smart home", BundleType: "discount", Times: 1, Discount: money.MustParse("23.92")},
+			},
+			lineTotals: map[int64]string{1: "42.49", 3: "93.08", 4: "30"},
+			bundledQty: map[int64]int64{1: 1, 3: 1},
+		},
+		{
+			name:          "two smaller bundles beat the biggest one",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 2}, {ProductID: 3, Qty: 1}, {ProductID: 4, Qty: 1}},
+			bundles:       []repo.Bundle{smartHome, piStarter, homeTrio},
+			expectedTotal: money.MustParse("195.57"),
+			expectedBundles: []service.AppliedBundle{
+				{BundleID: 1, Name: "Smart home", BundleType: "discount", Times: 1, Discount: money.MustParse("23.92")},
+				{BundleID: 2, Name: "Pi starter", BundleType: "price", Times: 1, Discount: money.MustParse("19.99")},
+			},
+			lineTotals: map[int64]string{1: "79.99", 3: "93.08", 4: "22.50"},
+			bundledQty: map[int64]int64{1: 2, 3: 1, 4: 1},
+		},
+		{
+			name:          "a bundle that doesn't fit",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 3}},
+			bundles:       []repo.Bundle{smartHome},
+			expectedTotal: money.MustParse("99.98"),
+			lineTotals:    map[int64]string{1: "99.98"},
+		},
+		{
+			name:          "a bundle dearer than its items is ignored",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 1}, {ProductID: 4, Qty: 1}},
+			bundles:       []repo.Bundle{pricey},
+			expectedTotal: money.MustParse("79.99"),
+			lineTotals:    map[int64]string{1: "49.99", 4: "30"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
+			bundleRepo := new(mockRepo.BundleRepository)
+
+			quoteCatalog(productRepo, promoRepo, googleHome, macBookPro, alexaSpeaker, raspberryPi)
+			productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+			orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+			orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(13), nil)
+			orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			bundleRepo.On("GetBundles", mock.Anything, checkoutAt).Return(tt.bundles, nil)
+			bundleRepo.On("GetBundlesTx", mock.Anything, mock.Anything, checkoutAt).Return(tt.bundles, nil)
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderRepo:         orderRepo,
+				ProductRepo:       productRepo,
+				PromoRepo:         promoRepo,
+				StockMovementRepo: stockMovementRepo,
+				OrderPromoRepo:    noOrderPromos(),
+				BundleRepo:        bundleRepo,
+				Clock:             clock.Fixed(checkoutAt),
+			})
+
+			quote, err := checkoutUsecase.QuoteCart(context.Background(), tt.cart)
+			assert.NoError(t, err)
+
+			res, err := checkoutUsecase.Checkout(context.Background(), tt.cart)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedTotal.String(), res.TotalAmount.String())
+			assert.Equal(t, tt.expectedBundles, res.Bundles)
+
+			for _, line := range res.Lines {
+				assert.Equal(t, money.MustParse(tt.lineTotals[line.ProductID]).String(), line.Total.String(), "total of product %d", line.ProductID)
+				assert.Equal(t, tt.bundledQty[line.ProductID], line.BundledQty, "bundled qty of product %d", line.ProductID)
+				assert.Equal(t, line.Subtotal.Sub(line.Total).String(), line.Discount.String())
+			}
+
+			assert.True(t, quote.Checkoutable)
+			assert.Equal(t, res.TotalAmount, quote.TotalAmount)
+			assert.Equal(t, res.Bundles, quote.Bundles)
+			assert.Equal(t, res.Lines, quote.Lines)
+
+			// the details carry the bundle prices, so refunds give back what
+			// was paid
+			orderRepo.AssertCalled(t, "CreateOrderDetails", mock.Anything, mock.Anything, mock.MatchedBy(func(details []repo.OrderDetail) bool {
+				for _, detail := range details {
+					if detail.Price.Cmp(money.MustParse(tt.lineTotals[detail.ProductID])) != 0 {
+						return false
+					}
+				}
+
+				return true
+			}))
+		})
+	}
+}
+
+func TestCheckoutBundlesLargeQty(t *testing.T) {
+	// two bundles competing for a million Google Homes would try about 10^12
+	// allocations without a bound
+	smartHome := repo.Bundle{BundleID: 1, Name: "Smart home", BundleType: "discount", Reward: money.MustParse("15"), Items: []repo.BundleItem{{BundleID: 1, ProductID: 1, Qty: 1}, {BundleID: 1, ProductID: 3, Qty: 1}}}
+	piStarter := repo.Bundle{BundleID: 2, Name: "Pi starter", BundleType: "price", Reward: money.MustParse("60"), Items: []repo.BundleItem{{BundleID: 2, ProductID: 1, Qty: 1}, {BundleID: 2, ProductID: 4, Qty: 1}}}
+
+	productRepo := new(mockRepo.ProductRepository)
+	promoRepo := new(mockRepo.PromoRepository)
+	bundleRepo := new(mockRepo.BundleRepository)
+	quoteCatalog(productRepo, promoRepo, googleHome, macBookPro, alexaSpeaker, raspberryPi)
+	bundleRepo.On("GetBundles", mock.Anything, checkoutAt).Return([]repo.Bundle{smartHome, piStarter}, nil)
+
+	checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+		ProductRepo:    productRepo,
+		PromoRepo:      promoRepo,
+		OrderPromoRepo: noOrderPromos(),
+		BundleRepo:     bundleRepo,
+		Limits:         service.CheckoutLimits{MaxQty: 1000000},
+		Clock:          clock.Fixed(checkoutAt),
+	})
+
+	done := make(chan service.CartQuote)
+	go func() {
+		quote, err := checkoutUsecase.QuoteCart(context.Background(), []repo.OrderDetail{{ProductID: 1, Qty: 1000000}, {ProductID: 3, Qty: 600000}, {ProductID: 4, Qty: 600000}})
+		assert.NoError(t, err)
+		done <- quote
+	}()
+
+	select {
+	case quote := <-done:
+		// every Google Home goes to a bundle, the Smart home saving the most
+		assert.Equal(t, []service.AppliedBundle{
+			{BundleID: 1, Name: "Smart home", BundleType: "discount", Times: 600000, Discount: money.MustParse("23.92").MulInt(600000)},
+			{BundleID: 2, Name: "Pi starter", BundleType: "price", Times: 400000, Discount: money.MustParse("19.99").MulInt(400000)},
+		}, quote.Bundles)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the bundle allocation didn't finish")
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+// AppliedCoupon is a coupon redeemed by a checkout. Discount is what it took
+// off the order; a product coupon adds a free line instead.
+type AppliedCoupon struct {
+	CouponID   int64         `json:"coupon_id"`
+	Code       string        `json:"code"`
+	CouponType string        `json:"coupon_type"`
+	Discount   money.Decimal `json:"discount"`
+}
+
+// normalizeCouponCodes upper cases the codes and drops blank and repeated
+// ones, keeping the order they were sent in.
+func normalizeCouponCodes(codes []string) []string {
+	res := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		code = NormalizeCouponCode(code)
+		if code == "" || seen[code] {
+			continue
+		}
+
+		seen[code] = true
+		res = append(res, code)
+	}
+
+	return res
+}
+
+func couponError(code apperr.Code, couponCode, message string) *apperr.Error {
+	return apperr.New(code, fmt.Sprintf("coupon %s %s", couponCode, message)).With("coupon_code", couponCode)
+}
+
+// applyCoupons redeems codes against the order priced in res, in the order
+// they were sent. The minimum basket is checked against what the order came
+// to before any coupon, and no discount takes the total below zero. A coupon
+// that can't be redeemed fails the checkout through stock.unavailable.
+func (c *CheckoutUsecaseImpl) applyCoupons(ctx context.Context, tx *sqlx.Tx, stock cartStock, codes []string, now time.Time, res *Checkout) error {
+	basket := res.TotalAmount
+
+	for _, code := range codes {
+		coupon, err := stock.coupon(ctx, tx, code)
+		if err != nil {
+			return err
+		}
+
+		var uses int64
+		if coupon.CouponID != 0 && coupon.UsageLimit > 0 {
+			uses, err = stock.couponUses(ctx, tx, coupon.CouponID)
+			if err != nil {
+				return err
+			}
+		}
+
+		rejected := couponRejection(code, coupon, uses, basket, now)
+		if rejected != nil {
+			err = stock.unavailable(res, rejected)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err = c.redeemCoupon(ctx, tx, stock, coupon, res)
+		if errors.Is(err, errPromotionSkipped) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// couponRejection says why coupon, looked up by code, can't be redeemed on an
+// order of basket, nil when it can.
+func couponRejection(code string, coupon repo.Coupon, uses int64, basket money.Decimal, now time.Time) error {
+	switch {
+	case coupon.CouponID == 0:
+		return couponError(CouponErrNotFound, code, "doesn't exist")
+	case !coupon.Active:
+		return couponError(CouponErrInactive, code, "is no longer active")
+	case coupon.ExpiresAt != nil && !now.Before(*coupon.ExpiresAt):
+		return couponError(CouponErrExpired, code, "has expired")
+	case coupon.UsageLimit > 0 && uses >= coupon.UsageLimit:
+		return couponError(CouponErrExhausted, code, "has been fully redeemed")
+	case basket.Cmp(coupon.MinBasket) < 0:
+		return couponError(CouponErrMinBasket, code, fmt.Sprintf("needs an order of at least %s", coupon.MinBasket)).
+			With("min_basket", coupon.MinBasket.String())
+	}
+
+	return nil
+}
+
+// redeemCoupon applies coupon to the order priced in res.
+func (c *CheckoutUsecaseImpl) redeemCoupon(ctx context.Context, tx *sqlx.Tx, stock cartStock, coupon repo.Coupon, res *Checkout) error {
+	applied := AppliedCoupon{
+		CouponID:   coupon.CouponID,
+		Code:       coupon.Code,
+		CouponType: coupon.CouponType,
+	}
+
+	switch coupon.CouponType {
+	case CouponTypePercent:
+		applied.Discount = c.currency().Round(res.TotalAmount.Mul(coupon.Reward).Div(money.NewFromInt(100)))
+	case CouponTypeFixed:
+		applied.Discount = coupon.Reward
+		if applied.Discount.Cmp(res.TotalAmount) > 0 {
+			applied.Discount = res.TotalAmount
+		}
+	case CouponTypeProduct:
+		gift := &ProductPromoFree{Stock: stock, StockPolicy: c.StockPolicy}
+		err := gift.giveLine(ctx, tx, coupon.Reward.IntPart(), CheckoutLine{CouponCode: coupon.Code, CouponID: coupon.CouponID}, res)
+		if err != nil {
+			return err
+		}
+	default:
+		// coupon writes are validated, so this is a type the shop dropped
+		err := stock.unavailable(res, couponError(CouponErrInactive, coupon.Code, "is no longer active"))
+		if err != nil {
+			return err
+		}
+
+		return errPromotionSkipped
+	}
+
+	res.TotalAmount = res.TotalAmount.Sub(applied.Discount)
+	res.Coupons = append(res.Coupons, applied)
+
+	return nil
+}
+
+// couponDiscount is what the coupons of res took off the order.
+func couponDiscount(res *Checkout) money.Decimal {
+	var discount money.Decimal
+	for _, coupon := range res.Coupons {
+		discount = discount.Add(coupon.Discount)
+	}
+
+	return discount
+}
+
+// spreadOrderDiscount takes discount off the prices of form in proportion to
+// them, rounded to the currency. Each line gets its share of what is left, so
+// the shares add up to discount exactly and the last line takes the rounding.
+func (c *CheckoutUsecaseImpl) spreadOrderDiscount(form []repo.OrderDetail, discount money.Decimal) {
+	var weight money.Decimal
+	for _, v := range form {
+		weight = weight.Add(v.Price)
+	}
+
+	for i := range form {
+		if discount.IsZero() || weight.IsZero() || form[i].Price.IsZero() || form[i].Price.IsNegative() {
+			continue
+		}
+
+		share := c.currency().Round(discount.Mul(form[i].Price).Div(weight))
+		if share.Cmp(form[i].Price) > 0 {
+			share = form[i].Price
+		}
+
+		weight = weight.Sub(form[i].Price)
+		discount = discount.Sub(share)
+		form[i].Price = form[i].Price.Sub(share)
+	}
+}
+
+// recordCouponRedemptions writes one redemption per coupon of res, in the
+// order's transaction.
+func (c *CheckoutUsecaseImpl) recordCouponRedemptions(tx *sqlx.Tx, ctx context.Context, orderID int64, now time.Time, res *Checkout) error {
+	if len(res.Coupons) == 0 {
+		return nil
+	}
+
+	redemptions := make([]repo.CouponRedemption, len(res.Coupons))
+	for i, coupon := range res.Coupons {
+		redemptions[i] = repo.CouponRedemption{
+			CouponID:  coupon.CouponID,
+			OrderID:   orderID,
+			Discount:  coupon.Discount,
+			CreatedAt: now,
+		}
+	}
+
+	err := c.CouponRepo.CreateCouponRedemptions(tx, ctx, redemptions)
+	if err != nil {
+		log.Printf("error while do CreateCouponRedemptions %+v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckoutCoupons(t *testing.T) {
+	expired := checkoutAt.Add(-time.Hour)
+	coupons := map[string]repo.Coupon{
+		"SAVE10":  {CouponID: 1, Code: "SAVE10", CouponType: "percent", Reward: money.MustParse("10"), Active: true},
+		"FIVEOFF": {CouponID: 2, Code: "FIVEOFF", CouponType: "fixed", Reward: money.MustParse("5"), Active: true},
+		"BIGOFF":  {CouponID: 3, Code: "BIGOFF", CouponType: "fixed", Reward: money.MustParse("1000"), Active: true},
+		"FREEPI":  {CouponID: 4, Code: "FREEPI", CouponType: "product", Reward: money.MustParse("4"), Active: true},
+		"OVER500": {CouponID: 5, Code: "OVER500", CouponType: "fixed", Reward: money.MustParse("50"), MinBasket: money.MustParse("500"), Active: true},
+		"OLD":     {CouponID: 6, Code: "OLD", CouponType: "fixed", Reward: money.MustParse("5"), Active: true, ExpiresAt: &expired},
+		"GONE":    {CouponID: 7, Code: "GONE", CouponType: "fixed", Reward: money.MustParse("5"), Active: true, UsageLimit: 10},
+		"OFF":     {CouponID: 9, Code: "OFF", CouponType: "fixed", Reward: money.MustParse("5")},
+	}
+
+	tests := []struct {
+		name            string
+		cart            []repo.OrderDetail
+		coupons         []string
+		expectedTotal   money.Decimal
+		expectedCoupons []service.AppliedCoupon
+		expectedLines   int
+		// giftCouponID is the coupon the free line's detail is stored under
+		giftCouponID int64
+		code         apperr.Code
+	}{
+		{
+			name:          "percent off the promo price",
+			cart:          []repo.OrderDetail{{ProductID: 3, Qty: 3}},
+			coupons:       []string{" save10 "},
+			expectedTotal: money.MustParse("266.08"),
+			expectedCoupons: []service.AppliedCoupon{
+				{CouponID: 1, Code: "SAVE10", CouponType: "percent", Discount: money.MustParse("29.57")},
+			},
+			expectedLines: 1,
+		},
+		{
+			name:          "coupons apply in order and a code counts once",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			coupons:       []string{"FIVEOFF", "SAVE10", "fiveoff"},
+			expectedTotal: money.MustParse("40.49"),
+			expectedCoupons: []service.AppliedCoupon{
+				{CouponID: 2, Code: "FIVEOFF", CouponType: "fixed", Discount: money.MustParse("5")},
+				{CouponID: 1, Code: "SAVE10", CouponType: "percent", Discount: money.MustParse("4.50")},
+			},
+			expectedLines: 1,
+		},
+		{
+			name:          "a fixed amount stops at zero",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			coupons:       []string{"BIGOFF"},
+			expectedTotal: money.MustParse("0"),
+			expectedCoupons: []service.AppliedCoupon{
+				{CouponID: 3, Code: "BIGOFF", CouponType: "fixed", Discount: money.MustParse("49.99")},
+			},
+			expectedLines: 1,
+		},
+		{
+			name:          "a free product is added as a free line",
+			cart:          []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			coupons:       []string{"FREEPI"},
+			expectedTotal: money.MustParse("49.99"),
+			expectedCoupons: []service.AppliedCoupon{
+				{CouponID: 4, Code: "FREEPI", CouponType: "product"},
+			},
+			expectedLines: 2,
+			giftCouponID:  4,
+		},
+		{
+			name:    "unknown code",
+			cart:    []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			coupons: []string{"NOPE"},
+			code:    service.CouponErrNotFound,
+		},
+		{
+			name:    "deactivated coupon",
+			cart:    []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			coupons: []string{"OFF"},
+			code:    service.CouponErrInactive,
+		},
+		{
+			name:    "basket below the minimum after promos",
+			cart:    []repo.OrderDetail{{ProductID: 3, Qty: 5}},
+			coupons: []string{"OVER500"},
+			code:    service.CouponErrMinBasket,
+		},
+		{
+			name:    "expired coupon",
+			cart:    []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			coupons: []string{"OLD"},
+			code:    service.CouponErrExpired,
+		},
+		{
+			name:    "usage limit reached",
+			cart:    []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			coupons: []string{"GONE"},
+			code:    service.CouponErrExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
+			couponRepo := new(mockRepo.CouponRepository)
+
+			quoteCatalog(productRepo, promoRepo, googleHome, macBookPro, alexaSpeaker, raspberryPi)
+			productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+			orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+			orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(11), nil)
+			orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			couponRepo.On("CreateCouponRedemptions", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			for code, coupon := range coupons {
+				couponRepo.On("GetCouponByCode", mock.Anything, code).Return(coupon, nil).Maybe()
+				couponRepo.On("GetCouponByCodeForUpdate", mock.Anything, mock.Anything, code).Return(coupon, nil).Maybe()
+			}
+			couponRepo.On("GetCouponByCode", mock.Anything, "NOPE").Return(repo.Coupon{}, nil).Maybe()
+			couponRepo.On("GetCouponByCodeForUpdate", mock.Anything, mock.Anything, "NOPE").Return(repo.Coupon{}, nil).Maybe()
+			couponRepo.On("CountCouponRedemptions", mock.Anything, int64(7)).Return(int64(10), nil).Maybe()
+			couponRepo.On("CountCouponRedemptionsTx", mock.Anything, mock.Anything, int64(7)).Return(int64(10), nil).Maybe()
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderPromoRepo:    noOrderPromos(),
+				BundleRepo:        noBundles(),
+				OrderRepo:         orderRepo,
+				ProductRepo:       productRepo,
+				PromoRepo:         promoRepo,
+				StockMovementRepo: stockMovementRepo,
+				CouponRepo:        couponRepo,
+				Clock:             clock.Fixed(checkoutAt),
+			})
+
+			ctx := context.Background()
+			quote, err := checkoutUsecase.QuoteCart(ctx, tt.cart, tt.coupons...)
+			assert.NoError(t, err)
+
+			res, err := checkoutUsecase.Checkout(ctx, tt.cart, tt.coupons...)
+			if tt.code != "" {
+				assert.Equal(t, tt.code, apperr.CodeOf(err))
+				assert.False(t, quote.Checkoutable)
+				assert.Len(t, quote.Warnings, 1)
+				assert.Empty(t, quote.Coupons)
+				orderRepo.AssertNotCalled(t, "CommitTx", mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedTotal, res.TotalAmount)
+			assert.Equal(t, tt.expectedCoupons, res.Coupons)
+			assert.Len(t, res.Lines, tt.expectedLines)
+
+			assert.True(t, quote.Checkoutable)
+			assert.Equal(t, res.TotalAmount, quote.TotalAmount)
+			assert.Equal(t, res.Coupons, quote.Coupons)
+			assert.Equal(t, res.Lines, quote.Lines)
+
+			couponRepo.AssertCalled(t, "CreateCouponRedemptions", mock.Anything, mock.Anything, mock.MatchedBy(func(redemptions []repo.CouponRedemption) bool {
+				if len(redemptions) != len(tt.expectedCoupons) {
+					return false
+				}
+
+				for i, r := range redemptions {
+					applied := tt.expectedCoupons[i]
+					if r.CouponID != applied.CouponID || r.OrderID != 11 || r.Discount.Cmp(applied.Discount) != 0 || !r.CreatedAt.Equal(checkoutAt) {
+						return false
+					}
+				}
+
+				return true
+			}))
+
+			if tt.giftCouponID != 0 {
+				orderRepo.AssertCalled(t, "CreateOrderDetails", mock.Anything, mock.Anything, mock.MatchedBy(func(details []repo.OrderDetail) bool {
+					gift := details[len(details)-1]
+					return gift.CouponID == tt.giftCouponID && gift.PromoID == 0 && gift.OrderPromoID == 0 && gift.Price.IsZero()
+				}))
+			}
+		})
+	}
+}
+
+func TestRefundCouponedOrder(t *testing.T) {
+	orderRepo := new(mockRepo.OrderRepository)
+	productRepo := new(mockRepo.ProductRepository)
+	promoRepo := new(mockRepo.PromoRepository)
+	stockMovementRepo := new(mockRepo.StockMovementRepository)
+	couponRepo := new(mockRepo.CouponRepository)
+
+	var details []repo.OrderDetail
+	quoteCatalog(productRepo, promoRepo, googleHome, macBookPro, alexaSpeaker, raspberryPi)
+	productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(repo.Product{}, nil)
+	orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+	orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+	orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(11), nil)
+	orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		details = append([]repo.OrderDetail(nil), args.Get(2).([]repo.OrderDetail)...)
+		for i := range details {
+			details[i].OrderDetailID = int64(i + 1)
+		}
+	})
+	orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	orderRepo.On("CreateOrderRefunds", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	orderRepo.On("CommitTx", mock.Anything).Return(nil)
+	stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	couponRepo.On("GetCouponByCodeForUpdate", mock.Anything, mock.Anything, "SAVE10").Return(repo.Coupon{CouponID: 1, Code: "SAVE10", CouponType: "percent", Reward: money.MustParse("10"), Active: true}, nil)
+	couponRepo.On("CreateCouponRedemptions", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+		OrderPromoRepo:    noOrderPromos(),
+		BundleRepo:        noBundles(),
+		OrderRepo:         orderRepo,
+		ProductRepo:       productRepo,
+		PromoRepo:         promoRepo,
+		StockMovementRepo: stockMovementRepo,
+		CouponRepo:        couponRepo,
+		Clock:             clock.Fixed(checkoutAt),
+	})
+
+	// 345.64 before the coupon, 34.56 off
+	res, err := checkoutUsecase.Checkout(context.Background(), []repo.OrderDetail{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 3}}, "SAVE10")
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("311.08").String(), res.TotalAmount.String())
+
+	var paid money.Decimal
+	for _, v := range details {
+		paid = paid.Add(v.Price)
+	}
+	assert.Equal(t, res.TotalAmount.String(), paid.String(), "the details carry the coupon")
+
+	order := repo.Order{OrderID: 11, Total: res.TotalAmount, Status: service.OrderStatusPaid}
+	orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(11)).Return(order, nil)
+	orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(11)).Return(details, nil)
+
+	orderUsecase := service.NewOrderUsecase(service.OrderUsecaseImpl{
+		OrderRepo:         orderRepo,
+		ProductRepo:       productRepo,
+		StockMovementRepo: stockMovementRepo,
+		Clock:             clock.Fixed(checkoutAt),
+	})
+
+	refunded, err := orderUsecase.CancelOrder(context.Background(), 11, "changed my mind")
+	assert.NoError(t, err)
+	assert.Equal(t, res.TotalAmount.String(), refunded.RefundedTotal.String())
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+)
+
+var (
+	// ErrEmptyCart is returned for a checkout without items.
+	ErrEmptyCart = apperr.New(apperr.CodeEmptyCart, "invalid checkout")
+	// ErrInvalidQty is returned for a line whose qty is below 1, or a product
+	// whose merged qty is above the limit.
+	ErrInvalidQty = apperr.New(apperr.CodeInvalidQty, "invalid checkout")
+	// ErrTooManyLines is returned for a checkout of more distinct products
+	// than the limit.
+	ErrTooManyLines = apperr.New(apperr.CodeTooManyLines, "invalid checkout")
+)
+
+// Caps of one checkout when no limit is configured.
+const (
+	// DefaultMaxCheckoutLines caps the distinct products.
+	DefaultMaxCheckoutLines = 50
+	// DefaultMaxCheckoutQty caps the units of each product, once its lines
+	// are merged.
+	DefaultMaxCheckoutQty = 1000
+)
+
+// CheckoutLimits bounds what a single checkout may ask for.
+type CheckoutLimits struct {
+	MaxLines int
+	MaxQty   int64
+}
+
+// normalizeCheckout validates the items of a checkout and merges the lines of
+// the same product into the first one, so promos see the cart's full qty of
+// each product. The caller's slice is left alone.
+func (c *CheckoutUsecaseImpl) normalizeCheckout(form []repo.OrderDetail) ([]repo.OrderDetail, error) {
+	if len(form) == 0 {
+		return nil, fmt.Errorf("%w: the cart is empty", ErrEmptyCart)
+	}
+
+	res := make([]repo.OrderDetail, 0, len(form))
+	lines := make(map[int64]int, len(form))
+	for _, v := range form {
+		if v.Qty < 1 {
+			return nil, fmt.Errorf("%w: qty of product %d must be at least 1", ErrInvalidQty.With("product_id", v.ProductID), v.ProductID)
+		}
+
+		if i, ok := lines[v.ProductID]; ok {
+			res[i].Qty += v.Qty
+			continue
+		}
+
+		lines[v.ProductID] = len(res)
+		res = append(res, v)
+	}
+
+	maxQty := c.maxQty()
+	for _, v := range res {
+		if v.Qty > maxQty {
+			return nil, fmt.Errorf("%w: qty of product %d must be at most %d", ErrInvalidQty.With("product_id", v.ProductID), v.ProductID, maxQty)
+		}
+	}
+
+	if max := c.maxLines(); len(res) > max {
+		return nil, fmt.Errorf("%w: at most %d products can be checked out at once", ErrTooManyLines, max)
+	}
+
+	return res, nil
+}
+
+func (c *CheckoutUsecaseImpl) maxLines() int {
+	if c.Limits.MaxLines <= 0 {
+		return DefaultMaxCheckoutLines
+	}
+
+	return c.Limits.MaxLines
+}
+
+func (c *CheckoutUsecaseImpl) maxQty() int64 {
+	if c.Limits.MaxQty <= 0 {
+		return DefaultMaxCheckoutQty
+	}
+
+	return c.Limits.MaxQty
+}
@@ -0,0 +1,182 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckoutMergesDuplicateLines(t *testing.T) {
+	orderRepo := new(mockRepo.OrderRepository)
+	productRepo := new(mockRepo.ProductRepository)
+	promoRepo := new(mockRepo.PromoRepository)
+	stockMovementRepo := new(mockRepo.StockMovementRepository)
+
+	quoteCatalog(productRepo, promoRepo, googleHome)
+	productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+	orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+	orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("199.96", "49.99", "149.97", 4)).Return(int64(1), nil)
+	orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, []repo.OrderDetail{
+		{OrderID: 1, ProductID: 1, PromoID: 1, Qty: 4, Price: money.MustParse("149.97")},
+	}).Return(nil)
+	orderRepo.On("CommitTx", mock.Anything).Return(nil)
+	stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+		OrderPromoRepo:    noOrderPromos(),
+		BundleRepo:        noBundles(),
+		OrderRepo:         orderRepo,
+		ProductRepo:       productRepo,
+		PromoRepo:         promoRepo,
+		StockMovementRepo: stockMovementRepo,
+		Clock:             clock.Fixed(checkoutAt),
+	})
+
+	// two lines of 2 Google Homes reach the buy 3 pay 2 promo together
+	form := []repo.OrderDetail{{ProductID: 1, Qty: 2}, {ProductID: 1, Qty: 2}}
+	res, err := checkoutUsecase.Checkout(context.Background(), form)
+	assert.NoError(t, err)
+	assert.Len(t, res.Lines, 1)
+	assert.Equal(t, int64(4), res.Lines[0].Qty)
+	assert.Equal(t, int64(1), res.Lines[0].PromoID)
+	assert.Equal(t, money.MustParse("149.97"), res.TotalAmount)
+
+	// the caller's items are left as they were
+	assert.Equal(t, []repo.OrderDetail{{ProductID: 1, Qty: 2}, {ProductID: 1, Qty: 2}}, form)
+	orderRepo.AssertExpectations(t)
+}
+
+func TestCheckoutInputValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		form      []repo.OrderDetail
+		limits    service.CheckoutLimits
+		err       error
+		code      apperr.Code
+		productID int64
+		errMsg    string
+	}{
+		{
+			name:   "empty cart",
+			err:    service.ErrEmptyCart,
+			code:   apperr.CodeEmptyCart,
+			errMsg: "invalid checkout: the cart is empty",
+		},
+		{
+			name:      "zero qty",
+			form:      []repo.OrderDetail{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 0}},
+			err:       service.ErrInvalidQty,
+			code:      apperr.CodeInvalidQty,
+			productID: 3,
+			errMsg:    "invalid checkout: qty of product 3 must be at least 1",
+		},
+		{
+			name:      "negative qty",
+			form:      []repo.OrderDetail{{ProductID: 3, Qty: -2}},
+			err:       service.ErrInvalidQty,
+			code:      apperr.CodeInvalidQty,
+			productID: 3,
+			errMsg:    "invalid checkout: qty of product 3 must be at least 1",
+		},
+		{
+			name:      "merged qty above the limit",
+			form:      []repo.OrderDetail{{ProductID: 1, Qty: 6}, {ProductID: 1, Qty: 5}},
+			limits:    service.CheckoutLimits{MaxQty: 10},
+			err:       service.ErrInvalidQty,
+			code:      apperr.CodeInvalidQty,
+			productID: 1,
+			errMsg:    "invalid checkout: qty of product 1 must be at most 10",
+		},
+		{
+			name:      "qty above the default limit",
+			form:      []repo.OrderDetail{{ProductID: 1, Qty: 1000001}},
+			err:       service.ErrInvalidQty,
+			code:      apperr.CodeInvalidQty,
+			productID: 1,
+			errMsg:    "invalid checkout: qty of product 1 must be at most 1000",
+		},
+		{
+			name:   "too many lines",
+			form:   []repo.OrderDetail{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 1}, {ProductID: 4, Qty: 1}},
+			limits: service.CheckoutLimits{MaxLines: 2},
+			err:    service.ErrTooManyLines,
+			code:   apperr.CodeTooManyLines,
+			errMsg: "invalid checkout: at most 2 products can be checked out at once",
+		},
+		{
+			name:      "unknown product",
+			form:      []repo.OrderDetail{{ProductID: 9, Qty: 1}},
+			code:      apperr.CodeProductNotFound,
+			productID: 9,
+			errMsg:    "product 9 doesn't exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+
+			orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil).Maybe()
+			orderRepo.On("RollbackTx", mock.Anything).Return(nil).Maybe()
+			promoRepo.On("GetPromosByProductID", mock.Anything, int64(9), checkoutAt).Return([]repo.Promo{}, nil).Maybe()
+			promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, int64(9), checkoutAt).Return([]repo.Promo{}, nil).Maybe()
+			productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(9)).Return(repo.Product{}, nil).Maybe()
+			productRepo.On("GetProductByProductID", mock.Anything, int64(9)).Return(repo.Product{}, nil).Maybe()
+			productRepo.On("GetProductByProductIDTx", mock.Anything, mock.Anything, int64(9)).Return(repo.Product{}, nil).Maybe()
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderPromoRepo: noOrderPromos(),
+				BundleRepo:     noBundles(),
+				OrderRepo:      orderRepo,
+				ProductRepo:    productRepo,
+				PromoRepo:      promoRepo,
+				Limits:         tt.limits,
+				Clock:          clock.Fixed(checkoutAt),
+			})
+
+			for name, call := range map[string]func() error{
+				"checkout": func() error {
+					_, err := checkoutUsecase.Checkout(context.Background(), tt.form)
+					return err
+				},
+				"quote": func() error {
+					_, err := checkoutUsecase.QuoteCart(context.Background(), tt.form)
+					return err
+				},
+			} {
+				err := call()
+				if tt.err != nil {
+					assert.ErrorIs(t, err, tt.err, name)
+				}
+				assert.EqualError(t, err, tt.errMsg, name)
+				assert.Equal(t, tt.code, apperr.CodeOf(err), name)
+
+				var appErr *apperr.Error
+				if assert.True(t, errors.As(err, &appErr), name) {
+					if tt.productID != 0 {
+						assert.Equal(t, tt.productID, appErr.Fields["product_id"], name)
+					} else {
+						assert.NotContains(t, appErr.Fields, "product_id", name)
+					}
+				}
+			}
+
+			orderRepo.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything, mock.Anything)
+			productRepo.AssertNotCalled(t, "UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+// AppliedOrderPromo is an order promo applied to a checkout. Discount is what
+// it took off the order; a gift adds a free line instead.
+type AppliedOrderPromo struct {
+	OrderPromoID int64         `json:"order_promo_id"`
+	PromoType    string        `json:"promo_type"`
+	Discount     money.Decimal `json:"discount"`
+}
+
+// OrderPromotion applies an order promo to the order priced in res and
+// returns what it took off the order. No discount takes the total below
+// zero.
+type OrderPromotion interface {
+	ApplyOrderPromotion(ctx context.Context, tx *sqlx.Tx, promo *repo.OrderPromo, res *Checkout) (money.Decimal, error)
+}
+
+type OrderPromoDiscount struct {
+	Currency money.Currency
+}
+
+// ApplyOrderPromotion takes Reward percent off the order, rounded to the
+// currency.
+func (p *OrderPromoDiscount) ApplyOrderPromotion(ctx context.Context, tx *sqlx.Tx, promo *repo.OrderPromo, res *Checkout) (money.Decimal, error) {
+	return p.Currency.Round(res.TotalAmount.Mul(promo.Reward).Div(money.NewFromInt(100))), nil
+}
+
+type OrderPromoAmount struct{}
+
+// ApplyOrderPromotion takes the Reward amount off the order, at most what the
+// order comes to.
+func (p *OrderPromoAmount) ApplyOrderPromotion(ctx context.Context, tx *sqlx.Tx, promo *repo.OrderPromo, res *Checkout) (money.Decimal, error) {
+	if promo.Reward.Cmp(res.TotalAmount) > 0 {
+		return res.TotalAmount, nil
+	}
+
+	return promo.Reward, nil
+}
+
+type OrderPromoGift struct {
+	Gift *ProductPromoFree
+}
+
+// ApplyOrderPromotion adds the product Reward as a free line and takes
+// nothing off the order.
+func (p *OrderPromoGift) ApplyOrderPromotion(ctx context.Context, tx *sqlx.Tx, promo *repo.OrderPromo, res *Checkout) (money.Decimal, error) {
+	err := p.Gift.giveLine(ctx, tx, promo.Reward.IntPart(), CheckoutLine{
+		PromoType:    promo.PromoType,
+		OrderPromoID: promo.OrderPromoID,
+	}, res)
+	return money.Decimal{}, err
+}
+
+type orderDiscountPromotion struct{}
+
+// NewOrderDiscountPromotion takes a percent off the order.
+func NewOrderDiscountPromotion() OrderPromotionKind {
+	return orderDiscountPromotion{}
+}
+
+func (orderDiscountPromotion) Name() string {
+	return OrderPromoTypeDiscount
+}
+
+func (orderDiscountPromotion) Validate(ctx context.Context, promo repo.OrderPromo, check PromoCheck) error {
+	if promo.Reward.IsNegative() || promo.Reward.IsZero() || promo.Reward.Cmp(money.NewFromInt(100)) > 0 {
+		return fmt.Errorf("%w: discount reward must be above 0 and at most 100", ErrInvalidPromo)
+	}
+
+	return nil
+}
+
+func (orderDiscountPromotion) OrderPromotion(promo repo.OrderPromo, env OrderPromotionEnv) OrderPromotion {
+	return &OrderPromoDiscount{Currency: env.Currency}
+}
+
+type orderAmountPromotion struct{}
+
+// NewOrderAmountPromotion takes a fixed amount off the order.
+func NewOrderAmountPromotion() OrderPromotionKind {
+	return orderAmountPromotion{}
+}
+
+func (orderAmountPromotion) Name() string {
+	return OrderPromoTypeAmount
+}
+
+func (orderAmountPromotion) Validate(ctx context.Context, promo repo.OrderPromo, check PromoCheck) error {
+	if promo.Reward.IsNegative() || promo.Reward.IsZero() {
+		return fmt.Errorf("%w: amount reward must be above 0", ErrInvalidPromo)
+	}
+
+	return nil
+}
+
+func (orderAmountPromotion) OrderPromotion(promo repo.OrderPromo, env OrderPromotionEnv) OrderPromotion {
+	return &OrderPromoAmount{}
+}
+
+type orderGiftPromotion struct{}
+
+// NewOrderGiftPromotion gives one unit of the reward product away with the
+// order.
+func NewOrderGiftPromotion() OrderPromotionKind {
+	return orderGiftPromotion{}
+}
+
+func (orderGiftPromotion) Name() string {
+	return OrderPromoTypeGift
+}
+
+func (orderGiftPromotion) Validate(ctx context.Context, promo repo.OrderPromo, check PromoCheck) error {
+	if promo.Reward.Cmp(money.NewFromInt(promo.Reward.IntPart())) != 0 {
+		return fmt.Errorf("%w: gift reward must be a product id", ErrInvalidPromo)
+	}
+
+	return check.Product(ctx, promo.Reward.IntPart(), "reward")
+}
+
+func (orderGiftPromotion) OrderPromotion(promo repo.OrderPromo, env OrderPromotionEnv) OrderPromotion {
+	return &OrderPromoGift{Gift: &ProductPromoFree{Stock: env.Stock, StockPolicy: env.StockPolicy}}
+}
+
+// applyOrderPromos runs the order promos over the order priced in res, once
+// every line has its promos. Thresholds are checked against what the lines
+// came to and how many items were bought, before any order promo, so the
+// promos don't depend on each other's order. Only the first promo of a stack
+// group that applies is given.
+func (c *CheckoutUsecaseImpl) applyOrderPromos(ctx context.Context, tx *sqlx.Tx, stock cartStock, now time.Time, res *Checkout) error {
+	promos, err := stock.orderPromos(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+
+	subtotal := res.TotalAmount
+
+	var items int64
+	for _, line := range res.Lines {
+		if !line.Free {
+			items += line.Qty
+		}
+	}
+
+	given := make(map[string]bool, len(promos))
+	for _, promo := range promos {
+		if !orderPromoReached(promo, subtotal, items) {
+			continue
+		}
+
+		if promo.StackGroup != "" && given[promo.StackGroup] {
+			continue
+		}
+
+		err = c.applyOrderPromo(ctx, tx, stock, promo, res)
+		if errors.Is(err, errPromotionSkipped) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		given[promo.StackGroup] = true
+	}
+
+	return nil
+}
+
+// orderPromoReached reports whether an order of subtotal and items reaches
+// every threshold promo sets.
+func orderPromoReached(promo repo.OrderPromo, subtotal money.Decimal, items int64) bool {
+	if !promo.MinSubtotal.IsZero() && subtotal.Cmp(promo.MinSubtotal) < 0 {
+		return false
+	}
+
+	return promo.MinItems == 0 || items >= promo.MinItems
+}
+
+// applyOrderPromo applies promo to the order priced in res.
+func (c *CheckoutUsecaseImpl) applyOrderPromo(ctx context.Context, tx *sqlx.Tx, stock cartStock, promo repo.OrderPromo, res *Checkout) error {
+	promotion := c.promotions().orderPromotion(promo, OrderPromotionEnv{
+		Stock:       stock,
+		StockPolicy: c.StockPolicy,
+		Currency:    c.currency(),
+	})
+	if promotion == nil {
+		// order promo writes are validated, so this is a kind the shop dropped
+		return errPromotionSkipped
+	}
+
+	discount, err := promotion.ApplyOrderPromotion(ctx, tx, &promo, res)
+	if err != nil {
+		return err
+	}
+
+	res.TotalAmount = res.TotalAmount.Sub(discount)
+	res.OrderPromos = append(res.OrderPromos, AppliedOrderPromo{
+		OrderPromoID: promo.OrderPromoID,
+		PromoType:    promo.PromoType,
+		Discount:     discount,
+	})
+
+	return nil
+}
+
+// orderPromoDiscount is what the order promos of res took off the order.
+func orderPromoDiscount(res *Checkout) money.Decimal {
+	var discount money.Decimal
+	for _, promo := range res.OrderPromos {
+		discount = discount.Add(promo.Discount)
+	}
+
+	return discount
+}
+
+// recordOrderDiscounts writes one discount record per order promo of res that
+// took an amount off the order, in the order's transaction. Gifts are stored
+// as order details instead.
+func (c *CheckoutUsecaseImpl) recordOrderDiscounts(tx *sqlx.Tx, ctx context.Context, orderID int64, res *Checkout) error {
+	var discounts []repo.OrderDiscount
+	for _, promo := range res.OrderPromos {
+		if promo.Discount.IsZero() {
+			continue
+		}
+
+		discounts = append(discounts, repo.OrderDiscount{
+			OrderID:      orderID,
+			OrderPromoID: promo.OrderPromoID,
+			PromoType:    promo.PromoType,
+			Discount:     promo.Discount,
+		})
+	}
+
+	if len(discounts) == 0 {
+		return nil
+	}
+
+	err := c.OrderRepo.CreateOrderDiscounts(tx, ctx, discounts)
+	if err != nil {
+		log.Printf("error while do CreateOrderDiscounts %+v", err)
+		return err
+	}
+
+	return nil
+}
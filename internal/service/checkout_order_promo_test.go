@@ -0,0 +1,216 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckoutOrderPromos(t *testing.T) {
+	tests := []struct {
+		name          string
+		cart          []repo.OrderDetail
+		promos        []repo.OrderPromo
+		expectedTotal money.Decimal
+		expectedPromo []service.AppliedOrderPromo
+		expectedLines int
+	}{
+		{
+			name: "percent off a subtotal after line promos",
+			cart: []repo.OrderDetail{{ProductID: 3, Qty: 3}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinSubtotal: money.MustParse("200")},
+			},
+			expectedTotal: money.MustParse("266.08"),
+			expectedPromo: []service.AppliedOrderPromo{
+				{OrderPromoID: 1, PromoType: "discount", Discount: money.MustParse("29.57")},
+			},
+			expectedLines: 1,
+		},
+		{
+			name: "subtotal below the threshold",
+			cart: []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinSubtotal: money.MustParse("200")},
+			},
+			expectedTotal: money.MustParse("49.99"),
+			expectedLines: 1,
+		},
+		{
+			name: "item count threshold leaves free units out",
+			cart: []repo.OrderDetail{{ProductID: 2, Qty: 1}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 2, PromoType: "amount", Reward: money.MustParse("10"), MinItems: 2},
+			},
+			expectedTotal: money.MustParse("5399.99"),
+			expectedLines: 2,
+		},
+		{
+			name: "item count threshold reached",
+			cart: []repo.OrderDetail{{ProductID: 1, Qty: 2}, {ProductID: 4, Qty: 1}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 2, PromoType: "amount", Reward: money.MustParse("10"), MinItems: 3},
+			},
+			expectedTotal: money.MustParse("119.98"),
+			expectedPromo: []service.AppliedOrderPromo{
+				{OrderPromoID: 2, PromoType: "amount", Discount: money.MustParse("10")},
+			},
+			expectedLines: 2,
+		},
+		{
+			name: "an amount stops at zero",
+			cart: []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 2, PromoType: "amount", Reward: money.MustParse("100"), MinItems: 1},
+			},
+			expectedTotal: money.MustParse("0"),
+			expectedPromo: []service.AppliedOrderPromo{
+				{OrderPromoID: 2, PromoType: "amount", Discount: money.MustParse("49.99")},
+			},
+			expectedLines: 1,
+		},
+		{
+			name: "a gift is added as a free line",
+			cart: []repo.OrderDetail{{ProductID: 1, Qty: 1}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 3, PromoType: "gift", Reward: money.MustParse("4"), MinSubtotal: money.MustParse("40")},
+			},
+			expectedTotal: money.MustParse("49.99"),
+			expectedPromo: []service.AppliedOrderPromo{
+				{OrderPromoID: 3, PromoType: "gift"},
+			},
+			expectedLines: 2,
+		},
+		{
+			name: "only the first promo of a stack group applies",
+			cart: []repo.OrderDetail{{ProductID: 2, Qty: 1}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 4, PromoType: "discount", Reward: money.MustParse("20"), MinSubtotal: money.MustParse("1000"), Priority: 2, StackGroup: "tier"},
+				{OrderPromoID: 5, PromoType: "discount", Reward: money.MustParse("10"), MinSubtotal: money.MustParse("100"), Priority: 1, StackGroup: "tier"},
+			},
+			expectedTotal: money.MustParse("4319.99"),
+			expectedPromo: []service.AppliedOrderPromo{
+				{OrderPromoID: 4, PromoType: "discount", Discount: money.MustParse("1080.00")},
+			},
+			expectedLines: 2,
+		},
+		{
+			name: "a lower tier applies when the higher one isn't reached",
+			cart: []repo.OrderDetail{{ProductID: 3, Qty: 3}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 4, PromoType: "discount", Reward: money.MustParse("20"), MinSubtotal: money.MustParse("1000"), Priority: 2, StackGroup: "tier"},
+				{OrderPromoID: 5, PromoType: "discount", Reward: money.MustParse("10"), MinSubtotal: money.MustParse("100"), Priority: 1, StackGroup: "tier"},
+			},
+			expectedTotal: money.MustParse("266.08"),
+			expectedPromo: []service.AppliedOrderPromo{
+				{OrderPromoID: 5, PromoType: "discount", Discount: money.MustParse("29.57")},
+			},
+			expectedLines: 1,
+		},
+		{
+			name: "promos outside a stack group add up",
+			cart: []repo.OrderDetail{{ProductID: 3, Qty: 3}},
+			promos: []repo.OrderPromo{
+				{OrderPromoID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinSubtotal: money.MustParse("200")},
+				{OrderPromoID: 2, PromoType: "amount", Reward: money.MustParse("10"), MinItems: 3},
+				{OrderPromoID: 6, PromoType: "cashback", Reward: money.MustParse("10"), MinItems: 1},
+			},
+			expectedTotal: money.MustParse("256.08"),
+			expectedPromo: []service.AppliedOrderPromo{
+				{OrderPromoID: 1, PromoType: "discount", Discount: money.MustParse("29.57")},
+				{OrderPromoID: 2, PromoType: "amount", Discount: money.MustParse("10")},
+			},
+			expectedLines: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
+			orderPromoRepo := new(mockRepo.OrderPromoRepository)
+
+			quoteCatalog(productRepo, promoRepo, googleHome, macBookPro, alexaSpeaker, raspberryPi)
+			productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+			orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+			orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(12), nil)
+			orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CreateOrderDiscounts", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderPromoRepo.On("GetOrderPromos", mock.Anything, checkoutAt).Return(tt.promos, nil)
+			orderPromoRepo.On("GetOrderPromosTx", mock.Anything, mock.Anything, checkoutAt).Return(tt.promos, nil)
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderRepo:         orderRepo,
+				ProductRepo:       productRepo,
+				PromoRepo:         promoRepo,
+				StockMovementRepo: stockMovementRepo,
+				OrderPromoRepo:    orderPromoRepo,
+				BundleRepo:        noBundles(),
+				Clock:             clock.Fixed(checkoutAt),
+			})
+
+			quote, err := checkoutUsecase.QuoteCart(context.Background(), tt.cart)
+			assert.NoError(t, err)
+
+			res, err := checkoutUsecase.Checkout(context.Background(), tt.cart)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedTotal, res.TotalAmount)
+			assert.Equal(t, tt.expectedPromo, res.OrderPromos)
+			assert.Len(t, res.Lines, tt.expectedLines)
+
+			assert.True(t, quote.Checkoutable)
+			assert.Equal(t, res.TotalAmount, quote.TotalAmount)
+			assert.Equal(t, res.OrderPromos, quote.OrderPromos)
+			assert.Equal(t, res.Lines, quote.Lines)
+
+			// the order's discounts are spread over the details, so a refund
+			// gives back no more than was paid
+			orderRepo.AssertCalled(t, "CreateOrderDetails", mock.Anything, mock.Anything, mock.MatchedBy(func(details []repo.OrderDetail) bool {
+				var paid money.Decimal
+				for _, detail := range details {
+					paid = paid.Add(detail.Price)
+				}
+
+				return paid.Cmp(tt.expectedTotal) == 0
+			}))
+
+			var discounts []repo.OrderDiscount
+			for _, applied := range tt.expectedPromo {
+				if !applied.Discount.IsZero() {
+					discounts = append(discounts, repo.OrderDiscount{OrderID: 12, OrderPromoID: applied.OrderPromoID, PromoType: applied.PromoType, Discount: applied.Discount})
+				}
+			}
+
+			if len(discounts) == 0 {
+				orderRepo.AssertNotCalled(t, "CreateOrderDiscounts", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				orderRepo.AssertCalled(t, "CreateOrderDiscounts", mock.Anything, mock.Anything, discounts)
+			}
+
+			for _, applied := range tt.expectedPromo {
+				if applied.PromoType != "gift" {
+					continue
+				}
+
+				orderRepo.AssertCalled(t, "CreateOrderDetails", mock.Anything, mock.Anything, mock.MatchedBy(func(details []repo.OrderDetail) bool {
+					last := details[len(details)-1]
+					return last.ProductID == 4 && last.Price.IsZero() && last.OrderPromoID == applied.OrderPromoID
+				}))
+			}
+		})
+	}
+}
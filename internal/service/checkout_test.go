@@ -4,19 +4,26 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	mockRepo "github.com/learn/api-shop/internal/generated/mock"
 	"github.com/learn/api-shop/internal/repo"
 	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+var checkoutAt = time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
 func TestCheckout(t *testing.T) {
 	tests := []struct {
 		name          string
 		orderDetails  []repo.OrderDetail
+		currency      money.Currency
+		stockPolicy   service.RewardStockPolicy
 		mockSetupFunc func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository)
 		expectedResp  service.Checkout
 		wantErr       bool
@@ -33,30 +40,78 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				productRepo.On("GetProductByProductID", mock.Anything, mock.Anything).Return(repo.Product{
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("328.5", "32.85", "295.65", 3)).Return(int64(1), nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, mock.Anything).Return(repo.Product{
 					ProductID: 3,
 					Sku:       "A304SD",
 					Name:      "Alexa Speaker",
-					Price:     109.500,
+					Price:     money.MustParse("109.500"),
 					Qty:       10,
 				}, nil)
 				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
 					PromoID:   3,
 					PromoType: "discount",
-					Reward:    10,
+					Reward:    money.MustParse("10"),
 					MinQty:    3,
-				}, nil)
+				}}, nil)
 
 				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				orderRepo.On("CommitTx", mock.Anything).Return(nil)
 
 			},
 			expectedResp: service.Checkout{
-				Items:       []string{"Alexa Speaker", "Alexa Speaker", "Alexa Speaker"},
-				TotalAmount: 295.65,
+				OrderID: 1,
+				Items:   []string{"Alexa Speaker", "Alexa Speaker", "Alexa Speaker"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 3, ProductName: "Alexa Speaker", Qty: 3, UnitPrice: money.MustParse("109.5"), Subtotal: money.MustParse("328.5"), Discount: money.MustParse("32.85"), Total: money.MustParse("295.65"), PromoID: 3, PromoType: "discount", Promos: []service.AppliedPromo{{PromoID: 3, PromoType: "discount"}}},
+				},
+				TotalAmount: money.MustParse("295.65"),
+				Currency:    "USD",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Discount ties are rounded with the configured currency rule",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 5,
+					Qty:       5,
+				},
+			},
+			currency: money.Currency{Code: "EUR", Places: 2, Rounding: money.HalfEven},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("31.25", "3.12", "28.13", 5)).Return(int64(1), nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, mock.Anything).Return(repo.Product{
+					ProductID: 5,
+					Sku:       "C0FF33",
+					Name:      "Coffee Beans",
+					Price:     money.MustParse("6.250"),
+					Qty:       20,
+				}, nil)
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
+					PromoID:   5,
+					ProductID: 5,
+					PromoType: "discount",
+					Reward:    money.MustParse("10"),
+					MinQty:    5,
+				}}, nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: service.Checkout{
+				OrderID: 1,
+				Items:   []string{"Coffee Beans", "Coffee Beans", "Coffee Beans", "Coffee Beans", "Coffee Beans"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 5, ProductName: "Coffee Beans", Qty: 5, UnitPrice: money.MustParse("6.25"), Subtotal: money.MustParse("31.25"), Discount: money.MustParse("3.12"), Total: money.MustParse("28.13"), PromoID: 5, PromoType: "discount", Promos: []service.AppliedPromo{{PromoID: 5, PromoType: "discount"}}},
+				},
+				TotalAmount: money.MustParse("28.13"),
+				Currency:    "EUR",
 			},
 			wantErr: false,
 		},
@@ -72,29 +127,34 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				productRepo.On("GetProductByProductID", mock.Anything, mock.Anything).Return(repo.Product{
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("149.97", "49.99", "99.98", 3)).Return(int64(1), nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, mock.Anything).Return(repo.Product{
 					ProductID: 1,
 					Sku:       "120P90",
 					Name:      "Google Home",
-					Price:     49.990,
+					Price:     money.MustParse("49.990"),
 					Qty:       10,
 				}, nil)
 				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
 					PromoID:   1,
 					PromoType: "product",
-					Reward:    1,
+					Reward:    money.MustParse("1"),
 					MinQty:    3,
-				}, nil)
+				}}, nil)
 				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 				orderRepo.On("CommitTx", mock.Anything).Return(nil)
 			},
 			expectedResp: service.Checkout{
-				Items:       []string{"Google Home", "Google Home", "Google Home"},
-				TotalAmount: 99.98,
+				OrderID: 1,
+				Items:   []string{"Google Home", "Google Home", "Google Home"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 1, ProductName: "Google Home", Qty: 3, UnitPrice: money.MustParse("49.99"), Subtotal: money.MustParse("149.97"), Discount: money.MustParse("49.99"), Total: money.MustParse("99.98"), PromoID: 1, PromoType: "product", Promos: []service.AppliedPromo{{PromoID: 1, PromoType: "product"}}},
+				},
+				TotalAmount: money.MustParse("99.98"),
+				Currency:    "USD",
 			},
 			wantErr: false,
 		},
@@ -114,46 +174,52 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				productRepo.On("GetProductByProductID", context.Background(), int64(1)).Return(repo.Product{
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("478.47", "82.84", "395.63", 6)).Return(int64(1), nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(1)).Return(repo.Product{
 					ProductID: 1,
 					Sku:       "120P90",
 					Name:      "Google Home",
-					Price:     49.990,
+					Price:     money.MustParse("49.990"),
 					Qty:       10,
 				}, nil)
 				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-				promoRepo.On("GetPromoByProductID", context.Background(), int64(1)).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, context.Background(), int64(1), checkoutAt).Return([]repo.Promo{{
 					PromoID:   1,
 					PromoType: "product",
-					Reward:    1,
+					Reward:    money.MustParse("1"),
 					MinQty:    3,
-				}, nil)
+				}}, nil)
 
-				productRepo.On("GetProductByProductID", context.Background(), int64(3)).Return(repo.Product{
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(3)).Return(repo.Product{
 					ProductID: 3,
 					Sku:       "A304SD",
 					Name:      "Alexa Speaker",
-					Price:     109.500,
+					Price:     money.MustParse("109.500"),
 					Qty:       10,
 				}, nil)
 				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-				promoRepo.On("GetPromoByProductID", context.Background(), int64(3)).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, context.Background(), int64(3), checkoutAt).Return([]repo.Promo{{
 					PromoID:   3,
 					PromoType: "discount",
-					Reward:    10,
+					Reward:    money.MustParse("10"),
 					MinQty:    3,
-				}, nil)
+				}}, nil)
 
 				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 				orderRepo.On("CommitTx", mock.Anything).Return(nil)
 			},
 			expectedResp: service.Checkout{
-				Items:       []string{"Google Home", "Google Home", "Google Home", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker"},
-				TotalAmount: 395.63,
+				OrderID: 1,
+				Items:   []string{"Google Home", "Google Home", "Google Home", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 1, ProductName: "Google Home", Qty: 3, UnitPrice: money.MustParse("49.99"), Subtotal: money.MustParse("149.97"), Discount: money.MustParse("49.99"), Total: money.MustParse("99.98"), PromoID: 1, PromoType: "product", Promos: []service.AppliedPromo{{PromoID: 1, PromoType: "product"}}},
+					{ProductID: 3, ProductName: "Alexa Speaker", Qty: 3, UnitPrice: money.MustParse("109.5"), Subtotal: money.MustParse("328.5"), Discount: money.MustParse("32.85"), Total: money.MustParse("295.65"), PromoID: 3, PromoType: "discount", Promos: []service.AppliedPromo{{PromoID: 3, PromoType: "discount"}}},
+				},
+				TotalAmount: money.MustParse("395.63"),
+				Currency:    "USD",
 			},
 			wantErr: false,
 		},
@@ -169,37 +235,234 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				productRepo.On("GetProductByProductID", context.TODO(), int64(2)).Return(repo.Product{
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("5399.99", "0", "5399.99", 2)).Return(int64(1), nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
 					ProductID: 2,
 					Sku:       "43N23P",
 					Name:      "MacBook Pro",
-					Price:     5399.990,
+					Price:     money.MustParse("5399.990"),
 					Qty:       5,
 				}, nil)
-				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, repo.Product{ProductID: 2, Qty: 1}).Return(nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, repo.Product{ProductID: 4, Qty: 1}).Return(nil)
 
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
 					PromoID:   2,
 					PromoType: "product",
-					Reward:    4,
+					Reward:    money.MustParse("4"),
 					MinQty:    1,
-				}, nil)
+				}}, nil)
 
-				productRepo.On("GetProductByProductID", context.TODO(), int64(4)).Return(repo.Product{
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
 					ProductID: 4,
 					Sku:       "234234",
 					Name:      "Raspberry Pi B",
-					Price:     30.000,
+					Price:     money.MustParse("30.000"),
 					Qty:       2,
 				}, nil)
-				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, []repo.OrderDetail{
+					{OrderID: 1, ProductID: 2, PromoID: 2, Price: money.MustParse("5399.99"), Qty: 1},
+					{OrderID: 1, ProductID: 4, PromoID: 2, Qty: 1},
+				}).Return(nil)
 				orderRepo.On("CommitTx", mock.Anything).Return(nil)
 
 			},
 			expectedResp: service.Checkout{
-				Items:       []string{"MacBook Pro", "Raspberry Pi B"},
-				TotalAmount: 5399.99,
+				OrderID: 1,
+				Items:   []string{"MacBook Pro", "Raspberry Pi B"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 2, ProductName: "MacBook Pro", Qty: 1, UnitPrice: money.MustParse("5399.99"), Subtotal: money.MustParse("5399.99"), Total: money.MustParse("5399.99"), PromoID: 2, PromoType: "product", Promos: []service.AppliedPromo{{PromoID: 2, PromoType: "product"}}},
+					{ProductID: 4, ProductName: "Raspberry Pi B", Qty: 1, UnitPrice: money.MustParse("30"), Subtotal: money.MustParse("30"), Discount: money.MustParse("30"), PromoID: 2, PromoType: "product", Free: true},
+				},
+				TotalAmount: money.MustParse("5399.99"),
+				Currency:    "USD",
+			},
+			wantErr: false,
+		},
+		{
+			name: "free Raspberry Pi B out of stock fails the checkout",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 2,
+					Qty:       1,
+				},
+			},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
+					PromoID:   2,
+					PromoType: "product",
+					Reward:    money.MustParse("4"),
+					MinQty:    1,
+				}}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
+					ProductID: 2,
+					Name:      "MacBook Pro",
+					Price:     money.MustParse("5399.990"),
+					Qty:       5,
+				}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
+					ProductID: 4,
+					Name:      "Raspberry Pi B",
+					Price:     money.MustParse("30.000"),
+					Qty:       0,
+				}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "free Raspberry Pi B out of stock is skipped with a warning",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 2,
+					Qty:       1,
+				},
+			},
+			stockPolicy: service.RewardStockSkip,
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("5399.99", "0", "5399.99", 1)).Return(int64(1), nil)
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
+					PromoID:   2,
+					PromoType: "product",
+					Reward:    money.MustParse("4"),
+					MinQty:    1,
+				}}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
+					ProductID: 2,
+					Name:      "MacBook Pro",
+					Price:     money.MustParse("5399.990"),
+					Qty:       5,
+				}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
+					ProductID: 4,
+					Name:      "Raspberry Pi B",
+					Price:     money.MustParse("30.000"),
+					Qty:       1,
+				}, nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, repo.Product{ProductID: 4, Qty: 1}).Return(repo.ErrInsufficientStock)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, repo.Product{ProductID: 2, Qty: 1}).Return(nil)
+				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, []repo.OrderDetail{
+					{OrderID: 1, ProductID: 2, Price: money.MustParse("5399.99"), Qty: 1},
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: service.Checkout{
+				OrderID: 1,
+				Items:   []string{"MacBook Pro"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 2, ProductName: "MacBook Pro", Qty: 1, UnitPrice: money.MustParse("5399.99"), Subtotal: money.MustParse("5399.99"), Total: money.MustParse("5399.99")},
+				},
+				TotalAmount: money.MustParse("5399.99"),
+				Currency:    "USD",
+				Warnings:    []string{"the free product Raspberry Pi B is out of stock and was not added"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "best stackable promos are combined",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 1,
+					Qty:       3,
+				},
+			},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, int64(1), checkoutAt).Return([]repo.Promo{
+					{PromoID: 1, ProductID: 1, PromoType: "product", Reward: money.MustParse("1"), MinQty: 3},
+					{PromoID: 6, ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 1},
+					{PromoID: 7, ProductID: 1, PromoType: "product", Reward: money.MustParse("4"), MinQty: 1},
+				}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(1)).Return(repo.Product{
+					ProductID: 1,
+					Name:      "Google Home",
+					Price:     money.MustParse("49.990"),
+					Qty:       10,
+				}, nil)
+				productRepo.On("GetProductByProductIDTx", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
+					ProductID: 4,
+					Name:      "Raspberry Pi B",
+					Price:     money.MustParse("30.000"),
+					Qty:       2,
+				}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
+					ProductID: 4,
+					Name:      "Raspberry Pi B",
+					Price:     money.MustParse("30.000"),
+					Qty:       2,
+				}, nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, repo.Product{ProductID: 4, Qty: 1}).Return(nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, repo.Product{ProductID: 1, Qty: 3}).Return(nil)
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("149.97", "49.99", "99.98", 4)).Return(int64(1), nil)
+				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, []repo.OrderDetail{
+					{OrderID: 1, ProductID: 1, PromoID: 1, Price: money.MustParse("99.98"), Qty: 3},
+					{OrderID: 1, ProductID: 4, PromoID: 7, Qty: 1},
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: service.Checkout{
+				OrderID: 1,
+				Items:   []string{"Google Home", "Google Home", "Google Home", "Raspberry Pi B"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 1, ProductName: "Google Home", Qty: 3, UnitPrice: money.MustParse("49.99"), Subtotal: money.MustParse("149.97"), Discount: money.MustParse("49.99"), Total: money.MustParse("99.98"), PromoID: 1, PromoType: "product", Promos: []service.AppliedPromo{{PromoID: 1, PromoType: "product"}, {PromoID: 7, PromoType: "product"}}},
+					{ProductID: 4, ProductName: "Raspberry Pi B", Qty: 1, UnitPrice: money.MustParse("30"), Subtotal: money.MustParse("30"), Discount: money.MustParse("30"), PromoID: 7, PromoType: "product", Free: true},
+				},
+				TotalAmount: money.MustParse("99.98"),
+				Currency:    "USD",
+			},
+			wantErr: false,
+		},
+		{
+			name: "exclusive promo wins when it saves more than any combination",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 3,
+					Qty:       3,
+				},
+			},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, int64(3), checkoutAt).Return([]repo.Promo{
+					{PromoID: 8, ProductID: 3, PromoType: "discount", Reward: money.MustParse("25"), MinQty: 1, Priority: 5, Exclusive: true},
+					{PromoID: 3, ProductID: 3, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 3},
+					{PromoID: 9, ProductID: 3, PromoType: "product", Reward: money.MustParse("4"), MinQty: 1},
+				}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(3)).Return(repo.Product{
+					ProductID: 3,
+					Name:      "Alexa Speaker",
+					Price:     money.MustParse("109.500"),
+					Qty:       10,
+				}, nil)
+				productRepo.On("GetProductByProductIDTx", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
+					ProductID: 4,
+					Name:      "Raspberry Pi B",
+					Price:     money.MustParse("30.000"),
+					Qty:       2,
+				}, nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, repo.Product{ProductID: 3, Qty: 3}).Return(nil)
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("328.5", "82.13", "246.37", 3)).Return(int64(1), nil)
+				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, []repo.OrderDetail{
+					{OrderID: 1, ProductID: 3, PromoID: 8, Price: money.MustParse("246.37"), Qty: 3},
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: service.Checkout{
+				OrderID: 1,
+				Items:   []string{"Alexa Speaker", "Alexa Speaker", "Alexa Speaker"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 3, ProductName: "Alexa Speaker", Qty: 3, UnitPrice: money.MustParse("109.5"), Subtotal: money.MustParse("328.5"), Discount: money.MustParse("82.13"), Total: money.MustParse("246.37"), PromoID: 8, PromoType: "discount", Promos: []service.AppliedPromo{{PromoID: 8, PromoType: "discount"}}},
+				},
+				TotalAmount: money.MustParse("246.37"),
+				Currency:    "USD",
 			},
 			wantErr: false,
 		},
@@ -215,18 +478,17 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
 					PromoID:   2,
 					PromoType: "discount",
-					Reward:    4,
+					Reward:    money.MustParse("4"),
 					MinQty:    1,
-				}, nil)
-				productRepo.On("GetProductByProductID", context.TODO(), int64(2)).Return(repo.Product{
+				}}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
 					ProductID: 2,
 					Sku:       "43N23P",
 					Name:      "MacBook Pro",
-					Price:     5399.990,
+					Price:     money.MustParse("5399.990"),
 					Qty:       1,
 				}, nil)
 			},
@@ -259,9 +521,21 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("BeginTx").Return(mock.Anything, nil)
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
+					PromoID:   2,
+					PromoType: "discount",
+					Reward:    money.MustParse("10"),
+					MinQty:    2,
+				}}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
+					ProductID: 2,
+					Sku:       "43N23P",
+					Name:      "MacBook Pro",
+					Price:     money.MustParse("5399.990"),
+					Qty:       5,
+				}, nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), errors.New("error"))
-
 			},
 			expectedResp: service.Checkout{},
 			wantErr:      true,
@@ -279,39 +553,68 @@ func TestCheckout(t *testing.T) {
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
 				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				productRepo.On("GetProductByProductID", context.TODO(), int64(2)).Return(repo.Product{
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
 					ProductID: 2,
 					Sku:       "43N23P",
 					Name:      "MacBook Pro",
-					Price:     5399.990,
+					Price:     money.MustParse("5399.990"),
 					Qty:       5,
 				}, nil)
 				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
 					PromoID:   2,
 					PromoType: "product",
-					Reward:    4,
+					Reward:    money.MustParse("4"),
 					MinQty:    1,
-				}, nil)
+				}}, nil)
 
-				productRepo.On("GetProductByProductID", context.TODO(), int64(4)).Return(repo.Product{
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
 					ProductID: 4,
 					Sku:       "234234",
 					Name:      "Raspberry Pi B",
-					Price:     30.000,
+					Price:     money.MustParse("30.000"),
 					Qty:       2,
 				}, nil)
 				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error"))
 			},
 			expectedResp: service.Checkout{
 				Items:       []string{"MacBook Pro", "Raspberry Pi B"},
-				TotalAmount: 5399.99,
+				TotalAmount: money.MustParse("5399.99"),
+				Currency:    "USD",
 			},
 			wantErr: true,
 		},
 		{
-			name: "error while get promo",
+			name: "error while commit transaction",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 3,
+					Qty:       1,
+				},
+			},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(3)).Return(repo.Product{
+					ProductID: 3,
+					Sku:       "A304SD",
+					Name:      "Alexa Speaker",
+					Price:     money.MustParse("109.500"),
+					Qty:       10,
+				}, nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("109.5", "0", "109.5", 1)).Return(int64(1), nil)
+				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(errors.New("error"))
+			},
+			expectedResp: service.Checkout{},
+			wantErr:      true,
+		},
+		{
+			name: "error while get promos",
 			orderDetails: []repo.OrderDetail{
 				{
 					ProductID: 2,
@@ -322,19 +625,13 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
-					PromoID:   2,
-					PromoType: "product",
-					Reward:    4,
-					MinQty:    1,
-				}, errors.New("error"))
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return(nil, errors.New("error"))
 			},
 			expectedResp: service.Checkout{},
 			wantErr:      true,
 		},
 		{
-			name: "error while GetProductByProductID",
+			name: "error while GetProductByProductIDForUpdate",
 			orderDetails: []repo.OrderDetail{
 				{
 					ProductID: 2,
@@ -346,18 +643,17 @@ func TestCheckout(t *testing.T) {
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
 				orderRepo.On("BeginTx").Return(mock.Anything, nil)
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
 					PromoID:   2,
 					PromoType: "discount",
-					Reward:    4,
+					Reward:    money.MustParse("4"),
 					MinQty:    1,
-				}, nil)
-				productRepo.On("GetProductByProductID", context.TODO(), int64(2)).Return(repo.Product{
+				}}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
 					ProductID: 2,
 					Sku:       "43N23P",
 					Name:      "MacBook Pro",
-					Price:     5399.990,
+					Price:     money.MustParse("5399.990"),
 					Qty:       1,
 				}, errors.New("error"))
 
@@ -365,6 +661,57 @@ func TestCheckout(t *testing.T) {
 			expectedResp: service.Checkout{},
 			wantErr:      true,
 		},
+		{
+			name: "stock held by a reservation can't be checked out",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 2,
+					Qty:       2,
+				},
+			},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return(nil, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
+					ProductID:   2,
+					Sku:         "43N23P",
+					Name:        "MacBook Pro",
+					Price:       money.MustParse("5399.990"),
+					Qty:         5,
+					ReservedQty: 4,
+				}, nil)
+			},
+			expectedResp: service.Checkout{},
+			wantErr:      true,
+		},
+		{
+			name: "archived product can't be checked out",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 2,
+					Qty:       1,
+				},
+			},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				archivedAt := checkoutAt.Add(-time.Hour)
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return(nil, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
+					ProductID:  2,
+					Sku:        "43N23P",
+					Name:       "MacBook Pro",
+					Price:      money.MustParse("5399.990"),
+					Qty:        5,
+					ArchivedAt: &archivedAt,
+				}, nil)
+			},
+			expectedResp: service.Checkout{},
+			wantErr:      true,
+		},
 		{
 			name: "error while update product qty",
 			orderDetails: []repo.OrderDetail{
@@ -377,25 +724,24 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
 					PromoID:   2,
 					PromoType: "product",
-					Reward:    4,
+					Reward:    money.MustParse("4"),
 					MinQty:    1,
-				}, nil)
-				productRepo.On("GetProductByProductID", context.TODO(), int64(2)).Return(repo.Product{
+				}}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
 					ProductID: 2,
 					Sku:       "43N23P",
 					Name:      "MacBook Pro",
-					Price:     5399.990,
+					Price:     money.MustParse("5399.990"),
 					Qty:       5,
 				}, nil)
-				productRepo.On("GetProductByProductID", context.TODO(), int64(4)).Return(repo.Product{
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
 					ProductID: 4,
 					Sku:       "234234",
 					Name:      "Raspberry Pi B",
-					Price:     30.000,
+					Price:     money.MustParse("30.000"),
 					Qty:       2,
 				}, nil)
 				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error"))
@@ -403,10 +749,36 @@ func TestCheckout(t *testing.T) {
 			},
 			expectedResp: service.Checkout{
 				Items:       []string{"MacBook Pro", "Raspberry Pi B"},
-				TotalAmount: 5399.99,
+				TotalAmount: money.MustParse("5399.99"),
+				Currency:    "USD",
 			},
 			wantErr: true,
 		},
+		{
+			name: "stock taken by a concurrent checkout",
+			orderDetails: []repo.OrderDetail{
+				{
+					ProductID: 3,
+					Qty:       1,
+				},
+			},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(3)).Return(repo.Product{
+					ProductID: 3,
+					Sku:       "A304SD",
+					Name:      "Alexa Speaker",
+					Price:     money.MustParse("109.500"),
+					Qty:       1,
+				}, nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(repo.ErrInsufficientStock)
+			},
+			expectedResp: service.Checkout{},
+			wantErr:      true,
+		},
 		{
 			name: "get free product detail",
 			orderDetails: []repo.OrderDetail{
@@ -419,32 +791,32 @@ func TestCheckout(t *testing.T) {
 				orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
 				defer orderRepo.On("RollbackTx", mock.Anything).Return(nil)
 
-				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
-				promoRepo.On("GetPromoByProductID", mock.Anything, mock.Anything).Return(repo.Promo{
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, mock.Anything, checkoutAt).Return([]repo.Promo{{
 					PromoID:   2,
 					PromoType: "product",
-					Reward:    4,
+					Reward:    money.MustParse("4"),
 					MinQty:    1,
-				}, nil)
-				productRepo.On("GetProductByProductID", context.TODO(), int64(2)).Return(repo.Product{
+				}}, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(2)).Return(repo.Product{
 					ProductID: 2,
 					Sku:       "43N23P",
 					Name:      "MacBook Pro",
-					Price:     5399.990,
+					Price:     money.MustParse("5399.990"),
 					Qty:       5,
 				}, nil)
-				productRepo.On("GetProductByProductID", context.TODO(), int64(4)).Return(repo.Product{
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, context.Background(), int64(4)).Return(repo.Product{
 					ProductID: 4,
 					Sku:       "234234",
 					Name:      "Raspberry Pi B",
-					Price:     30.000,
+					Price:     money.MustParse("30.000"),
 					Qty:       2,
 				}, errors.New("error"))
 
 			},
 			expectedResp: service.Checkout{
 				Items:       []string{"MacBook Pro", "Raspberry Pi B"},
-				TotalAmount: 5399.99,
+				TotalAmount: money.MustParse("5399.99"),
+				Currency:    "USD",
 			},
 			wantErr: true,
 		},
@@ -455,15 +827,24 @@ func TestCheckout(t *testing.T) {
 			orderRepo := new(mockRepo.OrderRepository)
 			productRepo := new(mockRepo.ProductRepository)
 			promoRepo := new(mockRepo.PromoRepository)
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
 
 			if tt.mockSetupFunc != nil {
 				tt.mockSetupFunc(orderRepo, productRepo, promoRepo)
 			}
+			stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
 
 			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
-				OrderRepo:   orderRepo,
-				ProductRepo: productRepo,
-				PromoRepo:   promoRepo,
+				OrderPromoRepo:    noOrderPromos(),
+				BundleRepo:        noBundles(),
+				OrderRepo:         orderRepo,
+				ProductRepo:       productRepo,
+				PromoRepo:         promoRepo,
+				StockMovementRepo: stockMovementRepo,
+				Currency:          tt.currency,
+				StockPolicy:       tt.stockPolicy,
+				Clock:             clock.Fixed(checkoutAt),
 			})
 
 			res, err := checkoutUsecase.Checkout(context.Background(), tt.orderDetails)
@@ -480,3 +861,109 @@ func TestCheckout(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckoutStockMovements(t *testing.T) {
+	setup := func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+		orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+		orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+		orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(7), nil)
+		orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, int64(2), checkoutAt).Return([]repo.Promo{{
+			PromoID:   2,
+			PromoType: "product",
+			Reward:    money.MustParse("4"),
+			MinQty:    1,
+		}}, nil)
+		productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(2)).Return(repo.Product{
+			ProductID: 2, Sku: "43N23P", Name: "MacBook Pro", Price: money.MustParse("5399.990"), Qty: 5,
+		}, nil)
+		productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(4)).Return(repo.Product{
+			ProductID: 4, Sku: "234234", Name: "Raspberry Pi B", Price: money.MustParse("30.000"), Qty: 2,
+		}, nil)
+		productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
+
+	t.Run("sale and reward are recorded with the actor", func(t *testing.T) {
+		orderRepo := new(mockRepo.OrderRepository)
+		productRepo := new(mockRepo.ProductRepository)
+		promoRepo := new(mockRepo.PromoRepository)
+		stockMovementRepo := new(mockRepo.StockMovementRepository)
+
+		setup(orderRepo, productRepo, promoRepo)
+		orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, repo.OrderStatusChange{
+			OrderID: 7, ToStatus: service.OrderStatusPendingPayment, Actor: "shop-web", CreatedAt: checkoutAt,
+		}).Return(nil)
+		orderRepo.On("CommitTx", mock.Anything).Return(nil)
+		stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, []repo.StockMovement{
+			{ProductID: 2, Delta: -2, Reason: service.StockReasonSale, OrderID: 7, Actor: "shop-web", CreatedAt: checkoutAt},
+			{ProductID: 4, Delta: -1, Reason: service.StockReasonReward, OrderID: 7, Actor: "shop-web", CreatedAt: checkoutAt},
+		}).Return(nil)
+
+		checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+			OrderPromoRepo:    noOrderPromos(),
+			BundleRepo:        noBundles(),
+			OrderRepo:         orderRepo,
+			ProductRepo:       productRepo,
+			PromoRepo:         promoRepo,
+			StockMovementRepo: stockMovementRepo,
+			Clock:             clock.Fixed(checkoutAt),
+		})
+
+		ctx := service.WithActor(context.Background(), "shop-web")
+		_, err := checkoutUsecase.Checkout(ctx, []repo.OrderDetail{{ProductID: 2, Qty: 2}})
+		assert.NoError(t, err)
+
+		stockMovementRepo.AssertExpectations(t)
+		orderRepo.AssertExpectations(t)
+	})
+
+	t.Run("ledger error rolls the checkout back", func(t *testing.T) {
+		orderRepo := new(mockRepo.OrderRepository)
+		productRepo := new(mockRepo.ProductRepository)
+		promoRepo := new(mockRepo.PromoRepository)
+		stockMovementRepo := new(mockRepo.StockMovementRepository)
+
+		setup(orderRepo, productRepo, promoRepo)
+		orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error"))
+
+		checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+			OrderPromoRepo:    noOrderPromos(),
+			BundleRepo:        noBundles(),
+			OrderRepo:         orderRepo,
+			ProductRepo:       productRepo,
+			PromoRepo:         promoRepo,
+			StockMovementRepo: stockMovementRepo,
+			Clock:             clock.Fixed(checkoutAt),
+		})
+
+		_, err := checkoutUsecase.Checkout(context.Background(), []repo.OrderDetail{{ProductID: 2, Qty: 2}})
+		assert.Error(t, err)
+
+		orderRepo.AssertNotCalled(t, "CommitTx", mock.Anything)
+		orderRepo.AssertCalled(t, "RollbackTx", mock.Anything)
+	})
+}
+
+func orderTotals(subtotal, discountTotal, total string, itemCount int64) interface{} {
+	return mock.MatchedBy(func(o repo.Order) bool {
+		return o.Date.Equal(checkoutAt) && o.Subtotal == money.MustParse(subtotal) && o.DiscountTotal == money.MustParse(discountTotal) && o.Total == money.MustParse(total) && o.ItemCount == itemCount
+	})
+}
+
+// noOrderPromos is an order promo repository with no order promo running.
+func noOrderPromos() *mockRepo.OrderPromoRepository {
+	orderPromoRepo := new(mockRepo.OrderPromoRepository)
+	orderPromoRepo.On("GetOrderPromos", mock.Anything, mock.Anything).Return([]repo.OrderPromo{}, nil).Maybe()
+	orderPromoRepo.On("GetOrderPromosTx", mock.Anything, mock.Anything, mock.Anything).Return([]repo.OrderPromo{}, nil).Maybe()
+	return orderPromoRepo
+}
+
+// noBundles is a bundle repository with no bundle running.
+func noBundles() *mockRepo.BundleRepository {
+	bundleRepo := new(mockRepo.BundleRepository)
+	bundleRepo.On("GetBundles", mock.Anything, mock.Anything).Return([]repo.Bundle{}, nil).Maybe()
+	bundleRepo.On("GetBundlesTx", mock.Anything, mock.Anything, mock.Anything).Return([]repo.Bundle{}, nil).Maybe()
+	return bundleRepo
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"go.uber.org/dig"
+)
+
+// Types of coupon, each reading its reward differently.
+const (
+	// CouponTypePercent takes Reward percent off the order.
+	CouponTypePercent = "percent"
+	// CouponTypeFixed takes the Reward amount off the order.
+	CouponTypeFixed = "fixed"
+	// CouponTypeProduct gives one unit of the product Reward away.
+	CouponTypeProduct = "product"
+)
+
+// Codes of a coupon that can't be redeemed, stable for clients to match on.
+const (
+	CouponErrNotFound  apperr.Code = "COUPON_NOT_FOUND"
+	CouponErrInactive  apperr.Code = "COUPON_INACTIVE"
+	CouponErrExpired   apperr.Code = "COUPON_EXPIRED"
+	CouponErrExhausted apperr.Code = "COUPON_EXHAUSTED"
+	CouponErrMinBasket apperr.Code = "COUPON_MIN_BASKET"
+)
+
+var (
+	// ErrInvalidCoupon wraps every validation failure of a coupon write.
+	ErrInvalidCoupon = apperr.New(apperr.CodeInvalidInput, "invalid coupon")
+	// ErrCouponNotFound is returned when a write targets an unknown coupon.
+	ErrCouponNotFound = apperr.New(apperr.CodeNotFound, "coupon not found")
+)
+
+// couponCodePattern is what a code looks like once it's upper cased.
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{2,31}$`)
+
+type (
+	CouponUsecase interface {
+		CreateCoupon(ctx context.Context, form repo.Coupon) (res repo.Coupon, err error)
+		GetCouponByCode(ctx context.Context, code string) (res repo.Coupon, err error)
+		DeactivateCoupon(ctx context.Context, code string) (res repo.Coupon, err error)
+	}
+
+	CouponUsecaseImpl struct {
+		dig.In
+		CouponRepo  repo.CouponRepository
+		ProductRepo repo.ProductRepository
+		Clock       clock.Clock `optional:"true"`
+	}
+)
+
+func NewCouponUsecase(impl CouponUsecaseImpl) CouponUsecase {
+	return &impl
+}
+
+// NormalizeCouponCode is how codes are stored and looked up, so customers
+// can type them in any case.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (c *CouponUsecaseImpl) CreateCoupon(ctx context.Context, form repo.Coupon) (res repo.Coupon, err error) {
+	form.Code = NormalizeCouponCode(form.Code)
+
+	err = c.validateCoupon(ctx, form)
+	if err != nil {
+		return res, err
+	}
+
+	form.CreatedAt = c.now()
+	form.CouponID, err = c.CouponRepo.CreateCoupon(ctx, form)
+	if err != nil {
+		log.Printf("error while do CreateCoupon %+v", err)
+		return res, err
+	}
+
+	return form, nil
+}
+
+// GetCouponByCode returns a zero coupon when no coupon has the code.
+func (c *CouponUsecaseImpl) GetCouponByCode(ctx context.Context, code string) (res repo.Coupon, err error) {
+	res, err = c.CouponRepo.GetCouponByCode(ctx, NormalizeCouponCode(code))
+	if err != nil {
+		log.Printf("error while do GetCouponByCode %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+// DeactivateCoupon stops the coupon from being redeemed but keeps it, so its
+// redemptions still point at a real row.
+func (c *CouponUsecaseImpl) DeactivateCoupon(ctx context.Context, code string) (res repo.Coupon, err error) {
+	res, err = c.GetCouponByCode(ctx, code)
+	if err != nil {
+		return res, err
+	}
+
+	if res.CouponID == 0 {
+		return res, fmt.Errorf("%w: %s", ErrCouponNotFound, NormalizeCouponCode(code))
+	}
+
+	err = c.CouponRepo.DeactivateCoupon(ctx, res.CouponID)
+	if err != nil {
+		log.Printf("error while do DeactivateCoupon %+v", err)
+		return res, err
+	}
+
+	res.Active = false
+
+	return res, nil
+}
+
+func (c *CouponUsecaseImpl) validateCoupon(ctx context.Context, form repo.Coupon) error {
+	if !couponCodePattern.MatchString(form.Code) {
+		return fmt.Errorf("%w: code must be 3 to 32 letters, digits, dashes or underscores", ErrInvalidCoupon)
+	}
+
+	if form.MinBasket.IsNegative() {
+		return fmt.Errorf("%w: min_basket can't be negative", ErrInvalidCoupon)
+	}
+
+	if form.UsageLimit < 0 {
+		return fmt.Errorf("%w: usage_limit can't be negative", ErrInvalidCoupon)
+	}
+
+	switch form.CouponType {
+	case CouponTypePercent:
+		if form.Reward.IsNegative() || form.Reward.IsZero() || form.Reward.Cmp(money.NewFromInt(100)) > 0 {
+			return fmt.Errorf("%w: percent reward must be above 0 and at most 100", ErrInvalidCoupon)
+		}
+	case CouponTypeFixed:
+		if form.Reward.IsNegative() || form.Reward.IsZero() {
+			return fmt.Errorf("%w: fixed reward must be above 0", ErrInvalidCoupon)
+		}
+	case CouponTypeProduct:
+		if form.Reward.Cmp(money.NewFromInt(form.Reward.IntPart())) != 0 {
+			return fmt.Errorf("%w: product reward must be a product id", ErrInvalidCoupon)
+		}
+
+		product, err := c.ProductRepo.GetProductByProductID(ctx, form.Reward.IntPart())
+		if err != nil {
+			log.Printf("error while do GetProductByProductID %+v", err)
+			return err
+		}
+
+		if product.ProductID == 0 || product.ArchivedAt != nil {
+			return fmt.Errorf("%w: product %d doesn't exist", ErrInvalidCoupon, form.Reward.IntPart())
+		}
+	default:
+		return fmt.Errorf("%w: unknown coupon_type %q", ErrInvalidCoupon, form.CouponType)
+	}
+
+	return nil
+}
+
+func (c *CouponUsecaseImpl) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Clock.Now()
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+)
+
+// MaxIdempotencyKeyLength is the longest key a client can send.
+const MaxIdempotencyKeyLength = 255
+
+var (
+	// ErrInvalidIdempotencyKey is returned for a key that can't be stored.
+	ErrInvalidIdempotencyKey = apperr.New(apperr.CodeInvalidInput, "invalid idempotency key")
+	// ErrIdempotencyKeyReused is returned when a key comes back with another
+	// request than the one it was first used with.
+	ErrIdempotencyKeyReused = apperr.New(apperr.CodeConflict, "idempotency key reused with a different request")
+)
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey tags ctx with the client's idempotency key for the
+// request.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFrom returns the idempotency key ctx was tagged with, if any.
+func IdempotencyKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
+}
+
+// checkoutRequestHash fingerprints the items of a checkout, in the order they
+// were sent, with its coupons. A checkout without coupons hashes as it did
+// before coupons existed.
+func checkoutRequestHash(form []repo.OrderDetail, couponCodes []string) string {
+	h := sha256.New()
+	for _, v := range form {
+		fmt.Fprintf(h, "%d:%d;", v.ProductID, v.Qty)
+	}
+
+	for _, code := range couponCodes {
+		fmt.Fprintf(h, "coupon:%s;", code)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claimIdempotencyKey claims key for the request fingerprinted by hash inside
+// tx. When the key was already used for the same request, replay is true and
+// res holds the response stored for it.
+func (c *CheckoutUsecaseImpl) claimIdempotencyKey(tx *sqlx.Tx, ctx context.Context, key string, hash string, now time.Time) (res Checkout, replay bool, err error) {
+	if len(key) > MaxIdempotencyKeyLength {
+		return res, false, fmt.Errorf("%w: longer than %d characters", ErrInvalidIdempotencyKey, MaxIdempotencyKeyLength)
+	}
+
+	created, err := c.IdempotencyRepo.CreateIdempotencyKey(tx, ctx, repo.IdempotencyKey{
+		Key:         key,
+		RequestHash: hash,
+		CreatedAt:   now,
+	})
+	if err != nil {
+		log.Printf("error while do CreateIdempotencyKey %+v", err)
+		return res, false, err
+	}
+
+	if created {
+		return res, false, nil
+	}
+
+	stored, err := c.IdempotencyRepo.GetIdempotencyKey(tx, ctx, key)
+	if err != nil {
+		log.Printf("error while do GetIdempotencyKey %+v", err)
+		return res, false, err
+	}
+
+	if stored.RequestHash != hash {
+		return res, false, ErrIdempotencyKeyReused
+	}
+
+	err = json.Unmarshal(stored.Response, &res)
+	if err != nil {
+		log.Printf("error while do Unmarshal %+v", err)
+		return res, false, err
+	}
+
+	return res, true, nil
+}
+
+// storeIdempotentResponse keeps res as the answer to every retry with key.
+func (c *CheckoutUsecaseImpl) storeIdempotentResponse(tx *sqlx.Tx, ctx context.Context, key string, res Checkout) error {
+	response, err := json.Marshal(res)
+	if err != nil {
+		log.Printf("error while do Marshal %+v", err)
+		return err
+	}
+
+	err = c.IdempotencyRepo.UpdateIdempotencyKeyResponse(tx, ctx, repo.IdempotencyKey{
+		Key:      key,
+		Response: response,
+		OrderID:  res.OrderID,
+	})
+	if err != nil {
+		log.Printf("error while do UpdateIdempotencyKeyResponse %+v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckoutIdempotencyKey(t *testing.T) {
+	type repos struct {
+		order       *mockRepo.OrderRepository
+		product     *mockRepo.ProductRepository
+		promo       *mockRepo.PromoRepository
+		idempotency *mockRepo.IdempotencyRepository
+	}
+
+	newCheckoutUsecase := func() (service.CheckoutUsecase, repos) {
+		r := repos{
+			order:       new(mockRepo.OrderRepository),
+			product:     new(mockRepo.ProductRepository),
+			promo:       new(mockRepo.PromoRepository),
+			idempotency: new(mockRepo.IdempotencyRepository),
+		}
+		stockMovementRepo := new(mockRepo.StockMovementRepository)
+		stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+		r.order.On("BeginTx").Return(&sqlx.Tx{}, nil)
+		r.order.On("RollbackTx", mock.Anything).Return(nil)
+
+		return service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+			OrderPromoRepo:    noOrderPromos(),
+			BundleRepo:        noBundles(),
+			OrderRepo:         r.order,
+			ProductRepo:       r.product,
+			PromoRepo:         r.promo,
+			StockMovementRepo: stockMovementRepo,
+			IdempotencyRepo:   r.idempotency,
+			Clock:             clock.Fixed(checkoutAt),
+		}), r
+	}
+
+	// one alexa speaker, without promo
+	expectCheckout := func(r repos) {
+		r.promo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, int64(3), checkoutAt).Return([]repo.Promo{}, nil)
+		r.product.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(3)).Return(repo.Product{
+			ProductID: 3, Sku: "A304SD", Name: "Alexa Speaker", Price: money.MustParse("109.500"), Qty: 10,
+		}, nil)
+		r.product.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		r.order.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("109.5", "0", "109.5", 1)).Return(int64(11), nil)
+		r.order.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		r.order.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
+
+	items := []repo.OrderDetail{{ProductID: 3, Qty: 1}}
+	ctx := service.WithIdempotencyKey(context.Background(), "retry-1")
+
+	t.Run("a retry gets the first response back", func(t *testing.T) {
+		checkoutUsecase, r := newCheckoutUsecase()
+		expectCheckout(r)
+		r.order.On("CommitTx", mock.Anything).Return(nil).Once()
+
+		var stored repo.IdempotencyKey
+		r.idempotency.On("CreateIdempotencyKey", mock.Anything, mock.Anything, mock.Anything).Return(true, nil).Once().
+			Run(func(args mock.Arguments) { stored = args.Get(2).(repo.IdempotencyKey) })
+		r.idempotency.On("UpdateIdempotencyKeyResponse", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once().
+			Run(func(args mock.Arguments) {
+				form := args.Get(2).(repo.IdempotencyKey)
+				stored.Response = form.Response
+				stored.OrderID = form.OrderID
+			})
+
+		first, err := checkoutUsecase.Checkout(ctx, items)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), first.OrderID)
+		assert.Equal(t, "retry-1", stored.Key)
+		assert.Equal(t, int64(11), stored.OrderID)
+
+		r.idempotency.On("CreateIdempotencyKey", mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Once()
+		r.idempotency.On("GetIdempotencyKey", mock.Anything, mock.Anything, "retry-1").Return(stored, nil).Once()
+
+		retry, err := checkoutUsecase.Checkout(ctx, items)
+		assert.NoError(t, err)
+		assert.Equal(t, first, retry)
+
+		// the retry neither took stock nor wrote an order
+		r.product.AssertNumberOfCalls(t, "UpdateProductQtyByProductID", 1)
+		r.order.AssertNumberOfCalls(t, "CreateOrder", 1)
+		r.order.AssertNumberOfCalls(t, "CommitTx", 1)
+		r.idempotency.AssertExpectations(t)
+	})
+
+	t.Run("a key reused with other items is rejected", func(t *testing.T) {
+		checkoutUsecase, r := newCheckoutUsecase()
+		r.idempotency.On("CreateIdempotencyKey", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		r.idempotency.On("GetIdempotencyKey", mock.Anything, mock.Anything, "retry-1").Return(repo.IdempotencyKey{
+			Key: "retry-1", RequestHash: "another request", Response: []byte(`{"order_id": 11}`),
+		}, nil)
+
+		_, err := checkoutUsecase.Checkout(ctx, items)
+		assert.ErrorIs(t, err, service.ErrIdempotencyKeyReused)
+
+		r.order.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("a failed checkout doesn't keep the key", func(t *testing.T) {
+		checkoutUsecase, r := newCheckoutUsecase()
+		expectCheckout(r)
+		r.idempotency.On("CreateIdempotencyKey", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		r.idempotency.On("UpdateIdempotencyKeyResponse", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error"))
+
+		_, err := checkoutUsecase.Checkout(ctx, items)
+		assert.EqualError(t, err, "error")
+
+		r.order.AssertNotCalled(t, "CommitTx", mock.Anything)
+		r.order.AssertCalled(t, "RollbackTx", mock.Anything)
+	})
+
+	t.Run("a key too long is rejected", func(t *testing.T) {
+		checkoutUsecase, r := newCheckoutUsecase()
+
+		_, err := checkoutUsecase.Checkout(service.WithIdempotencyKey(context.Background(), strings.Repeat("k", 256)), items)
+		assert.ErrorIs(t, err, service.ErrInvalidIdempotencyKey)
+
+		r.idempotency.AssertNotCalled(t, "CreateIdempotencyKey", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("a cart checkout runs in the cart's transaction without the client's key", func(t *testing.T) {
+		checkoutUsecase, r := newCheckoutUsecase()
+		expectCheckout(r)
+
+		cartRepo := new(mockRepo.CartRepository)
+		cartRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+		cartRepo.On("RollbackTx", mock.Anything).Return(nil)
+		cartRepo.On("GetCartByCartIDForUpdate", mock.Anything, mock.Anything, int64(5)).Return(repo.Cart{
+			CartID: 5, Status: repo.CartStatusOpen, CreatedAt: checkoutAt, UpdatedAt: checkoutAt,
+		}, nil)
+		cartRepo.On("GetCartItemsByCartIDTx", mock.Anything, mock.Anything, int64(5)).Return([]repo.CartItem{{CartID: 5, ProductID: 3, Qty: 1}}, nil)
+		cartRepo.On("UpdateCart", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		cartRepo.On("CommitTx", mock.Anything).Return(nil)
+
+		cartUsecase := service.NewCartUsecase(service.CartUsecaseImpl{
+			CartRepo:    cartRepo,
+			CheckoutSvc: checkoutUsecase,
+			Clock:       clock.Fixed(checkoutAt),
+		})
+
+		res, err := cartUsecase.CheckoutCart(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), res.OrderID)
+		cartRepo.AssertCalled(t, "CommitTx", mock.Anything)
+		r.order.AssertNotCalled(t, "BeginTx")
+		r.order.AssertNotCalled(t, "CommitTx", mock.Anything)
+		r.idempotency.AssertNotCalled(t, "CreateIdempotencyKey", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
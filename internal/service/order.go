@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/cursor"
+	"github.com/learn/api-shop/pkg/money"
+	"go.uber.org/dig"
+)
+
+type (
+	OrderQuery struct {
+		From  time.Time
+		To    time.Time
+		First int
+		After string
+	}
+
+	OrderEdge struct {
+		Cursor string     `json:"cursor"`
+		Node   repo.Order `json:"node"`
+	}
+
+	OrderPage struct {
+		Edges    []OrderEdge `json:"edges"`
+		PageInfo PageInfo    `json:"page_info"`
+	}
+
+	OrderUsecase interface {
+		GetOrderByOrderID(ctx context.Context, orderID int64) (res repo.Order, err error)
+		GetOrders(ctx context.Context, form OrderQuery) (res OrderPage, err error)
+		GetOrderLinesByOrderID(ctx context.Context, orderID int64) (res []repo.OrderLine, err error)
+		GetOrderDiscountsByOrderID(ctx context.Context, orderID int64) (res []repo.OrderDiscount, err error)
+		CancelOrder(ctx context.Context, orderID int64, reason string) (res repo.Order, err error)
+		RefundOrder(ctx context.Context, form OrderRefundRequest) (res repo.Order, err error)
+	}
+
+	OrderUsecaseImpl struct {
+		dig.In
+		OrderRepo         repo.OrderRepository
+		ProductRepo       repo.ProductRepository
+		StockMovementRepo repo.StockMovementRepository
+		Currency          money.Currency `optional:"true"`
+		Clock             clock.Clock    `optional:"true"`
+	}
+)
+
+func NewOrderUsecase(impl OrderUsecaseImpl) OrderUsecase {
+	return &impl
+}
+
+func (o *OrderUsecaseImpl) GetOrderByOrderID(ctx context.Context, orderID int64) (res repo.Order, err error) {
+	res, err = o.OrderRepo.GetOrderByOrderID(ctx, orderID)
+	if err != nil {
+		log.Printf("error while do GetOrderByOrderID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (o *OrderUsecaseImpl) GetOrders(ctx context.Context, form OrderQuery) (res OrderPage, err error) {
+	filter := repo.OrderFilter{
+		From:  form.From,
+		To:    form.To,
+		Limit: pageSize(form.First) + 1,
+	}
+
+	if form.After != "" {
+		_, filter.AfterID, err = cursor.Decode(form.After)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	orders, err := o.OrderRepo.GetOrders(ctx, filter)
+	if err != nil {
+		log.Printf("error while do GetOrders %+v", err)
+		return res, err
+	}
+
+	if len(orders) > pageSize(form.First) {
+		orders = orders[:pageSize(form.First)]
+		res.PageInfo.HasNextPage = true
+	}
+
+	res.Edges = make([]OrderEdge, len(orders))
+	for i, v := range orders {
+		res.Edges[i] = OrderEdge{
+			Cursor: cursor.Encode("", v.OrderID),
+			Node:   v,
+		}
+	}
+
+	if len(res.Edges) > 0 {
+		res.PageInfo.EndCursor = res.Edges[len(res.Edges)-1].Cursor
+	}
+
+	return res, nil
+}
+
+func (o *OrderUsecaseImpl) GetOrderLinesByOrderID(ctx context.Context, orderID int64) (res []repo.OrderLine, err error) {
+	res, err = o.OrderRepo.GetOrderLinesByOrderID(ctx, orderID)
+	if err != nil {
+		log.Printf("error while do GetOrderLinesByOrderID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+// GetOrderDiscountsByOrderID returns what the order promos took off the order.
+func (o *OrderUsecaseImpl) GetOrderDiscountsByOrderID(ctx context.Context, orderID int64) (res []repo.OrderDiscount, err error) {
+	res, err = o.OrderRepo.GetOrderDiscountsByOrderID(ctx, orderID)
+	if err != nil {
+		log.Printf("error while do GetOrderDiscountsByOrderID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
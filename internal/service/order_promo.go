@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/clock"
+	"go.uber.org/dig"
+)
+
+// Types of order promo, each reading its reward differently.
+const (
+	// OrderPromoTypeDiscount takes Reward percent off the order.
+	OrderPromoTypeDiscount = "discount"
+	// OrderPromoTypeAmount takes the Reward amount off the order.
+	OrderPromoTypeAmount = "amount"
+	// OrderPromoTypeGift gives one unit of the product Reward away.
+	OrderPromoTypeGift = "gift"
+)
+
+type (
+	OrderPromoUsecase interface {
+		GetOrderPromos(ctx context.Context) (res []repo.OrderPromo, err error)
+		GetOrderPromoByOrderPromoID(ctx context.Context, orderPromoID int64) (res repo.OrderPromo, err error)
+		CreateOrderPromo(ctx context.Context, form repo.OrderPromo) (res repo.OrderPromo, err error)
+		DeactivateOrderPromo(ctx context.Context, orderPromoID int64) (res repo.OrderPromo, err error)
+	}
+
+	OrderPromoUsecaseImpl struct {
+		dig.In
+		ProductRepo    repo.ProductRepository
+		OrderPromoRepo repo.OrderPromoRepository
+		Clock          clock.Clock        `optional:"true"`
+		Promotions     *PromotionRegistry `optional:"true"`
+	}
+)
+
+func NewOrderPromoUsecase(impl OrderPromoUsecaseImpl) OrderPromoUsecase {
+	return &impl
+}
+
+// GetOrderPromos returns the order promos running right now.
+func (c *OrderPromoUsecaseImpl) GetOrderPromos(ctx context.Context) (res []repo.OrderPromo, err error) {
+	res, err = c.OrderPromoRepo.GetOrderPromos(ctx, c.now())
+	if err != nil {
+		log.Printf("error while do GetOrderPromos %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *OrderPromoUsecaseImpl) GetOrderPromoByOrderPromoID(ctx context.Context, orderPromoID int64) (res repo.OrderPromo, err error) {
+	res, err = c.OrderPromoRepo.GetOrderPromoByOrderPromoID(ctx, orderPromoID)
+	if err != nil {
+		log.Printf("error while do GetOrderPromoByOrderPromoID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *OrderPromoUsecaseImpl) CreateOrderPromo(ctx context.Context, form repo.OrderPromo) (res repo.OrderPromo, err error) {
+	err = c.validateOrderPromo(ctx, form)
+	if err != nil {
+		return res, err
+	}
+
+	form.OrderPromoID, err = c.OrderPromoRepo.CreateOrderPromo(ctx, form)
+	if err != nil {
+		log.Printf("error while do CreateOrderPromo %+v", err)
+		return res, err
+	}
+
+	return form, nil
+}
+
+// DeactivateOrderPromo switches the order promo off but keeps it, so orders
+// that used it still point at a real row.
+func (c *OrderPromoUsecaseImpl) DeactivateOrderPromo(ctx context.Context, orderPromoID int64) (res repo.OrderPromo, err error) {
+	res, err = c.GetOrderPromoByOrderPromoID(ctx, orderPromoID)
+	if err != nil {
+		return res, err
+	}
+
+	if res.OrderPromoID == 0 {
+		return res, fmt.Errorf("%w: %d", ErrPromoNotFound, orderPromoID)
+	}
+
+	err = c.OrderPromoRepo.DeactivateOrderPromo(ctx, orderPromoID)
+	if err != nil {
+		log.Printf("error while do DeactivateOrderPromo %+v", err)
+		return res, err
+	}
+
+	res.Active = false
+
+	return res, nil
+}
+
+func (c *OrderPromoUsecaseImpl) validateOrderPromo(ctx context.Context, form repo.OrderPromo) error {
+	if form.MinSubtotal.IsNegative() || form.MinItems < 0 {
+		return fmt.Errorf("%w: thresholds can't be negative", ErrInvalidPromo)
+	}
+
+	// without a threshold the promo would be a discount on every order
+	if form.MinSubtotal.IsZero() && form.MinItems == 0 {
+		return fmt.Errorf("%w: min_subtotal or min_items must be set", ErrInvalidPromo)
+	}
+
+	kind, ok := c.promotions().OrderKind(form.PromoType)
+	if !ok {
+		return fmt.Errorf("%w: unknown order promo_type %q", ErrInvalidPromo, form.PromoType)
+	}
+
+	err := kind.Validate(ctx, form, promoCheck{ProductRepo: c.ProductRepo})
+	if err != nil {
+		return err
+	}
+
+	return validatePromoWindow(form.StartsAt, form.EndsAt, form.Timezone)
+}
+
+func (c *OrderPromoUsecaseImpl) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Clock.Now()
+}
+
+func (c *OrderPromoUsecaseImpl) promotions() *PromotionRegistry {
+	if c.Promotions == nil {
+		return builtinPromotions
+	}
+
+	return c.Promotions
+}
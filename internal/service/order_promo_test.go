@@ -0,0 +1,174 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOrderPromoCreateOrderPromo(t *testing.T) {
+	archivedAt := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	startsAt := time.Date(2023, 6, 10, 0, 0, 0, 0, time.UTC)
+	endsAt := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		form          repo.OrderPromo
+		mockSetupFunc func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository)
+		expectedResp  repo.OrderPromo
+		expectedErr   error
+	}{
+		{
+			name: "discount over a subtotal",
+			form: repo.OrderPromo{PromoType: "discount", Reward: money.MustParse("10"), MinSubtotal: money.MustParse("200"), Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {
+				orderPromoRepo.On("CreateOrderPromo", mock.Anything, mock.Anything).Return(int64(1), nil)
+			},
+			expectedResp: repo.OrderPromo{OrderPromoID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinSubtotal: money.MustParse("200"), Active: true},
+		},
+		{
+			name: "gift over an item count",
+			form: repo.OrderPromo{PromoType: "gift", Reward: money.MustParse("4"), MinItems: 5, Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(4)).Return(raspberryPi, nil)
+				orderPromoRepo.On("CreateOrderPromo", mock.Anything, mock.Anything).Return(int64(2), nil)
+			},
+			expectedResp: repo.OrderPromo{OrderPromoID: 2, PromoType: "gift", Reward: money.MustParse("4"), MinItems: 5, Active: true},
+		},
+		{
+			name:          "no threshold",
+			form:          repo.OrderPromo{PromoType: "amount", Reward: money.MustParse("10")},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name:          "negative threshold",
+			form:          repo.OrderPromo{PromoType: "amount", Reward: money.MustParse("10"), MinSubtotal: money.MustParse("-1"), MinItems: 2},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name:          "discount above 100",
+			form:          repo.OrderPromo{PromoType: "discount", Reward: money.MustParse("100.5"), MinItems: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name:          "amount of zero",
+			form:          repo.OrderPromo{PromoType: "amount", MinItems: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name:          "gift reward is not a product id",
+			form:          repo.OrderPromo{PromoType: "gift", Reward: money.MustParse("4.5"), MinItems: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name: "gift reward references an archived product",
+			form: repo.OrderPromo{PromoType: "gift", Reward: money.MustParse("4"), MinItems: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {
+				archived := raspberryPi
+				archived.ArchivedAt = &archivedAt
+				productRepo.On("GetProductByProductID", mock.Anything, int64(4)).Return(archived, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name:          "unknown promo type",
+			form:          repo.OrderPromo{PromoType: "cashback", Reward: money.MustParse("10"), MinItems: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name:          "window ends before it starts",
+			form:          repo.OrderPromo{PromoType: "amount", Reward: money.MustParse("10"), MinItems: 1, StartsAt: &startsAt, EndsAt: &endsAt},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name: "repository error",
+			form: repo.OrderPromo{PromoType: "amount", Reward: money.MustParse("10"), MinItems: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, orderPromoRepo *mockRepo.OrderPromoRepository) {
+				orderPromoRepo.On("CreateOrderPromo", mock.Anything, mock.Anything).Return(int64(0), errors.New("error"))
+			},
+			expectedErr: errors.New("error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := new(mockRepo.ProductRepository)
+			orderPromoRepo := new(mockRepo.OrderPromoRepository)
+			tt.mockSetupFunc(productRepo, orderPromoRepo)
+
+			orderPromoUsecase := service.NewOrderPromoUsecase(service.OrderPromoUsecaseImpl{
+				ProductRepo:    productRepo,
+				OrderPromoRepo: orderPromoRepo,
+			})
+
+			res, err := orderPromoUsecase.CreateOrderPromo(context.Background(), tt.form)
+			if tt.expectedErr != nil {
+				if errors.Is(tt.expectedErr, service.ErrInvalidPromo) {
+					assert.ErrorIs(t, err, service.ErrInvalidPromo)
+				} else {
+					assert.EqualError(t, err, tt.expectedErr.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			productRepo.AssertExpectations(t)
+			orderPromoRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderPromoWrites(t *testing.T) {
+	promo := repo.OrderPromo{OrderPromoID: 3, PromoType: "amount", Reward: money.MustParse("10"), MinItems: 3, Active: true}
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	orderPromoRepo := new(mockRepo.OrderPromoRepository)
+	orderPromoRepo.On("GetOrderPromos", mock.Anything, now).Return([]repo.OrderPromo{promo}, nil)
+	orderPromoRepo.On("GetOrderPromoByOrderPromoID", mock.Anything, int64(3)).Return(promo, nil)
+	orderPromoRepo.On("GetOrderPromoByOrderPromoID", mock.Anything, int64(9)).Return(repo.OrderPromo{}, nil)
+	orderPromoRepo.On("DeactivateOrderPromo", mock.Anything, int64(3)).Return(nil)
+
+	orderPromoUsecase := service.NewOrderPromoUsecase(service.OrderPromoUsecaseImpl{
+		ProductRepo:    new(mockRepo.ProductRepository),
+		OrderPromoRepo: orderPromoRepo,
+		Clock:          clock.Fixed(now),
+	})
+
+	ctx := context.Background()
+
+	t.Run("lists the order promos running now", func(t *testing.T) {
+		res, err := orderPromoUsecase.GetOrderPromos(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []repo.OrderPromo{promo}, res)
+	})
+
+	t.Run("deactivate keeps the order promo", func(t *testing.T) {
+		res, err := orderPromoUsecase.DeactivateOrderPromo(ctx, 3)
+		assert.NoError(t, err)
+		assert.False(t, res.Active)
+		orderPromoRepo.AssertCalled(t, "DeactivateOrderPromo", mock.Anything, int64(3))
+	})
+
+	t.Run("deactivate unknown order promo", func(t *testing.T) {
+		_, err := orderPromoUsecase.DeactivateOrderPromo(ctx, 9)
+		assert.ErrorIs(t, err, service.ErrPromoNotFound)
+		orderPromoRepo.AssertNotCalled(t, "DeactivateOrderPromo", mock.Anything, int64(9))
+	})
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+// ErrInvalidRefund wraps every validation failure of a refund.
+var ErrInvalidRefund = apperr.New(apperr.CodeInvalidInput, "invalid refund")
+
+type (
+	RefundLine struct {
+		OrderDetailID int64
+		Qty           int64
+	}
+
+	OrderRefundRequest struct {
+		OrderID int64
+		Lines   []RefundLine
+		Reason  string
+	}
+)
+
+// CancelOrder cancels an order that isn't fulfilled yet and puts its stock,
+// free rewards included, back on hand. A paid order gets every line refunded
+// in full, an order still pending payment has nothing to refund.
+func (o *OrderUsecaseImpl) CancelOrder(ctx context.Context, orderID int64, reason string) (res repo.Order, err error) {
+	return o.refund(ctx, orderID, reason, true, nil)
+}
+
+// RefundOrder refunds part of the qty of some lines of a paid order and puts
+// it back on hand. The order becomes refunded once nothing is left to refund
+// on its priced lines.
+func (o *OrderUsecaseImpl) RefundOrder(ctx context.Context, form OrderRefundRequest) (res repo.Order, err error) {
+	if len(form.Lines) == 0 {
+		return res, fmt.Errorf("%w: no line to refund", ErrInvalidRefund)
+	}
+
+	return o.refund(ctx, form.OrderID, form.Reason, false, form.Lines)
+}
+
+func (o *OrderUsecaseImpl) refund(ctx context.Context, orderID int64, reason string, cancel bool, lines []RefundLine) (res repo.Order, err error) {
+	tx, err := o.OrderRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer o.OrderRepo.RollbackTx(tx)
+
+	res, err = o.OrderRepo.GetOrderByOrderIDForUpdate(tx, ctx, orderID)
+	if err != nil {
+		log.Printf("error while do GetOrderByOrderIDForUpdate %+v", err)
+		return res, err
+	}
+
+	if res.OrderID == 0 {
+		return res, ErrOrderNotFound
+	}
+
+	// whether the refund is partial is only known once the lines are
+	// counted, an order that can be partially refunded can be refunded too
+	to := OrderStatusPartiallyRefunded
+	if cancel {
+		to = OrderStatusCancelled
+	}
+
+	err = checkOrderTransition(res.Status, to)
+	if err != nil {
+		return res, err
+	}
+
+	details, err := o.OrderRepo.GetOrderDetailsByOrderID(tx, ctx, orderID)
+	if err != nil {
+		log.Printf("error while do GetOrderDetailsByOrderID %+v", err)
+		return res, err
+	}
+
+	refundQty := make(map[int64]int64, len(details))
+	if cancel {
+		for _, v := range details {
+			refundQty[v.OrderDetailID] = v.Qty - v.RefundedQty
+		}
+	} else {
+		refundQty, err = refundQtyByLine(details, lines)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	now := o.now()
+	stockReason := StockReasonRefund
+	if cancel {
+		stockReason = StockReasonCancellation
+	}
+
+	// nothing was paid for an order pending payment, cancelling it only
+	// gives the stock back
+	unpaid := res.Status == OrderStatusPendingPayment
+
+	var (
+		refunds   []repo.OrderRefund
+		movements []repo.StockMovement
+		remaining int64
+	)
+	for _, v := range details {
+		qty := refundQty[v.OrderDetailID]
+		if qty == 0 {
+			remaining += leftToRefund(v)
+			continue
+		}
+
+		err = o.restock(tx, ctx, v.ProductID, qty)
+		if err != nil {
+			return res, err
+		}
+
+		movements = append(movements, repo.StockMovement{
+			ProductID: v.ProductID,
+			Delta:     qty,
+			Reason:    stockReason,
+			OrderID:   orderID,
+			Actor:     ActorFrom(ctx),
+			CreatedAt: now,
+		})
+
+		if unpaid {
+			continue
+		}
+
+		amount := o.refundAmount(res, v, qty)
+		v.RefundedQty += qty
+		v.RefundedAmount = v.RefundedAmount.Add(amount)
+		remaining += leftToRefund(v)
+
+		err = o.OrderRepo.UpdateOrderDetailRefund(tx, ctx, v)
+		if err != nil {
+			log.Printf("error while do UpdateOrderDetailRefund %+v", err)
+			return res, err
+		}
+
+		refunds = append(refunds, repo.OrderRefund{
+			OrderID:       orderID,
+			OrderDetailID: v.OrderDetailID,
+			ProductID:     v.ProductID,
+			Qty:           qty,
+			Amount:        amount,
+			Reason:        reason,
+			Actor:         ActorFrom(ctx),
+			CreatedAt:     now,
+		})
+		res.RefundedTotal = res.RefundedTotal.Add(amount)
+	}
+
+	if len(refunds) > 0 {
+		err = o.OrderRepo.CreateOrderRefunds(tx, ctx, refunds)
+		if err != nil {
+			log.Printf("error while do CreateOrderRefunds %+v", err)
+			return res, err
+		}
+	}
+
+	if len(movements) > 0 {
+		err = o.StockMovementRepo.CreateStockMovements(tx, ctx, movements)
+		if err != nil {
+			log.Printf("error while do CreateStockMovements %+v", err)
+			return res, err
+		}
+	}
+
+	from := res.Status
+	switch {
+	case cancel:
+		res.Status = OrderStatusCancelled
+	case remaining == 0:
+		res.Status = OrderStatusRefunded
+	default:
+		res.Status = OrderStatusPartiallyRefunded
+	}
+
+	err = o.OrderRepo.UpdateOrderStatus(tx, ctx, res)
+	if err != nil {
+		log.Printf("error while do UpdateOrderStatus %+v", err)
+		return res, err
+	}
+
+	err = recordOrderStatus(tx, ctx, o.OrderRepo, res, from, reason, now)
+	if err != nil {
+		return res, err
+	}
+
+	err = o.OrderRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+// leftToRefund is the qty of v that can still be refunded. Zero priced lines,
+// like free rewards, have nothing to refund, so they never keep an order
+// partially refunded.
+func leftToRefund(v repo.OrderDetail) int64 {
+	if v.Price.IsZero() {
+		return 0
+	}
+
+	return v.Qty - v.RefundedQty
+}
+
+// refundQtyByLine sums the qty asked for each line of the order and checks it
+// is left to refund.
+func refundQtyByLine(details []repo.OrderDetail, lines []RefundLine) (map[int64]int64, error) {
+	byID := make(map[int64]repo.OrderDetail, len(details))
+	for _, v := range details {
+		byID[v.OrderDetailID] = v
+	}
+
+	res := make(map[int64]int64, len(lines))
+	for _, v := range lines {
+		if v.Qty < 1 {
+			return nil, fmt.Errorf("%w: qty must be at least 1", ErrInvalidRefund)
+		}
+
+		if _, ok := byID[v.OrderDetailID]; !ok {
+			return nil, fmt.Errorf("%w: line %d isn't part of the order", ErrInvalidRefund, v.OrderDetailID)
+		}
+
+		res[v.OrderDetailID] += v.Qty
+	}
+
+	for id, qty := range res {
+		detail := byID[id]
+		if left := detail.Qty - detail.RefundedQty; qty > left {
+			return nil, fmt.Errorf("%w: only %d left to refund on line %d", ErrInvalidRefund, left, id)
+		}
+	}
+
+	return res, nil
+}
+
+// refundAmount is qty's share of what is left to refund on the line. The last
+// qty gets exactly the rest, so rounding never refunds more than was paid.
+// Nothing refunds more than is left of the order's total either, which
+// orders stored before their coupons and order promos were spread over
+// their details rely on.
+func (o *OrderUsecaseImpl) refundAmount(order repo.Order, v repo.OrderDetail, qty int64) money.Decimal {
+	leftQty := v.Qty - v.RefundedQty
+	amount := v.Price.Sub(v.RefundedAmount)
+	if qty != leftQty {
+		amount = o.currency().Round(amount.MulInt(qty).Div(money.NewFromInt(leftQty)))
+	}
+
+	leftTotal := order.Total.Sub(order.RefundedTotal)
+	if leftTotal.IsNegative() {
+		return money.Decimal{}
+	}
+
+	if amount.Cmp(leftTotal) > 0 {
+		return leftTotal
+	}
+
+	return amount
+}
+
+func (o *OrderUsecaseImpl) restock(tx *sqlx.Tx, ctx context.Context, productID, qty int64) error {
+	_, err := o.ProductRepo.AdjustProductQty(tx, ctx, productID, qty)
+	if err != nil {
+		log.Printf("error while do AdjustProductQty %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (o *OrderUsecaseImpl) now() time.Time {
+	if o.Clock == nil {
+		return time.Now()
+	}
+
+	return o.Clock.Now()
+}
+
+// currency falls back to money.DefaultCurrency when none is configured.
+func (o *OrderUsecaseImpl) currency() money.Currency {
+	if o.Currency.Code == "" {
+		return money.DefaultCurrency
+	}
+
+	return o.Currency
+}
@@ -0,0 +1,341 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOrderRefunds(t *testing.T) {
+	paid := repo.Order{OrderID: 1, Date: checkoutAt, Total: money.MustParse("100"), Status: service.OrderStatusPaid}
+	partiallyRefunded := paid
+	partiallyRefunded.Status = service.OrderStatusPartiallyRefunded
+	partiallyRefunded.RefundedTotal = money.MustParse("33.33")
+
+	// three speakers sold for 100 and a raspberry pi given away
+	details := []repo.OrderDetail{
+		{OrderDetailID: 1, OrderID: 1, ProductID: 3, PromoID: 2, Price: money.MustParse("100"), Qty: 3},
+		{OrderDetailID: 2, OrderID: 1, ProductID: 4, PromoID: 2, Qty: 1},
+	}
+	refundedOnce := []repo.OrderDetail{
+		{OrderDetailID: 1, OrderID: 1, ProductID: 3, PromoID: 2, Price: money.MustParse("100"), Qty: 3, RefundedQty: 1, RefundedAmount: money.MustParse("33.33")},
+		{OrderDetailID: 2, OrderID: 1, ProductID: 4, PromoID: 2, Qty: 1, RefundedQty: 1},
+	}
+
+	tests := []struct {
+		name          string
+		cancel        bool
+		form          service.OrderRefundRequest
+		mockSetupFunc func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository)
+		expectedResp  repo.Order
+		expectedErr   error
+	}{
+		{
+			name:   "cancel restocks every line, free rewards included",
+			cancel: true,
+			form:   service.OrderRefundRequest{OrderID: 1, Reason: "changed my mind"},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(paid, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(details, nil)
+				orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, repo.OrderDetail{OrderDetailID: 1, OrderID: 1, ProductID: 3, PromoID: 2, Price: money.MustParse("100"), Qty: 3, RefundedQty: 3, RefundedAmount: money.MustParse("100")}).Return(nil)
+				orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, repo.OrderDetail{OrderDetailID: 2, OrderID: 1, ProductID: 4, PromoID: 2, Qty: 1, RefundedQty: 1}).Return(nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(3), int64(3)).Return(repo.Product{}, nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(4), int64(1)).Return(repo.Product{}, nil)
+				orderRepo.On("CreateOrderRefunds", mock.Anything, mock.Anything, []repo.OrderRefund{
+					{OrderID: 1, OrderDetailID: 1, ProductID: 3, Qty: 3, Amount: money.MustParse("100"), Reason: "changed my mind", Actor: "alice", CreatedAt: checkoutAt},
+					{OrderID: 1, OrderDetailID: 2, ProductID: 4, Qty: 1, Reason: "changed my mind", Actor: "alice", CreatedAt: checkoutAt},
+				}).Return(nil)
+				stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, []repo.StockMovement{
+					{ProductID: 3, Delta: 3, Reason: service.StockReasonCancellation, OrderID: 1, Actor: "alice", CreatedAt: checkoutAt},
+					{ProductID: 4, Delta: 1, Reason: service.StockReasonCancellation, OrderID: 1, Actor: "alice", CreatedAt: checkoutAt},
+				}).Return(nil)
+				cancelled := paid
+				cancelled.Status = service.OrderStatusCancelled
+				cancelled.RefundedTotal = money.MustParse("100")
+				orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, cancelled).Return(nil)
+				orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, repo.OrderStatusChange{
+					OrderID: 1, FromStatus: service.OrderStatusPaid, ToStatus: service.OrderStatusCancelled, Actor: "alice", Note: "changed my mind", CreatedAt: checkoutAt,
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Order{OrderID: 1, Date: checkoutAt, Total: money.MustParse("100"), Status: service.OrderStatusCancelled, RefundedTotal: money.MustParse("100")},
+		},
+		{
+			name:   "cancelling an unpaid order only restocks it",
+			cancel: true,
+			form:   service.OrderRefundRequest{OrderID: 1, Reason: "changed my mind"},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				unpaid := paid
+				unpaid.Status = service.OrderStatusPendingPayment
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(unpaid, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(details, nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(3), int64(3)).Return(repo.Product{}, nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(4), int64(1)).Return(repo.Product{}, nil)
+				stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, []repo.StockMovement{
+					{ProductID: 3, Delta: 3, Reason: service.StockReasonCancellation, OrderID: 1, Actor: "alice", CreatedAt: checkoutAt},
+					{ProductID: 4, Delta: 1, Reason: service.StockReasonCancellation, OrderID: 1, Actor: "alice", CreatedAt: checkoutAt},
+				}).Return(nil)
+				cancelled := paid
+				cancelled.Status = service.OrderStatusCancelled
+				orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, cancelled).Return(nil)
+				orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, repo.OrderStatusChange{
+					OrderID: 1, FromStatus: service.OrderStatusPendingPayment, ToStatus: service.OrderStatusCancelled, Actor: "alice", Note: "changed my mind", CreatedAt: checkoutAt,
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Order{OrderID: 1, Date: checkoutAt, Total: money.MustParse("100"), Status: service.OrderStatusCancelled},
+		},
+		{
+			name: "refunding every paid line refunds the order, the free reward kept",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 1, Qty: 3}}},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(paid, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(details, nil)
+				orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, repo.OrderDetail{OrderDetailID: 1, OrderID: 1, ProductID: 3, PromoID: 2, Price: money.MustParse("100"), Qty: 3, RefundedQty: 3, RefundedAmount: money.MustParse("100")}).Return(nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(3), int64(3)).Return(repo.Product{}, nil)
+				orderRepo.On("CreateOrderRefunds", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				refunded := paid
+				refunded.Status = service.OrderStatusRefunded
+				refunded.RefundedTotal = money.MustParse("100")
+				orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, refunded).Return(nil)
+				orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, repo.OrderStatusChange{
+					OrderID: 1, FromStatus: service.OrderStatusPaid, ToStatus: service.OrderStatusRefunded, Actor: "alice", CreatedAt: checkoutAt,
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Order{OrderID: 1, Date: checkoutAt, Total: money.MustParse("100"), Status: service.OrderStatusRefunded, RefundedTotal: money.MustParse("100")},
+		},
+		{
+			name: "partial refund is rounded to the currency",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 1, Qty: 1}, {OrderDetailID: 2, Qty: 1}}, Reason: "damaged"},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(paid, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(details, nil)
+				orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, refundedOnce[0]).Return(nil)
+				orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, refundedOnce[1]).Return(nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(3), int64(1)).Return(repo.Product{}, nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(4), int64(1)).Return(repo.Product{}, nil)
+				orderRepo.On("CreateOrderRefunds", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, []repo.StockMovement{
+					{ProductID: 3, Delta: 1, Reason: service.StockReasonRefund, OrderID: 1, Actor: "alice", CreatedAt: checkoutAt},
+					{ProductID: 4, Delta: 1, Reason: service.StockReasonRefund, OrderID: 1, Actor: "alice", CreatedAt: checkoutAt},
+				}).Return(nil)
+				orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, partiallyRefunded).Return(nil)
+				orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, repo.OrderStatusChange{
+					OrderID: 1, FromStatus: service.OrderStatusPaid, ToStatus: service.OrderStatusPartiallyRefunded, Actor: "alice", Note: "damaged", CreatedAt: checkoutAt,
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: partiallyRefunded,
+		},
+		{
+			name: "refunding what is left refunds the order",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 1, Qty: 1}, {OrderDetailID: 1, Qty: 1}}},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(partiallyRefunded, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(refundedOnce, nil)
+				orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, repo.OrderDetail{OrderDetailID: 1, OrderID: 1, ProductID: 3, PromoID: 2, Price: money.MustParse("100"), Qty: 3, RefundedQty: 3, RefundedAmount: money.MustParse("100")}).Return(nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(3), int64(2)).Return(repo.Product{}, nil)
+				orderRepo.On("CreateOrderRefunds", mock.Anything, mock.Anything, []repo.OrderRefund{
+					{OrderID: 1, OrderDetailID: 1, ProductID: 3, Qty: 2, Amount: money.MustParse("66.67"), Actor: "alice", CreatedAt: checkoutAt},
+				}).Return(nil)
+				stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				refunded := partiallyRefunded
+				refunded.Status = service.OrderStatusRefunded
+				refunded.RefundedTotal = money.MustParse("100")
+				orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, refunded).Return(nil)
+				orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, repo.OrderStatusChange{
+					OrderID: 1, FromStatus: service.OrderStatusPartiallyRefunded, ToStatus: service.OrderStatusRefunded, Actor: "alice", CreatedAt: checkoutAt,
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Order{OrderID: 1, Date: checkoutAt, Total: money.MustParse("100"), Status: service.OrderStatusRefunded, RefundedTotal: money.MustParse("100")},
+		},
+		{
+			name:   "unknown order",
+			cancel: true,
+			form:   service.OrderRefundRequest{OrderID: 9},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(9)).Return(repo.Order{}, nil)
+			},
+			expectedErr: service.ErrOrderNotFound,
+		},
+		{
+			name:   "partially refunded order can't be cancelled",
+			cancel: true,
+			form:   service.OrderRefundRequest{OrderID: 1},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(partiallyRefunded, nil)
+			},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name:   "fulfilled order can't be cancelled",
+			cancel: true,
+			form:   service.OrderRefundRequest{OrderID: 1},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				fulfilled := paid
+				fulfilled.Status = service.OrderStatusFulfilled
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(fulfilled, nil)
+			},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name: "unpaid order can't be refunded",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 1, Qty: 1}}},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				pending := paid
+				pending.Status = service.OrderStatusPendingPayment
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(pending, nil)
+			},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name: "cancelled order can't be refunded",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 1, Qty: 1}}},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				cancelled := paid
+				cancelled.Status = service.OrderStatusCancelled
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(cancelled, nil)
+			},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name: "refund more than is left",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 1, Qty: 3}}},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(partiallyRefunded, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(refundedOnce, nil)
+			},
+			expectedErr: service.ErrInvalidRefund,
+		},
+		{
+			name: "refund a line of another order",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 7, Qty: 1}}},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(paid, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(details, nil)
+			},
+			expectedErr: service.ErrInvalidRefund,
+		},
+		{
+			name: "refund never exceeds what is left of the order total",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 1, Qty: 2}}, Reason: "damaged"},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				// an order placed before coupons were spread over its details:
+				// it was charged 90 but its details still add up to 100
+				charged := partiallyRefunded
+				charged.Total = money.MustParse("90")
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(charged, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(refundedOnce, nil)
+				orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, repo.OrderDetail{OrderDetailID: 1, OrderID: 1, ProductID: 3, PromoID: 2, Price: money.MustParse("100"), Qty: 3, RefundedQty: 3, RefundedAmount: money.MustParse("90")}).Return(nil)
+				productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(3), int64(2)).Return(repo.Product{}, nil)
+				orderRepo.On("CreateOrderRefunds", mock.Anything, mock.Anything, []repo.OrderRefund{
+					{OrderID: 1, OrderDetailID: 1, ProductID: 3, Qty: 2, Amount: money.MustParse("56.67"), Reason: "damaged", Actor: "alice", CreatedAt: checkoutAt},
+				}).Return(nil)
+				stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				refunded := charged
+				refunded.Status = service.OrderStatusRefunded
+				refunded.RefundedTotal = money.MustParse("90")
+				orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, refunded).Return(nil)
+				orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Order{OrderID: 1, Date: checkoutAt, Total: money.MustParse("90"), Status: service.OrderStatusRefunded, RefundedTotal: money.MustParse("90")},
+		},
+		{
+			name: "refund zero qty",
+			form: service.OrderRefundRequest{OrderID: 1, Lines: []service.RefundLine{{OrderDetailID: 1}}},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(paid, nil)
+				orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return(details, nil)
+			},
+			expectedErr: service.ErrInvalidRefund,
+		},
+		{
+			name: "refund without lines",
+			form: service.OrderRefundRequest{OrderID: 1},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+			},
+			expectedErr: service.ErrInvalidRefund,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+			productRepo := new(mockRepo.ProductRepository)
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
+
+			orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil).Maybe()
+			orderRepo.On("RollbackTx", mock.Anything).Return(nil).Maybe()
+			tt.mockSetupFunc(orderRepo, productRepo, stockMovementRepo)
+
+			orderUsecase := service.NewOrderUsecase(service.OrderUsecaseImpl{
+				OrderRepo:         orderRepo,
+				ProductRepo:       productRepo,
+				StockMovementRepo: stockMovementRepo,
+				Clock:             clock.Fixed(checkoutAt),
+			})
+
+			ctx := service.WithActor(context.Background(), "alice")
+
+			var (
+				res repo.Order
+				err error
+			)
+			if tt.cancel {
+				res, err = orderUsecase.CancelOrder(ctx, tt.form.OrderID, tt.form.Reason)
+			} else {
+				res, err = orderUsecase.RefundOrder(ctx, tt.form)
+			}
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				orderRepo.AssertNotCalled(t, "CommitTx", mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			orderRepo.AssertExpectations(t)
+			productRepo.AssertExpectations(t)
+			stockMovementRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderRefundRollsBackOnRestockError(t *testing.T) {
+	orderRepo := new(mockRepo.OrderRepository)
+	productRepo := new(mockRepo.ProductRepository)
+
+	orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+	orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+	orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(repo.Order{OrderID: 1, Status: service.OrderStatusPaid}, nil)
+	orderRepo.On("GetOrderDetailsByOrderID", mock.Anything, mock.Anything, int64(1)).Return([]repo.OrderDetail{
+		{OrderDetailID: 1, OrderID: 1, ProductID: 3, Price: money.MustParse("100"), Qty: 3},
+	}, nil)
+	orderRepo.On("UpdateOrderDetailRefund", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(3), int64(3)).Return(repo.Product{}, errors.New("error"))
+
+	orderUsecase := service.NewOrderUsecase(service.OrderUsecaseImpl{
+		OrderRepo:   orderRepo,
+		ProductRepo: productRepo,
+	})
+
+	_, err := orderUsecase.CancelOrder(context.Background(), 1, "")
+	assert.EqualError(t, err, "error")
+
+	orderRepo.AssertNotCalled(t, "CommitTx", mock.Anything)
+	orderRepo.AssertCalled(t, "RollbackTx", mock.Anything)
+}
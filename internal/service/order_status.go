@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"go.uber.org/dig"
+)
+
+// Order statuses. Checkout leaves an order pending payment; it is then paid,
+// fulfilled and delivered. Cancelled and refunded orders can't change
+// anymore.
+const (
+	OrderStatusPendingPayment    = "pending_payment"
+	OrderStatusPaid              = "paid"
+	OrderStatusFulfilled         = "fulfilled"
+	OrderStatusDelivered         = "delivered"
+	OrderStatusCancelled         = "cancelled"
+	OrderStatusPartiallyRefunded = "partially_refunded"
+	OrderStatusRefunded          = "refunded"
+)
+
+// orderTransitions lists the statuses each status can move to. An order can
+// be cancelled until it is fulfilled, and refunded once it is paid.
+var orderTransitions = map[string][]string{
+	OrderStatusPendingPayment:    {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:              {OrderStatusFulfilled, OrderStatusCancelled, OrderStatusPartiallyRefunded, OrderStatusRefunded},
+	OrderStatusFulfilled:         {OrderStatusDelivered, OrderStatusPartiallyRefunded, OrderStatusRefunded},
+	OrderStatusDelivered:         {OrderStatusPartiallyRefunded, OrderStatusRefunded},
+	OrderStatusPartiallyRefunded: {OrderStatusPartiallyRefunded, OrderStatusRefunded},
+	OrderStatusCancelled:         nil,
+	OrderStatusRefunded:          nil,
+}
+
+var (
+	// ErrOrderNotFound is returned when a write targets an unknown order.
+	ErrOrderNotFound = apperr.New(apperr.CodeNotFound, "order not found")
+	// ErrInvalidOrderTransition is returned when the order's status doesn't
+	// allow the change.
+	ErrInvalidOrderTransition = apperr.New(apperr.CodeConflict, "invalid order transition")
+)
+
+type (
+	OrderTransition struct {
+		OrderID int64
+		Status  string
+		Note    string
+	}
+
+	OrderStatusUsecase interface {
+		TransitionOrder(ctx context.Context, form OrderTransition) (res repo.Order, err error)
+		GetOrderStatusHistory(ctx context.Context, orderID int64) (res []repo.OrderStatusChange, err error)
+	}
+
+	OrderStatusUsecaseImpl struct {
+		dig.In
+		OrderRepo repo.OrderRepository
+		Clock     clock.Clock `optional:"true"`
+	}
+)
+
+func NewOrderStatusUsecase(impl OrderStatusUsecaseImpl) OrderStatusUsecase {
+	return &impl
+}
+
+// CanTransitionOrder reports whether an order in status from may move to
+// status to.
+func CanTransitionOrder(from, to string) bool {
+	for _, v := range orderTransitions[from] {
+		if v == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkOrderTransition(from, to string) error {
+	if _, ok := orderTransitions[to]; !ok {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidOrderTransition, to)
+	}
+
+	if !CanTransitionOrder(from, to) {
+		return fmt.Errorf("%w: a %s order can't become %s", ErrInvalidOrderTransition, from, to)
+	}
+
+	return nil
+}
+
+// TransitionOrder moves the order along its fulfilment: paid, fulfilled and
+// delivered. Cancellations and refunds give stock and money back, so they go
+// through CancelOrder and RefundOrder instead.
+func (o *OrderStatusUsecaseImpl) TransitionOrder(ctx context.Context, form OrderTransition) (res repo.Order, err error) {
+	tx, err := o.OrderRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer o.OrderRepo.RollbackTx(tx)
+
+	res, err = o.OrderRepo.GetOrderByOrderIDForUpdate(tx, ctx, form.OrderID)
+	if err != nil {
+		log.Printf("error while do GetOrderByOrderIDForUpdate %+v", err)
+		return res, err
+	}
+
+	if res.OrderID == 0 {
+		return res, ErrOrderNotFound
+	}
+
+	err = checkOrderTransition(res.Status, form.Status)
+	if err != nil {
+		return res, err
+	}
+
+	switch form.Status {
+	case OrderStatusCancelled, OrderStatusPartiallyRefunded, OrderStatusRefunded:
+		return res, fmt.Errorf("%w: use cancelOrder or refundOrder to make an order %s", ErrInvalidOrderTransition, form.Status)
+	}
+
+	from := res.Status
+	res.Status = form.Status
+
+	err = o.OrderRepo.UpdateOrderStatus(tx, ctx, res)
+	if err != nil {
+		log.Printf("error while do UpdateOrderStatus %+v", err)
+		return res, err
+	}
+
+	err = recordOrderStatus(tx, ctx, o.OrderRepo, res, from, form.Note, o.now())
+	if err != nil {
+		return res, err
+	}
+
+	err = o.OrderRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (o *OrderStatusUsecaseImpl) GetOrderStatusHistory(ctx context.Context, orderID int64) (res []repo.OrderStatusChange, err error) {
+	res, err = o.OrderRepo.GetOrderStatusHistoryByOrderID(ctx, orderID)
+	if err != nil {
+		log.Printf("error while do GetOrderStatusHistoryByOrderID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (o *OrderStatusUsecaseImpl) now() time.Time {
+	if o.Clock == nil {
+		return time.Now()
+	}
+
+	return o.Clock.Now()
+}
+
+// recordOrderStatus writes the move of order from status from to its current
+// status in the order's history, on behalf of the context's actor.
+func recordOrderStatus(tx *sqlx.Tx, ctx context.Context, orderRepo repo.OrderRepository, order repo.Order, from, note string, at time.Time) error {
+	err := orderRepo.CreateOrderStatusChange(tx, ctx, repo.OrderStatusChange{
+		OrderID:    order.OrderID,
+		FromStatus: from,
+		ToStatus:   order.Status,
+		Actor:      ActorFrom(ctx),
+		Note:       note,
+		CreatedAt:  at,
+	})
+	if err != nil {
+		log.Printf("error while do CreateOrderStatusChange %+v", err)
+		return err
+	}
+
+	return nil
+}
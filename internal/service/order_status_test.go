@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTransitionOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        string
+		form        service.OrderTransition
+		expectedErr error
+	}{
+		{
+			name: "payment",
+			from: service.OrderStatusPendingPayment,
+			form: service.OrderTransition{OrderID: 1, Status: service.OrderStatusPaid, Note: "card 4242"},
+		},
+		{
+			name: "fulfilment",
+			from: service.OrderStatusPaid,
+			form: service.OrderTransition{OrderID: 1, Status: service.OrderStatusFulfilled},
+		},
+		{
+			name: "delivery",
+			from: service.OrderStatusFulfilled,
+			form: service.OrderTransition{OrderID: 1, Status: service.OrderStatusDelivered},
+		},
+		{
+			name:        "an unpaid order can't be fulfilled",
+			from:        service.OrderStatusPendingPayment,
+			form:        service.OrderTransition{OrderID: 1, Status: service.OrderStatusFulfilled},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name:        "a delivered order can't go back",
+			from:        service.OrderStatusDelivered,
+			form:        service.OrderTransition{OrderID: 1, Status: service.OrderStatusPaid},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name:        "a cancelled order can't change",
+			from:        service.OrderStatusCancelled,
+			form:        service.OrderTransition{OrderID: 1, Status: service.OrderStatusPaid},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name:        "cancellation goes through cancelOrder",
+			from:        service.OrderStatusPaid,
+			form:        service.OrderTransition{OrderID: 1, Status: service.OrderStatusCancelled},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name:        "unknown status",
+			from:        service.OrderStatusPaid,
+			form:        service.OrderTransition{OrderID: 1, Status: "shipped"},
+			expectedErr: service.ErrInvalidOrderTransition,
+		},
+		{
+			name:        "unknown order",
+			form:        service.OrderTransition{OrderID: 9, Status: service.OrderStatusPaid},
+			expectedErr: service.ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+
+			order := repo.Order{OrderID: tt.form.OrderID, Date: checkoutAt, Status: tt.from}
+			if tt.from == "" {
+				order = repo.Order{}
+			}
+
+			orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+			orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+			orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, tt.form.OrderID).Return(order, nil)
+			if tt.expectedErr == nil {
+				moved := order
+				moved.Status = tt.form.Status
+				orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, moved).Return(nil)
+				orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, repo.OrderStatusChange{
+					OrderID: 1, FromStatus: tt.from, ToStatus: tt.form.Status, Actor: "alice", Note: tt.form.Note, CreatedAt: checkoutAt,
+				}).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			}
+
+			orderStatusUsecase := service.NewOrderStatusUsecase(service.OrderStatusUsecaseImpl{
+				OrderRepo: orderRepo,
+				Clock:     clock.Fixed(checkoutAt),
+			})
+
+			res, err := orderStatusUsecase.TransitionOrder(service.WithActor(context.Background(), "alice"), tt.form)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				orderRepo.AssertNotCalled(t, "UpdateOrderStatus", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.form.Status, res.Status)
+			}
+
+			orderRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTransitionOrderRollsBackOnHistoryError(t *testing.T) {
+	orderRepo := new(mockRepo.OrderRepository)
+
+	orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+	orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+	orderRepo.On("GetOrderByOrderIDForUpdate", mock.Anything, mock.Anything, int64(1)).Return(repo.Order{OrderID: 1, Status: service.OrderStatusPaid}, nil)
+	orderRepo.On("UpdateOrderStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("error"))
+
+	orderStatusUsecase := service.NewOrderStatusUsecase(service.OrderStatusUsecaseImpl{OrderRepo: orderRepo})
+
+	_, err := orderStatusUsecase.TransitionOrder(context.Background(), service.OrderTransition{OrderID: 1, Status: service.OrderStatusFulfilled})
+	assert.EqualError(t, err, "error")
+
+	orderRepo.AssertNotCalled(t, "CommitTx", mock.Anything)
+}
+
+func TestCanTransitionOrder(t *testing.T) {
+	assert.True(t, service.CanTransitionOrder(service.OrderStatusPendingPayment, service.OrderStatusCancelled))
+	assert.True(t, service.CanTransitionOrder(service.OrderStatusDelivered, service.OrderStatusRefunded))
+	assert.True(t, service.CanTransitionOrder(service.OrderStatusPartiallyRefunded, service.OrderStatusPartiallyRefunded))
+	assert.False(t, service.CanTransitionOrder(service.OrderStatusFulfilled, service.OrderStatusCancelled))
+	assert.False(t, service.CanTransitionOrder(service.OrderStatusPendingPayment, service.OrderStatusRefunded))
+	assert.False(t, service.CanTransitionOrder(service.OrderStatusRefunded, service.OrderStatusPaid))
+	assert.False(t, service.CanTransitionOrder("", service.OrderStatusPaid))
+}
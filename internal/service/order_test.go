@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/cursor"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOrderGetOrders(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	from := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		form          service.OrderQuery
+		mockSetupFunc func(orderRepo *mockRepo.OrderRepository)
+		expectedResp  service.OrderPage
+		wantErr       bool
+	}{
+		{
+			name: "first page has a next page",
+			form: service.OrderQuery{From: from, First: 1},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository) {
+				orderRepo.On("GetOrders", mock.Anything, repo.OrderFilter{From: from, Limit: 2}).
+					Return([]repo.Order{{OrderID: 9, Date: date, Total: money.MustParse("10")}, {OrderID: 8, Date: date, Total: money.MustParse("20")}}, nil)
+			},
+			expectedResp: service.OrderPage{
+				Edges: []service.OrderEdge{
+					{Cursor: cursor.Encode("", 9), Node: repo.Order{OrderID: 9, Date: date, Total: money.MustParse("10")}},
+				},
+				PageInfo: service.PageInfo{EndCursor: cursor.Encode("", 9), HasNextPage: true},
+			},
+		},
+		{
+			name: "after cursor is passed to the repository",
+			form: service.OrderQuery{First: 5, After: cursor.Encode("", 9)},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository) {
+				orderRepo.On("GetOrders", mock.Anything, repo.OrderFilter{AfterID: 9, Limit: 6}).
+					Return([]repo.Order{{OrderID: 8, Date: date, Total: money.MustParse("20")}}, nil)
+			},
+			expectedResp: service.OrderPage{
+				Edges: []service.OrderEdge{
+					{Cursor: cursor.Encode("", 8), Node: repo.Order{OrderID: 8, Date: date, Total: money.MustParse("20")}},
+				},
+				PageInfo: service.PageInfo{EndCursor: cursor.Encode("", 8)},
+			},
+		},
+		{
+			name:    "invalid cursor",
+			form:    service.OrderQuery{After: "!!"},
+			wantErr: true,
+		},
+		{
+			name: "error while GetOrders",
+			form: service.OrderQuery{},
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository) {
+				orderRepo.On("GetOrders", mock.Anything, mock.Anything).Return(nil, errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+
+			if tt.mockSetupFunc != nil {
+				tt.mockSetupFunc(orderRepo)
+			}
+
+			orderUsecase := service.NewOrderUsecase(service.OrderUsecaseImpl{
+				OrderRepo: orderRepo,
+			})
+
+			res, err := orderUsecase.GetOrders(context.Background(), tt.form)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			orderRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderLookups(t *testing.T) {
+	order := repo.Order{OrderID: 1, Date: time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC), Total: money.MustParse("295.65")}
+	lines := []repo.OrderLine{{OrderDetailID: 1, OrderID: 1, ProductID: 3, ProductName: "Alexa Speaker", Price: money.MustParse("295.65"), Qty: 3}}
+
+	orderRepo := new(mockRepo.OrderRepository)
+	orderRepo.On("GetOrderByOrderID", mock.Anything, int64(1)).Return(order, nil)
+	orderRepo.On("GetOrderByOrderID", mock.Anything, int64(9)).Return(repo.Order{}, errors.New("error"))
+	orderRepo.On("GetOrderLinesByOrderID", mock.Anything, int64(1)).Return(lines, nil)
+	orderRepo.On("GetOrderLinesByOrderID", mock.Anything, int64(9)).Return(nil, errors.New("error"))
+
+	orderUsecase := service.NewOrderUsecase(service.OrderUsecaseImpl{
+		OrderRepo: orderRepo,
+	})
+
+	ctx := context.Background()
+
+	res, err := orderUsecase.GetOrderByOrderID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, order, res)
+
+	_, err = orderUsecase.GetOrderByOrderID(ctx, 9)
+	assert.Error(t, err)
+
+	resLines, err := orderUsecase.GetOrderLinesByOrderID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, lines, resLines)
+
+	_, err = orderUsecase.GetOrderLinesByOrderID(ctx, 9)
+	assert.Error(t, err)
+
+	orderRepo.AssertExpectations(t)
+}
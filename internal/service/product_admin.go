@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"go.uber.org/dig"
+)
+
+var (
+	// ErrInvalidProduct wraps every validation failure of a product write.
+	ErrInvalidProduct = apperr.New(apperr.CodeInvalidInput, "invalid product")
+	// ErrProductNotFound is returned when a write targets an unknown product.
+	ErrProductNotFound = apperr.New(apperr.CodeProductNotFound, "product not found")
+)
+
+type (
+	StockAdjustment struct {
+		ProductID int64
+		Delta     int64
+		Reason    string
+	}
+
+	ProductUsecase interface {
+		CreateProduct(ctx context.Context, form repo.Product) (res repo.Product, err error)
+		UpdateProduct(ctx context.Context, form repo.Product) (res repo.Product, err error)
+		ArchiveProduct(ctx context.Context, productID int64) (res repo.Product, err error)
+		AdjustStock(ctx context.Context, form StockAdjustment) (res repo.Product, err error)
+	}
+
+	ProductUsecaseImpl struct {
+		dig.In
+		ProductRepo       repo.ProductRepository
+		StockMovementRepo repo.StockMovementRepository
+		Clock             clock.Clock `optional:"true"`
+	}
+)
+
+func NewProductUsecase(impl ProductUsecaseImpl) ProductUsecase {
+	return &impl
+}
+
+func (c *ProductUsecaseImpl) CreateProduct(ctx context.Context, form repo.Product) (res repo.Product, err error) {
+	form.Sku, form.Name = strings.TrimSpace(form.Sku), strings.TrimSpace(form.Name)
+
+	err = validateProduct(form)
+	if err != nil {
+		return res, err
+	}
+
+	if form.Qty < 0 {
+		return res, fmt.Errorf("%w: qty can't be negative", ErrInvalidProduct)
+	}
+
+	tx, err := c.ProductRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.ProductRepo.RollbackTx(tx)
+
+	res, err = c.ProductRepo.CreateProduct(tx, ctx, form)
+	if err != nil {
+		log.Printf("error while do CreateProduct %+v", err)
+		return res, err
+	}
+
+	if res.Qty != 0 {
+		err = c.recordMovement(tx, ctx, res.ProductID, res.Qty, StockReasonInitial)
+		if err != nil {
+			return repo.Product{}, err
+		}
+	}
+
+	err = c.ProductRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return repo.Product{}, err
+	}
+
+	return res, nil
+}
+
+// UpdateProduct replaces the sku, name and price of the product identified by
+// form.ProductID. form.Qty is ignored, stock moves through AdjustStock.
+func (c *ProductUsecaseImpl) UpdateProduct(ctx context.Context, form repo.Product) (res repo.Product, err error) {
+	form.Sku, form.Name = strings.TrimSpace(form.Sku), strings.TrimSpace(form.Name)
+
+	err = validateProduct(form)
+	if err != nil {
+		return res, err
+	}
+
+	res, err = c.ProductRepo.UpdateProduct(ctx, form)
+	if err != nil {
+		log.Printf("error while do UpdateProduct %+v", err)
+		return res, err
+	}
+
+	if res.ProductID == 0 {
+		return res, fmt.Errorf("%w: %d", ErrProductNotFound, form.ProductID)
+	}
+
+	return res, nil
+}
+
+// ArchiveProduct takes the product off the catalog and out of checkout. The
+// row is kept so order details that reference it stay valid.
+func (c *ProductUsecaseImpl) ArchiveProduct(ctx context.Context, productID int64) (res repo.Product, err error) {
+	res, err = c.ProductRepo.ArchiveProduct(ctx, productID, c.now())
+	if err != nil {
+		log.Printf("error while do ArchiveProduct %+v", err)
+		return res, err
+	}
+
+	if res.ProductID == 0 {
+		return res, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+	}
+
+	return res, nil
+}
+
+func (c *ProductUsecaseImpl) AdjustStock(ctx context.Context, form StockAdjustment) (res repo.Product, err error) {
+	if form.Delta == 0 {
+		return res, fmt.Errorf("%w: delta can't be zero", ErrInvalidProduct)
+	}
+
+	if !isAdjustmentReason(form.Reason) {
+		return res, fmt.Errorf("%w: unknown stock reason %q", ErrInvalidProduct, form.Reason)
+	}
+
+	product, err := c.ProductRepo.GetProductByProductID(ctx, form.ProductID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductID %+v", err)
+		return res, err
+	}
+
+	if product.ProductID == 0 {
+		return res, fmt.Errorf("%w: %d", ErrProductNotFound, form.ProductID)
+	}
+
+	tx, err := c.ProductRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.ProductRepo.RollbackTx(tx)
+
+	res, err = c.ProductRepo.AdjustProductQty(tx, ctx, form.ProductID, form.Delta)
+	if errors.Is(err, repo.ErrInsufficientStock) {
+		return res, insufficientStockError(product)
+	}
+	if err != nil {
+		log.Printf("error while do AdjustProductQty %+v", err)
+		return res, err
+	}
+
+	err = c.recordMovement(tx, ctx, form.ProductID, form.Delta, form.Reason)
+	if err != nil {
+		return repo.Product{}, err
+	}
+
+	err = c.ProductRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return repo.Product{}, err
+	}
+
+	return res, nil
+}
+
+func (c *ProductUsecaseImpl) recordMovement(tx *sqlx.Tx, ctx context.Context, productID, delta int64, reason string) error {
+	err := c.StockMovementRepo.CreateStockMovements(tx, ctx, []repo.StockMovement{{
+		ProductID: productID,
+		Delta:     delta,
+		Reason:    reason,
+		Actor:     ActorFrom(ctx),
+		CreatedAt: c.now(),
+	}})
+	if err != nil {
+		log.Printf("error while do CreateStockMovements %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *ProductUsecaseImpl) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Clock.Now()
+}
+
+func validateProduct(form repo.Product) error {
+	if form.Sku == "" {
+		return fmt.Errorf("%w: sku is required", ErrInvalidProduct)
+	}
+
+	if form.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidProduct)
+	}
+
+	if form.Price.IsNegative() {
+		return fmt.Errorf("%w: price can't be negative", ErrInvalidProduct)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProductCreateAndUpdate(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	headphones := repo.Product{ProductID: 5, Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9"), Qty: 4}
+
+	tests := []struct {
+		name          string
+		update        bool
+		form          repo.Product
+		mockSetupFunc func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository)
+		expectedResp  repo.Product
+		expectedErr   error
+	}{
+		{
+			name: "create trims sku and name",
+			form: repo.Product{Sku: " HP01 ", Name: "Headphones ", Price: money.MustParse("79.9"), Qty: 4},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				productRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				productRepo.On("RollbackTx", mock.Anything).Return(nil)
+				productRepo.On("CreateProduct", mock.Anything, mock.Anything, repo.Product{Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9"), Qty: 4}).Return(headphones, nil)
+				stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, []repo.StockMovement{
+					{ProductID: 5, Delta: 4, Reason: service.StockReasonInitial, Actor: service.DefaultActor, CreatedAt: now},
+				}).Return(nil)
+				productRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: headphones,
+		},
+		{
+			name:          "create without sku",
+			form:          repo.Product{Name: "Headphones", Price: money.MustParse("79.9")},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {},
+			expectedErr:   service.ErrInvalidProduct,
+		},
+		{
+			name:          "create with negative price",
+			form:          repo.Product{Sku: "HP01", Name: "Headphones", Price: money.MustParse("-1")},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {},
+			expectedErr:   service.ErrInvalidProduct,
+		},
+		{
+			name:          "create with negative qty",
+			form:          repo.Product{Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9"), Qty: -1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {},
+			expectedErr:   service.ErrInvalidProduct,
+		},
+		{
+			name: "create with a taken sku",
+			form: repo.Product{Sku: "120P90", Name: "Headphones", Price: money.MustParse("79.9")},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				productRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				productRepo.On("RollbackTx", mock.Anything).Return(nil)
+				productRepo.On("CreateProduct", mock.Anything, mock.Anything, mock.Anything).Return(repo.Product{}, repo.ErrDuplicateSku)
+			},
+			expectedErr: repo.ErrDuplicateSku,
+		},
+		{
+			name: "create product without stock records no movement",
+			form: repo.Product{Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9")},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				empty := headphones
+				empty.Qty = 0
+				productRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+				productRepo.On("RollbackTx", mock.Anything).Return(nil)
+				productRepo.On("CreateProduct", mock.Anything, mock.Anything, mock.Anything).Return(empty, nil)
+				productRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Product{ProductID: 5, Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9")},
+		},
+		{
+			name:   "update product",
+			update: true,
+			form:   headphones,
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				productRepo.On("UpdateProduct", mock.Anything, headphones).Return(headphones, nil)
+			},
+			expectedResp: headphones,
+		},
+		{
+			name:   "update unknown product",
+			update: true,
+			form:   headphones,
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, stockMovementRepo *mockRepo.StockMovementRepository) {
+				productRepo.On("UpdateProduct", mock.Anything, headphones).Return(repo.Product{}, nil)
+			},
+			expectedErr: service.ErrProductNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := new(mockRepo.ProductRepository)
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
+			tt.mockSetupFunc(productRepo, stockMovementRepo)
+
+			productUsecase := service.NewProductUsecase(service.ProductUsecaseImpl{
+				ProductRepo:       productRepo,
+				StockMovementRepo: stockMovementRepo,
+				Clock:             clock.Fixed(now),
+			})
+
+			var (
+				res repo.Product
+				err error
+			)
+			if tt.update {
+				res, err = productUsecase.UpdateProduct(context.Background(), tt.form)
+			} else {
+				res, err = productUsecase.CreateProduct(context.Background(), tt.form)
+			}
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			productRepo.AssertExpectations(t)
+			stockMovementRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProductArchiveAndAdjustStock(t *testing.T) {
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	headphones := repo.Product{ProductID: 5, Sku: "HP01", Name: "Headphones", Price: money.MustParse("79.9"), Qty: 4}
+	archived := headphones
+	archived.ArchivedAt = &now
+	restocked := headphones
+	restocked.Qty = 10
+
+	productRepo := new(mockRepo.ProductRepository)
+	stockMovementRepo := new(mockRepo.StockMovementRepository)
+
+	productRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+	productRepo.On("RollbackTx", mock.Anything).Return(nil)
+	productRepo.On("CommitTx", mock.Anything).Return(nil)
+	productRepo.On("ArchiveProduct", mock.Anything, int64(5), now).Return(archived, nil)
+	productRepo.On("ArchiveProduct", mock.Anything, int64(9), now).Return(repo.Product{}, nil)
+	productRepo.On("GetProductByProductID", mock.Anything, int64(5)).Return(headphones, nil)
+	productRepo.On("GetProductByProductID", mock.Anything, int64(9)).Return(repo.Product{}, nil)
+	productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(5), int64(6)).Return(restocked, nil)
+	productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(5), int64(-5)).Return(repo.Product{}, repo.ErrInsufficientStock)
+	productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(5), int64(-1)).Return(repo.Product{}, errors.New("error"))
+	productRepo.On("AdjustProductQty", mock.Anything, mock.Anything, int64(5), int64(2)).Return(restocked, nil)
+	stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, []repo.StockMovement{
+		{ProductID: 5, Delta: 6, Reason: service.StockReasonRestock, Actor: "alice", CreatedAt: now},
+	}).Return(nil)
+	stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, []repo.StockMovement{
+		{ProductID: 5, Delta: 2, Reason: service.StockReasonReturned, Actor: service.DefaultActor, CreatedAt: now},
+	}).Return(errors.New("ledger error"))
+
+	productUsecase := service.NewProductUsecase(service.ProductUsecaseImpl{
+		ProductRepo:       productRepo,
+		StockMovementRepo: stockMovementRepo,
+		Clock:             clock.Fixed(now),
+	})
+
+	ctx := context.Background()
+
+	res, err := productUsecase.ArchiveProduct(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, archived, res)
+
+	_, err = productUsecase.ArchiveProduct(ctx, 9)
+	assert.ErrorIs(t, err, service.ErrProductNotFound)
+
+	res, err = productUsecase.AdjustStock(service.WithActor(ctx, "alice"), service.StockAdjustment{ProductID: 5, Delta: 6, Reason: service.StockReasonRestock})
+	assert.NoError(t, err)
+	assert.Equal(t, restocked, res)
+
+	_, err = productUsecase.AdjustStock(ctx, service.StockAdjustment{ProductID: 5, Delta: 2, Reason: service.StockReasonReturned})
+	assert.EqualError(t, err, "ledger error")
+
+	_, err = productUsecase.AdjustStock(ctx, service.StockAdjustment{ProductID: 5, Delta: -5, Reason: service.StockReasonDamaged})
+	assert.EqualError(t, err, "the product Headphones qty is not enough to fulfill the request")
+
+	_, err = productUsecase.AdjustStock(ctx, service.StockAdjustment{ProductID: 5, Delta: -1, Reason: service.StockReasonLost})
+	assert.EqualError(t, err, "error")
+
+	_, err = productUsecase.AdjustStock(ctx, service.StockAdjustment{ProductID: 9, Delta: 1, Reason: service.StockReasonRestock})
+	assert.ErrorIs(t, err, service.ErrProductNotFound)
+
+	_, err = productUsecase.AdjustStock(ctx, service.StockAdjustment{ProductID: 5, Delta: 0, Reason: service.StockReasonRestock})
+	assert.ErrorIs(t, err, service.ErrInvalidProduct)
+
+	_, err = productUsecase.AdjustStock(ctx, service.StockAdjustment{ProductID: 5, Delta: 1, Reason: "gift"})
+	assert.ErrorIs(t, err, service.ErrInvalidProduct)
+
+	_, err = productUsecase.AdjustStock(ctx, service.StockAdjustment{ProductID: 5, Delta: 1, Reason: service.StockReasonSale})
+	assert.ErrorIs(t, err, service.ErrInvalidProduct)
+
+	productRepo.AssertExpectations(t)
+	stockMovementRepo.AssertExpectations(t)
+}
@@ -2,42 +2,339 @@ package service
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/money"
 )
 
+// errPromotionSkipped tells the caller a promotion was not applied, without
+// failing the checkout.
+var errPromotionSkipped = errors.New("promotion skipped")
+
 type Promotion interface {
-	ApplyPromotion(item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error
+	ApplyPromotion(ctx context.Context, tx *sqlx.Tx, item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error
 }
 
+// ProductPromoDiscount makes FreeQty units free for every BuyQty units of the
+// line, so buy 3 pay 2 gives two units away on a line of six. It is skipped
+// unless something is left to pay for every BuyQty units.
 type ProductPromoDiscount struct {
+	BuyQty  int64
+	FreeQty int64
 }
 
-func (p *ProductPromoDiscount) ApplyPromotion(item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error {
-	tmpQty := v.Qty - 1
-	item.Price = float64(tmpQty) * productDetail.Price
+func (p *ProductPromoDiscount) ApplyPromotion(ctx context.Context, tx *sqlx.Tx, item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error {
+	if p.FreeQty < 1 || p.FreeQty >= p.BuyQty || v.Qty < p.BuyQty {
+		return errPromotionSkipped
+	}
+
+	// the free units are taken off whatever the line costs at this point so
+	// they stack with promos applied before them, never below zero
+	discount := productDetail.Price.MulInt(v.Qty / p.BuyQty * p.FreeQty)
+	if discount.Cmp(item.Price) > 0 {
+		discount = item.Price
+	}
+
+	item.Price = item.Price.Sub(discount)
 	return nil
 }
 
 type ProductPromoFree struct {
-	ProductRepo repo.ProductRepository
+	Stock       cartStock
+	StockPolicy RewardStockPolicy
+}
+
+// ApplyPromotion gives one reward product away. The reward's stock is taken
+// from Stock like any other line; when none is left, or the reward was
+// archived, the checkout either fails or goes on without the gift, depending
+// on StockPolicy.
+func (p *ProductPromoFree) ApplyPromotion(ctx context.Context, tx *sqlx.Tx, item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error {
+	return p.giveLine(ctx, tx, promo.Reward.IntPart(), CheckoutLine{
+		PromoID:   promo.PromoID,
+		PromoType: promo.PromoType,
+	}, res)
+}
+
+func (p *ProductPromoFree) outOfStock(reward repo.Product, res *Checkout) error {
+	if p.StockPolicy == RewardStockSkip {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("the free product %s is out of stock and was not added", reward.Name))
+		return errPromotionSkipped
+	}
+
+	err := p.Stock.unavailable(res, apperr.New(apperr.CodeOutOfStock, fmt.Sprintf("the free product %s is out of stock", reward.Name)).
+		With("product_id", reward.ProductID).
+		With("available_qty", reward.Available()))
+	if err != nil {
+		return err
+	}
+
+	return errPromotionSkipped
 }
 
-func (p *ProductPromoFree) ApplyPromotion(item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error {
-	productRewardDetail, err := p.ProductRepo.GetProductByProductID(context.Background(), int64(promo.Reward))
+// giveLine adds one unit of productID as a free line, attributed the way line
+// says. A product out of stock is handled like the promo's reward, following
+// StockPolicy.
+func (p *ProductPromoFree) giveLine(ctx context.Context, tx *sqlx.Tx, productID int64, line CheckoutLine, res *Checkout) error {
+	reward, err := p.Stock.product(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	if reward.Available() < 1 || reward.ArchivedAt != nil {
+		return p.outOfStock(reward, res)
+	}
+
+	err = p.Stock.take(ctx, tx, reward.ProductID, 1)
+	if errors.Is(err, repo.ErrInsufficientStock) {
+		return p.outOfStock(reward, res)
+	}
 	if err != nil {
-		log.Printf("error while do GetProductByProductID %+v", err)
 		return err
 	}
-	res.Items = append(res.Items, productRewardDetail.Name)
+
+	line.ProductID = reward.ProductID
+	line.ProductName = reward.Name
+	line.Qty = 1
+	line.UnitPrice = reward.Price
+	line.Subtotal = reward.Price
+	line.Discount = reward.Price
+	line.Free = true
+
+	res.Items = append(res.Items, reward.Name)
+	res.Lines = append(res.Lines, line)
+
 	return nil
 }
 
 type DiscountPromo struct {
+	Currency money.Currency
+}
+
+// ApplyPromotion takes Reward percent off what the line costs at this point.
+// The discount is rounded to the currency so the line total never carries
+// sub-cent amounts.
+func (p *DiscountPromo) ApplyPromotion(ctx context.Context, tx *sqlx.Tx, item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error {
+	discount := p.Currency.Round(item.Price.Mul(promo.Reward).Div(money.NewFromInt(100)))
+	item.Price = item.Price.Sub(discount)
+	return nil
 }
 
-func (p *DiscountPromo) ApplyPromotion(item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error {
-	item.Price = (productDetail.Price * float64(v.Qty)) - ((productDetail.Price * float64(v.Qty)) * (promo.Reward / 100))
+type TieredPromo struct {
+	Currency money.Currency
+}
+
+// ApplyPromotion takes the percent of the highest tier the line's qty reaches
+// off what the line costs at this point, rounded like DiscountPromo. A line
+// below every tier is skipped.
+func (p *TieredPromo) ApplyPromotion(ctx context.Context, tx *sqlx.Tx, item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *Checkout) error {
+	tier, ok := promoTierFor(promo.Tiers, v.Qty)
+	if !ok {
+		return errPromotionSkipped
+	}
+
+	discount := p.Currency.Round(item.Price.Mul(tier.Percent).Div(money.NewFromInt(100)))
+	item.Price = item.Price.Sub(discount)
 	return nil
 }
+
+// promoTierFor returns the tier with the highest min qty qty reaches.
+func promoTierFor(tiers repo.PromoTiers, qty int64) (repo.PromoTier, bool) {
+	var res repo.PromoTier
+	found := false
+	for _, tier := range tiers {
+		if qty >= tier.MinQty && (!found || tier.MinQty > res.MinQty) {
+			res = tier
+			found = true
+		}
+	}
+
+	return res, found
+}
+
+type discountPromotion struct{}
+
+// NewDiscountPromotion takes a percent off the line.
+func NewDiscountPromotion() PromotionKind {
+	return discountPromotion{}
+}
+
+func (discountPromotion) Name() string {
+	return PromoTypeDiscount
+}
+
+func (discountPromotion) Params() []PromoParam {
+	return []PromoParam{{Name: "reward", Type: PromoParamPercent, Description: "percent taken off the line"}}
+}
+
+func (discountPromotion) Validate(ctx context.Context, promo repo.Promo, check PromoCheck) error {
+	return validatePromoPercent(promo, "reward", promo.Reward)
+}
+
+func (discountPromotion) Promotion(promo repo.Promo, productID int64, env PromotionEnv) Promotion {
+	return &DiscountPromo{Currency: env.Currency}
+}
+
+func (discountPromotion) GiftProductID(promo repo.Promo, productID int64) int64 {
+	return 0
+}
+
+type freeUnitPromotion struct{}
+
+// NewFreeUnitPromotion makes one unit free for every min qty units of the
+// line, like buy 3 pay 2.
+func NewFreeUnitPromotion() PromotionKind {
+	return freeUnitPromotion{}
+}
+
+func (freeUnitPromotion) Name() string {
+	return PromoTypeFreeUnit
+}
+
+func (freeUnitPromotion) Params() []PromoParam {
+	return []PromoParam{}
+}
+
+func (freeUnitPromotion) Validate(ctx context.Context, promo repo.Promo, check PromoCheck) error {
+	return validatePromoFreeUnit(promo)
+}
+
+func (freeUnitPromotion) Promotion(promo repo.Promo, productID int64, env PromotionEnv) Promotion {
+	return &ProductPromoDiscount{BuyQty: promo.MinQty, FreeQty: 1}
+}
+
+func (freeUnitPromotion) GiftProductID(promo repo.Promo, productID int64) int64 {
+	return 0
+}
+
+type buyGetPromotion struct{}
+
+// NewBuyGetPromotion makes free qty units free for every min qty units of the
+// line, like buy 5 pay 3.
+func NewBuyGetPromotion() PromotionKind {
+	return buyGetPromotion{}
+}
+
+func (buyGetPromotion) Name() string {
+	return PromoTypeBuyGet
+}
+
+func (buyGetPromotion) Params() []PromoParam {
+	return []PromoParam{{Name: "free_qty", Type: PromoParamFreeQty, Description: "units given away for every min_qty units of the line"}}
+}
+
+func (buyGetPromotion) Validate(ctx context.Context, promo repo.Promo, check PromoCheck) error {
+	return validatePromoFreeQty(promo, "free_qty", promo.FreeQty)
+}
+
+func (buyGetPromotion) Promotion(promo repo.Promo, productID int64, env PromotionEnv) Promotion {
+	return &ProductPromoDiscount{BuyQty: promo.MinQty, FreeQty: promo.FreeQty}
+}
+
+func (buyGetPromotion) GiftProductID(promo repo.Promo, productID int64) int64 {
+	return 0
+}
+
+type tieredPromotion struct{}
+
+// NewTieredPromotion takes a percent off the line that grows with its qty,
+// like 10% from 2 units and 20% from 5.
+func NewTieredPromotion() PromotionKind {
+	return tieredPromotion{}
+}
+
+func (tieredPromotion) Name() string {
+	return PromoTypeTiered
+}
+
+func (tieredPromotion) Params() []PromoParam {
+	return []PromoParam{{Name: "tiers", Type: PromoParamTiers, Description: "percent taken off the line from each min_qty up"}}
+}
+
+func (tieredPromotion) Validate(ctx context.Context, promo repo.Promo, check PromoCheck) error {
+	return validatePromoTiers(promo, "tiers", promo.Tiers)
+}
+
+func (tieredPromotion) Promotion(promo repo.Promo, productID int64, env PromotionEnv) Promotion {
+	return &TieredPromo{Currency: env.Currency}
+}
+
+func (tieredPromotion) GiftProductID(promo repo.Promo, productID int64) int64 {
+	return 0
+}
+
+type giftPromotion struct{}
+
+// NewGiftPromotion gives one unit of the reward product away with the line.
+func NewGiftPromotion() PromotionKind {
+	return giftPromotion{}
+}
+
+func (giftPromotion) Name() string {
+	return PromoTypeGift
+}
+
+func (giftPromotion) Params() []PromoParam {
+	return []PromoParam{{Name: "reward", Type: PromoParamProductID, Description: "product given away"}}
+}
+
+func (giftPromotion) Validate(ctx context.Context, promo repo.Promo, check PromoCheck) error {
+	return validatePromoProductID(ctx, promo, "reward", promo.Reward, check)
+}
+
+func (giftPromotion) Promotion(promo repo.Promo, productID int64, env PromotionEnv) Promotion {
+	return &ProductPromoFree{Stock: env.Stock, StockPolicy: env.StockPolicy}
+}
+
+func (giftPromotion) GiftProductID(promo repo.Promo, productID int64) int64 {
+	return promo.Reward.IntPart()
+}
+
+type productPromotion struct{}
+
+// NewProductPromotion is the promo_type older promos were written with. It
+// is a free unit when the reward is the promo's own product and a gift
+// otherwise.
+func NewProductPromotion() PromotionKind {
+	return productPromotion{}
+}
+
+func (productPromotion) Name() string {
+	return PromoTypeProduct
+}
+
+func (productPromotion) Params() []PromoParam {
+	return []PromoParam{{Name: "reward", Type: PromoParamProductID, Description: "the promo's own product for a free unit, any other product to give it away"}}
+}
+
+func (p productPromotion) Validate(ctx context.Context, promo repo.Promo, check PromoCheck) error {
+	err := validatePromoProductID(ctx, promo, "reward", promo.Reward, check)
+	if err != nil {
+		return err
+	}
+
+	if p.GiftProductID(promo, promo.ProductID) == 0 {
+		return validatePromoFreeUnit(promo)
+	}
+
+	return nil
+}
+
+func (p productPromotion) Promotion(promo repo.Promo, productID int64, env PromotionEnv) Promotion {
+	if p.GiftProductID(promo, productID) == 0 {
+		return freeUnitPromotion{}.Promotion(promo, productID, env)
+	}
+
+	return giftPromotion{}.Promotion(promo, productID, env)
+}
+
+func (productPromotion) GiftProductID(promo repo.Promo, productID int64) int64 {
+	if promo.Reward.IntPart() == productID {
+		return 0
+	}
+
+	return promo.Reward.IntPart()
+}
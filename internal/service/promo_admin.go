@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"go.uber.org/dig"
+)
+
+// Names of the built-in promotion kinds.
+const (
+	PromoTypeDiscount = "discount"
+	PromoTypeFreeUnit = "free_unit"
+	PromoTypeGift     = "gift"
+	// PromoTypeProduct is either a free unit or a gift depending on its
+	// reward, kept for promos written before the two were told apart.
+	PromoTypeProduct = "product"
+	PromoTypeBuyGet  = "buy_get"
+	PromoTypeTiered  = "tiered"
+)
+
+var (
+	// ErrInvalidPromo wraps every validation failure of a promo write.
+	ErrInvalidPromo = apperr.New(apperr.CodePromoInvalid, "invalid promo")
+	// ErrPromoNotFound is returned when a write targets an unknown promo.
+	ErrPromoNotFound = apperr.New(apperr.CodeNotFound, "promo not found")
+)
+
+type (
+	PromoUsecase interface {
+		GetPromos(ctx context.Context) (res []repo.Promo, err error)
+		GetPromoByPromoID(ctx context.Context, promoID int64) (res repo.Promo, err error)
+		CreatePromo(ctx context.Context, form repo.Promo) (res repo.Promo, err error)
+		UpdatePromo(ctx context.Context, form repo.Promo) (res repo.Promo, err error)
+		DeactivatePromo(ctx context.Context, promoID int64) (res repo.Promo, err error)
+		DeletePromo(ctx context.Context, promoID int64) (err error)
+		GetPromoTypes(ctx context.Context) (res []PromoType, err error)
+	}
+
+	PromoUsecaseImpl struct {
+		dig.In
+		ProductRepo repo.ProductRepository
+		PromoRepo   repo.PromoRepository
+		Promotions  *PromotionRegistry `optional:"true"`
+		Clock       clock.Clock        `optional:"true"`
+	}
+)
+
+func NewPromoUsecase(impl PromoUsecaseImpl) PromoUsecase {
+	return &impl
+}
+
+// GetPromos returns the promos running right now.
+func (c *PromoUsecaseImpl) GetPromos(ctx context.Context) (res []repo.Promo, err error) {
+	res, err = c.PromoRepo.GetAllPromo(ctx, c.now())
+	if err != nil {
+		log.Printf("error while do GetAllPromo %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *PromoUsecaseImpl) GetPromoByPromoID(ctx context.Context, promoID int64) (res repo.Promo, err error) {
+	res, err = c.PromoRepo.GetPromoByPromoID(ctx, promoID)
+	if err != nil {
+		log.Printf("error while do GetPromoByPromoID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *PromoUsecaseImpl) CreatePromo(ctx context.Context, form repo.Promo) (res repo.Promo, err error) {
+	err = c.validatePromo(ctx, form)
+	if err != nil {
+		return res, err
+	}
+
+	form.PromoID, err = c.PromoRepo.CreatePromo(ctx, form)
+	if err != nil {
+		log.Printf("error while do CreatePromo %+v", err)
+		return res, err
+	}
+
+	return form, nil
+}
+
+// UpdatePromo replaces every field of the promo identified by form.PromoID.
+func (c *PromoUsecaseImpl) UpdatePromo(ctx context.Context, form repo.Promo) (res repo.Promo, err error) {
+	_, err = c.existingPromo(ctx, form.PromoID)
+	if err != nil {
+		return res, err
+	}
+
+	err = c.validatePromo(ctx, form)
+	if err != nil {
+		return res, err
+	}
+
+	err = c.PromoRepo.UpdatePromo(ctx, form)
+	if err != nil {
+		log.Printf("error while do UpdatePromo %+v", err)
+		return res, err
+	}
+
+	return form, nil
+}
+
+// DeactivatePromo switches the promo off but keeps it, so orders that used it
+// still point at a real row.
+func (c *PromoUsecaseImpl) DeactivatePromo(ctx context.Context, promoID int64) (res repo.Promo, err error) {
+	res, err = c.existingPromo(ctx, promoID)
+	if err != nil {
+		return res, err
+	}
+
+	err = c.PromoRepo.DeactivatePromo(ctx, promoID)
+	if err != nil {
+		log.Printf("error while do DeactivatePromo %+v", err)
+		return res, err
+	}
+
+	res.Active = false
+
+	return res, nil
+}
+
+func (c *PromoUsecaseImpl) DeletePromo(ctx context.Context, promoID int64) (err error) {
+	_, err = c.existingPromo(ctx, promoID)
+	if err != nil {
+		return err
+	}
+
+	err = c.PromoRepo.DeletePromo(ctx, promoID)
+	if err != nil {
+		log.Printf("error while do DeletePromo %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *PromoUsecaseImpl) existingPromo(ctx context.Context, promoID int64) (res repo.Promo, err error) {
+	res, err = c.PromoRepo.GetPromoByPromoID(ctx, promoID)
+	if err != nil {
+		log.Printf("error while do GetPromoByPromoID %+v", err)
+		return res, err
+	}
+
+	if res.PromoID == 0 {
+		return res, fmt.Errorf("%w: %d", ErrPromoNotFound, promoID)
+	}
+
+	return res, nil
+}
+
+func (c *PromoUsecaseImpl) validatePromo(ctx context.Context, form repo.Promo) error {
+	if form.MinQty < 1 {
+		return fmt.Errorf("%w: min_qty must be at least 1", ErrInvalidPromo)
+	}
+
+	check := promoCheck{ProductRepo: c.ProductRepo}
+	err := check.Product(ctx, form.ProductID, "product_id")
+	if err != nil {
+		return err
+	}
+
+	kind, ok := c.promotions().Kind(form.PromoType)
+	if !ok {
+		return fmt.Errorf("%w: unknown promo_type %q", ErrInvalidPromo, form.PromoType)
+	}
+
+	err = kind.Validate(ctx, form, check)
+	if err != nil {
+		return err
+	}
+
+	return validatePromoWindow(form.StartsAt, form.EndsAt, form.Timezone)
+}
+
+// validatePromoWindow checks the schedule of a promo, which order promos
+// share with product promos.
+func validatePromoWindow(startsAt, endsAt *time.Time, timezone string) error {
+	if timezone != "" {
+		_, err := time.LoadLocation(timezone)
+		if err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidPromo, timezone)
+		}
+	}
+
+	if startsAt != nil && endsAt != nil && !startsAt.Before(*endsAt) {
+		return fmt.Errorf("%w: starts_at must be before ends_at", ErrInvalidPromo)
+	}
+
+	return nil
+}
+
+// promoCheck looks up what a promo refers to for the kind validating it.
+type promoCheck struct {
+	ProductRepo repo.ProductRepository
+}
+
+func (p promoCheck) Product(ctx context.Context, productID int64, param string) error {
+	product, err := p.ProductRepo.GetProductByProductID(ctx, productID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductID %+v", err)
+		return err
+	}
+
+	if product.ProductID == 0 {
+		return fmt.Errorf("%w: %s references unknown product %d", ErrInvalidPromo, param, productID)
+	}
+
+	if product.ArchivedAt != nil {
+		return fmt.Errorf("%w: %s references archived product %d", ErrInvalidPromo, param, productID)
+	}
+
+	return nil
+}
+
+// validatePromoPercent checks a PromoParamPercent field of promo.
+func validatePromoPercent(promo repo.Promo, name string, value money.Decimal) error {
+	if value.IsNegative() || value.Cmp(money.NewFromInt(100)) > 0 {
+		return fmt.Errorf("%w: %s %s must be between 0 and 100", ErrInvalidPromo, promo.PromoType, name)
+	}
+
+	return nil
+}
+
+// validatePromoProductID checks a PromoParamProductID field of promo.
+func validatePromoProductID(ctx context.Context, promo repo.Promo, name string, value money.Decimal, check PromoCheck) error {
+	if value.Cmp(money.NewFromInt(value.IntPart())) != 0 {
+		return fmt.Errorf("%w: %s %s must be a product id", ErrInvalidPromo, promo.PromoType, name)
+	}
+
+	return check.Product(ctx, value.IntPart(), name)
+}
+
+// validatePromoFreeQty checks a PromoParamFreeQty field of promo.
+func validatePromoFreeQty(promo repo.Promo, name string, value int64) error {
+	if value < 1 || value >= promo.MinQty {
+		return fmt.Errorf("%w: %s %s must be at least 1 and below min_qty", ErrInvalidPromo, promo.PromoType, name)
+	}
+
+	return nil
+}
+
+// validatePromoTiers checks the break points of a tiered promo. None may sit
+// below the promo's min qty, which decides when the promo is considered.
+func validatePromoTiers(promo repo.Promo, name string, tiers repo.PromoTiers) error {
+	if len(tiers) == 0 {
+		return fmt.Errorf("%w: %s %s needs at least one tier", ErrInvalidPromo, promo.PromoType, name)
+	}
+
+	for i, tier := range tiers {
+		if tier.MinQty < promo.MinQty {
+			return fmt.Errorf("%w: %s %s min_qty must be at least the promo's min_qty", ErrInvalidPromo, promo.PromoType, name)
+		}
+
+		if i > 0 && tier.MinQty <= tiers[i-1].MinQty {
+			return fmt.Errorf("%w: %s %s must be in increasing min_qty", ErrInvalidPromo, promo.PromoType, name)
+		}
+
+		if !tier.Percent.IsNegative() && !tier.Percent.IsZero() && tier.Percent.Cmp(money.NewFromInt(100)) <= 0 {
+			continue
+		}
+
+		return fmt.Errorf("%w: %s %s percent must be above 0 and at most 100", ErrInvalidPromo, promo.PromoType, name)
+	}
+
+	return nil
+}
+
+// validatePromoFreeUnit checks a promo that makes one unit of its own
+// product free for every min qty units of the line. With a min qty of 1
+// the whole line would be free.
+func validatePromoFreeUnit(promo repo.Promo) error {
+	if promo.MinQty < 2 {
+		return fmt.Errorf("%w: %s min_qty must be at least 2", ErrInvalidPromo, promo.PromoType)
+	}
+
+	return nil
+}
+
+// GetPromoTypes lists the promo types promos can be written with.
+func (c *PromoUsecaseImpl) GetPromoTypes(ctx context.Context) (res []PromoType, err error) {
+	return c.promotions().Types(), nil
+}
+
+func (c *PromoUsecaseImpl) promotions() *PromotionRegistry {
+	if c.Promotions == nil {
+		return builtinPromotions
+	}
+
+	return c.Promotions
+}
+
+func (c *PromoUsecaseImpl) now() time.Time {
+	if c.Clock == nil {
+		return time.Now()
+	}
+
+	return c.Clock.Now()
+}
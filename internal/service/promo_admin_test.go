@@ -0,0 +1,328 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPromoCreatePromo(t *testing.T) {
+	googleHome := repo.Product{ProductID: 1, Sku: "120P90", Name: "Google Home", Price: money.MustParse("49.99"), Qty: 10}
+	startsAt := time.Date(2023, 6, 10, 0, 0, 0, 0, time.UTC)
+	endsAt := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	tiers := repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("10")}, {MinQty: 5, Percent: money.MustParse("20")}}
+
+	tests := []struct {
+		name          string
+		form          repo.Promo
+		mockSetupFunc func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository)
+		expectedResp  repo.Promo
+		expectedErr   error
+	}{
+		{
+			name: "discount promo",
+			form: repo.Promo{ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 3, Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				promoRepo.On("CreatePromo", mock.Anything, mock.Anything).Return(int64(4), nil)
+			},
+			expectedResp: repo.Promo{PromoID: 4, ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 3, Active: true},
+		},
+		{
+			name: "product promo rewards an existing product",
+			form: repo.Promo{ProductID: 1, PromoType: "product", Reward: money.MustParse("1"), MinQty: 2, Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				promoRepo.On("CreatePromo", mock.Anything, mock.Anything).Return(int64(5), nil)
+			},
+			expectedResp: repo.Promo{PromoID: 5, ProductID: 1, PromoType: "product", Reward: money.MustParse("1"), MinQty: 2, Active: true},
+		},
+		{
+			name: "free unit promo takes no reward",
+			form: repo.Promo{ProductID: 1, PromoType: "free_unit", MinQty: 3, Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				promoRepo.On("CreatePromo", mock.Anything, mock.Anything).Return(int64(6), nil)
+			},
+			expectedResp: repo.Promo{PromoID: 6, ProductID: 1, PromoType: "free_unit", MinQty: 3, Active: true},
+		},
+		{
+			name: "free unit promo for every unit of the line",
+			form: repo.Promo{ProductID: 1, PromoType: "free_unit", MinQty: 1, Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "product promo rewarding its own product for every unit of the line",
+			form: repo.Promo{ProductID: 1, PromoType: "product", Reward: money.MustParse("1"), MinQty: 1, Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "gift reward references an unknown product",
+			form: repo.Promo{ProductID: 1, PromoType: "gift", Reward: money.MustParse("9"), MinQty: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				productRepo.On("GetProductByProductID", mock.Anything, int64(9)).Return(repo.Product{}, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name:          "min qty below one",
+			form:          repo.Promo{ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 0},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {},
+			expectedErr:   service.ErrInvalidPromo,
+		},
+		{
+			name: "unknown promo type",
+			form: repo.Promo{ProductID: 1, PromoType: "cashback", Reward: money.MustParse("10"), MinQty: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "unknown product",
+			form: repo.Promo{ProductID: 9, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(9)).Return(repo.Product{}, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "discount above 100",
+			form: repo.Promo{ProductID: 1, PromoType: "discount", Reward: money.MustParse("100.5"), MinQty: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "negative discount",
+			form: repo.Promo{ProductID: 1, PromoType: "discount", Reward: money.MustParse("-1"), MinQty: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "product reward is not a product id",
+			form: repo.Promo{ProductID: 1, PromoType: "product", Reward: money.MustParse("1.5"), MinQty: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "product reward references an unknown product",
+			form: repo.Promo{ProductID: 1, PromoType: "product", Reward: money.MustParse("9"), MinQty: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				productRepo.On("GetProductByProductID", mock.Anything, int64(9)).Return(repo.Product{}, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "buy get promo gives fewer units than it needs",
+			form: repo.Promo{ProductID: 1, PromoType: "buy_get", MinQty: 5, FreeQty: 2, Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				promoRepo.On("CreatePromo", mock.Anything, mock.Anything).Return(int64(7), nil)
+			},
+			expectedResp: repo.Promo{PromoID: 7, ProductID: 1, PromoType: "buy_get", MinQty: 5, FreeQty: 2, Active: true},
+		},
+		{
+			name: "tiered promo",
+			form: repo.Promo{ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: tiers, Active: true},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				promoRepo.On("CreatePromo", mock.Anything, mock.Anything).Return(int64(8), nil)
+			},
+			expectedResp: repo.Promo{PromoID: 8, ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: tiers, Active: true},
+		},
+		{
+			name: "buy get gives no units away",
+			form: repo.Promo{ProductID: 1, PromoType: "buy_get", MinQty: 3, FreeQty: 0},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "buy get gives every unit away",
+			form: repo.Promo{ProductID: 1, PromoType: "buy_get", MinQty: 3, FreeQty: 3},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "tiered promo without tiers",
+			form: repo.Promo{ProductID: 1, PromoType: "tiered", MinQty: 2},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "tier below the promo's min qty",
+			form: repo.Promo{ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: repo.PromoTiers{{MinQty: 1, Percent: money.MustParse("5")}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "tiers out of order",
+			form: repo.Promo{ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: repo.PromoTiers{{MinQty: 5, Percent: money.MustParse("20")}, {MinQty: 2, Percent: money.MustParse("10")}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "tiers sharing a min qty",
+			form: repo.Promo{ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("10")}, {MinQty: 2, Percent: money.MustParse("20")}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "tier of zero percent",
+			form: repo.Promo{ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("0")}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "tier above 100 percent",
+			form: repo.Promo{ProductID: 1, PromoType: "tiered", MinQty: 2, Tiers: repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("100.5")}}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "unknown timezone",
+			form: repo.Promo{ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 1, Timezone: "Mars/Olympus"},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "window ends before it starts",
+			form: repo.Promo{ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 1, StartsAt: &startsAt, EndsAt: &endsAt},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+			},
+			expectedErr: service.ErrInvalidPromo,
+		},
+		{
+			name: "repository error",
+			form: repo.Promo{ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 1},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository) {
+				productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+				promoRepo.On("CreatePromo", mock.Anything, mock.Anything).Return(int64(0), errors.New("error"))
+			},
+			expectedErr: errors.New("error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			tt.mockSetupFunc(productRepo, promoRepo)
+
+			promoUsecase := service.NewPromoUsecase(service.PromoUsecaseImpl{
+				ProductRepo: productRepo,
+				PromoRepo:   promoRepo,
+			})
+
+			res, err := promoUsecase.CreatePromo(context.Background(), tt.form)
+			if tt.expectedErr != nil {
+				if errors.Is(tt.expectedErr, service.ErrInvalidPromo) {
+					assert.ErrorIs(t, err, service.ErrInvalidPromo)
+				} else {
+					assert.EqualError(t, err, tt.expectedErr.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			productRepo.AssertExpectations(t)
+			promoRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPromoWrites(t *testing.T) {
+	googleHome := repo.Product{ProductID: 1, Sku: "120P90", Name: "Google Home", Price: money.MustParse("49.99"), Qty: 10}
+	promo := repo.Promo{PromoID: 3, ProductID: 1, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 3, Active: true}
+	now := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+
+	productRepo := new(mockRepo.ProductRepository)
+	promoRepo := new(mockRepo.PromoRepository)
+
+	productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+	promoRepo.On("GetAllPromo", mock.Anything, now).Return([]repo.Promo{promo}, nil)
+	promoRepo.On("GetPromoByPromoID", mock.Anything, int64(3)).Return(promo, nil)
+	promoRepo.On("GetPromoByPromoID", mock.Anything, int64(9)).Return(repo.Promo{}, nil)
+	promoRepo.On("UpdatePromo", mock.Anything, mock.Anything).Return(nil)
+	promoRepo.On("DeactivatePromo", mock.Anything, int64(3)).Return(nil)
+	promoRepo.On("DeletePromo", mock.Anything, int64(3)).Return(nil)
+
+	promoUsecase := service.NewPromoUsecase(service.PromoUsecaseImpl{
+		ProductRepo: productRepo,
+		PromoRepo:   promoRepo,
+		Clock:       clock.Fixed(now),
+	})
+
+	ctx := context.Background()
+
+	promos, err := promoUsecase.GetPromos(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []repo.Promo{promo}, promos)
+
+	updated := promo
+	updated.Reward = money.MustParse("15")
+	res, err := promoUsecase.UpdatePromo(ctx, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, res)
+
+	updated.PromoID = 9
+	_, err = promoUsecase.UpdatePromo(ctx, updated)
+	assert.ErrorIs(t, err, service.ErrPromoNotFound)
+
+	res, err = promoUsecase.DeactivatePromo(ctx, 3)
+	assert.NoError(t, err)
+	assert.False(t, res.Active)
+
+	_, err = promoUsecase.DeactivatePromo(ctx, 9)
+	assert.ErrorIs(t, err, service.ErrPromoNotFound)
+
+	err = promoUsecase.DeletePromo(ctx, 3)
+	assert.NoError(t, err)
+
+	err = promoUsecase.DeletePromo(ctx, 9)
+	assert.ErrorIs(t, err, service.ErrPromoNotFound)
+
+	promoRepo.AssertNotCalled(t, "DeletePromo", mock.Anything, int64(9))
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+	"go.uber.org/dig"
+)
+
+// Types of a PromoParam. Kinds validate the fields they read against them.
+const (
+	// PromoParamPercent is a decimal between 0 and 100.
+	PromoParamPercent = "percent"
+	// PromoParamProductID is the id of a product that exists and isn't
+	// archived.
+	PromoParamProductID = "product_id"
+	// PromoParamFreeQty is a whole number of units from 1 up to, but not
+	// including, the promo's min qty.
+	PromoParamFreeQty = "free_qty"
+	// PromoParamTiers is a list of break points with a min qty of at least
+	// the promo's, each above the one before, and a percent above 0 up to
+	// 100.
+	PromoParamTiers = "tiers"
+)
+
+// Dig value groups the kinds are provided in.
+const (
+	// PromotionGroup holds the PromotionKinds.
+	PromotionGroup = "promotions"
+	// OrderPromotionGroup holds the OrderPromotionKinds.
+	OrderPromotionGroup = "order_promotions"
+	// BundleGroup holds the BundleKinds.
+	BundleGroup = "bundles"
+)
+
+type (
+	// PromoParam describes one field of a promo its kind reads.
+	PromoParam struct {
+		// Name is the promo field holding the value.
+		Name        string `json:"name"`
+		Type        string `json:"type"`
+		Description string `json:"description"`
+	}
+
+	// PromoType is a registered promotion kind as shown to clients.
+	PromoType struct {
+		Name   string       `json:"name"`
+		Params []PromoParam `json:"params"`
+	}
+
+	// PromotionEnv is what a promotion may use while it prices a line.
+	PromotionEnv struct {
+		// Stock is nil when the promotion is only valued, never applied.
+		Stock       cartStock
+		StockPolicy RewardStockPolicy
+		Currency    money.Currency
+	}
+
+	// PromotionKind is one kind of promo checkout can apply. Promos name
+	// their kind in promo_type.
+	PromotionKind interface {
+		Name() string
+		// Params is the schema of the promo fields the kind reads.
+		Params() []PromoParam
+		// Validate checks the fields of a promo being written against
+		// Params, failing with an error wrapping ErrInvalidPromo.
+		Validate(ctx context.Context, promo repo.Promo, check PromoCheck) error
+		// Promotion returns what applies promo to a line of productID.
+		Promotion(promo repo.Promo, productID int64, env PromotionEnv) Promotion
+		// GiftProductID is the product promo gives away on a line of
+		// productID, zero when it lowers the line's price instead.
+		GiftProductID(promo repo.Promo, productID int64) int64
+	}
+
+	// OrderPromotionEnv is what an order promotion may use while it prices
+	// an order.
+	OrderPromotionEnv struct {
+		Stock       cartStock
+		StockPolicy RewardStockPolicy
+		Currency    money.Currency
+	}
+
+	// OrderPromotionKind is one kind of order promo checkout can apply.
+	// Order promos name their kind in promo_type, apart from the product
+	// promo kinds.
+	OrderPromotionKind interface {
+		Name() string
+		// Validate checks the reward of an order promo being written,
+		// failing with an error wrapping ErrInvalidPromo.
+		Validate(ctx context.Context, promo repo.OrderPromo, check PromoCheck) error
+		// OrderPromotion returns what applies promo to an order.
+		OrderPromotion(promo repo.OrderPromo, env OrderPromotionEnv) OrderPromotion
+	}
+
+	// BundleKind is one kind of bundle checkout can apply. Bundles name
+	// their kind in bundle_type.
+	BundleKind interface {
+		Name() string
+		// Validate checks the reward of a bundle being written, failing
+		// with an error wrapping ErrInvalidPromo.
+		Validate(ctx context.Context, bundle repo.Bundle, check PromoCheck) error
+		// BundlePrice is what one application of bundle costs, given list,
+		// what its items cost on their own.
+		BundlePrice(bundle repo.Bundle, list money.Decimal, currency money.Currency) money.Decimal
+	}
+
+	// PromoCheck looks up what a promo being written refers to.
+	PromoCheck interface {
+		// Product fails with ErrInvalidPromo unless productID is a product
+		// that exists and isn't archived. param names the field holding it.
+		Product(ctx context.Context, productID int64, param string) error
+	}
+
+	// PromotionRegistry holds the promotion kinds by name.
+	PromotionRegistry struct {
+		kinds       map[string]PromotionKind
+		orderKinds  map[string]OrderPromotionKind
+		bundleKinds map[string]BundleKind
+	}
+
+	PromotionRegistryParams struct {
+		dig.In
+		Kinds       []PromotionKind      `group:"promotions"`
+		OrderKinds  []OrderPromotionKind `group:"order_promotions"`
+		BundleKinds []BundleKind         `group:"bundles"`
+	}
+)
+
+// builtinPromotions is used by services built without a registry.
+var builtinPromotions = mustPromotionRegistry(PromotionRegistryParams{
+	Kinds:       BuiltinPromotionKinds(),
+	OrderKinds:  BuiltinOrderPromotionKinds(),
+	BundleKinds: BuiltinBundleKinds(),
+})
+
+// BuiltinPromotionKinds are the promotion kinds the shop ships with.
+func BuiltinPromotionKinds() []PromotionKind {
+	return []PromotionKind{
+		NewDiscountPromotion(),
+		NewFreeUnitPromotion(),
+		NewGiftPromotion(),
+		NewProductPromotion(),
+		NewBuyGetPromotion(),
+		NewTieredPromotion(),
+	}
+}
+
+// BuiltinOrderPromotionKinds are the order promotion kinds the shop ships
+// with.
+func BuiltinOrderPromotionKinds() []OrderPromotionKind {
+	return []OrderPromotionKind{
+		NewOrderDiscountPromotion(),
+		NewOrderAmountPromotion(),
+		NewOrderGiftPromotion(),
+	}
+}
+
+// BuiltinBundleKinds are the bundle kinds the shop ships with.
+func BuiltinBundleKinds() []BundleKind {
+	return []BundleKind{
+		NewPriceBundle(),
+		NewDiscountBundle(),
+	}
+}
+
+// NewPromotionRegistry registers every kind provided in PromotionGroup,
+// OrderPromotionGroup and BundleGroup. Two kinds of a group can't share a
+// name.
+func NewPromotionRegistry(p PromotionRegistryParams) (*PromotionRegistry, error) {
+	r := &PromotionRegistry{
+		kinds:       make(map[string]PromotionKind, len(p.Kinds)),
+		orderKinds:  make(map[string]OrderPromotionKind, len(p.OrderKinds)),
+		bundleKinds: make(map[string]BundleKind, len(p.BundleKinds)),
+	}
+	for _, kind := range p.Kinds {
+		if _, ok := r.kinds[kind.Name()]; ok {
+			return nil, fmt.Errorf("promotion kind %q registered twice", kind.Name())
+		}
+
+		r.kinds[kind.Name()] = kind
+	}
+
+	for _, kind := range p.OrderKinds {
+		if _, ok := r.orderKinds[kind.Name()]; ok {
+			return nil, fmt.Errorf("order promotion kind %q registered twice", kind.Name())
+		}
+
+		r.orderKinds[kind.Name()] = kind
+	}
+
+	for _, kind := range p.BundleKinds {
+		if _, ok := r.bundleKinds[kind.Name()]; ok {
+			return nil, fmt.Errorf("bundle kind %q registered twice", kind.Name())
+		}
+
+		r.bundleKinds[kind.Name()] = kind
+	}
+
+	return r, nil
+}
+
+func mustPromotionRegistry(p PromotionRegistryParams) *PromotionRegistry {
+	r, err := NewPromotionRegistry(p)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+func (r *PromotionRegistry) Kind(name string) (PromotionKind, bool) {
+	kind, ok := r.kinds[name]
+	return kind, ok
+}
+
+func (r *PromotionRegistry) OrderKind(name string) (OrderPromotionKind, bool) {
+	kind, ok := r.orderKinds[name]
+	return kind, ok
+}
+
+func (r *PromotionRegistry) BundleKind(name string) (BundleKind, bool) {
+	kind, ok := r.bundleKinds[name]
+	return kind, ok
+}
+
+// Types lists the registered kinds by name.
+func (r *PromotionRegistry) Types() []PromoType {
+	res := make([]PromoType, 0, len(r.kinds))
+	for _, kind := range r.kinds {
+		res = append(res, PromoType{Name: kind.Name(), Params: kind.Params()})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// promotion returns what applies promo to a line of productID, nil for a kind
+// that isn't registered.
+func (r *PromotionRegistry) promotion(promo repo.Promo, productID int64, env PromotionEnv) Promotion {
+	kind, ok := r.kinds[promo.PromoType]
+	if !ok {
+		return nil
+	}
+
+	return kind.Promotion(promo, productID, env)
+}
+
+func (r *PromotionRegistry) giftProductID(promo repo.Promo, productID int64) int64 {
+	kind, ok := r.kinds[promo.PromoType]
+	if !ok {
+		return 0
+	}
+
+	return kind.GiftProductID(promo, productID)
+}
+
+// isGift reports whether promo gives a product away instead of lowering the
+// price of the line of productID.
+func (r *PromotionRegistry) isGift(promo repo.Promo, productID int64) bool {
+	return r.giftProductID(promo, productID) != 0
+}
+
+// orderPromotion returns what applies promo to an order, nil for a kind that
+// isn't registered.
+func (r *PromotionRegistry) orderPromotion(promo repo.OrderPromo, env OrderPromotionEnv) OrderPromotion {
+	kind, ok := r.orderKinds[promo.PromoType]
+	if !ok {
+		return nil
+	}
+
+	return kind.OrderPromotion(promo, env)
+}
@@ -0,0 +1,278 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// flatOffPromotion takes a fixed amount off the line, the kind of promo a
+// team adds without touching checkout.
+type flatOffPromotion struct{}
+
+func (flatOffPromotion) Name() string {
+	return "flat_off"
+}
+
+func (flatOffPromotion) Params() []service.PromoParam {
+	return []service.PromoParam{{Name: "reward", Type: service.PromoParamPercent, Description: "amount taken off the line"}}
+}
+
+func (flatOffPromotion) Validate(ctx context.Context, promo repo.Promo, check service.PromoCheck) error {
+	if promo.Reward.IsNegative() {
+		return fmt.Errorf("%w: flat_off reward can't be negative", service.ErrInvalidPromo)
+	}
+
+	return nil
+}
+
+func (flatOffPromotion) Promotion(promo repo.Promo, productID int64, env service.PromotionEnv) service.Promotion {
+	return flatOffPromotion{}
+}
+
+func (flatOffPromotion) GiftProductID(promo repo.Promo, productID int64) int64 {
+	return 0
+}
+
+func (flatOffPromotion) ApplyPromotion(ctx context.Context, tx *sqlx.Tx, item *repo.OrderDetail, v repo.OrderDetail, productDetail *repo.Product, promo *repo.Promo, res *service.Checkout) error {
+	item.Price = item.Price.Sub(promo.Reward)
+	return nil
+}
+
+// roundDownOrderPromotion takes the cents off the order.
+type roundDownOrderPromotion struct{}
+
+func (roundDownOrderPromotion) Name() string {
+	return "round_down"
+}
+
+func (roundDownOrderPromotion) Validate(ctx context.Context, promo repo.OrderPromo, check service.PromoCheck) error {
+	return nil
+}
+
+func (roundDownOrderPromotion) OrderPromotion(promo repo.OrderPromo, env service.OrderPromotionEnv) service.OrderPromotion {
+	return roundDownOrderPromotion{}
+}
+
+func (roundDownOrderPromotion) ApplyOrderPromotion(ctx context.Context, tx *sqlx.Tx, promo *repo.OrderPromo, res *service.Checkout) (money.Decimal, error) {
+	return res.TotalAmount.Sub(money.NewFromInt(res.TotalAmount.IntPart())), nil
+}
+
+// amountOffBundle takes a fixed amount off the bundle's items.
+type amountOffBundle struct{}
+
+func (amountOffBundle) Name() string {
+	return "amount_off"
+}
+
+func (amountOffBundle) Validate(ctx context.Context, bundle repo.Bundle, check service.PromoCheck) error {
+	return nil
+}
+
+func (amountOffBundle) BundlePrice(bundle repo.Bundle, list money.Decimal, currency money.Currency) money.Decimal {
+	return list.Sub(bundle.Reward)
+}
+
+func TestPromotionRegistry(t *testing.T) {
+	t.Run("a kind can't be registered twice", func(t *testing.T) {
+		_, err := service.NewPromotionRegistry(service.PromotionRegistryParams{
+			Kinds: []service.PromotionKind{service.NewGiftPromotion(), service.NewGiftPromotion()},
+		})
+		assert.EqualError(t, err, `promotion kind "gift" registered twice`)
+	})
+
+	t.Run("types are listed by name", func(t *testing.T) {
+		registry, err := service.NewPromotionRegistry(service.PromotionRegistryParams{
+			Kinds: append(service.BuiltinPromotionKinds(), flatOffPromotion{}),
+		})
+		assert.NoError(t, err)
+
+		names := []string{}
+		for _, promoType := range registry.Types() {
+			names = append(names, promoType.Name)
+		}
+		assert.Equal(t, []string{"buy_get", "discount", "flat_off", "free_unit", "gift", "product", "tiered"}, names)
+	})
+
+	t.Run("an order kind can't be registered twice", func(t *testing.T) {
+		_, err := service.NewPromotionRegistry(service.PromotionRegistryParams{
+			OrderKinds: []service.OrderPromotionKind{service.NewOrderGiftPromotion(), service.NewOrderGiftPromotion()},
+		})
+		assert.EqualError(t, err, `order promotion kind "gift" registered twice`)
+	})
+
+	t.Run("a registered order kind prices the order", func(t *testing.T) {
+		registry, err := service.NewPromotionRegistry(service.PromotionRegistryParams{
+			Kinds:      service.BuiltinPromotionKinds(),
+			OrderKinds: append(service.BuiltinOrderPromotionKinds(), roundDownOrderPromotion{}),
+		})
+		assert.NoError(t, err)
+
+		productRepo := new(mockRepo.ProductRepository)
+		productRepo.On("GetProductByProductID", mock.Anything, int64(1)).Return(googleHome, nil)
+		promoRepo := new(mockRepo.PromoRepository)
+		promoRepo.On("GetPromosByProductID", mock.Anything, int64(1), checkoutAt).Return([]repo.Promo{}, nil)
+		orderPromoRepo := new(mockRepo.OrderPromoRepository)
+		orderPromoRepo.On("GetOrderPromos", mock.Anything, checkoutAt).Return([]repo.OrderPromo{{OrderPromoID: 3, PromoType: "round_down", MinItems: 1}}, nil)
+
+		checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+			OrderPromoRepo: orderPromoRepo,
+			BundleRepo:     noBundles(),
+			ProductRepo:    productRepo,
+			PromoRepo:      promoRepo,
+			Promotions:     registry,
+			Clock:          clock.Fixed(checkoutAt),
+		})
+
+		quote, err := checkoutUsecase.QuoteCart(context.Background(), []repo.OrderDetail{{ProductID: 1, Qty: 1}})
+		assert.NoError(t, err)
+		assert.Equal(t, []service.AppliedOrderPromo{{OrderPromoID: 3, PromoType: "round_down", Discount: money.MustParse("0.99")}}, quote.OrderPromos)
+		assert.Equal(t, money.MustParse("49"), quote.TotalAmount)
+	})
+
+	t.Run("a bundle kind can't be registered twice", func(t *testing.T) {
+		_, err := service.NewPromotionRegistry(service.PromotionRegistryParams{
+			BundleKinds: []service.BundleKind{service.NewPriceBundle(), service.NewPriceBundle()},
+		})
+		assert.EqualError(t, err, `bundle kind "price" registered twice`)
+	})
+
+	t.Run("a registered bundle kind prices the bundle", func(t *testing.T) {
+		registry, err := service.NewPromotionRegistry(service.PromotionRegistryParams{
+			Kinds:       service.BuiltinPromotionKinds(),
+			BundleKinds: append(service.BuiltinBundleKinds(), amountOffBundle{}),
+		})
+		assert.NoError(t, err)
+
+		productRepo := new(mockRepo.ProductRepository)
+		promoRepo := new(mockRepo.PromoRepository)
+		for _, p := range []repo.Product{googleHome, alexaSpeaker} {
+			productRepo.On("GetProductByProductID", mock.Anything, p.ProductID).Return(p, nil)
+			promoRepo.On("GetPromosByProductID", mock.Anything, p.ProductID, checkoutAt).Return([]repo.Promo{}, nil)
+		}
+		bundleRepo := new(mockRepo.BundleRepository)
+		bundleRepo.On("GetBundles", mock.Anything, checkoutAt).Return([]repo.Bundle{{BundleID: 4, Name: "Speakers", BundleType: "amount_off", Reward: money.MustParse("9.49"), Items: []repo.BundleItem{{BundleID: 4, ProductID: 1, Qty: 1}, {BundleID: 4, ProductID: 3, Qty: 1}}}}, nil)
+
+		checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+			OrderPromoRepo: noOrderPromos(),
+			BundleRepo:     bundleRepo,
+			ProductRepo:    productRepo,
+			PromoRepo:      promoRepo,
+			Promotions:     registry,
+			Clock:          clock.Fixed(checkoutAt),
+		})
+
+		quote, err := checkoutUsecase.QuoteCart(context.Background(), []repo.OrderDetail{{ProductID: 1, Qty: 1}, {ProductID: 3, Qty: 1}})
+		assert.NoError(t, err)
+		assert.Equal(t, []service.AppliedBundle{{BundleID: 4, Name: "Speakers", BundleType: "amount_off", Times: 1, Discount: money.MustParse("9.49")}}, quote.Bundles)
+		assert.Equal(t, money.MustParse("150"), quote.TotalAmount)
+	})
+
+	t.Run("a registered kind validates the promos written with it", func(t *testing.T) {
+		registry, err := service.NewPromotionRegistry(service.PromotionRegistryParams{
+			Kinds: append(service.BuiltinPromotionKinds(), flatOffPromotion{}),
+		})
+		assert.NoError(t, err)
+
+		productRepo := new(mockRepo.ProductRepository)
+		productRepo.On("GetProductByProductID", mock.Anything, int64(3)).Return(alexaSpeaker, nil)
+
+		promoUsecase := service.NewPromoUsecase(service.PromoUsecaseImpl{
+			ProductRepo: productRepo,
+			PromoRepo:   new(mockRepo.PromoRepository),
+			Promotions:  registry,
+		})
+
+		_, err = promoUsecase.CreatePromo(context.Background(), repo.Promo{ProductID: 3, PromoType: "flat_off", Reward: money.MustParse("-1"), MinQty: 1})
+		assert.ErrorIs(t, err, service.ErrInvalidPromo)
+	})
+
+	tests := []struct {
+		name          string
+		kinds         []service.PromotionKind
+		cart          []repo.OrderDetail
+		promos        map[int64][]repo.Promo
+		expectedLines []service.CheckoutLine
+	}{
+		{
+			name: "free unit",
+			cart: []repo.OrderDetail{{ProductID: 1, Qty: 3}},
+			promos: map[int64][]repo.Promo{
+				1: {{PromoID: 1, PromoType: "free_unit", MinQty: 3}},
+			},
+			expectedLines: []service.CheckoutLine{
+				{ProductID: 1, ProductName: "Google Home", Qty: 3, UnitPrice: money.MustParse("49.99"), Subtotal: money.MustParse("149.97"), Discount: money.MustParse("49.99"), Total: money.MustParse("99.98"), PromoID: 1, PromoType: "free_unit", Promos: []service.AppliedPromo{{PromoID: 1, PromoType: "free_unit"}}},
+			},
+		},
+		{
+			name: "gift",
+			cart: []repo.OrderDetail{{ProductID: 2, Qty: 1}},
+			promos: map[int64][]repo.Promo{
+				2: {{PromoID: 2, PromoType: "gift", Reward: money.MustParse("4"), MinQty: 1}},
+			},
+			expectedLines: []service.CheckoutLine{
+				{ProductID: 2, ProductName: "MacBook Pro", Qty: 1, UnitPrice: money.MustParse("5399.99"), Subtotal: money.MustParse("5399.99"), Total: money.MustParse("5399.99"), PromoID: 2, PromoType: "gift", Promos: []service.AppliedPromo{{PromoID: 2, PromoType: "gift"}}},
+				{ProductID: 4, ProductName: "Raspberry Pi B", Qty: 1, UnitPrice: money.MustParse("30"), Subtotal: money.MustParse("30"), Discount: money.MustParse("30"), PromoID: 2, PromoType: "gift", Free: true},
+			},
+		},
+		{
+			name:  "registered kind",
+			kinds: []service.PromotionKind{flatOffPromotion{}},
+			cart:  []repo.OrderDetail{{ProductID: 3, Qty: 1}},
+			promos: map[int64][]repo.Promo{
+				3: {{PromoID: 7, PromoType: "flat_off", Reward: money.MustParse("9.5"), MinQty: 1}},
+			},
+			expectedLines: []service.CheckoutLine{
+				{ProductID: 3, ProductName: "Alexa Speaker", Qty: 1, UnitPrice: money.MustParse("109.5"), Subtotal: money.MustParse("109.5"), Discount: money.MustParse("9.5"), Total: money.MustParse("100"), PromoID: 7, PromoType: "flat_off", Promos: []service.AppliedPromo{{PromoID: 7, PromoType: "flat_off"}}},
+			},
+		},
+		{
+			name: "unregistered kind is ignored",
+			cart: []repo.OrderDetail{{ProductID: 3, Qty: 1}},
+			promos: map[int64][]repo.Promo{
+				3: {{PromoID: 7, PromoType: "flat_off", Reward: money.MustParse("9.5"), MinQty: 1}},
+			},
+			expectedLines: []service.CheckoutLine{
+				{ProductID: 3, ProductName: "Alexa Speaker", Qty: 1, UnitPrice: money.MustParse("109.5"), Subtotal: money.MustParse("109.5"), Total: money.MustParse("109.5")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := service.NewPromotionRegistry(service.PromotionRegistryParams{
+				Kinds: append(service.BuiltinPromotionKinds(), tt.kinds...),
+			})
+			assert.NoError(t, err)
+
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			for _, p := range []repo.Product{googleHome, macBookPro, alexaSpeaker, raspberryPi} {
+				productRepo.On("GetProductByProductID", mock.Anything, p.ProductID).Return(p, nil).Maybe()
+				promoRepo.On("GetPromosByProductID", mock.Anything, p.ProductID, checkoutAt).Return(tt.promos[p.ProductID], nil).Maybe()
+			}
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderPromoRepo: noOrderPromos(),
+				BundleRepo:     noBundles(),
+				ProductRepo:    productRepo,
+				PromoRepo:      promoRepo,
+				Promotions:     registry,
+				Clock:          clock.Fixed(checkoutAt),
+			})
+
+			quote, err := checkoutUsecase.QuoteCart(context.Background(), tt.cart)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLines, quote.Lines)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+const (
+	// promos that change the line's price and promos that give a product away
+	// fall in these stack groups unless the promo names its own
+	stackGroupPrice = "price"
+	stackGroupGift  = "gift"
+
+	// maxPromoCandidates bounds how many promos of a line are considered, the
+	// selection tries every combination of them.
+	maxPromoCandidates = 10
+)
+
+func promoStackGroup(promotions *PromotionRegistry, promo repo.Promo, productID int64) string {
+	if promo.StackGroup != "" {
+		return promo.StackGroup
+	}
+
+	if promotions.isGift(promo, productID) {
+		return stackGroupGift
+	}
+
+	return stackGroupPrice
+}
+
+// eligiblePromos keeps the promos whose min qty is reached, in the order they
+// are applied: highest priority first, then by id.
+func eligiblePromos(promos []repo.Promo, qty int64) []repo.Promo {
+	res := make([]repo.Promo, 0, len(promos))
+	for _, promo := range promos {
+		if qty >= promo.MinQty {
+			res = append(res, promo)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Priority != res[j].Priority {
+			return res[i].Priority > res[j].Priority
+		}
+		return res[i].PromoID < res[j].PromoID
+	})
+
+	if len(res) > maxPromoCandidates {
+		res = res[:maxPromoCandidates]
+	}
+
+	return res
+}
+
+// promosStack reports whether promos may be applied together: an exclusive
+// promo stands alone and no two promos may share a stack group.
+func promosStack(promotions *PromotionRegistry, promos []repo.Promo, productID int64) bool {
+	if len(promos) < 2 {
+		return true
+	}
+
+	groups := make(map[string]bool, len(promos))
+	for _, promo := range promos {
+		if promo.Exclusive {
+			return false
+		}
+
+		group := promoStackGroup(promotions, promo, productID)
+		if groups[group] {
+			return false
+		}
+		groups[group] = true
+	}
+
+	return true
+}
+
+type promoPick struct {
+	promos   []repo.Promo
+	benefit  money.Decimal
+	priority int64
+}
+
+// better orders two stackable combinations: the bigger saving for the
+// customer wins, then the higher total priority, then fewer promos and
+// finally the lower promo ids so the pick never depends on row order.
+func (p promoPick) better(o promoPick) bool {
+	if c := p.benefit.Cmp(o.benefit); c != 0 {
+		return c > 0
+	}
+
+	if p.priority != o.priority {
+		return p.priority > o.priority
+	}
+
+	if len(p.promos) != len(o.promos) {
+		return len(p.promos) < len(o.promos)
+	}
+
+	for i := range p.promos {
+		if p.promos[i].PromoID != o.promos[i].PromoID {
+			return p.promos[i].PromoID < o.promos[i].PromoID
+		}
+	}
+
+	return false
+}
+
+// selectPromos picks the combination of the product's promos that is best for
+// the customer. The result is in application order.
+func (c *CheckoutUsecaseImpl) selectPromos(ctx context.Context, tx *sqlx.Tx, stock cartStock, v repo.OrderDetail, productDetail *repo.Product, promos []repo.Promo) ([]repo.Promo, error) {
+	eligible := eligiblePromos(promos, v.Qty)
+	if len(eligible) < 2 {
+		return eligible, nil
+	}
+
+	giftValues := make(map[int64]money.Decimal)
+	best := promoPick{}
+
+	for mask := 1; mask < 1<<len(eligible); mask++ {
+		pick := promoPick{}
+		for i, promo := range eligible {
+			if mask&(1<<i) != 0 {
+				pick.promos = append(pick.promos, promo)
+				pick.priority += promo.Priority
+			}
+		}
+
+		if !promosStack(c.promotions(), pick.promos, v.ProductID) {
+			continue
+		}
+
+		benefit, err := c.promoBenefit(ctx, tx, stock, v, productDetail, pick.promos, giftValues)
+		if err != nil {
+			return nil, err
+		}
+		pick.benefit = benefit
+
+		if pick.better(best) {
+			best = pick
+		}
+	}
+
+	return best.promos, nil
+}
+
+// promoBenefit prices the line with promos applied on a scratch copy and
+// returns what the customer saves, counting gifts at their price. Gifts that
+// are out of stock are worth nothing.
+func (c *CheckoutUsecaseImpl) promoBenefit(ctx context.Context, tx *sqlx.Tx, stock cartStock, v repo.OrderDetail, productDetail *repo.Product, promos []repo.Promo, giftValues map[int64]money.Decimal) (money.Decimal, error) {
+	subtotal := productDetail.Price.MulInt(v.Qty)
+	item := repo.OrderDetail{Price: subtotal}
+	gifts := money.Decimal{}
+
+	for i := range promos {
+		promo := &promos[i]
+		if giftID := c.promotions().giftProductID(*promo, v.ProductID); giftID != 0 {
+			value, ok := giftValues[promo.PromoID]
+			if !ok {
+				reward, err := stock.price(ctx, tx, giftID)
+				if err != nil {
+					return value, err
+				}
+
+				if reward.Available() > 0 && reward.ArchivedAt == nil {
+					value = reward.Price
+				}
+				giftValues[promo.PromoID] = value
+			}
+
+			gifts = gifts.Add(value)
+			continue
+		}
+
+		// gifts were valued above, so no stock is ever taken here
+		promotion := c.promotionFor(v, promo, nil)
+		if promotion == nil {
+			continue
+		}
+
+		err := promotion.ApplyPromotion(ctx, tx, &item, v, productDetail, promo, &Checkout{})
+		if errors.Is(err, errPromotionSkipped) {
+			continue
+		}
+		if err != nil {
+			return money.Decimal{}, err
+		}
+	}
+
+	return subtotal.Sub(item.Price).Add(gifts), nil
+}
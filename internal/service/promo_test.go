@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQuantityPromos(t *testing.T) {
+	// buy 3 pay 2 and buy 5 pay 3 on a Google Home at 49.99, 10% from 2 and
+	// 20% from 5 Alexa Speakers at 109.5
+	buy3Pay2 := repo.Promo{PromoID: 1, ProductID: 1, PromoType: "free_unit", MinQty: 3}
+	buy5Pay3 := repo.Promo{PromoID: 2, ProductID: 1, PromoType: "buy_get", MinQty: 5, FreeQty: 2}
+	tiered := repo.Promo{PromoID: 3, ProductID: 3, PromoType: "tiered", MinQty: 2, Tiers: repo.PromoTiers{{MinQty: 2, Percent: money.MustParse("10")}, {MinQty: 5, Percent: money.MustParse("20")}}}
+	tieredFrom3 := repo.Promo{PromoID: 4, ProductID: 3, PromoType: "tiered", MinQty: 2, StackGroup: "tier", Tiers: repo.PromoTiers{{MinQty: 3, Percent: money.MustParse("15")}}}
+	discount := repo.Promo{PromoID: 5, ProductID: 3, PromoType: "discount", Reward: money.MustParse("5"), MinQty: 1}
+
+	tests := []struct {
+		name          string
+		line          repo.OrderDetail
+		promos        []repo.Promo
+		expectedTotal string
+		// expectedPromoID is zero when no promo applies
+		expectedPromoID int64
+	}{
+		{name: "buy 3 pay 2 below 3", line: repo.OrderDetail{ProductID: 1, Qty: 2}, promos: []repo.Promo{buy3Pay2}, expectedTotal: "99.98"},
+		{name: "buy 3 pay 2 at 3", line: repo.OrderDetail{ProductID: 1, Qty: 3}, promos: []repo.Promo{buy3Pay2}, expectedTotal: "99.98", expectedPromoID: 1},
+		{name: "buy 3 pay 2 at 5", line: repo.OrderDetail{ProductID: 1, Qty: 5}, promos: []repo.Promo{buy3Pay2}, expectedTotal: "199.96", expectedPromoID: 1},
+		{name: "buy 3 pay 2 at 6", line: repo.OrderDetail{ProductID: 1, Qty: 6}, promos: []repo.Promo{buy3Pay2}, expectedTotal: "199.96", expectedPromoID: 1},
+		{name: "buy 3 pay 2 at 9", line: repo.OrderDetail{ProductID: 1, Qty: 9}, promos: []repo.Promo{buy3Pay2}, expectedTotal: "299.94", expectedPromoID: 1},
+		{name: "free unit for every unit of the line", line: repo.OrderDetail{ProductID: 1, Qty: 2}, promos: []repo.Promo{{PromoID: 8, ProductID: 1, PromoType: "free_unit", MinQty: 1}}, expectedTotal: "99.98"},
+		{name: "buy 5 pay 3 below 5", line: repo.OrderDetail{ProductID: 1, Qty: 4}, promos: []repo.Promo{buy5Pay3}, expectedTotal: "199.96"},
+		{name: "buy 5 pay 3 at 5", line: repo.OrderDetail{ProductID: 1, Qty: 5}, promos: []repo.Promo{buy5Pay3}, expectedTotal: "149.97", expectedPromoID: 2},
+		{name: "buy 5 pay 3 at 9", line: repo.OrderDetail{ProductID: 1, Qty: 9}, promos: []repo.Promo{buy5Pay3}, expectedTotal: "349.93", expectedPromoID: 2},
+		{name: "buy 5 pay 3 at 10", line: repo.OrderDetail{ProductID: 1, Qty: 10}, promos: []repo.Promo{buy5Pay3}, expectedTotal: "299.94", expectedPromoID: 2},
+		{name: "tiered below the first tier", line: repo.OrderDetail{ProductID: 3, Qty: 1}, promos: []repo.Promo{tiered}, expectedTotal: "109.5"},
+		{name: "tiered at the first tier", line: repo.OrderDetail{ProductID: 3, Qty: 2}, promos: []repo.Promo{tiered}, expectedTotal: "197.10", expectedPromoID: 3},
+		{name: "tiered below the second tier", line: repo.OrderDetail{ProductID: 3, Qty: 4}, promos: []repo.Promo{tiered}, expectedTotal: "394.20", expectedPromoID: 3},
+		{name: "tiered at the second tier", line: repo.OrderDetail{ProductID: 3, Qty: 5}, promos: []repo.Promo{tiered}, expectedTotal: "438.00", expectedPromoID: 3},
+		{name: "tiered above the last tier", line: repo.OrderDetail{ProductID: 3, Qty: 6}, promos: []repo.Promo{tiered}, expectedTotal: "525.60", expectedPromoID: 3},
+		{name: "tiered promo reached before its first tier", line: repo.OrderDetail{ProductID: 3, Qty: 2}, promos: []repo.Promo{tieredFrom3}, expectedTotal: "219"},
+		{name: "tier not reached leaves the other promo", line: repo.OrderDetail{ProductID: 3, Qty: 2}, promos: []repo.Promo{tieredFrom3, discount}, expectedTotal: "208.05", expectedPromoID: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			productRepo.On("GetProductByProductID", mock.Anything, tt.line.ProductID).Return(map[int64]repo.Product{1: googleHome, 3: alexaSpeaker}[tt.line.ProductID], nil)
+			promoRepo.On("GetPromosByProductID", mock.Anything, tt.line.ProductID, checkoutAt).Return(tt.promos, nil)
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderPromoRepo: noOrderPromos(),
+				BundleRepo:     noBundles(),
+				ProductRepo:    productRepo,
+				PromoRepo:      promoRepo,
+				Clock:          clock.Fixed(checkoutAt),
+			})
+
+			quote, err := checkoutUsecase.QuoteCart(context.Background(), []repo.OrderDetail{tt.line})
+			assert.NoError(t, err)
+			assert.Len(t, quote.Lines, 1)
+
+			line := quote.Lines[0]
+			assert.Equal(t, money.MustParse(tt.expectedTotal).String(), line.Total.String())
+			assert.Equal(t, line.Subtotal.Sub(line.Total).String(), line.Discount.String())
+			assert.Equal(t, tt.expectedPromoID, line.PromoID)
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/money"
+)
+
+type (
+	// CartQuote is a cart priced like checkout would price it, without placing
+	// the order. Checkoutable is false when checking the cart out now would
+	// fail, Warnings say why.
+	CartQuote struct {
+		Items        []string            `json:"items"`
+		Lines        []CheckoutLine      `json:"lines"`
+		TotalAmount  money.Decimal       `json:"total_amount"`
+		Currency     string              `json:"currency"`
+		Bundles      []AppliedBundle     `json:"bundles"`
+		OrderPromos  []AppliedOrderPromo `json:"order_promos"`
+		Coupons      []AppliedCoupon     `json:"coupons"`
+		Warnings     []string            `json:"warnings"`
+		Checkoutable bool                `json:"checkoutable"`
+	}
+
+	// cartStock is where a priced cart gets its products, promos and coupons
+	// from and takes its stock from. unavailable decides what a line or
+	// coupon that can't be sold does to the pricing: it fails with err, or is
+	// noted and the pricing goes on.
+	cartStock interface {
+		product(ctx context.Context, tx *sqlx.Tx, productID int64) (repo.Product, error)
+		// price reads a product that is only priced, like a bundle item or a
+		// gift being valued, without locking it.
+		price(ctx context.Context, tx *sqlx.Tx, productID int64) (repo.Product, error)
+		promos(ctx context.Context, tx *sqlx.Tx, productID int64, at time.Time) ([]repo.Promo, error)
+		bundles(ctx context.Context, tx *sqlx.Tx, at time.Time) ([]repo.Bundle, error)
+		orderPromos(ctx context.Context, tx *sqlx.Tx, at time.Time) ([]repo.OrderPromo, error)
+		take(ctx context.Context, tx *sqlx.Tx, productID, qty int64) error
+		coupon(ctx context.Context, tx *sqlx.Tx, code string) (repo.Coupon, error)
+		couponUses(ctx context.Context, tx *sqlx.Tx, couponID int64) (uses int64, err error)
+		unavailable(res *Checkout, err error) error
+	}
+
+	// lockedStock is checkout's stock: products and coupons are locked, and
+	// products decremented, in the checkout transaction. Everything else is
+	// read in it too, so a checkout never waits for a second connection while
+	// it holds one.
+	lockedStock struct {
+		ProductRepo    repo.ProductRepository
+		CouponRepo     repo.CouponRepository
+		PromoRepo      repo.PromoRepository
+		BundleRepo     repo.BundleRepository
+		OrderPromoRepo repo.OrderPromoRepository
+	}
+
+	// quoteStock is a quote's stock: products and coupons are read without
+	// locks and the qty the cart takes is only counted, so later lines of the
+	// cart see it gone as they would in checkout.
+	quoteStock struct {
+		ProductRepo    repo.ProductRepository
+		CouponRepo     repo.CouponRepository
+		PromoRepo      repo.PromoRepository
+		BundleRepo     repo.BundleRepository
+		OrderPromoRepo repo.OrderPromoRepository
+		taken          map[int64]int64
+		blocked        bool
+	}
+)
+
+func (s *lockedStock) product(ctx context.Context, tx *sqlx.Tx, productID int64) (repo.Product, error) {
+	// the row stays locked until the checkout commits or rolls back, so a
+	// concurrent checkout can't pass the same stock check
+	res, err := s.ProductRepo.GetProductByProductIDForUpdate(tx, ctx, productID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductIDForUpdate %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *lockedStock) price(ctx context.Context, tx *sqlx.Tx, productID int64) (repo.Product, error) {
+	res, err := s.ProductRepo.GetProductByProductIDTx(tx, ctx, productID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductIDTx %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *lockedStock) promos(ctx context.Context, tx *sqlx.Tx, productID int64, at time.Time) ([]repo.Promo, error) {
+	res, err := s.PromoRepo.GetPromosByProductIDTx(tx, ctx, productID, at)
+	if err != nil {
+		log.Printf("error while do GetPromosByProductIDTx %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *lockedStock) bundles(ctx context.Context, tx *sqlx.Tx, at time.Time) ([]repo.Bundle, error) {
+	res, err := s.BundleRepo.GetBundlesTx(tx, ctx, at)
+	if err != nil {
+		log.Printf("error while do GetBundlesTx %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *lockedStock) orderPromos(ctx context.Context, tx *sqlx.Tx, at time.Time) ([]repo.OrderPromo, error) {
+	res, err := s.OrderPromoRepo.GetOrderPromosTx(tx, ctx, at)
+	if err != nil {
+		log.Printf("error while do GetOrderPromosTx %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *lockedStock) take(ctx context.Context, tx *sqlx.Tx, productID, qty int64) error {
+	err := s.ProductRepo.UpdateProductQtyByProductID(tx, ctx, repo.Product{
+		ProductID: productID,
+		Qty:       qty,
+	})
+	if err != nil {
+		log.Printf("error while do UpdateProductQtyByProductID %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *lockedStock) coupon(ctx context.Context, tx *sqlx.Tx, code string) (repo.Coupon, error) {
+	res, err := s.CouponRepo.GetCouponByCodeForUpdate(tx, ctx, code)
+	if err != nil {
+		log.Printf("error while do GetCouponByCodeForUpdate %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *lockedStock) couponUses(ctx context.Context, tx *sqlx.Tx, couponID int64) (uses int64, err error) {
+	uses, err = s.CouponRepo.CountCouponRedemptionsTx(tx, ctx, couponID)
+	if err != nil {
+		log.Printf("error while do CountCouponRedemptionsTx %+v", err)
+		return 0, err
+	}
+
+	return uses, nil
+}
+
+func (s *lockedStock) unavailable(res *Checkout, err error) error {
+	return err
+}
+
+func (s *quoteStock) product(ctx context.Context, tx *sqlx.Tx, productID int64) (repo.Product, error) {
+	res, err := s.ProductRepo.GetProductByProductID(ctx, productID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductID %+v", err)
+		return res, err
+	}
+
+	res.Qty -= s.taken[productID]
+	return res, nil
+}
+
+func (s *quoteStock) price(ctx context.Context, tx *sqlx.Tx, productID int64) (repo.Product, error) {
+	res, err := s.ProductRepo.GetProductByProductID(ctx, productID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *quoteStock) promos(ctx context.Context, tx *sqlx.Tx, productID int64, at time.Time) ([]repo.Promo, error) {
+	res, err := s.PromoRepo.GetPromosByProductID(ctx, productID, at)
+	if err != nil {
+		log.Printf("error while do GetPromosByProductID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *quoteStock) bundles(ctx context.Context, tx *sqlx.Tx, at time.Time) ([]repo.Bundle, error) {
+	res, err := s.BundleRepo.GetBundles(ctx, at)
+	if err != nil {
+		log.Printf("error while do GetBundles %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *quoteStock) orderPromos(ctx context.Context, tx *sqlx.Tx, at time.Time) ([]repo.OrderPromo, error) {
+	res, err := s.OrderPromoRepo.GetOrderPromos(ctx, at)
+	if err != nil {
+		log.Printf("error while do GetOrderPromos %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *quoteStock) take(ctx context.Context, tx *sqlx.Tx, productID, qty int64) error {
+	s.taken[productID] += qty
+	return nil
+}
+
+func (s *quoteStock) coupon(ctx context.Context, tx *sqlx.Tx, code string) (repo.Coupon, error) {
+	res, err := s.CouponRepo.GetCouponByCode(ctx, code)
+	if err != nil {
+		log.Printf("error while do GetCouponByCode %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *quoteStock) couponUses(ctx context.Context, tx *sqlx.Tx, couponID int64) (uses int64, err error) {
+	uses, err = s.CouponRepo.CountCouponRedemptions(ctx, couponID)
+	if err != nil {
+		log.Printf("error while do CountCouponRedemptions %+v", err)
+		return 0, err
+	}
+
+	return uses, nil
+}
+
+func (s *quoteStock) unavailable(res *Checkout, err error) error {
+	res.Warnings = append(res.Warnings, err.Error())
+	s.blocked = true
+	return nil
+}
+
+// QuoteCart prices form and couponCodes through the same pipeline as
+// Checkout, at the current time, but writes nothing: no order is placed, no
+// stock is taken and no coupon is redeemed. What would make the checkout
+// fail, like a line out of stock or a coupon that can't be redeemed, is
+// reported in the quote's warnings instead.
+func (c *CheckoutUsecaseImpl) QuoteCart(ctx context.Context, form []repo.OrderDetail, couponCodes ...string) (res CartQuote, err error) {
+	stock := &quoteStock{
+		ProductRepo:    c.ProductRepo,
+		CouponRepo:     c.CouponRepo,
+		PromoRepo:      c.PromoRepo,
+		BundleRepo:     c.BundleRepo,
+		OrderPromoRepo: c.OrderPromoRepo,
+		taken:          make(map[int64]int64, len(form)),
+	}
+
+	items, err := c.normalizeCheckout(form)
+	if err != nil {
+		return res, err
+	}
+
+	priced := Checkout{Currency: c.currency().Code}
+
+	err = c.priceCart(ctx, nil, stock, items, normalizeCouponCodes(couponCodes), c.now(), &priced)
+	if err != nil {
+		return res, err
+	}
+
+	return CartQuote{
+		Items:        priced.Items,
+		Lines:        priced.Lines,
+		TotalAmount:  priced.TotalAmount,
+		Currency:     priced.Currency,
+		Bundles:      priced.Bundles,
+		OrderPromos:  priced.OrderPromos,
+		Coupons:      priced.Coupons,
+		Warnings:     priced.Warnings,
+		Checkoutable: !stock.blocked,
+	}, nil
+}
@@ -0,0 +1,203 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// quoteCatalog is the seeded shop: a free Raspberry Pi with every MacBook Pro,
+// 3 Google Homes for the price of 2 and 10% off 3 Alexa Speakers.
+func quoteCatalog(productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository, products ...repo.Product) {
+	for _, p := range products {
+		productRepo.On("GetProductByProductID", mock.Anything, p.ProductID).Return(p, nil).Maybe()
+		productRepo.On("GetProductByProductIDTx", mock.Anything, mock.Anything, p.ProductID).Return(p, nil).Maybe()
+		productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, p.ProductID).Return(p, nil).Maybe()
+	}
+
+	promos := map[int64][]repo.Promo{
+		1: {{PromoID: 1, PromoType: "product", Reward: money.MustParse("1"), MinQty: 3}},
+		2: {{PromoID: 2, PromoType: "product", Reward: money.MustParse("4"), MinQty: 1}},
+		3: {{PromoID: 3, PromoType: "discount", Reward: money.MustParse("10"), MinQty: 3}},
+		4: {},
+	}
+	// quotes read the catalog on the pool, checkouts in their transaction
+	for productID, p := range promos {
+		promoRepo.On("GetPromosByProductID", mock.Anything, productID, checkoutAt).Return(p, nil).Maybe()
+		promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, productID, checkoutAt).Return(p, nil).Maybe()
+	}
+}
+
+var (
+	googleHome   = repo.Product{ProductID: 1, Sku: "120P90", Name: "Google Home", Price: money.MustParse("49.99"), Qty: 10}
+	macBookPro   = repo.Product{ProductID: 2, Sku: "43N23P", Name: "MacBook Pro", Price: money.MustParse("5399.99"), Qty: 5}
+	alexaSpeaker = repo.Product{ProductID: 3, Sku: "A304SD", Name: "Alexa Speaker", Price: money.MustParse("109.5"), Qty: 10}
+	raspberryPi  = repo.Product{ProductID: 4, Sku: "234234", Name: "Raspberry Pi B", Price: money.MustParse("30"), Qty: 2}
+)
+
+func TestQuoteCartMatchesCheckout(t *testing.T) {
+	carts := map[string][]repo.OrderDetail{
+		"discount":             {{ProductID: 3, Qty: 3}},
+		"buy 3 pay 2":          {{ProductID: 1, Qty: 3}},
+		"free reward":          {{ProductID: 2, Qty: 1}},
+		"every promo together": {{ProductID: 1, Qty: 3}, {ProductID: 2, Qty: 1}, {ProductID: 3, Qty: 4}, {ProductID: 4, Qty: 1}},
+	}
+
+	for name, cart := range carts {
+		t.Run(name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
+
+			quoteCatalog(productRepo, promoRepo, googleHome, macBookPro, alexaSpeaker, raspberryPi)
+			productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+			orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+			orderRepo.On("CreateOrder", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
+			orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderPromoRepo:    noOrderPromos(),
+				BundleRepo:        noBundles(),
+				OrderRepo:         orderRepo,
+				ProductRepo:       productRepo,
+				PromoRepo:         promoRepo,
+				StockMovementRepo: stockMovementRepo,
+				Clock:             clock.Fixed(checkoutAt),
+			})
+
+			quote, err := checkoutUsecase.QuoteCart(context.Background(), cart)
+			assert.NoError(t, err)
+
+			// the quote took nothing and wrote nothing
+			productRepo.AssertNotCalled(t, "UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything)
+			orderRepo.AssertNotCalled(t, "BeginTx")
+
+			checkout, err := checkoutUsecase.Checkout(context.Background(), cart)
+			assert.NoError(t, err)
+
+			assert.True(t, quote.Checkoutable)
+			assert.Equal(t, checkout.Items, quote.Items)
+			assert.Equal(t, checkout.Lines, quote.Lines)
+			assert.Equal(t, checkout.TotalAmount, quote.TotalAmount)
+			assert.Equal(t, checkout.Currency, quote.Currency)
+			assert.Equal(t, checkout.Warnings, quote.Warnings)
+		})
+	}
+}
+
+func TestQuoteCart(t *testing.T) {
+	archived := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		cart         []repo.OrderDetail
+		products     []repo.Product
+		stockPolicy  service.RewardStockPolicy
+		expectedResp service.CartQuote
+	}{
+		{
+			name:     "line out of stock is still priced",
+			cart:     []repo.OrderDetail{{ProductID: 3, Qty: 11}},
+			products: []repo.Product{alexaSpeaker},
+			expectedResp: service.CartQuote{
+				Items: []string{"Alexa Speaker", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker", "Alexa Speaker"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 3, ProductName: "Alexa Speaker", Qty: 11, UnitPrice: money.MustParse("109.5"), Subtotal: money.MustParse("1204.5"), Discount: money.MustParse("120.45"), Total: money.MustParse("1084.05"), PromoID: 3, PromoType: "discount", Promos: []service.AppliedPromo{{PromoID: 3, PromoType: "discount"}}},
+				},
+				TotalAmount: money.MustParse("1084.05"),
+				Currency:    "USD",
+				Warnings:    []string{"the product Alexa Speaker qty is not enough to fulfill the request"},
+			},
+		},
+		{
+			name:     "lines of the same product are merged",
+			cart:     []repo.OrderDetail{{ProductID: 4, Qty: 2}, {ProductID: 4, Qty: 1}},
+			products: []repo.Product{raspberryPi},
+			expectedResp: service.CartQuote{
+				Items: []string{"Raspberry Pi B", "Raspberry Pi B", "Raspberry Pi B"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 4, ProductName: "Raspberry Pi B", Qty: 3, UnitPrice: money.MustParse("30"), Subtotal: money.MustParse("90"), Total: money.MustParse("90")},
+				},
+				TotalAmount: money.MustParse("90"),
+				Currency:    "USD",
+				Warnings:    []string{"the product Raspberry Pi B qty is not enough to fulfill the request"},
+			},
+		},
+		{
+			name:     "reward out of stock fails the checkout",
+			cart:     []repo.OrderDetail{{ProductID: 2, Qty: 1}},
+			products: []repo.Product{macBookPro, {ProductID: 4, Name: "Raspberry Pi B", Price: money.MustParse("30")}},
+			expectedResp: service.CartQuote{
+				Items: []string{"MacBook Pro"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 2, ProductName: "MacBook Pro", Qty: 1, UnitPrice: money.MustParse("5399.99"), Subtotal: money.MustParse("5399.99"), Total: money.MustParse("5399.99")},
+				},
+				TotalAmount: money.MustParse("5399.99"),
+				Currency:    "USD",
+				Warnings:    []string{"the free product Raspberry Pi B is out of stock"},
+			},
+		},
+		{
+			name:        "reward out of stock is skipped",
+			cart:        []repo.OrderDetail{{ProductID: 2, Qty: 1}},
+			products:    []repo.Product{macBookPro, {ProductID: 4, Name: "Raspberry Pi B", Price: money.MustParse("30")}},
+			stockPolicy: service.RewardStockSkip,
+			expectedResp: service.CartQuote{
+				Items: []string{"MacBook Pro"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 2, ProductName: "MacBook Pro", Qty: 1, UnitPrice: money.MustParse("5399.99"), Subtotal: money.MustParse("5399.99"), Total: money.MustParse("5399.99")},
+				},
+				TotalAmount:  money.MustParse("5399.99"),
+				Currency:     "USD",
+				Warnings:     []string{"the free product Raspberry Pi B is out of stock and was not added"},
+				Checkoutable: true,
+			},
+		},
+		{
+			name:     "archived product is left out",
+			cart:     []repo.OrderDetail{{ProductID: 4, Qty: 1}},
+			products: []repo.Product{{ProductID: 4, Name: "Raspberry Pi B", Price: money.MustParse("30"), Qty: 2, ArchivedAt: &archived}},
+			expectedResp: service.CartQuote{
+				Currency: "USD",
+				Warnings: []string{"the product Raspberry Pi B is no longer available"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			quoteCatalog(productRepo, promoRepo, tt.products...)
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderPromoRepo: noOrderPromos(),
+				BundleRepo:     noBundles(),
+				ProductRepo:    productRepo,
+				PromoRepo:      promoRepo,
+				StockPolicy:    tt.stockPolicy,
+				Clock:          clock.Fixed(checkoutAt),
+			})
+
+			res, err := checkoutUsecase.QuoteCart(context.Background(), tt.cart)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedResp, res)
+
+			productRepo.AssertNotCalled(t, "UpdateProductQtyByProductID", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/apperr"
+)
+
+var (
+	// ErrReservationNotFound is returned for an unknown reservation.
+	ErrReservationNotFound = apperr.New(apperr.CodeNotFound, "reservation not found")
+	// ErrReservationClosed is returned when a reservation was already
+	// confirmed, released or expired.
+	ErrReservationClosed = apperr.New(apperr.CodeConflict, "reservation is closed")
+	// ErrReservationExpired is returned when confirming a reservation past
+	// its expiry that the sweeper hasn't expired yet.
+	ErrReservationExpired = apperr.New(apperr.CodeConflict, "reservation has expired")
+)
+
+// DefaultReservationTTL is how long stock is held when no TTL is configured.
+const DefaultReservationTTL = 15 * time.Minute
+
+// expireBatchSize is how many expired reservations a sweep lists at a time.
+const expireBatchSize = 100
+
+// ReservationPolicy is how long a reservation holds stock and how often the
+// sweeper looks for expired ones.
+type ReservationPolicy struct {
+	TTL           time.Duration
+	SweepInterval time.Duration
+}
+
+// ReserveCart holds the stock of form until the reservation is confirmed,
+// released or expires. Held stock stays on hand but isn't available to other
+// checkouts or reservations.
+func (c *CheckoutUsecaseImpl) ReserveCart(ctx context.Context, form []repo.OrderDetail) (res repo.Reservation, err error) {
+	// the cart is validated and merged like a checkout so a reservation holds
+	// and later prices one line per product
+	form, err = c.normalizeCheckout(form)
+	if err != nil {
+		return res, err
+	}
+
+	tx, err := c.ReservationRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.ReservationRepo.RollbackTx(tx)
+
+	now := c.now()
+	for _, v := range form {
+		err = c.holdProduct(tx, ctx, v)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	res = repo.Reservation{
+		Status:    repo.ReservationStatusActive,
+		ExpiresAt: now.Add(c.reservationTTL()),
+		CreatedAt: now,
+	}
+
+	res.ReservationID, err = c.ReservationRepo.CreateReservation(tx, ctx, res)
+	if err != nil {
+		log.Printf("error while do CreateReservation %+v", err)
+		return repo.Reservation{}, err
+	}
+
+	res.Items = make([]repo.ReservationItem, len(form))
+	for i, v := range form {
+		res.Items[i] = repo.ReservationItem{
+			ReservationID: res.ReservationID,
+			ProductID:     v.ProductID,
+			Qty:           v.Qty,
+		}
+	}
+
+	err = c.ReservationRepo.CreateReservationItems(tx, ctx, res.Items)
+	if err != nil {
+		log.Printf("error while do CreateReservationItems %+v", err)
+		return repo.Reservation{}, err
+	}
+
+	err = c.ReservationRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return repo.Reservation{}, err
+	}
+
+	return res, nil
+}
+
+func (c *CheckoutUsecaseImpl) holdProduct(tx *sqlx.Tx, ctx context.Context, v repo.OrderDetail) error {
+	productDetail, err := c.ProductRepo.GetProductByProductIDForUpdate(tx, ctx, v.ProductID)
+	if err != nil {
+		log.Printf("error while do GetProductByProductIDForUpdate %+v", err)
+		return err
+	}
+
+	if productDetail.ProductID == 0 {
+		return productNotFoundError(v.ProductID)
+	}
+
+	if productDetail.ArchivedAt != nil {
+		return productUnavailableError(productDetail)
+	}
+
+	err = c.ProductRepo.AdjustProductReservedQty(tx, ctx, v.ProductID, v.Qty)
+	if errors.Is(err, repo.ErrInsufficientStock) {
+		return insufficientStockError(productDetail)
+	}
+	if err != nil {
+		log.Printf("error while do AdjustProductReservedQty %+v", err)
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmReservation turns an active reservation into an order. The held
+// stock is handed back to the checkout in the same transaction, so the cart
+// is priced with the promos running now and can't lose its stock on the way.
+func (c *CheckoutUsecaseImpl) ConfirmReservation(ctx context.Context, reservationID int64) (res Checkout, err error) {
+	tx, err := c.OrderRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.OrderRepo.RollbackTx(tx)
+
+	reservation, err := c.openReservation(tx, ctx, reservationID)
+	if err != nil {
+		return res, err
+	}
+
+	if !c.now().Before(reservation.ExpiresAt) {
+		return res, fmt.Errorf("%w: reservation %d expired at %s", ErrReservationExpired, reservationID, reservation.ExpiresAt.Format(time.RFC3339))
+	}
+
+	err = c.releaseHolds(tx, ctx, reservation.Items)
+	if err != nil {
+		return res, err
+	}
+
+	form := make([]repo.OrderDetail, len(reservation.Items))
+	for i, v := range reservation.Items {
+		form[i] = repo.OrderDetail{
+			ProductID: v.ProductID,
+			Qty:       v.Qty,
+		}
+	}
+
+	// reservations held before carts were merged may still list a product
+	// twice
+	form, err = c.normalizeCheckout(form)
+	if err != nil {
+		return res, err
+	}
+
+	res, err = c.placeOrder(ctx, tx, form, nil)
+	if err != nil {
+		return res, err
+	}
+
+	reservation.Status = repo.ReservationStatusConfirmed
+	reservation.OrderID = res.OrderID
+	err = c.ReservationRepo.UpdateReservationStatus(tx, ctx, reservation, c.now())
+	if err != nil {
+		log.Printf("error while do UpdateReservationStatus %+v", err)
+		return res, err
+	}
+
+	err = c.OrderRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+// ReleaseReservation gives the stock of an active reservation back.
+func (c *CheckoutUsecaseImpl) ReleaseReservation(ctx context.Context, reservationID int64) (res repo.Reservation, err error) {
+	return c.closeReservation(ctx, reservationID, repo.ReservationStatusReleased)
+}
+
+// ExpireReservations releases the active reservations past their expiry,
+// expireBatchSize at a time until none are left, and returns how many were
+// expired. A reservation that fails to expire is logged and skipped so it
+// doesn't hold up the others; the next sweep tries it again. It's run by the
+// ReservationSweeper.
+func (c *CheckoutUsecaseImpl) ExpireReservations(ctx context.Context) (count int, err error) {
+	now := c.now()
+
+	var afterID int64
+	for {
+		reservationIDs, err := c.ReservationRepo.GetExpiredReservationIDs(ctx, now, afterID, expireBatchSize)
+		if err != nil {
+			log.Printf("error while do GetExpiredReservationIDs %+v", err)
+			return count, err
+		}
+
+		for _, reservationID := range reservationIDs {
+			afterID = reservationID
+
+			_, err = c.closeReservation(ctx, reservationID, repo.ReservationStatusExpired)
+			if errors.Is(err, ErrReservationClosed) {
+				// confirmed or released since it was listed
+				continue
+			}
+			if err != nil {
+				log.Printf("error while do expire reservation %d %+v", reservationID, err)
+				continue
+			}
+
+			count++
+		}
+
+		if len(reservationIDs) < expireBatchSize {
+			return count, nil
+		}
+	}
+}
+
+func (c *CheckoutUsecaseImpl) closeReservation(ctx context.Context, reservationID int64, status string) (res repo.Reservation, err error) {
+	tx, err := c.ReservationRepo.BeginTx()
+	if err != nil {
+		log.Printf("error while do BeginTx %+v", err)
+		return res, err
+	}
+
+	defer c.ReservationRepo.RollbackTx(tx)
+
+	res, err = c.openReservation(tx, ctx, reservationID)
+	if err != nil {
+		return repo.Reservation{}, err
+	}
+
+	err = c.releaseHolds(tx, ctx, res.Items)
+	if err != nil {
+		return repo.Reservation{}, err
+	}
+
+	res.Status = status
+	err = c.ReservationRepo.UpdateReservationStatus(tx, ctx, res, c.now())
+	if err != nil {
+		log.Printf("error while do UpdateReservationStatus %+v", err)
+		return repo.Reservation{}, err
+	}
+
+	err = c.ReservationRepo.CommitTx(tx)
+	if err != nil {
+		log.Printf("error while do CommitTx %+v", err)
+		return repo.Reservation{}, err
+	}
+
+	return res, nil
+}
+
+// openReservation locks an active reservation inside tx and loads its items.
+func (c *CheckoutUsecaseImpl) openReservation(tx *sqlx.Tx, ctx context.Context, reservationID int64) (res repo.Reservation, err error) {
+	res, err = c.ReservationRepo.GetReservationByReservationIDForUpdate(tx, ctx, reservationID)
+	if err != nil {
+		log.Printf("error while do GetReservationByReservationIDForUpdate %+v", err)
+		return res, err
+	}
+
+	if res.ReservationID == 0 {
+		return res, ErrReservationNotFound
+	}
+
+	if res.Status != repo.ReservationStatusActive {
+		return res, fmt.Errorf("%w: reservation %d is %s", ErrReservationClosed, reservationID, res.Status)
+	}
+
+	res.Items, err = c.ReservationRepo.GetReservationItemsByReservationID(tx, ctx, reservationID)
+	if err != nil {
+		log.Printf("error while do GetReservationItemsByReservationID %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (c *CheckoutUsecaseImpl) releaseHolds(tx *sqlx.Tx, ctx context.Context, items []repo.ReservationItem) error {
+	for _, v := range items {
+		err := c.ProductRepo.AdjustProductReservedQty(tx, ctx, v.ProductID, -v.Qty)
+		if err != nil {
+			log.Printf("error while do AdjustProductReservedQty %+v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *CheckoutUsecaseImpl) reservationTTL() time.Duration {
+	if c.ReservationPolicy.TTL <= 0 {
+		return DefaultReservationTTL
+	}
+
+	return c.ReservationPolicy.TTL
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"go.uber.org/dig"
+)
+
+// DefaultSweepInterval is how often expired reservations are looked for when
+// no interval is configured.
+const DefaultSweepInterval = time.Minute
+
+// ReservationSweeper periodically expires the reservations past their TTL so
+// their stock becomes available again.
+type ReservationSweeper struct {
+	checkoutSvc CheckoutUsecase
+	interval    time.Duration
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stopped bool
+}
+
+type ReservationSweeperImpl struct {
+	dig.In
+	CheckoutSvc CheckoutUsecase
+	Policy      ReservationPolicy `optional:"true"`
+}
+
+func NewReservationSweeper(impl ReservationSweeperImpl) *ReservationSweeper {
+	interval := impl.Policy.SweepInterval
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+
+	return &ReservationSweeper{
+		checkoutSvc: impl.CheckoutSvc,
+		interval:    interval,
+	}
+}
+
+// Start runs the sweeper in the background until Stop is called. Starting a
+// running or stopped sweeper does nothing.
+func (s *ReservationSweeper) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil || s.stopped {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.run(ctx, s.done)
+}
+
+// Stop cancels the running sweep and waits for it to return, or for ctx to
+// end first.
+func (s *ReservationSweeper) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.stopped = nil, true
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *ReservationSweeper) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *ReservationSweeper) sweep(ctx context.Context) {
+	count, err := s.checkoutSvc.ExpireReservations(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("error while do ExpireReservations %+v", err)
+	}
+
+	if count > 0 {
+		log.Printf("expired %d reservations", count)
+	}
+}
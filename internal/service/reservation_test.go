@@ -0,0 +1,405 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReserveCart(t *testing.T) {
+	speaker := repo.Product{ProductID: 3, Sku: "A304SD", Name: "Alexa Speaker", Price: money.MustParse("109.500"), Qty: 10}
+	archivedAt := checkoutAt.Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		form          []repo.OrderDetail
+		mockSetupFunc func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository)
+		expectedResp  repo.Reservation
+		expectedErr   error
+		expectedMsg   string
+	}{
+		{
+			name: "holds the stock until the ttl",
+			form: []repo.OrderDetail{{ProductID: 3, Qty: 2}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(3)).Return(speaker, nil)
+				productRepo.On("AdjustProductReservedQty", mock.Anything, mock.Anything, int64(3), int64(2)).Return(nil)
+				reservationRepo.On("CreateReservation", mock.Anything, mock.Anything, repo.Reservation{
+					Status:    repo.ReservationStatusActive,
+					ExpiresAt: checkoutAt.Add(10 * time.Minute),
+					CreatedAt: checkoutAt,
+				}).Return(int64(8), nil)
+				reservationRepo.On("CreateReservationItems", mock.Anything, mock.Anything, []repo.ReservationItem{
+					{ReservationID: 8, ProductID: 3, Qty: 2},
+				}).Return(nil)
+				reservationRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Reservation{
+				ReservationID: 8,
+				Status:        repo.ReservationStatusActive,
+				ExpiresAt:     checkoutAt.Add(10 * time.Minute),
+				CreatedAt:     checkoutAt,
+				Items:         []repo.ReservationItem{{ReservationID: 8, ProductID: 3, Qty: 2}},
+			},
+		},
+		{
+			name:          "empty cart",
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {},
+			expectedErr:   service.ErrEmptyCart,
+		},
+		{
+			name:          "zero qty",
+			form:          []repo.OrderDetail{{ProductID: 3}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {},
+			expectedErr:   service.ErrInvalidQty,
+		},
+		{
+			name:          "too many lines",
+			form:          []repo.OrderDetail{{ProductID: 1, Qty: 1}, {ProductID: 2, Qty: 1}, {ProductID: 3, Qty: 1}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {},
+			expectedErr:   service.ErrTooManyLines,
+		},
+		{
+			name: "duplicate lines are held once",
+			form: []repo.OrderDetail{{ProductID: 3, Qty: 1}, {ProductID: 3, Qty: 2}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(3)).Return(speaker, nil).Once()
+				productRepo.On("AdjustProductReservedQty", mock.Anything, mock.Anything, int64(3), int64(3)).Return(nil).Once()
+				reservationRepo.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything).Return(int64(9), nil)
+				reservationRepo.On("CreateReservationItems", mock.Anything, mock.Anything, []repo.ReservationItem{
+					{ReservationID: 9, ProductID: 3, Qty: 3},
+				}).Return(nil)
+				reservationRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: repo.Reservation{
+				ReservationID: 9,
+				Status:        repo.ReservationStatusActive,
+				ExpiresAt:     checkoutAt.Add(10 * time.Minute),
+				CreatedAt:     checkoutAt,
+				Items:         []repo.ReservationItem{{ReservationID: 9, ProductID: 3, Qty: 3}},
+			},
+		},
+		{
+			name: "unknown product",
+			form: []repo.OrderDetail{{ProductID: 9, Qty: 1}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(9)).Return(repo.Product{}, nil)
+			},
+			expectedMsg: "product 9 doesn't exist",
+		},
+		{
+			name: "archived product",
+			form: []repo.OrderDetail{{ProductID: 3, Qty: 1}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {
+				archived := speaker
+				archived.ArchivedAt = &archivedAt
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(3)).Return(archived, nil)
+			},
+			expectedMsg: "the product Alexa Speaker is no longer available",
+		},
+		{
+			name: "not enough unreserved stock",
+			form: []repo.OrderDetail{{ProductID: 3, Qty: 11}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(3)).Return(speaker, nil)
+				productRepo.On("AdjustProductReservedQty", mock.Anything, mock.Anything, int64(3), int64(11)).Return(repo.ErrInsufficientStock)
+			},
+			expectedMsg: "the product Alexa Speaker qty is not enough to fulfill the request",
+		},
+		{
+			name: "error while CreateReservation",
+			form: []repo.OrderDetail{{ProductID: 3, Qty: 1}},
+			mockSetupFunc: func(productRepo *mockRepo.ProductRepository, reservationRepo *mockRepo.ReservationRepository) {
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(3)).Return(speaker, nil)
+				productRepo.On("AdjustProductReservedQty", mock.Anything, mock.Anything, int64(3), int64(1)).Return(nil)
+				reservationRepo.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), errors.New("error"))
+			},
+			expectedMsg: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productRepo := new(mockRepo.ProductRepository)
+			reservationRepo := new(mockRepo.ReservationRepository)
+
+			reservationRepo.On("BeginTx").Return(&sqlx.Tx{}, nil).Maybe()
+			reservationRepo.On("RollbackTx", mock.Anything).Return(nil).Maybe()
+			tt.mockSetupFunc(productRepo, reservationRepo)
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderPromoRepo:    noOrderPromos(),
+				BundleRepo:        noBundles(),
+				ProductRepo:       productRepo,
+				ReservationRepo:   reservationRepo,
+				ReservationPolicy: service.ReservationPolicy{TTL: 10 * time.Minute},
+				Limits:            service.CheckoutLimits{MaxLines: 2},
+				Clock:             clock.Fixed(checkoutAt),
+			})
+
+			res, err := checkoutUsecase.ReserveCart(context.Background(), tt.form)
+			switch {
+			case tt.expectedErr != nil:
+				assert.ErrorIs(t, err, tt.expectedErr)
+			case tt.expectedMsg != "":
+				assert.EqualError(t, err, tt.expectedMsg)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			productRepo.AssertExpectations(t)
+			reservationRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConfirmReservation(t *testing.T) {
+	active := repo.Reservation{ReservationID: 8, Status: repo.ReservationStatusActive, ExpiresAt: checkoutAt.Add(time.Minute), CreatedAt: checkoutAt.Add(-time.Minute)}
+	items := []repo.ReservationItem{{ReservationItemID: 1, ReservationID: 8, ProductID: 3, Qty: 2}}
+
+	tests := []struct {
+		name          string
+		mockSetupFunc func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository, reservationRepo *mockRepo.ReservationRepository)
+		expectedResp  service.Checkout
+		expectedErr   error
+	}{
+		{
+			name: "held stock is sold through the checkout",
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository, reservationRepo *mockRepo.ReservationRepository) {
+				reservationRepo.On("GetReservationByReservationIDForUpdate", mock.Anything, mock.Anything, int64(8)).Return(active, nil)
+				reservationRepo.On("GetReservationItemsByReservationID", mock.Anything, mock.Anything, int64(8)).Return(items, nil)
+				productRepo.On("AdjustProductReservedQty", mock.Anything, mock.Anything, int64(3), int64(-2)).Return(nil)
+
+				promoRepo.On("GetPromosByProductIDTx", mock.Anything, mock.Anything, int64(3), checkoutAt).Return(nil, nil)
+				productRepo.On("GetProductByProductIDForUpdate", mock.Anything, mock.Anything, int64(3)).Return(repo.Product{
+					ProductID: 3, Sku: "A304SD", Name: "Alexa Speaker", Price: money.MustParse("109.500"), Qty: 10,
+				}, nil)
+				productRepo.On("UpdateProductQtyByProductID", mock.Anything, mock.Anything, repo.Product{ProductID: 3, Qty: 2}).Return(nil)
+				orderRepo.On("CreateOrder", mock.Anything, mock.Anything, orderTotals("219", "0", "219", 2)).Return(int64(11), nil)
+				orderRepo.On("CreateOrderStatusChange", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				orderRepo.On("CreateOrderDetails", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+				confirmed := active
+				confirmed.Status = repo.ReservationStatusConfirmed
+				confirmed.OrderID = 11
+				confirmed.Items = items
+				reservationRepo.On("UpdateReservationStatus", mock.Anything, mock.Anything, confirmed, checkoutAt).Return(nil)
+				orderRepo.On("CommitTx", mock.Anything).Return(nil)
+			},
+			expectedResp: service.Checkout{
+				OrderID: 11,
+				Items:   []string{"Alexa Speaker", "Alexa Speaker"},
+				Lines: []service.CheckoutLine{
+					{ProductID: 3, ProductName: "Alexa Speaker", Qty: 2, UnitPrice: money.MustParse("109.5"), Subtotal: money.MustParse("219"), Discount: money.MustParse("0"), Total: money.MustParse("219")},
+				},
+				TotalAmount: money.MustParse("219"),
+				Currency:    "USD",
+			},
+		},
+		{
+			name: "unknown reservation",
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository, reservationRepo *mockRepo.ReservationRepository) {
+				reservationRepo.On("GetReservationByReservationIDForUpdate", mock.Anything, mock.Anything, int64(8)).Return(repo.Reservation{}, nil)
+			},
+			expectedErr: service.ErrReservationNotFound,
+		},
+		{
+			name: "released reservation",
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository, reservationRepo *mockRepo.ReservationRepository) {
+				released := active
+				released.Status = repo.ReservationStatusReleased
+				reservationRepo.On("GetReservationByReservationIDForUpdate", mock.Anything, mock.Anything, int64(8)).Return(released, nil)
+			},
+			expectedErr: service.ErrReservationClosed,
+		},
+		{
+			name: "reservation past its expiry",
+			mockSetupFunc: func(orderRepo *mockRepo.OrderRepository, productRepo *mockRepo.ProductRepository, promoRepo *mockRepo.PromoRepository, reservationRepo *mockRepo.ReservationRepository) {
+				expired := active
+				expired.ExpiresAt = checkoutAt
+				reservationRepo.On("GetReservationByReservationIDForUpdate", mock.Anything, mock.Anything, int64(8)).Return(expired, nil)
+				reservationRepo.On("GetReservationItemsByReservationID", mock.Anything, mock.Anything, int64(8)).Return(items, nil)
+			},
+			expectedErr: service.ErrReservationExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderRepo := new(mockRepo.OrderRepository)
+			productRepo := new(mockRepo.ProductRepository)
+			promoRepo := new(mockRepo.PromoRepository)
+			reservationRepo := new(mockRepo.ReservationRepository)
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
+
+			orderRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+			orderRepo.On("RollbackTx", mock.Anything).Return(nil)
+			stockMovementRepo.On("CreateStockMovements", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+			tt.mockSetupFunc(orderRepo, productRepo, promoRepo, reservationRepo)
+
+			checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+				OrderPromoRepo:    noOrderPromos(),
+				BundleRepo:        noBundles(),
+				OrderRepo:         orderRepo,
+				ProductRepo:       productRepo,
+				PromoRepo:         promoRepo,
+				ReservationRepo:   reservationRepo,
+				StockMovementRepo: stockMovementRepo,
+				Clock:             clock.Fixed(checkoutAt),
+			})
+
+			res, err := checkoutUsecase.ConfirmReservation(context.Background(), 8)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				orderRepo.AssertNotCalled(t, "CommitTx", mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			orderRepo.AssertExpectations(t)
+			productRepo.AssertExpectations(t)
+			reservationRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReleaseAndExpireReservations(t *testing.T) {
+	active := repo.Reservation{ReservationID: 8, Status: repo.ReservationStatusActive, ExpiresAt: checkoutAt.Add(-time.Minute)}
+	items := []repo.ReservationItem{{ReservationItemID: 1, ReservationID: 8, ProductID: 3, Qty: 2}, {ReservationItemID: 2, ReservationID: 8, ProductID: 4, Qty: 1}}
+	confirmed := repo.Reservation{ReservationID: 9, Status: repo.ReservationStatusConfirmed, OrderID: 11}
+
+	productRepo := new(mockRepo.ProductRepository)
+	reservationRepo := new(mockRepo.ReservationRepository)
+
+	reservationRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+	reservationRepo.On("RollbackTx", mock.Anything).Return(nil)
+	reservationRepo.On("CommitTx", mock.Anything).Return(nil)
+	reservationRepo.On("GetReservationByReservationIDForUpdate", mock.Anything, mock.Anything, int64(8)).Return(active, nil)
+	reservationRepo.On("GetReservationByReservationIDForUpdate", mock.Anything, mock.Anything, int64(9)).Return(confirmed, nil)
+	reservationRepo.On("GetReservationItemsByReservationID", mock.Anything, mock.Anything, int64(8)).Return(items, nil)
+	reservationRepo.On("GetExpiredReservationIDs", mock.Anything, checkoutAt, int64(0), mock.Anything).Return([]int64{8, 9}, nil).Once()
+	reservationRepo.On("GetExpiredReservationIDs", mock.Anything, checkoutAt, int64(0), mock.Anything).Return(nil, errors.New("error")).Once()
+	reservationRepo.On("UpdateReservationStatus", mock.Anything, mock.Anything, mock.MatchedBy(func(r repo.Reservation) bool {
+		return r.ReservationID == 8 && (r.Status == repo.ReservationStatusReleased || r.Status == repo.ReservationStatusExpired)
+	}), checkoutAt).Return(nil)
+	productRepo.On("AdjustProductReservedQty", mock.Anything, mock.Anything, int64(3), int64(-2)).Return(nil)
+	productRepo.On("AdjustProductReservedQty", mock.Anything, mock.Anything, int64(4), int64(-1)).Return(nil)
+
+	checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+		OrderPromoRepo:  noOrderPromos(),
+		BundleRepo:      noBundles(),
+		ProductRepo:     productRepo,
+		ReservationRepo: reservationRepo,
+		Clock:           clock.Fixed(checkoutAt),
+	})
+
+	ctx := context.Background()
+
+	res, err := checkoutUsecase.ReleaseReservation(ctx, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, repo.ReservationStatusReleased, res.Status)
+	assert.Equal(t, items, res.Items)
+
+	_, err = checkoutUsecase.ReleaseReservation(ctx, 9)
+	assert.ErrorIs(t, err, service.ErrReservationClosed)
+
+	// the confirmed reservation is skipped, only the active one expires
+	count, err := checkoutUsecase.ExpireReservations(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = checkoutUsecase.ExpireReservations(ctx)
+	assert.Error(t, err)
+
+	productRepo.AssertNumberOfCalls(t, "AdjustProductReservedQty", 4)
+	reservationRepo.AssertExpectations(t)
+}
+
+func TestExpireReservationsInBatches(t *testing.T) {
+	// a full first batch, where reservation 2 fails to expire, then a short
+	// one listed after the last id of the first
+	first := make([]int64, 100)
+	for i := range first {
+		first[i] = int64(i + 1)
+	}
+
+	productRepo := new(mockRepo.ProductRepository)
+	reservationRepo := new(mockRepo.ReservationRepository)
+
+	reservationRepo.On("BeginTx").Return(&sqlx.Tx{}, nil)
+	reservationRepo.On("RollbackTx", mock.Anything).Return(nil)
+	reservationRepo.On("CommitTx", mock.Anything).Return(nil)
+	reservationRepo.On("GetExpiredReservationIDs", mock.Anything, checkoutAt, int64(0), mock.Anything).Return(first, nil).Once()
+	reservationRepo.On("GetExpiredReservationIDs", mock.Anything, checkoutAt, int64(100), mock.Anything).Return([]int64{101}, nil).Once()
+	reservationRepo.On("GetReservationByReservationIDForUpdate", mock.Anything, mock.Anything, int64(2)).Return(repo.Reservation{}, errors.New("error"))
+	reservationRepo.On("GetReservationByReservationIDForUpdate", mock.Anything, mock.Anything, mock.Anything).Return(func(_ *sqlx.Tx, _ context.Context, reservationID int64) repo.Reservation {
+		return repo.Reservation{ReservationID: reservationID, Status: repo.ReservationStatusActive, ExpiresAt: checkoutAt.Add(-time.Minute)}
+	}, nil)
+	reservationRepo.On("GetReservationItemsByReservationID", mock.Anything, mock.Anything, mock.Anything).Return([]repo.ReservationItem{}, nil)
+	reservationRepo.On("UpdateReservationStatus", mock.Anything, mock.Anything, mock.MatchedBy(func(r repo.Reservation) bool {
+		return r.Status == repo.ReservationStatusExpired
+	}), checkoutAt).Return(nil)
+
+	checkoutUsecase := service.NewCheckoutUsecase(service.CheckoutUsecaseImpl{
+		OrderPromoRepo:  noOrderPromos(),
+		BundleRepo:      noBundles(),
+		ProductRepo:     productRepo,
+		ReservationRepo: reservationRepo,
+		Clock:           clock.Fixed(checkoutAt),
+	})
+
+	count, err := checkoutUsecase.ExpireReservations(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 100, count)
+
+	reservationRepo.AssertNumberOfCalls(t, "GetExpiredReservationIDs", 2)
+	reservationRepo.AssertNumberOfCalls(t, "UpdateReservationStatus", 100)
+	reservationRepo.AssertExpectations(t)
+}
+
+func TestReservationSweeper(t *testing.T) {
+	checkoutSvc := new(mockRepo.CheckoutUsecase)
+	swept := make(chan struct{}, 1)
+	checkoutSvc.On("ExpireReservations", mock.Anything).Return(1, nil).Run(func(args mock.Arguments) {
+		select {
+		case swept <- struct{}{}:
+		default:
+		}
+	})
+
+	sweeper := service.NewReservationSweeper(service.ReservationSweeperImpl{
+		CheckoutSvc: checkoutSvc,
+		Policy:      service.ReservationPolicy{SweepInterval: time.Millisecond},
+	})
+
+	sweeper.Start()
+
+	select {
+	case <-swept:
+	case <-time.After(time.Second):
+		t.Fatal("the sweeper didn't run")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, sweeper.Stop(ctx))
+
+	// a stopped sweeper stays stopped
+	calls := len(checkoutSvc.Calls)
+	sweeper.Start()
+	time.Sleep(10 * time.Millisecond)
+	assert.Len(t, checkoutSvc.Calls, calls)
+	assert.NoError(t, sweeper.Stop(ctx))
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/pkg/cursor"
+	"go.uber.org/dig"
+)
+
+// Reasons recorded on stock movements. Only the adjustment reasons can be
+// used by AdjustStock, the others are written by the code that moves stock.
+const (
+	StockReasonRestock    = "restock"
+	StockReasonCorrection = "correction"
+	StockReasonDamaged    = "damaged"
+	StockReasonLost       = "lost"
+	StockReasonReturned   = "returned"
+
+	StockReasonInitial      = "initial"
+	StockReasonSale         = "sale"
+	StockReasonReward       = "reward"
+	StockReasonCancellation = "cancellation"
+	StockReasonRefund       = "refund"
+)
+
+type (
+	StockMovementQuery struct {
+		ProductID int64
+		First     int
+		After     string
+	}
+
+	StockMovementEdge struct {
+		Cursor string             `json:"cursor"`
+		Node   repo.StockMovement `json:"node"`
+	}
+
+	StockMovementPage struct {
+		Edges    []StockMovementEdge `json:"edges"`
+		PageInfo PageInfo            `json:"page_info"`
+	}
+
+	StockUsecase interface {
+		GetStockMovements(ctx context.Context, form StockMovementQuery) (res StockMovementPage, err error)
+		ReconcileStock(ctx context.Context) (res []repo.StockDrift, err error)
+	}
+
+	StockUsecaseImpl struct {
+		dig.In
+		StockMovementRepo repo.StockMovementRepository
+	}
+)
+
+func NewStockUsecase(impl StockUsecaseImpl) StockUsecase {
+	return &impl
+}
+
+// GetStockMovements returns a product's movement history, newest first.
+func (s *StockUsecaseImpl) GetStockMovements(ctx context.Context, form StockMovementQuery) (res StockMovementPage, err error) {
+	filter := repo.StockMovementFilter{
+		ProductID: form.ProductID,
+		Limit:     pageSize(form.First) + 1,
+	}
+
+	if form.After != "" {
+		_, filter.AfterID, err = cursor.Decode(form.After)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	movements, err := s.StockMovementRepo.GetStockMovements(ctx, filter)
+	if err != nil {
+		log.Printf("error while do GetStockMovements %+v", err)
+		return res, err
+	}
+
+	if len(movements) > pageSize(form.First) {
+		movements = movements[:pageSize(form.First)]
+		res.PageInfo.HasNextPage = true
+	}
+
+	res.Edges = make([]StockMovementEdge, len(movements))
+	for i, v := range movements {
+		res.Edges[i] = StockMovementEdge{
+			Cursor: cursor.Encode("", v.MovementID),
+			Node:   v,
+		}
+	}
+
+	if len(res.Edges) > 0 {
+		res.PageInfo.EndCursor = res.Edges[len(res.Edges)-1].Cursor
+	}
+
+	return res, nil
+}
+
+// ReconcileStock recomputes qty from the ledger and returns the products that
+// drifted from it. Nothing is changed, fixing a drift is left to an operator.
+func (s *StockUsecaseImpl) ReconcileStock(ctx context.Context) (res []repo.StockDrift, err error) {
+	res, err = s.StockMovementRepo.GetStockDrift(ctx)
+	if err != nil {
+		log.Printf("error while do GetStockDrift %+v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func isAdjustmentReason(reason string) bool {
+	switch reason {
+	case StockReasonRestock, StockReasonCorrection, StockReasonDamaged, StockReasonLost, StockReasonReturned:
+		return true
+	default:
+		return false
+	}
+}
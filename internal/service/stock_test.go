@@ -0,0 +1,112 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockRepo "github.com/learn/api-shop/internal/generated/mock"
+	"github.com/learn/api-shop/internal/repo"
+	"github.com/learn/api-shop/internal/service"
+	"github.com/learn/api-shop/pkg/cursor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStockGetStockMovements(t *testing.T) {
+	createdAt := time.Date(2023, 6, 3, 10, 0, 0, 0, time.UTC)
+	sale := repo.StockMovement{MovementID: 9, ProductID: 1, Delta: -3, Reason: service.StockReasonSale, OrderID: 7, Actor: service.DefaultActor, CreatedAt: createdAt}
+	opening := repo.StockMovement{MovementID: 4, ProductID: 1, Delta: 10, Reason: "opening", Actor: "migration", CreatedAt: createdAt}
+
+	tests := []struct {
+		name          string
+		form          service.StockMovementQuery
+		mockSetupFunc func(stockMovementRepo *mockRepo.StockMovementRepository)
+		expectedResp  service.StockMovementPage
+		wantErr       bool
+	}{
+		{
+			name: "first page has a next page",
+			form: service.StockMovementQuery{ProductID: 1, First: 1},
+			mockSetupFunc: func(stockMovementRepo *mockRepo.StockMovementRepository) {
+				stockMovementRepo.On("GetStockMovements", mock.Anything, repo.StockMovementFilter{ProductID: 1, Limit: 2}).
+					Return([]repo.StockMovement{sale, opening}, nil)
+			},
+			expectedResp: service.StockMovementPage{
+				Edges:    []service.StockMovementEdge{{Cursor: cursor.Encode("", 9), Node: sale}},
+				PageInfo: service.PageInfo{EndCursor: cursor.Encode("", 9), HasNextPage: true},
+			},
+		},
+		{
+			name: "after cursor is passed to the repository",
+			form: service.StockMovementQuery{ProductID: 1, First: 5, After: cursor.Encode("", 9)},
+			mockSetupFunc: func(stockMovementRepo *mockRepo.StockMovementRepository) {
+				stockMovementRepo.On("GetStockMovements", mock.Anything, repo.StockMovementFilter{ProductID: 1, AfterID: 9, Limit: 6}).
+					Return([]repo.StockMovement{opening}, nil)
+			},
+			expectedResp: service.StockMovementPage{
+				Edges:    []service.StockMovementEdge{{Cursor: cursor.Encode("", 4), Node: opening}},
+				PageInfo: service.PageInfo{EndCursor: cursor.Encode("", 4)},
+			},
+		},
+		{
+			name:    "invalid cursor",
+			form:    service.StockMovementQuery{ProductID: 1, After: "!!"},
+			wantErr: true,
+		},
+		{
+			name: "error while GetStockMovements",
+			form: service.StockMovementQuery{ProductID: 1},
+			mockSetupFunc: func(stockMovementRepo *mockRepo.StockMovementRepository) {
+				stockMovementRepo.On("GetStockMovements", mock.Anything, mock.Anything).Return(nil, errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stockMovementRepo := new(mockRepo.StockMovementRepository)
+
+			if tt.mockSetupFunc != nil {
+				tt.mockSetupFunc(stockMovementRepo)
+			}
+
+			stockUsecase := service.NewStockUsecase(service.StockUsecaseImpl{
+				StockMovementRepo: stockMovementRepo,
+			})
+
+			res, err := stockUsecase.GetStockMovements(context.Background(), tt.form)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResp, res)
+			}
+
+			stockMovementRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStockReconcileStock(t *testing.T) {
+	drift := []repo.StockDrift{{ProductID: 4, Sku: "234234", Name: "Raspberry Pi B", Qty: 2, LedgerQty: 1}}
+
+	stockMovementRepo := new(mockRepo.StockMovementRepository)
+	stockMovementRepo.On("GetStockDrift", mock.Anything).Return(drift, nil).Once()
+	stockMovementRepo.On("GetStockDrift", mock.Anything).Return(nil, errors.New("error")).Once()
+
+	stockUsecase := service.NewStockUsecase(service.StockUsecaseImpl{
+		StockMovementRepo: stockMovementRepo,
+	})
+
+	res, err := stockUsecase.ReconcileStock(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, drift, res)
+
+	_, err = stockUsecase.ReconcileStock(context.Background())
+	assert.Error(t, err)
+
+	stockMovementRepo.AssertExpectations(t)
+}
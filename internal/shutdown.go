@@ -7,18 +7,25 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/learn/api-shop/internal/service"
 	"go.uber.org/dig"
 )
 
 func Shutdown(p struct {
 	dig.In
-	Pg  *sqlx.DB
-	Srv *http.Server
+	Pg      *sqlx.DB
+	Srv     *http.Server
+	Sweeper *service.ReservationSweeper
 }) error {
 	log.Printf("Shutdown at %s\n", time.Now().String())
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// the sweeper goes first so no sweep is left running on a closed pool
+	if err := p.Sweeper.Stop(ctx); err != nil {
+		return err
+	}
+
 	if err := p.Pg.Close(); err != nil {
 		return err
 	}
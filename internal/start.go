@@ -5,14 +5,18 @@ import (
 	"net/http"
 
 	"github.com/learn/api-shop/internal/infra"
+	"github.com/learn/api-shop/internal/service"
 	"go.uber.org/dig"
 )
 
 func Start(p struct {
 	dig.In
-	Cfg *infra.MuxCfg
-	Srv *http.Server
+	Cfg     *infra.MuxCfg
+	Srv     *http.Server
+	Sweeper *service.ReservationSweeper
 }) (err error) {
+	p.Sweeper.Start()
+
 	log.Println("Server Start ", p.Cfg.Address)
 	return p.Srv.ListenAndServe()
 }
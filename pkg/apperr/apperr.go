@@ -0,0 +1,89 @@
+// Package apperr holds errors the shop reports to its clients. Each carries
+// a Code clients can match on instead of parsing the message.
+package apperr
+
+import "errors"
+
+// Code tells clients what kind of failure an Error is.
+type Code string
+
+const (
+	CodeOutOfStock         Code = "OUT_OF_STOCK"
+	CodeProductNotFound    Code = "PRODUCT_NOT_FOUND"
+	CodeProductUnavailable Code = "PRODUCT_UNAVAILABLE"
+	CodePromoInvalid       Code = "PROMO_INVALID"
+	CodeInvalidInput       Code = "INVALID_INPUT"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeConflict           Code = "CONFLICT"
+	CodeEmptyCart          Code = "EMPTY_CART"
+	CodeInvalidQty         Code = "INVALID_QTY"
+	CodeTooManyLines       Code = "TOO_MANY_LINES"
+	// CodeInternal is every failure without a code of its own, like a
+	// database outage. Its details are never shown to clients.
+	CodeInternal Code = "INTERNAL"
+)
+
+// Error is a failure clients may be told about.
+type Error struct {
+	Code    Code
+	Message string
+	// Fields are machine readable details, like the product_id the error is
+	// about.
+	Fields map[string]interface{}
+}
+
+// New returns an Error with no fields. Sentinel errors are declared with it
+// and wrapped with fmt.Errorf("%w: ...") for the details.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// With returns a copy of e carrying the field as well, so sentinels are never
+// changed.
+func (e *Error) With(key string, value interface{}) *Error {
+	fields := make(map[string]interface{}, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+
+	fields[key] = value
+	return &Error{Code: e.Code, Message: e.Message, Fields: fields}
+}
+
+// Is reports whether target is the sentinel e was made from, so a copy made by
+// With still matches it with errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || len(t.Fields) != 0 {
+		return false
+	}
+
+	return t.Code == e.Code && t.Message == e.Message
+}
+
+// Extensions is the code and fields of e, in the shape GraphQL reports as an
+// error's extensions.
+func (e *Error) Extensions() map[string]interface{} {
+	ext := make(map[string]interface{}, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		ext[k] = v
+	}
+
+	ext["code"] = string(e.Code)
+	return ext
+}
+
+// CodeOf is the code of the first Error in err's chain, CodeInternal when
+// there is none.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return CodeInternal
+}
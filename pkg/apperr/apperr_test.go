@@ -0,0 +1,59 @@
+package apperr_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/learn/api-shop/pkg/apperr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOf(t *testing.T) {
+	errNotFound := apperr.New(apperr.CodeNotFound, "order not found")
+
+	tests := []struct {
+		name string
+		err  error
+		want apperr.Code
+	}{
+		{name: "error", err: errNotFound, want: apperr.CodeNotFound},
+		{name: "wrapped error", err: fmt.Errorf("%w: 7", errNotFound), want: apperr.CodeNotFound},
+		{name: "plain error", err: errors.New("connection refused"), want: apperr.CodeInternal},
+		{name: "nil", want: apperr.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, apperr.CodeOf(tt.err))
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	errOutOfStock := apperr.New(apperr.CodeOutOfStock, "out of stock")
+
+	err := errOutOfStock.With("product_id", int64(3)).With("available_qty", int64(1))
+
+	assert.Equal(t, "out of stock", err.Error())
+	assert.Equal(t, map[string]interface{}{
+		"code":          "OUT_OF_STOCK",
+		"product_id":    int64(3),
+		"available_qty": int64(1),
+	}, err.Extensions())
+
+	// the sentinel is left alone
+	assert.Equal(t, map[string]interface{}{"code": "OUT_OF_STOCK"}, errOutOfStock.Extensions())
+}
+
+func TestIs(t *testing.T) {
+	errInvalidQty := apperr.New(apperr.CodeInvalidQty, "invalid checkout")
+	errEmptyCart := apperr.New(apperr.CodeEmptyCart, "invalid checkout")
+
+	err := fmt.Errorf("%w: qty of product 3 must be at least 1", errInvalidQty.With("product_id", int64(3)))
+
+	assert.ErrorIs(t, err, errInvalidQty)
+	assert.NotErrorIs(t, err, errEmptyCart)
+	// a copy with fields is not a sentinel other errors match
+	assert.NotErrorIs(t, errInvalidQty, errInvalidQty.With("product_id", int64(3)))
+}
@@ -0,0 +1,26 @@
+package clock
+
+import "time"
+
+// Clock tells the current time. Services take one so tests can pin "now".
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// New returns the wall clock.
+func New() Clock {
+	return realClock{}
+}
+
+// Fixed is a Clock that always returns the same instant.
+type Fixed time.Time
+
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
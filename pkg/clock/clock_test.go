@@ -0,0 +1,26 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/learn/api-shop/pkg/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixed(t *testing.T) {
+	at := time.Date(2023, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	c := clock.Fixed(at)
+
+	assert.Equal(t, at, c.Now())
+	assert.Equal(t, at, c.Now())
+}
+
+func TestNew(t *testing.T) {
+	before := time.Now()
+	now := clock.New().Now()
+
+	assert.False(t, now.Before(before))
+	assert.False(t, now.After(time.Now()))
+}
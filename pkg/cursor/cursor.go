@@ -0,0 +1,35 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode builds an opaque keyset cursor from the sort value of the last row
+// and its id, the id being the tie breaker for equal sort values.
+func Encode(value string, id int64) string {
+	return base64.URLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10) + ":" + value))
+}
+
+func Decode(c string) (value string, id int64, err error) {
+	raw, err := base64.URLEncoding.DecodeString(c)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return "", 0, ErrInvalidCursor
+	}
+
+	id, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+
+	return parts[1], id, nil
+}
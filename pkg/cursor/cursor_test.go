@@ -0,0 +1,54 @@
+package cursor_test
+
+import (
+	"testing"
+
+	"github.com/learn/api-shop/pkg/cursor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		id    int64
+	}{
+		{
+			name:  "plain value",
+			value: "Google Home",
+			id:    1,
+		},
+		{
+			name:  "value with separator",
+			value: "a:b:c",
+			id:    42,
+		},
+		{
+			name:  "empty value",
+			value: "",
+			id:    7,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, id, err := cursor.Decode(cursor.Encode(tc.value, tc.id))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.value, value)
+			assert.Equal(t, tc.id, id)
+		})
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	testCases := []string{
+		"not base64!",
+		"bm9zZXBhcmF0b3I=",
+		"YWJjOmRlZg==",
+	}
+
+	for _, c := range testCases {
+		_, _, err := cursor.Decode(c)
+		assert.ErrorIs(t, err, cursor.ErrInvalidCursor, c)
+	}
+}
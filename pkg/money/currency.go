@@ -0,0 +1,76 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type RoundingMode int
+
+const (
+	// HalfUp rounds ties away from zero, 0.125 -> 0.13.
+	HalfUp RoundingMode = iota
+	// HalfEven rounds ties to the even neighbour (banker's rounding),
+	// 0.125 -> 0.12 and 0.135 -> 0.14.
+	HalfEven
+)
+
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "half_up":
+		return HalfUp, nil
+	case "half_even", "bankers":
+		return HalfEven, nil
+	default:
+		return HalfUp, fmt.Errorf("money: unknown rounding mode %q", s)
+	}
+}
+
+func (m RoundingMode) String() string {
+	if m == HalfEven {
+		return "half_even"
+	}
+
+	return "half_up"
+}
+
+// Currency carries the ISO 4217 code of the amounts being priced and the rule
+// used to round them to the currency's minor unit.
+type Currency struct {
+	Code     string
+	Places   int32
+	Rounding RoundingMode
+}
+
+var DefaultCurrency = Currency{
+	Code:     "USD",
+	Places:   2,
+	Rounding: HalfUp,
+}
+
+func (c Currency) Round(d Decimal) Decimal {
+	return d.Round(c.Places, c.Rounding)
+}
+
+// divRound divides n by d rounding the quotient with mode.
+func divRound(n, d *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+
+	cmp := twice.Cmp(new(big.Int).Abs(d))
+	if cmp < 0 || (cmp == 0 && mode == HalfEven && q.Bit(0) == 0) {
+		return q
+	}
+
+	if (n.Sign() < 0) != (d.Sign() < 0) {
+		return q.Sub(q, big.NewInt(1))
+	}
+
+	return q.Add(q, big.NewInt(1))
+}
@@ -0,0 +1,41 @@
+package money_test
+
+import (
+	"testing"
+
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoundingMode(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected money.RoundingMode
+		wantErr  bool
+	}{
+		{in: "", expected: money.HalfUp},
+		{in: "half_up", expected: money.HalfUp},
+		{in: "HALF_EVEN", expected: money.HalfEven},
+		{in: "bankers", expected: money.HalfEven},
+		{in: "ceiling", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		mode, err := money.ParseRoundingMode(tc.in)
+		if tc.wantErr {
+			assert.Error(t, err, tc.in)
+			continue
+		}
+
+		assert.NoError(t, err, tc.in)
+		assert.Equal(t, tc.expected, mode, tc.in)
+	}
+}
+
+func TestCurrencyRound(t *testing.T) {
+	half := money.MustParse("2.345")
+
+	assert.Equal(t, "2.35", money.DefaultCurrency.Round(half).String())
+	assert.Equal(t, "2.34", money.Currency{Code: "USD", Places: 2, Rounding: money.HalfEven}.Round(half).String())
+	assert.Equal(t, "2", money.Currency{Code: "JPY", Places: 0, Rounding: money.HalfUp}.Round(half).String())
+}
@@ -0,0 +1,258 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of fractional digits a Decimal keeps internally. It is
+// wider than the numeric(50, 3) columns so intermediate results such as a
+// percentage of a price are exact before they get rounded to a Currency.
+const Scale = 6
+
+// unitsPerOne is 10^Scale, the units of 1.
+const unitsPerOne = 1_000_000
+
+var (
+	ErrInvalidDecimal = errors.New("money: invalid decimal")
+	ErrOverflow       = errors.New("money: decimal out of range")
+
+	scaleFactor = big.NewInt(unitsPerOne)
+)
+
+// Decimal is a fixed point number stored as an integer count of 10^-Scale
+// units. The zero value is 0. Arithmetic whose result doesn't fit the units
+// panics with an error wrapping ErrOverflow, like integer division by zero
+// panics, instead of silently wrapping around.
+type Decimal struct {
+	units int64
+}
+
+func NewFromInt(i int64) Decimal {
+	if i > math.MaxInt64/unitsPerOne || i < math.MinInt64/unitsPerOne {
+		overflow("%d", i)
+	}
+
+	return Decimal{units: i * unitsPerOne}
+}
+
+func NewFromFloat(f float64) Decimal {
+	return MustParse(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Parse reads a decimal string such as "49.990", "-1.5" or "1e3". Digits past
+// Scale are rounded half to even.
+func Parse(s string) (Decimal, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+
+	n := new(big.Int).Mul(r.Num(), scaleFactor)
+	units := divRound(n, r.Denom(), HalfEven)
+	if !units.IsInt64() {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+
+	return Decimal{units: units.Int64()}, nil
+}
+
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	units := d.units + o.units
+	if (o.units > 0 && units < d.units) || (o.units < 0 && units > d.units) {
+		overflow("%s + %s", d, o)
+	}
+
+	return Decimal{units: units}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	units := d.units - o.units
+	if (o.units > 0 && units > d.units) || (o.units < 0 && units < d.units) {
+		overflow("%s - %s", d, o)
+	}
+
+	return Decimal{units: units}
+}
+
+func (d Decimal) Neg() Decimal {
+	if d.units == math.MinInt64 {
+		overflow("-%s", d)
+	}
+
+	return Decimal{units: -d.units}
+}
+
+func (d Decimal) MulInt(n int64) Decimal {
+	units := d.units * n
+	// MinInt64 / -1 is MinInt64 again, so that case doesn't show in the
+	// division
+	if d.units != 0 && (units/d.units != n || (d.units == -1 && n == math.MinInt64)) {
+		overflow("%s * %d", d, n)
+	}
+
+	return Decimal{units: units}
+}
+
+// Mul multiplies two decimals, rounding half to even at Scale.
+func (d Decimal) Mul(o Decimal) Decimal {
+	n := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(o.units))
+	return fromUnits(divRound(n, scaleFactor, HalfEven), "%s * %s", d, o)
+}
+
+// Div divides two decimals, rounding half to even at Scale. Dividing by zero
+// panics like integer division does.
+func (d Decimal) Div(o Decimal) Decimal {
+	n := new(big.Int).Mul(big.NewInt(d.units), scaleFactor)
+	return fromUnits(divRound(n, big.NewInt(o.units), HalfEven), "%s / %s", d, o)
+}
+
+// Round rounds to the given number of fractional digits with mode.
+func (d Decimal) Round(places int32, mode RoundingMode) Decimal {
+	if places >= Scale {
+		return d
+	}
+
+	if places < 0 {
+		places = 0
+	}
+
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(Scale-places)), nil)
+	q := divRound(big.NewInt(d.units), factor, mode)
+	return fromUnits(q.Mul(q, factor), "%s rounded to %d places", d, places)
+}
+
+// fromUnits is the Decimal of units, which overflows when they don't fit an
+// int64. format and args describe the operation for the panic.
+func fromUnits(units *big.Int, format string, args ...interface{}) Decimal {
+	if !units.IsInt64() {
+		overflow(format, args...)
+	}
+
+	return Decimal{units: units.Int64()}
+}
+
+func overflow(format string, args ...interface{}) {
+	panic(fmt.Errorf("%w: %s", ErrOverflow, fmt.Sprintf(format, args...)))
+}
+
+func (d Decimal) Cmp(o Decimal) int {
+	switch {
+	case d.units < o.units:
+		return -1
+	case d.units > o.units:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+func (d Decimal) IsNegative() bool {
+	return d.units < 0
+}
+
+// IntPart returns the integer part, truncated toward zero.
+func (d Decimal) IntPart() int64 {
+	return d.units / scaleFactor.Int64()
+}
+
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String formats the decimal without trailing fractional zeros.
+func (d Decimal) String() string {
+	return strings.TrimSuffix(strings.TrimRight(d.format(Scale), "0"), ".")
+}
+
+// StringFixed formats the decimal with exactly places fractional digits,
+// rounding half up when digits have to be dropped.
+func (d Decimal) StringFixed(places int32) string {
+	if places > Scale {
+		return d.format(Scale) + strings.Repeat("0", int(places-Scale))
+	}
+
+	return d.Round(places, HalfUp).format(places)
+}
+
+func (d Decimal) format(places int32) string {
+	units := d.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+	}
+
+	abs := new(big.Int).Abs(big.NewInt(units)).String()
+	if len(abs) <= Scale {
+		abs = strings.Repeat("0", Scale-len(abs)+1) + abs
+	}
+
+	intPart, frac := abs[:len(abs)-Scale], abs[len(abs)-Scale:]
+	if places == 0 {
+		return sign + intPart
+	}
+
+	return sign + intPart + "." + frac[:places]
+}
+
+// Scan implements sql.Scanner so a Decimal can be read from numeric columns.
+func (d *Decimal) Scan(src interface{}) (err error) {
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+	case []byte:
+		*d, err = Parse(string(v))
+	case string:
+		*d, err = Parse(v)
+	case int64:
+		*d = NewFromInt(v)
+	case float64:
+		*d = NewFromFloat(v)
+	default:
+		err = fmt.Errorf("%w: cannot scan %T", ErrInvalidDecimal, src)
+	}
+
+	return err
+}
+
+// Value implements driver.Valuer, writing the exact decimal text so Postgres
+// numeric columns never see a binary float.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// MarshalJSON encodes the decimal as a JSON string to keep it exact.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Decimal) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(b, &s); err != nil {
+		// plain JSON numbers are accepted as well
+		s = string(b)
+	}
+
+	*d, err = Parse(s)
+	return err
+}
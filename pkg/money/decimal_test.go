@@ -0,0 +1,183 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/learn/api-shop/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAndString(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected string
+		wantErr  bool
+	}{
+		{in: "49.990", expected: "49.99"},
+		{in: "30.000", expected: "30"},
+		{in: "-1.5", expected: "-1.5"},
+		{in: "0.000001", expected: "0.000001"},
+		{in: "0.0000005", expected: "0"},
+		{in: "0.0000015", expected: "0.000002"},
+		{in: "1e3", expected: "1000"},
+		{in: "not a number", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		d, err := money.Parse(tc.in)
+		if tc.wantErr {
+			assert.Error(t, err, tc.in)
+			continue
+		}
+
+		assert.NoError(t, err, tc.in)
+		assert.Equal(t, tc.expected, d.String(), tc.in)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	price := money.MustParse("109.5")
+
+	subtotal := price.MulInt(3)
+	assert.Equal(t, "328.5", subtotal.String())
+
+	discount := subtotal.Mul(money.NewFromInt(10)).Div(money.NewFromInt(100))
+	assert.Equal(t, "32.85", discount.String())
+	assert.Equal(t, "295.65", subtotal.Sub(discount).String())
+
+	assert.Equal(t, "0.3", money.NewFromFloat(0.1).Add(money.NewFromFloat(0.2)).String())
+	assert.Equal(t, "0.333333", money.NewFromInt(1).Div(money.NewFromInt(3)).String())
+	assert.Equal(t, "-5", money.NewFromInt(5).Neg().String())
+	assert.Equal(t, int64(5399), money.MustParse("5399.99").IntPart())
+	assert.Equal(t, 5399.99, money.MustParse("5399.99").Float64())
+	assert.Equal(t, 1, money.NewFromInt(2).Cmp(money.NewFromInt(1)))
+	assert.Equal(t, -1, money.NewFromInt(1).Cmp(money.NewFromInt(2)))
+	assert.Equal(t, 0, money.NewFromInt(1).Cmp(money.MustParse("1.000")))
+	assert.True(t, money.Decimal{}.IsZero())
+	assert.True(t, money.NewFromInt(-1).IsNegative())
+}
+
+func TestOverflow(t *testing.T) {
+	max := money.MustParse("9223372036854.775807")
+	min := money.MustParse("-9223372036854.775808")
+	unit := money.MustParse("0.000001")
+
+	tests := []struct {
+		name string
+		op   func() money.Decimal
+	}{
+		{name: "add", op: func() money.Decimal { return max.Add(unit) }},
+		{name: "sub", op: func() money.Decimal { return min.Sub(unit) }},
+		{name: "neg", op: func() money.Decimal { return min.Neg() }},
+		{name: "mul int", op: func() money.Decimal { return max.MulInt(2) }},
+		{name: "mul int of the smallest unit", op: func() money.Decimal { return unit.Neg().MulInt(-9223372036854775808) }},
+		{name: "mul", op: func() money.Decimal { return max.Mul(money.NewFromInt(2)) }},
+		{name: "div", op: func() money.Decimal { return max.Div(money.MustParse("0.5")) }},
+		{name: "round", op: func() money.Decimal { return max.Round(2, money.HalfUp) }},
+		{name: "from int", op: func() money.Decimal { return money.NewFromInt(9223372036855) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				err, _ := recover().(error)
+				assert.ErrorIs(t, err, money.ErrOverflow)
+			}()
+
+			tt.op()
+			t.Error("expected a panic")
+		})
+	}
+
+	// the edges themselves still work
+	assert.Equal(t, "9223372036854.775807", min.Add(max).Add(max).Add(unit).String())
+	assert.Equal(t, "-9223372036854.775807", max.Neg().String())
+	assert.Equal(t, "-9223372036854.775808", unit.MulInt(-9223372036854775808).String())
+	assert.Equal(t, "9223372036854", money.NewFromInt(9223372036854).String())
+	assert.Equal(t, "9223372036854.77", max.Sub(money.MustParse("0.005807")).Round(2, money.HalfEven).String())
+}
+
+func TestRound(t *testing.T) {
+	testCases := []struct {
+		in       string
+		places   int32
+		mode     money.RoundingMode
+		expected string
+	}{
+		{in: "0.125", places: 2, mode: money.HalfUp, expected: "0.13"},
+		{in: "0.125", places: 2, mode: money.HalfEven, expected: "0.12"},
+		{in: "0.135", places: 2, mode: money.HalfEven, expected: "0.14"},
+		{in: "-0.125", places: 2, mode: money.HalfUp, expected: "-0.13"},
+		{in: "-0.125", places: 2, mode: money.HalfEven, expected: "-0.12"},
+		{in: "7.4985", places: 2, mode: money.HalfUp, expected: "7.5"},
+		{in: "7.4949", places: 2, mode: money.HalfUp, expected: "7.49"},
+		{in: "2.5", places: 0, mode: money.HalfEven, expected: "2"},
+		{in: "2.5", places: 0, mode: money.HalfUp, expected: "3"},
+		{in: "1.234567", places: 8, mode: money.HalfUp, expected: "1.234567"},
+	}
+
+	for _, tc := range testCases {
+		result := money.MustParse(tc.in).Round(tc.places, tc.mode)
+		assert.Equal(t, tc.expected, result.String(), "%s at %d %s", tc.in, tc.places, tc.mode)
+	}
+}
+
+func TestStringFixed(t *testing.T) {
+	assert.Equal(t, "30.00", money.NewFromInt(30).StringFixed(2))
+	assert.Equal(t, "0.13", money.MustParse("0.125").StringFixed(2))
+	assert.Equal(t, "-1.50", money.MustParse("-1.5").StringFixed(2))
+	assert.Equal(t, "2", money.MustParse("1.5").StringFixed(0))
+	assert.Equal(t, "1.50000000", money.MustParse("1.5").StringFixed(8))
+}
+
+func TestScanAndValue(t *testing.T) {
+	testCases := []struct {
+		name     string
+		src      interface{}
+		expected string
+		wantErr  bool
+	}{
+		{name: "numeric bytes", src: []byte("109.500"), expected: "109.5"},
+		{name: "string", src: "5399.990", expected: "5399.99"},
+		{name: "int64", src: int64(4), expected: "4"},
+		{name: "float64", src: 2.2, expected: "2.2"},
+		{name: "null", src: nil, expected: "0"},
+		{name: "unsupported type", src: true, wantErr: true},
+		{name: "invalid text", src: "not a float", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var d money.Decimal
+			err := d.Scan(tc.src)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, money.ErrInvalidDecimal)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, d.String())
+
+			v, err := d.Value()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(map[string]money.Decimal{"total": money.MustParse("295.65")})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"total":"295.65"}`, string(b))
+
+	var out struct {
+		A money.Decimal `json:"a"`
+		B money.Decimal `json:"b"`
+	}
+	err = json.Unmarshal([]byte(`{"a":"49.99","b":10.5}`), &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "49.99", out.A.String())
+	assert.Equal(t, "10.5", out.B.String())
+}
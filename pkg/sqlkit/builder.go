@@ -12,3 +12,9 @@ func ReplaceSQL(old, searchPattern string) string {
 	}
 	return old
 }
+
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -36,3 +36,30 @@ func TestReplaceSQL(t *testing.T) {
 		}
 	}
 }
+
+func TestEscapeLike(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected string
+	}{
+		{
+			in:       "Google Home",
+			expected: "Google Home",
+		},
+		{
+			in:       "100%_off",
+			expected: `100\%\_off`,
+		},
+		{
+			in:       `back\slash`,
+			expected: `back\\slash`,
+		},
+	}
+
+	for _, tc := range testCases {
+		result := sqlkit.EscapeLike(tc.in)
+		if result != tc.expected {
+			t.Errorf("Failed test case: %v. Got %v but expected %v", tc.in, result, tc.expected)
+		}
+	}
+}